@@ -8,6 +8,37 @@ CREATE TABLE `roles` (
   UNIQUE KEY `id_UNIQUE` (`id`),
   UNIQUE KEY `name_UNIQUE` (`name`)
 ) ENGINE=InnoDB AUTO_INCREMENT=4 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `holiday_calendars` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `region` varchar(20) NOT NULL DEFAULT '',
+  `is_default` tinyint(1) NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name_UNIQUE` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+INSERT INTO `holiday_calendars` (`name`, `is_default`) VALUES ('Default', 1);
+CREATE TABLE `sites` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `holiday_calendar_id` int(11) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name_UNIQUE` (`name`),
+  CONSTRAINT `fk_sites_holiday_calendar` FOREIGN KEY (`holiday_calendar_id`) REFERENCES `holiday_calendars` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `kiosks` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `site_id` int(11) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `is_active` tinyint(1) NOT NULL DEFAULT 1,
+  `last_seen_at` datetime DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `token_hash_UNIQUE` (`token_hash`),
+  CONSTRAINT `fk_kiosks_site` FOREIGN KEY (`site_id`) REFERENCES `sites` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 CREATE TABLE `users` (
   `id` int(11) NOT NULL AUTO_INCREMENT,
   `first_name` varchar(45) DEFAULT NULL,
@@ -18,11 +49,22 @@ CREATE TABLE `users` (
   `is_active` tinyint(4) NOT NULL DEFAULT 0,
   `role_id` int(11) NOT NULL DEFAULT 1,
   `manager_id` int(11) DEFAULT NULL,
+  `time_zone` varchar(64) NOT NULL DEFAULT 'UTC',
+  `contracted_hours` decimal(5,2) NOT NULL DEFAULT 40.00,
+  `site_id` int(11) DEFAULT NULL,
+  `pin_hash` varbinary(60) DEFAULT NULL,
+  `pin_failed_attempts` int(11) NOT NULL DEFAULT 0,
+  `pin_locked_until` datetime DEFAULT NULL,
+  `holiday_calendar_id` int(11) DEFAULT NULL,
+  `token_version` int(11) NOT NULL DEFAULT 0,
   PRIMARY KEY (`id`),
   UNIQUE KEY `id_UNIQUE` (`id`),
   UNIQUE KEY `email_UNIQUE` (`email`),
   KEY `fk_users_1_idx` (`role_id`),
-  CONSTRAINT `fk_role_id` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`) ON DELETE NO ACTION ON UPDATE NO ACTION
+  KEY `site_idx` (`site_id`),
+  CONSTRAINT `fk_role_id` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`) ON DELETE NO ACTION ON UPDATE NO ACTION,
+  CONSTRAINT `fk_users_site` FOREIGN KEY (`site_id`) REFERENCES `sites` (`id`) ON DELETE SET NULL,
+  CONSTRAINT `fk_users_holiday_calendar` FOREIGN KEY (`holiday_calendar_id`) REFERENCES `holiday_calendars` (`id`) ON DELETE SET NULL
 ) ENGINE=InnoDB AUTO_INCREMENT=61 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 CREATE TABLE `user_invitations` (
   `token` varchar(255) NOT NULL,
@@ -38,9 +80,20 @@ CREATE TABLE `timestamps` (
   `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
   `updated_at` timestamp NOT NULL DEFAULT current_timestamp(),
   `version` int(11) NOT NULL DEFAULT 0,
+  `status` varchar(16) NOT NULL DEFAULT 'approved',
+  `is_manual` tinyint(1) NOT NULL DEFAULT 0,
+  `reason` varchar(255) DEFAULT NULL,
+  `decided_by` int(11) DEFAULT NULL,
+  `decided_at` timestamp NULL DEFAULT NULL,
+  `is_auto_generated` tinyint(1) NOT NULL DEFAULT 0,
+  `kiosk_id` int(11) DEFAULT NULL,
   PRIMARY KEY (`id`),
   KEY `fk_user_idx` (`user_id`),
-  CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+  KEY `status_idx` (`status`),
+  KEY `user_status_time_idx` (`user_id`,`status`,`time`),
+  KEY `user_type_time_idx` (`user_id`,`stamp_type`,`status`,`time`),
+  CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_timestamps_kiosk` FOREIGN KEY (`kiosk_id`) REFERENCES `kiosks` (`id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=147 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 CREATE TABLE `password_resets` (
   `token` varchar(255) NOT NULL,
@@ -48,3 +101,331 @@ CREATE TABLE `password_resets` (
   `expiry` timestamp NOT NULL,
   PRIMARY KEY (`token`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `idempotency_keys` (
+  `user_id` int(11) NOT NULL,
+  `idempotency_key` varchar(255) NOT NULL,
+  `method` varchar(10) NOT NULL,
+  `path` varchar(255) NOT NULL,
+  `request_hash` char(64) NOT NULL,
+  `status_code` int(11) NOT NULL DEFAULT 0,
+  `content_type` varchar(255) NOT NULL DEFAULT '',
+  `body` mediumblob DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `expires_at` timestamp NOT NULL,
+  PRIMARY KEY (`user_id`,`idempotency_key`),
+  KEY `expires_at_idx` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `pay_periods` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `starts_at` datetime NOT NULL,
+  `ends_at` datetime NOT NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'draft',
+  `version` int(11) NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `user_status_range_idx` (`user_id`,`status`,`starts_at`,`ends_at`),
+  CONSTRAINT `fk_pay_periods_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `pay_period_transitions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `period_id` int(11) NOT NULL,
+  `from_status` varchar(16) NOT NULL,
+  `to_status` varchar(16) NOT NULL,
+  `actor_id` int(11) NOT NULL,
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `period_idx` (`period_id`),
+  CONSTRAINT `fk_pay_period_transitions_period` FOREIGN KEY (`period_id`) REFERENCES `pay_periods` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `overtime_policies` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `daily_threshold` int(11) NOT NULL DEFAULT 0,
+  `weekly_threshold` int(11) NOT NULL DEFAULT 0,
+  `daily_multiplier` decimal(4,2) NOT NULL DEFAULT 1.00,
+  `weekly_multiplier` decimal(4,2) NOT NULL DEFAULT 1.00,
+  `rest_day_multiplier` decimal(4,2) NOT NULL DEFAULT 1.00,
+  `holiday_multiplier` decimal(4,2) NOT NULL DEFAULT 1.00,
+  `rest_days` varchar(20) NOT NULL DEFAULT '',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `overtime_policy_assignments` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `policy_id` int(11) NOT NULL,
+  `user_id` int(11) DEFAULT NULL,
+  `role_id` int(11) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `user_id_UNIQUE` (`user_id`),
+  UNIQUE KEY `role_id_UNIQUE` (`role_id`),
+  CONSTRAINT `fk_overtime_assignments_policy` FOREIGN KEY (`policy_id`) REFERENCES `overtime_policies` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_overtime_assignments_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_overtime_assignments_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `public_holidays` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `calendar_id` int(11) NOT NULL,
+  `date` date NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `kind` varchar(16) NOT NULL DEFAULT 'public',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `calendar_date_UNIQUE` (`calendar_id`, `date`),
+  CONSTRAINT `fk_public_holidays_calendar` FOREIGN KEY (`calendar_id`) REFERENCES `holiday_calendars` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `stamp_types` (
+  `name` varchar(32) NOT NULL,
+  `category` varchar(16) NOT NULL,
+  `description` varchar(255) NOT NULL DEFAULT '',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `stamp_transitions` (
+  `from_type` varchar(32) NOT NULL,
+  `to_type` varchar(32) NOT NULL,
+  PRIMARY KEY (`from_type`,`to_type`),
+  CONSTRAINT `fk_stamp_transitions_from` FOREIGN KEY (`from_type`) REFERENCES `stamp_types` (`name`) ON DELETE CASCADE,
+  CONSTRAINT `fk_stamp_transitions_to` FOREIGN KEY (`to_type`) REFERENCES `stamp_types` (`name`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+INSERT INTO `stamp_types` (`name`, `category`, `description`) VALUES
+('sign-in','start','Starts a shift'),
+('sign-out','end','Ends a shift'),
+('start-break','unpaid-break','Starts an unpaid break'),
+('end-break','work','Ends a break');
+INSERT INTO `stamp_transitions` (`from_type`, `to_type`) VALUES
+('sign-out','sign-in'),
+('sign-in','sign-out'),
+('sign-in','start-break'),
+('start-break','end-break'),
+('end-break','sign-out'),
+('end-break','start-break');
+CREATE TABLE `clients` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name_UNIQUE` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `projects` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `client_id` int(11) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `client_id_idx` (`client_id`),
+  CONSTRAINT `fk_projects_client` FOREIGN KEY (`client_id`) REFERENCES `clients` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `tasks` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `project_id` int(11) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `project_id_idx` (`project_id`),
+  CONSTRAINT `fk_tasks_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `task_entries` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `task_id` int(11) NOT NULL,
+  `started_at` datetime NOT NULL,
+  `ended_at` datetime DEFAULT NULL,
+  `note` varchar(255) NOT NULL DEFAULT '',
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `user_started_idx` (`user_id`,`started_at`),
+  KEY `task_id_idx` (`task_id`),
+  CONSTRAINT `fk_task_entries_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_task_entries_task` FOREIGN KEY (`task_id`) REFERENCES `tasks` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `billing_rates` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) DEFAULT NULL,
+  `role_id` int(11) DEFAULT NULL,
+  `hourly_rate` decimal(10,2) NOT NULL,
+  `currency` char(3) NOT NULL,
+  `effective_from` date NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `user_effective_idx` (`user_id`,`effective_from`),
+  KEY `role_effective_idx` (`role_id`,`effective_from`),
+  CONSTRAINT `fk_billing_rates_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_billing_rates_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `shift_clients` (
+  `sign_in_id` int(11) NOT NULL,
+  `client_id` int(11) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`sign_in_id`),
+  KEY `client_id_idx` (`client_id`),
+  CONSTRAINT `fk_shift_clients_sign_in` FOREIGN KEY (`sign_in_id`) REFERENCES `timestamps` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_shift_clients_client` FOREIGN KEY (`client_id`) REFERENCES `clients` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `shift_templates` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `weekdays` tinyint(3) unsigned NOT NULL,
+  `start_time` time NOT NULL,
+  `end_time` time NOT NULL,
+  `location` varchar(100) NOT NULL DEFAULT '',
+  `role` varchar(50) NOT NULL DEFAULT '',
+  `created_by` int(11) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name_UNIQUE` (`name`),
+  CONSTRAINT `fk_shift_templates_creator` FOREIGN KEY (`created_by`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `planned_shifts` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `starts_at` datetime NOT NULL,
+  `ends_at` datetime NOT NULL,
+  `location` varchar(100) NOT NULL DEFAULT '',
+  `role` varchar(50) NOT NULL DEFAULT '',
+  `template_id` int(11) DEFAULT NULL,
+  `published_at` datetime DEFAULT NULL,
+  `created_by` int(11) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `user_starts_idx` (`user_id`,`starts_at`),
+  KEY `starts_idx` (`starts_at`),
+  CONSTRAINT `fk_planned_shifts_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_planned_shifts_template` FOREIGN KEY (`template_id`) REFERENCES `shift_templates` (`id`) ON DELETE SET NULL,
+  CONSTRAINT `fk_planned_shifts_creator` FOREIGN KEY (`created_by`) REFERENCES `users` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `work_patterns` (
+  `user_id` int(11) NOT NULL,
+  `weekdays` tinyint(3) unsigned NOT NULL,
+  `start_time` time NOT NULL,
+  `end_time` time NOT NULL,
+  `late_grace_minutes` int(11) DEFAULT NULL,
+  `early_grace_minutes` int(11) DEFAULT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`user_id`),
+  CONSTRAINT `fk_work_patterns_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `leave_types` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(50) NOT NULL,
+  `paid` tinyint(1) NOT NULL DEFAULT 1,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name_UNIQUE` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `leave_policies` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `leave_type_id` int(11) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `days_per_month` decimal(5,2) NOT NULL,
+  `carry_over_cap` decimal(5,2) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name_UNIQUE` (`name`),
+  CONSTRAINT `fk_leave_policies_type` FOREIGN KEY (`leave_type_id`) REFERENCES `leave_types` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `leave_policy_assignments` (
+  `user_id` int(11) NOT NULL,
+  `leave_type_id` int(11) NOT NULL,
+  `policy_id` int(11) NOT NULL,
+  `starts_on` date NOT NULL,
+  PRIMARY KEY (`user_id`,`leave_type_id`),
+  CONSTRAINT `fk_leave_assignments_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_leave_assignments_type` FOREIGN KEY (`leave_type_id`) REFERENCES `leave_types` (`id`),
+  CONSTRAINT `fk_leave_assignments_policy` FOREIGN KEY (`policy_id`) REFERENCES `leave_policies` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `leave_requests` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `leave_type_id` int(11) NOT NULL,
+  `start_date` date NOT NULL,
+  `end_date` date NOT NULL,
+  `half_day` tinyint(1) NOT NULL DEFAULT 0,
+  `days` decimal(5,2) NOT NULL,
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `status` varchar(16) NOT NULL DEFAULT 'pending',
+  `decided_by` int(11) DEFAULT NULL,
+  `decided_at` datetime DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `user_dates_idx` (`user_id`,`start_date`,`end_date`),
+  CONSTRAINT `fk_leave_requests_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_leave_requests_type` FOREIGN KEY (`leave_type_id`) REFERENCES `leave_types` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `leave_request_days` (
+  `request_id` int(11) NOT NULL,
+  `date` date NOT NULL,
+  `amount` decimal(3,2) NOT NULL DEFAULT 1.00,
+  PRIMARY KEY (`request_id`,`date`),
+  CONSTRAINT `fk_leave_days_request` FOREIGN KEY (`request_id`) REFERENCES `leave_requests` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `sessions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `device` varchar(100) NOT NULL DEFAULT '',
+  `user_agent` varchar(255) NOT NULL DEFAULT '',
+  `ip_address` varchar(45) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL,
+  `last_used_at` datetime NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `user_idx` (`user_id`),
+  CONSTRAINT `fk_sessions_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `refresh_tokens` (
+  `token_hash` char(64) NOT NULL,
+  `session_id` int(11) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`token_hash`),
+  KEY `session_idx` (`session_id`),
+  CONSTRAINT `fk_refresh_tokens_session` FOREIGN KEY (`session_id`) REFERENCES `sessions` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `signing_keys` (
+  `kid` varchar(36) NOT NULL,
+  `algorithm` varchar(10) NOT NULL,
+  `private_key` varbinary(2048) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `activates_at` datetime NOT NULL,
+  `expires_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`kid`),
+  KEY `activates_at_idx` (`activates_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `user_totp` (
+  `user_id` int(11) NOT NULL,
+  `secret` varchar(255) NOT NULL,
+  `last_counter` bigint(20) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL,
+  `enabled_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`user_id`),
+  CONSTRAINT `fk_user_totp_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `mfa_recovery_codes` (
+  `user_id` int(11) NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`user_id`,`code_hash`),
+  CONSTRAINT `fk_mfa_recovery_codes_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `mfa_challenges` (
+  `token_hash` char(64) NOT NULL,
+  `user_id` int(11) NOT NULL,
+  `device` varchar(100) NOT NULL DEFAULT '',
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL,
+  `expires_at` datetime NOT NULL,
+  PRIMARY KEY (`token_hash`),
+  KEY `user_idx` (`user_id`),
+  CONSTRAINT `fk_mfa_challenges_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE `login_throttles` (
+  `scope` varchar(10) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `failures` int(11) NOT NULL DEFAULT 0,
+  `last_failed_at` datetime NOT NULL,
+  `locked_until` datetime DEFAULT NULL,
+  PRIMARY KEY (`scope`,`subject`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -6,6 +6,7 @@ import (
 	"expvar"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"syscall"
@@ -34,7 +35,11 @@ type application struct {
 	logger        *zap.SugaredLogger
 	mailer        mailer.Client
 	authenticator auth.Authenticator
+	signingKeys   *auth.KeySet
 	rateLimiter   ratelimiter.Limiter
+	kioskLimiter  ratelimiter.Limiter
+
+	complianceRules []store.ComplianceRule
 }
 
 // config holds the configuration settings for the application.
@@ -48,6 +53,15 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	compliance  store.ComplianceConfig
+	autoSignOut autoSignOutConfig
+	invoice     invoiceConfig
+	punctuality store.PunctualityGrace
+	kiosk       kioskConfig
+	mfa         mfaConfig
+	login       loginConfig
+
+	trustedProxies []netip.Prefix // Proxies whose X-Forwarded-For and X-Real-IP headers are trusted
 }
 
 type redisConfig struct {
@@ -63,9 +77,13 @@ type authConfig struct {
 }
 
 type tokenConfig struct {
-	secret string
-	exp    time.Duration
-	iss    string
+	exp         time.Duration
+	iss         string
+	refreshExp  time.Duration // Lifetime of a login session; refreshing does not extend it
+	algorithm   string        // RS256 or EdDSA
+	keyRotation time.Duration // How long a signing key is used before it is replaced
+	keyGrace    time.Duration // How long a replaced key still verifies tokens; at least exp
+	keyKEK      []byte        // Encrypts signing keys and TOTP secrets in the database; nil stores them unencrypted
 }
 
 type basicConfig struct {
@@ -102,15 +120,15 @@ func (app *application) mount() http.Handler {
 
 	// middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(app.RealIPMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		// Set the allowed origin for CORS
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:3000")},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key"},
+		ExposedHeaders:   []string{"Link", "Idempotent-Replayed", refreshTokenHeader},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -125,6 +143,9 @@ func (app *application) mount() http.Handler {
 	// processing should be stopped.
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	// public keys for verifying access tokens
+	r.Get("/.well-known/jwks.json", app.jwksHandler)
+
 	// routes
 	r.Route("/v1", func(r chi.Router) {
 		// health check
@@ -142,21 +163,29 @@ func (app *application) mount() http.Handler {
 			r.Use(app.AuthTokenMiddleware)
 			r.Get("/", app.getUserHandler)
 			r.Patch("/change-password", app.changePasswordHandler)
+			r.Patch("/time-zone", app.updateTimeZoneHandler)
+			r.Put("/pin", app.setPINHandler)
 		})
 
 		// timestamps
 		r.Route("/timestamps", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
-			r.Post("/", app.createTimestampHandler)
+			r.With(app.IdempotencyMiddleware).Post("/", app.createTimestampHandler)
 			r.Get("/", app.getTimestampHandler)
 			r.Get("/latest", app.getLatestTimestampHandler)
+			r.With(app.IdempotencyMiddleware).Post("/manual", app.createManualTimestampHandler)
+			r.Get("/pending", app.checkRolePrecedenceMiddleware("manager", app.getPendingTimestampsHandler))
+			r.Get("/lint/{userID}", app.checkRolePrecedenceMiddleware("manager", app.getLedgerReportHandler))
 
 			r.Route("/{timestampID}", func(r chi.Router) {
 				r.Use(app.timestampsContextMiddleware)
 				r.Get("/", app.checkTimestampOwnership("manager", app.getTimestampHandler))
 
-				r.Patch("/", app.checkRolePrecedenceMiddleware("manager", app.updateTimestampHandler))
-				r.Delete("/", app.checkRolePrecedenceMiddleware("manager", app.deleteTimestampHandler))
+				r.With(app.IdempotencyMiddleware).Patch("/", app.checkRolePrecedenceMiddleware("manager", app.updateTimestampHandler))
+				r.With(app.IdempotencyMiddleware).Delete("/", app.checkRolePrecedenceMiddleware("manager", app.deleteTimestampHandler))
+
+				r.Patch("/approve", app.checkRolePrecedenceMiddleware("manager", app.approveTimestampHandler))
+				r.Patch("/reject", app.checkRolePrecedenceMiddleware("manager", app.rejectTimestampHandler))
 			})
 		})
 
@@ -164,9 +193,243 @@ func (app *application) mount() http.Handler {
 		r.Route("/shifts", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
 			r.Get("/", app.getFinishedShiftsHandler)
+			r.Get("/current", app.getCurrentShiftHandler)
 			r.Get("/{userID}", app.checkRolePrecedenceMiddleware("manager", app.getFinishedShiftsByUserHandler))
 		})
 
+		// pay periods
+		r.Route("/pay-periods", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Post("/", app.createPayPeriodHandler)
+			r.Get("/", app.getPayPeriodsHandler)
+			r.Get("/users/{userID}", app.checkRolePrecedenceMiddleware("manager", app.getPayPeriodsByUserHandler))
+
+			r.Route("/{periodID}", func(r chi.Router) {
+				r.Use(app.payPeriodsContextMiddleware)
+				r.Get("/", app.checkPayPeriodOwnership("manager", app.getPayPeriodHandler))
+				r.Patch("/submit", app.checkPayPeriodOwnership("manager", app.submitPayPeriodHandler))
+				r.Patch("/approve", app.checkRolePrecedenceMiddleware("manager", app.approvePayPeriodHandler))
+				r.Patch("/return", app.checkRolePrecedenceMiddleware("manager", app.returnPayPeriodHandler))
+				r.Patch("/lock", app.checkRolePrecedenceMiddleware("manager", app.lockPayPeriodHandler))
+				r.Patch("/reopen", app.checkRolePrecedenceMiddleware("admin", app.reopenPayPeriodHandler))
+			})
+		})
+
+		// overtime policies
+		r.Route("/overtime-policies", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.checkRolePrecedenceMiddleware("manager", app.getOvertimePoliciesHandler))
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createOvertimePolicyHandler))
+
+			r.Route("/{policyID}", func(r chi.Router) {
+				r.Patch("/", app.checkRolePrecedenceMiddleware("admin", app.updateOvertimePolicyHandler))
+				r.Delete("/", app.checkRolePrecedenceMiddleware("admin", app.deleteOvertimePolicyHandler))
+				r.Put("/users/{userID}", app.checkRolePrecedenceMiddleware("admin", app.assignOvertimePolicyToUserHandler))
+				r.Put("/roles/{roleID}", app.checkRolePrecedenceMiddleware("admin", app.assignOvertimePolicyToRoleHandler))
+			})
+		})
+
+		// stamp types
+		r.Route("/stamp-graph", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getStampGraphHandler)
+			r.Put("/", app.checkRolePrecedenceMiddleware("admin", app.updateStampGraphHandler))
+		})
+
+		// holidays
+		r.Route("/holidays", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getHolidaysHandler)
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createHolidayHandler))
+			r.Delete("/{holidayID}", app.checkRolePrecedenceMiddleware("admin", app.deleteHolidayHandler))
+		})
+
+		r.Route("/holiday-calendars", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getHolidayCalendarsHandler)
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createHolidayCalendarHandler))
+			r.Delete("/{calendarID}", app.checkRolePrecedenceMiddleware("admin", app.deleteHolidayCalendarHandler))
+			r.Post("/{calendarID}/import", app.checkRolePrecedenceMiddleware("admin", app.importHolidayCalendarHandler))
+		})
+
+		// leave
+		r.Route("/leave-types", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getLeaveTypesHandler)
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createLeaveTypeHandler))
+		})
+
+		r.Route("/leave-policies", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.checkRolePrecedenceMiddleware("manager", app.getLeavePoliciesHandler))
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createLeavePolicyHandler))
+			r.Put("/{policyID}/users/{userID}", app.checkRolePrecedenceMiddleware("admin", app.assignLeavePolicyHandler))
+		})
+
+		r.Route("/leave-requests", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.IdempotencyMiddleware).Post("/", app.createLeaveRequestHandler)
+			r.Get("/", app.getLeaveRequestsHandler)
+			r.Get("/pending", app.checkRolePrecedenceMiddleware("manager", app.getPendingLeaveRequestsHandler))
+
+			r.Route("/{leaveRequestID}", func(r chi.Router) {
+				r.Use(app.leaveRequestsContextMiddleware)
+				r.Get("/", app.checkLeaveRequestOwnership("manager", app.getLeaveRequestHandler))
+				r.Patch("/approve", app.checkRolePrecedenceMiddleware("manager", app.approveLeaveRequestHandler))
+				r.Patch("/reject", app.checkRolePrecedenceMiddleware("manager", app.rejectLeaveRequestHandler))
+				r.Patch("/cancel", app.cancelLeaveRequestHandler)
+			})
+		})
+
+		r.Route("/leave-balances", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getLeaveBalancesHandler)
+			r.Get("/{userID}", app.checkRolePrecedenceMiddleware("manager", app.getLeaveBalancesByUserHandler))
+		})
+
+		// compliance
+		r.Route("/compliance", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/team", app.checkRolePrecedenceMiddleware("manager", app.getTeamViolationsHandler))
+		})
+
+		// clients, projects and tasks
+		r.Route("/clients", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getClientsHandler)
+			r.Post("/", app.checkRolePrecedenceMiddleware("manager", app.createClientHandler))
+		})
+
+		r.Route("/projects", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getProjectsHandler)
+			r.Post("/", app.checkRolePrecedenceMiddleware("manager", app.createProjectHandler))
+
+			r.Route("/{projectID}", func(r chi.Router) {
+				r.Use(app.projectsContextMiddleware)
+				r.Get("/", app.getProjectHandler)
+				r.Get("/tasks", app.getTasksHandler)
+				r.Post("/tasks", app.checkRolePrecedenceMiddleware("manager", app.createTaskHandler))
+			})
+		})
+
+		// task time
+		r.Route("/task-entries", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getTaskEntriesHandler)
+			r.With(app.IdempotencyMiddleware).Post("/", app.createTaskEntryHandler)
+			r.Get("/report", app.checkRolePrecedenceMiddleware("manager", app.getTaskHoursReportHandler))
+			r.Get("/timer", app.getTaskTimerHandler)
+			r.With(app.IdempotencyMiddleware).Post("/timer", app.startTaskTimerHandler)
+			r.Post("/timer/stop", app.stopTaskTimerHandler)
+			r.Delete("/{entryID}", app.deleteTaskEntryHandler)
+		})
+
+		// billing
+		r.Route("/billing-rates", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.checkRolePrecedenceMiddleware("manager", app.getBillingRatesHandler))
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createBillingRateHandler))
+		})
+
+		r.Route("/shift-clients", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Put("/{signInID}", app.assignShiftClientHandler)
+			r.Delete("/{signInID}", app.unassignShiftClientHandler)
+		})
+
+		r.Route("/invoices", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/draft", app.checkRolePrecedenceMiddleware("manager", app.getInvoiceDraftHandler))
+		})
+
+		// scheduling
+		r.Route("/planned-shifts", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/upcoming", app.getUpcomingShiftsHandler)
+			r.Get("/", app.checkRolePrecedenceMiddleware("manager", app.getTeamScheduleHandler))
+			r.Post("/", app.checkRolePrecedenceMiddleware("manager", app.createPlannedShiftHandler))
+			r.Post("/publish", app.checkRolePrecedenceMiddleware("manager", app.publishRosterHandler))
+
+			r.Route("/{plannedShiftID}", func(r chi.Router) {
+				r.Use(app.plannedShiftsContextMiddleware)
+				r.Patch("/", app.checkRolePrecedenceMiddleware("manager", app.updatePlannedShiftHandler))
+				r.Delete("/", app.checkRolePrecedenceMiddleware("manager", app.deletePlannedShiftHandler))
+			})
+		})
+
+		r.Route("/shift-templates", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.checkRolePrecedenceMiddleware("manager", app.getShiftTemplatesHandler))
+			r.Post("/", app.checkRolePrecedenceMiddleware("manager", app.createShiftTemplateHandler))
+			r.Delete("/{templateID}", app.checkRolePrecedenceMiddleware("manager", app.deleteShiftTemplateHandler))
+			r.Post("/{templateID}/apply", app.checkRolePrecedenceMiddleware("manager", app.applyShiftTemplateHandler))
+		})
+
+		// punctuality
+		r.Route("/work-patterns", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/{userID}", app.checkRolePrecedenceMiddleware("manager", app.getWorkPatternHandler))
+			r.Put("/{userID}", app.checkRolePrecedenceMiddleware("manager", app.setWorkPatternHandler))
+			r.Delete("/{userID}", app.checkRolePrecedenceMiddleware("manager", app.deleteWorkPatternHandler))
+		})
+
+		r.Route("/punctuality", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getPunctualityHandler)
+			r.Get("/team", app.checkRolePrecedenceMiddleware("manager", app.getTeamPunctualityHandler))
+			r.Get("/{userID}", app.checkRolePrecedenceMiddleware("manager", app.getPunctualityByUserHandler))
+		})
+
+		// sites
+		r.Route("/sites", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.checkRolePrecedenceMiddleware("manager", app.getSitesHandler))
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createSiteHandler))
+			r.Put("/{siteID}/holiday-calendar", app.checkRolePrecedenceMiddleware("admin", app.setSiteHolidayCalendarHandler))
+		})
+
+		// kiosks
+		r.Route("/kiosks", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.checkRolePrecedenceMiddleware("admin", app.getKiosksHandler))
+			r.Post("/", app.checkRolePrecedenceMiddleware("admin", app.createKioskHandler))
+			r.Post("/{kioskID}/token", app.checkRolePrecedenceMiddleware("admin", app.rotateKioskTokenHandler))
+			r.Delete("/{kioskID}", app.checkRolePrecedenceMiddleware("admin", app.deactivateKioskHandler))
+		})
+
+		// Shared kiosks authenticate with their device token instead of a user token
+		r.Route("/kiosk", func(r chi.Router) {
+			r.Use(app.KioskAuthMiddleware)
+			r.Get("/users", app.getKioskUsersHandler)
+			r.Post("/timestamps", app.createKioskTimestampHandler)
+		})
+
+		// timesheets
+		r.Route("/timesheets", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getTimesheetHandler)
+			r.Get("/team", app.checkRolePrecedenceMiddleware("manager", app.getTeamTimesheetsHandler))
+			r.Get("/{userID}", app.checkRolePrecedenceMiddleware("manager", app.getTimesheetByUserHandler))
+		})
+
+		// sessions
+		r.Route("/sessions", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getSessionsHandler)
+			r.Delete("/{sessionID}", app.revokeSessionHandler)
+		})
+
+		// two-factor authentication
+		r.Route("/mfa", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getMFAHandler)
+			r.Post("/totp", app.startTOTPEnrollmentHandler)
+			r.Post("/totp/confirm", app.confirmTOTPHandler)
+			r.Delete("/totp", app.disableTOTPHandler)
+			r.Post("/recovery-codes", app.regenerateRecoveryCodesHandler)
+		})
+
 		// users
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
@@ -178,9 +441,14 @@ func (app *application) mount() http.Handler {
 
 			r.Route("/{userID}", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
-				r.Patch("/", app.checkRolePrecedenceMiddleware("manager", app.updateUserHandler))
+				r.With(app.IdempotencyMiddleware).Patch("/", app.checkRolePrecedenceMiddleware("manager", app.updateUserHandler))
 				r.Get("/", app.checkRolePrecedenceMiddleware("manager", app.getUserHandler))
 				r.Delete("/", app.checkRolePrecedenceMiddleware("manager", app.deleteUserHandler))
+				r.Put("/site", app.checkRolePrecedenceMiddleware("manager", app.setUserSiteHandler))
+				r.Put("/holiday-calendar", app.checkRolePrecedenceMiddleware("manager", app.setUserHolidayCalendarHandler))
+				r.Delete("/sessions", app.checkRolePrecedenceMiddleware("admin", app.revokeUserSessionsHandler))
+				r.Delete("/mfa", app.checkRolePrecedenceMiddleware("admin", app.resetUserMFAHandler))
+				r.Delete("/lockout", app.checkRolePrecedenceMiddleware("admin", app.unlockUserHandler))
 			})
 
 			r.Group(func(r chi.Router) {
@@ -191,8 +459,11 @@ func (app *application) mount() http.Handler {
 
 		// public routes
 		r.Route("/authentication", func(r chi.Router) {
-			r.Post("/user", app.registerUserHandler)
+			r.With(app.IdempotencyMiddleware).Post("/user", app.registerUserHandler)
 			r.Post("/token", app.createTokenHandler)
+			r.Post("/refresh", app.refreshTokenHandler)
+			r.Post("/mfa", app.verifyMFAHandler)
+			r.Post("/mfa/enroll", app.enrollMFAChallengeHandler)
 			r.Post("/request-password-reset", app.requestPasswordResetHandler)
 			r.Put("/reset-password/{token}", app.resetPasswordHandler)
 		})
@@ -226,6 +497,16 @@ func (app *application) run(mux http.Handler) error {
 	// Create a channel to receive errors from the shutdown process
 	shutdown := make(chan error)
 
+	// Background jobs run until the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if app.config.autoSignOut.enabled {
+		go app.runAutoSignOut(jobsCtx)
+	}
+
+	go app.runKeyRotation(jobsCtx)
+
 	// Start a goroutine to handle the shutdown of the server
 	go func() {
 		// Create a channel to receive os signals
@@ -244,6 +525,9 @@ func (app *application) run(mux http.Handler) error {
 		// Log the signal
 		app.logger.Infow("signal caught", "signal", s.String())
 
+		// Stop the background jobs
+		stopJobs()
+
 		// Shutdown the server
 		shutdown <- srv.Shutdown(ctx)
 	}()
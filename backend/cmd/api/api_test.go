@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/auth"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store/cache"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// newTestApplication returns an application backed by the mock stores, without Redis and rate limiting.
+func newTestApplication(t *testing.T, cfg config) *application {
+	t.Helper()
+
+	return &application{
+		config:        cfg,
+		store:         store.NewMockStore(),
+		cacheStorage:  cache.NewMockStore(),
+		logger:        zap.NewNop().Sugar(),
+		authenticator: &auth.TestAuthenticator{},
+	}
+}
+
+// testToken returns an access token of userID for a session that the mock session store reports as active.
+func testToken(t *testing.T, app *application, userID int64) string {
+	t.Helper()
+
+	token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+		"sub": userID,
+		"sid": int64(1),
+		"ver": 0,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func executeRequest(req *http.Request, mux http.Handler) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
@@ -3,16 +3,24 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/mailer"
 	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// loginConfig configures the throttling of failed logins.
+type loginConfig struct {
+	account store.LoginPolicy // Failed logins per email address
+	ip      store.LoginPolicy // Failed logins per IP address, across accounts
+}
+
 // RegisterUserPayload represents the payload for registering a new user.
 type RegisterUserPayload struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
@@ -43,9 +51,11 @@ type RequestPasswordResetPayload struct {
 }
 
 // CreateUserTokenPayload represents the payload for creating a user token.
+// Device optionally names the device the session is started on.
 type CreateUserTokenPayload struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
+	Device   string `json:"device" validate:"max=100"`
 }
 
 // registerUserHandler godoc
@@ -217,14 +227,17 @@ func (app *application) requestPasswordResetHandler(w http.ResponseWriter, r *ht
 // createTokenHandler godoc
 //
 //	@Summary		Creates a token
-//	@Description	Creates a token for a user
+//	@Description	Creates a token for a user and starts a session. The session's refresh token is returned in the Refresh-Token header. Users with two-factor authentication, and users whose role requires it, get an MFA challenge instead and complete the login at POST /authentication/mfa.
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateUserTokenPayload	true	"User credentials"
-//	@Success		200		{string}	string					"Token"
+//	@Success		201		{string}	string					"Token"
+//	@Success		202		{object}	MFAChallengeResponse	"Two-factor authentication required"
+//	@Header			201		{string}	Refresh-Token			"Refresh token of the new session"
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
+//	@Failure		429		{object}	error					"Too many failed logins for the account or IP address"
 //	@Failure		500		{object}	error
 //	@Router			/authentication/token [post]
 func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Request) {
@@ -241,8 +254,24 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	ctx := r.Context()
+	ip := clientIP(r)
+
+	// Every attempt counts against the account and the IP address until it succeeds
+	attempt, err := app.store.LoginThrottles.Attempt(ctx, payload.Email, ip, app.config.login.account, app.config.login.ip)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if attempt.RetryAfter > 0 {
+		app.logger.Warnw("login throttled", "email", payload.Email, "ip", ip, "retry after", attempt.RetryAfter.String())
+		app.rateLimitExceededResponse(w, r, attempt.RetryAfter.String())
+		return
+	}
+
 	// Get the user by email
-	user, err := app.store.Users.GetByEmail(r.Context(), payload.Email)
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
@@ -255,21 +284,39 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 
 	// Compare the provided password to the user's password
 	if err := user.Password.Compare(payload.Password); err != nil {
+		if attempt.Locks {
+			app.logger.Warnw("account locked after failed logins", "user_id", user.ID, "ip", ip)
+			app.notifyAccountLocked(user, ip)
+		}
 		app.unauthorizedErrorResponse(w, r, err)
 		return
 	}
 
-	// Create the JWT token
-	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"iss": app.config.auth.token.iss,
-		"aud": app.config.auth.token.iss,
+	// Two-factor users finish the login with a code; until then the attempt stays counted
+	secret, required, err := app.getMFAState(ctx, user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if secret.Enabled() || required {
+		app.mfaChallengeResponse(w, r, user, payload.Device, secret.Enabled())
+		return
+	}
+
+	if err := app.store.LoginThrottles.Succeed(ctx, payload.Email, ip); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Start a session the token can later be refreshed in
+	session, err := app.startSession(w, r, user, payload.Device)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
 	}
 
-	token, err := app.authenticator.GenerateToken(claims)
+	token, err := app.generateAccessToken(user, session.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -284,7 +331,7 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 // changePasswordHandler godoc
 //
 //	@Summary		Change the user's password
-//	@Description	Allows a user to change their password by providing the old and new passwords
+//	@Description	Allows a user to change their password by providing the old and new passwords. Every session and access token of the user is revoked, so the user has to log in again.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
@@ -329,8 +376,88 @@ func (app *application) changePasswordHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(r.Context(), user.ID)
+	}
+
 	// Send a successful response with no content
 	if err := app.jsonResponse(w, http.StatusNoContent, ""); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+// unlockUserHandler godoc
+//
+//	@Summary		Unlocks a user's account
+//	@Description	Clears the failed logins of a user so that the user can log in again right away
+//	@Tags			users
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Account unlocked"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/lockout [delete]
+func (app *application) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.getUser(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.LoginThrottles.Unlock(ctx, user.Email); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, errors.New("the account has no failed logins"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("account unlocked", "user_id", userID, "by", getUserFromContext(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notifyAccountLocked emails a user whose account was locked after too many failed logins.
+func (app *application) notifyAccountLocked(user *store.User, ip string) {
+	policy := app.config.login.account
+	now := time.Now()
+	loc := user.Location()
+
+	vars := struct {
+		Failures    int
+		IPAddress   string
+		LastAttempt string
+		LockedUntil string
+	}{
+		Failures:    policy.MaxFailures,
+		IPAddress:   ip,
+		LastAttempt: now.In(loc).Format("2006-01-02 15:04 MST"),
+		LockedUntil: now.Add(policy.Lockout).In(loc).Format("2006-01-02 15:04 MST"),
+	}
+
+	isProdEnv := app.config.env == "production"
+
+	status, err := app.mailer.Send(mailer.AccountLockedTemplate, user.Email, vars, !isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending account locked email", "user", user.ID, "error", err)
+		return
+	}
+
+	app.logger.Infow("Email sent", "status code", status)
+}
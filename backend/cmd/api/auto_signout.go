@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/mailer"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+)
+
+// autoSignOutConfig configures the job that closes forgotten shifts.
+type autoSignOutConfig struct {
+	enabled  bool
+	maxShift time.Duration // Shifts open longer than this are closed
+	interval time.Duration // How often to look for open shifts
+}
+
+// runAutoSignOut closes forgotten shifts every interval until ctx is cancelled.
+func (app *application) runAutoSignOut(ctx context.Context) {
+	ticker := time.NewTicker(app.config.autoSignOut.interval)
+	defer ticker.Stop()
+
+	app.logger.Infow("auto sign-out job started", "max shift", app.config.autoSignOut.maxShift.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			app.logger.Infow("auto sign-out job stopped")
+			return
+		case <-ticker.C:
+			if err := app.closeForgottenShifts(ctx); err != nil {
+				app.logger.Errorw("auto sign-out failed", "error", err)
+			}
+		}
+	}
+}
+
+// closeForgottenShifts signs out every user whose shift has been open longer than the configured limit.
+// A failure for one user is logged and does not stop the others.
+func (app *application) closeForgottenShifts(ctx context.Context) error {
+	maxShift := app.config.autoSignOut.maxShift
+
+	open, err := app.store.Timestamps.GetOpenShifts(ctx, time.Now().Add(-maxShift))
+	if err != nil {
+		return err
+	}
+
+	for _, latest := range open {
+		closed, err := app.store.Timestamps.AutoSignOut(ctx, latest.UserID, maxShift)
+		if err != nil {
+			if errors.Is(err, store.ErrPeriodLocked) {
+				app.logger.Warnw("open shift is in a locked period", "user", latest.UserID)
+				continue
+			}
+			app.logger.Errorw("error closing open shift", "user", latest.UserID, "error", err)
+			continue
+		}
+
+		if closed == nil {
+			// The user signed out in the meantime
+			continue
+		}
+
+		app.logger.Infow("closed forgotten shift", "user", closed.UserID, "sign-out", closed.SignOut)
+
+		app.notifyAutoSignOut(ctx, closed)
+	}
+
+	return nil
+}
+
+// notifyAutoSignOut emails the user and the user's manager that a shift was closed automatically.
+func (app *application) notifyAutoSignOut(ctx context.Context, closed *store.ClosedShift) {
+	user, err := app.store.Users.GetByID(ctx, closed.UserID)
+	if err != nil {
+		app.logger.Errorw("error loading user for auto sign-out notice", "user", closed.UserID, "error", err)
+		return
+	}
+
+	recipients := []*store.User{user}
+	if user.ManagerID != 0 {
+		manager, err := app.store.Users.GetByID(ctx, user.ManagerID)
+		if err != nil {
+			app.logger.Errorw("error loading manager for auto sign-out notice", "user", closed.UserID, "error", err)
+		} else {
+			recipients = append(recipients, manager)
+		}
+	}
+
+	isProdEnv := app.config.env == "production"
+	maxShift := app.config.autoSignOut.maxShift
+
+	for _, recipient := range recipients {
+		// Each recipient reads the times in their own time zone
+		loc := recipient.Location()
+		vars := struct {
+			Name     string
+			SignIn   string
+			SignOut  string
+			MaxShift string
+		}{
+			Name:     user.FirstName + " " + user.LastName,
+			SignIn:   closed.SignIn.In(loc).Format("2006-01-02 15:04 MST"),
+			SignOut:  closed.SignOut.In(loc).Format("2006-01-02 15:04 MST"),
+			MaxShift: maxShift.String(),
+		}
+
+		status, err := app.mailer.Send(mailer.AutoSignOutTemplate, recipient.Email, vars, !isProdEnv)
+		if err != nil {
+			app.logger.Errorw("error sending auto sign-out email", "user", recipient.ID, "error", err)
+			continue
+		}
+
+		app.logger.Infow("Email sent", "status code", status)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateBillingRatePayload represents the payload for creating a billing rate.
+// Exactly one of UserID and RoleID must be set.
+type CreateBillingRatePayload struct {
+	UserID        *int64  `json:"user_id" validate:"omitempty,min=1"`
+	RoleID        *int64  `json:"role_id" validate:"omitempty,min=1"`
+	HourlyRate    float64 `json:"hourly_rate" validate:"gte=0"`
+	Currency      string  `json:"currency" validate:"omitempty,len=3,alpha"`
+	EffectiveFrom string  `json:"effective_from" validate:"required"`
+}
+
+// AssignShiftClientPayload represents the payload for billing a shift to a client.
+type AssignShiftClientPayload struct {
+	ClientID int64 `json:"client_id" validate:"required,min=1"`
+}
+
+// getBillingRatesHandler godoc
+//
+//	@Summary		Fetches the rate history of a user or role
+//	@Description	Fetches the billing rates of a user or of a role, newest first
+//	@Tags			billing
+//	@Produce		json
+//	@Param			user_id	query		int	false	"User ID"
+//	@Param			role_id	query		int	false	"Role ID, used when user_id is not given"
+//	@Success		200		{object}	[]store.BillingRate
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/billing-rates [get]
+func (app *application) getBillingRatesHandler(w http.ResponseWriter, r *http.Request) {
+	var userID, roleID int64
+	var err error
+
+	qs := r.URL.Query()
+	if u := qs.Get("user_id"); u != "" {
+		if userID, err = strconv.ParseInt(u, 10, 64); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if ro := qs.Get("role_id"); ro != "" {
+		if roleID, err = strconv.ParseInt(ro, 10, 64); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if userID == 0 && roleID == 0 {
+		app.badRequestResponse(w, r, errors.New("user_id or role_id is required"))
+		return
+	}
+
+	rates, err := app.store.Billing.GetRates(r.Context(), userID, roleID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rates); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createBillingRateHandler godoc
+//
+//	@Summary		Creates a billing rate
+//	@Description	Sets the hourly rate of a user or role from a date on. Earlier rates stay in effect for earlier dates.
+//	@Tags			billing
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateBillingRatePayload	true	"Billing rate"
+//	@Success		201		{object}	store.BillingRate
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/billing-rates [post]
+func (app *application) createBillingRateHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateBillingRatePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if (payload.UserID == nil) == (payload.RoleID == nil) {
+		app.badRequestResponse(w, r, errors.New("exactly one of user_id and role_id is required"))
+		return
+	}
+
+	if _, err := time.Parse(time.DateOnly, payload.EffectiveFrom); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	currency := strings.ToUpper(payload.Currency)
+	if currency == "" {
+		currency = app.config.invoice.currency
+	}
+
+	rate := &store.BillingRate{
+		UserID:        payload.UserID,
+		RoleID:        payload.RoleID,
+		HourlyRate:    payload.HourlyRate,
+		Currency:      currency,
+		EffectiveFrom: payload.EffectiveFrom,
+	}
+
+	if err := app.store.Billing.CreateRate(r.Context(), rate); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, rate); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// assignShiftClientHandler godoc
+//
+//	@Summary		Bills a shift to a client
+//	@Description	Bills the shift opened by a sign-in stamp to a client. Users may assign their own shifts; managers may assign any shift.
+//	@Tags			billing
+//	@Accept			json
+//	@Param			signInID	path		int							true	"ID of the sign-in timestamp"
+//	@Param			payload		body		AssignShiftClientPayload	true	"Client"
+//	@Success		204			{string}	string						"Client assigned"
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shift-clients/{signInID} [put]
+func (app *application) assignShiftClientHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AssignShiftClientPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.withShiftOwnerOrManager(w, r, func(w http.ResponseWriter, r *http.Request, signInID int64) error {
+		return app.store.Billing.AssignShiftClient(r.Context(), signInID, payload.ClientID)
+	})
+}
+
+// unassignShiftClientHandler godoc
+//
+//	@Summary		Removes the client of a shift
+//	@Description	Stops billing the shift opened by a sign-in stamp to a client. Users may unassign their own shifts; managers may unassign any shift.
+//	@Tags			billing
+//	@Param			signInID	path		int		true	"ID of the sign-in timestamp"
+//	@Success		204			{string}	string	"Client removed"
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shift-clients/{signInID} [delete]
+func (app *application) unassignShiftClientHandler(w http.ResponseWriter, r *http.Request) {
+	app.withShiftOwnerOrManager(w, r, func(w http.ResponseWriter, r *http.Request, signInID int64) error {
+		return app.store.Billing.UnassignShiftClient(r.Context(), signInID)
+	})
+}
+
+// withShiftOwnerOrManager runs fn for the sign-in stamp in the signInID URL parameter if it belongs to
+// the authenticated user, or otherwise if the user is a manager, and responds with 204 on success.
+func (app *application) withShiftOwnerOrManager(w http.ResponseWriter, r *http.Request, fn func(http.ResponseWriter, *http.Request, int64) error) {
+	signInID, err := strconv.ParseInt(chi.URLParam(r, "signInID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	stamp, err := app.store.Timestamps.GetByID(r.Context(), signInID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	handle := func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r, stamp.ID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	if stamp.UserID == getUserFromContext(r).ID {
+		handle(w, r)
+		return
+	}
+
+	app.checkRolePrecedenceMiddleware("manager", handle).ServeHTTP(w, r)
+}
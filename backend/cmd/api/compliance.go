@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+)
+
+// TeamMemberViolations lists the compliance violations of one team member.
+type TeamMemberViolations struct {
+	UserID     int64                       `json:"user_id"`
+	FirstName  string                      `json:"first_name"`
+	LastName   string                      `json:"last_name"`
+	Violations []store.ComplianceViolation `json:"violations"`
+}
+
+// getTeamViolationsHandler godoc
+//
+//	@Summary		Fetches the compliance violations of the manager's team
+//	@Description	Checks the finished shifts of every user managed by the authenticated user against the break, rest and daily work rules
+//	@Tags			compliance
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD), defaults to the first day of the current month"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD), defaults to the last day of the current month"
+//	@Success		200		{object}	[]TeamMemberViolations
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/compliance/team [get]
+func (app *application) getTeamViolationsHandler(w http.ResponseWriter, r *http.Request) {
+	manager := getUserFromContext(r)
+
+	team, err := app.store.Users.GetByManager(r.Context(), manager.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report := make([]TeamMemberViolations, 0, len(team))
+	for _, member := range team {
+		from, to, err := parseDateRange(r, member.Location())
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		timesheet, err := app.buildTimesheet(r.Context(), member, from, to)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if len(timesheet.Violations) == 0 {
+			continue
+		}
+
+		report = append(report, TeamMemberViolations{
+			UserID:     member.ID,
+			FirstName:  member.FirstName,
+			LastName:   member.LastName,
+			Violations: timesheet.Violations,
+		})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
 )
 
 // internalServerError godoc
@@ -59,6 +61,25 @@ func (app *application) conflictResponse(w http.ResponseWriter, r *http.Request,
 	writeJSONError(w, http.StatusConflict, err.Error())
 }
 
+// ledgerConflictResponse godoc
+//
+//	@Summary		Ledger Conflict
+//	@Description	Logs a ledger conflict and writes a JSON error response with status 409 listing the broken positions
+//	@Tags			errors
+//	@Produce		json
+//	@Param			error	body		store.LedgerError	true	"Ledger error"
+//	@Router			/errors/ledger-conflict [post]
+func (app *application) ledgerConflictResponse(w http.ResponseWriter, r *http.Request, err *store.LedgerError) {
+	app.logger.Warnw("ledger conflict", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+
+	type envelope struct {
+		Error      string                  `json:"error"`
+		Violations []store.LedgerViolation `json:"violations"`
+	}
+
+	writeJSON(w, http.StatusConflict, &envelope{Error: err.Error(), Violations: err.Violations})
+}
+
 // notFoundResponse godoc
 //
 //	@Summary		Not Found
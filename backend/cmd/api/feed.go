@@ -57,6 +57,12 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Render the feed in the user's time zone
+	loc := user.Location()
+	for i := range feed {
+		feed[i].In(loc)
+	}
+
 	// Send the user feed as a JSON response
 	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
 		app.internalServerError(w, r, err)
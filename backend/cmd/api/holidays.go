@@ -0,0 +1,514 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/ical"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCalendarBytes limits the size of imported iCalendar files.
+const maxCalendarBytes = 1 << 20
+
+// maxImportYears limits how many years of recurring holidays one import expands to.
+const maxImportYears = 10
+
+// CreateHolidayPayload represents the payload for adding a holiday. Omitting the calendar adds it to the default calendar.
+type CreateHolidayPayload struct {
+	CalendarID int64  `json:"calendar_id"`
+	Date       string `json:"date" validate:"required,datetime=2006-01-02"`
+	Name       string `json:"name" validate:"required,max=100"`
+	Kind       string `json:"kind" validate:"omitempty,oneof=public closure"`
+}
+
+// CreateHolidayCalendarPayload represents the payload for creating a holiday calendar.
+type CreateHolidayCalendarPayload struct {
+	Name      string `json:"name" validate:"required,max=100"`
+	Region    string `json:"region" validate:"max=20"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// SetHolidayCalendarPayload represents the payload for assigning a holiday calendar. A null calendar removes the assignment.
+type SetHolidayCalendarPayload struct {
+	CalendarID *int64 `json:"calendar_id"`
+}
+
+// HolidayImportResult reports the outcome of an iCalendar import.
+type HolidayImportResult struct {
+	CalendarID int64  `json:"calendar_id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Added      int    `json:"added"`
+	Replaced   int    `json:"replaced"`
+
+	Skipped []SkippedHolidayEvent `json:"skipped"` // Events left out because their recurrence is not supported
+}
+
+// SkippedHolidayEvent is an event of an imported calendar that was not imported.
+type SkippedHolidayEvent struct {
+	UID     string `json:"uid"`
+	Summary string `json:"summary"`
+	Reason  string `json:"reason"`
+}
+
+// getHolidaysHandler godoc
+//
+//	@Summary		Fetches holidays
+//	@Description	Fetches the holidays of a calendar between two dates, by default those of the current year in the calendar the user follows
+//	@Tags			holidays
+//	@Produce		json
+//	@Param			calendar_id	query		int		false	"Calendar ID"
+//	@Param			from		query		string	false	"First date (YYYY-MM-DD)"
+//	@Param			to			query		string	false	"Last date (YYYY-MM-DD)"
+//	@Success		200			{object}	[]store.Holiday
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/holidays [get]
+func (app *application) getHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	from := strconv.Itoa(year) + "-01-01"
+	to := strconv.Itoa(year) + "-12-31"
+
+	qs := r.URL.Query()
+	if f := qs.Get("from"); f != "" {
+		if _, err := time.Parse(time.DateOnly, f); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		from = f
+	}
+	if t := qs.Get("to"); t != "" {
+		if _, err := time.Parse(time.DateOnly, t); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		to = t
+	}
+
+	var holidays []store.Holiday
+	var err error
+
+	if c := qs.Get("calendar_id"); c != "" {
+		var calendarID int64
+		if calendarID, err = strconv.ParseInt(c, 10, 64); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		holidays, err = app.store.Holidays.GetBetween(r.Context(), calendarID, from, to)
+	} else {
+		holidays, err = app.store.Holidays.GetForUser(r.Context(), getUserFromContext(r).ID, from, to)
+	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, holidays); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createHolidayHandler godoc
+//
+//	@Summary		Creates a holiday
+//	@Description	Adds a public holiday or company closure to a calendar; work on it is paid at the holiday multiplier of the overtime policy and no time is expected on it
+//	@Tags			holidays
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateHolidayPayload	true	"Holiday"
+//	@Success		201		{object}	store.Holiday
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/holidays [post]
+func (app *application) createHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateHolidayPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	holiday := &store.Holiday{
+		CalendarID: payload.CalendarID,
+		Date:       payload.Date,
+		Name:       payload.Name,
+		Kind:       payload.Kind,
+	}
+
+	if err := app.store.Holidays.Create(r.Context(), holiday); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, holiday); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteHolidayHandler godoc
+//
+//	@Summary		Deletes a public holiday
+//	@Description	Deletes a public holiday by ID
+//	@Tags			holidays
+//	@Param			holidayID	path		int		true	"Holiday ID"
+//	@Success		204			{string}	string	"Holiday deleted"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/holidays/{holidayID} [delete]
+func (app *application) deleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	holidayID, err := strconv.ParseInt(chi.URLParam(r, "holidayID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Holidays.Delete(r.Context(), holidayID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getHolidayCalendarsHandler godoc
+//
+//	@Summary		Fetches holiday calendars
+//	@Description	Fetches all holiday calendars ordered by name
+//	@Tags			holidays
+//	@Produce		json
+//	@Success		200	{object}	[]store.HolidayCalendar
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/holiday-calendars [get]
+func (app *application) getHolidayCalendarsHandler(w http.ResponseWriter, r *http.Request) {
+	calendars, err := app.store.Holidays.GetCalendars(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, calendars); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createHolidayCalendarHandler godoc
+//
+//	@Summary		Creates a holiday calendar
+//	@Description	Creates a holiday calendar, e.g. for a region. Users without a calendar of their own or of their site follow the default calendar.
+//	@Tags			holidays
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateHolidayCalendarPayload	true	"Calendar"
+//	@Success		201		{object}	store.HolidayCalendar
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/holiday-calendars [post]
+func (app *application) createHolidayCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateHolidayCalendarPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	calendar := &store.HolidayCalendar{
+		Name:      payload.Name,
+		Region:    payload.Region,
+		IsDefault: payload.IsDefault,
+	}
+
+	if err := app.store.Holidays.CreateCalendar(r.Context(), calendar); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, calendar); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteHolidayCalendarHandler godoc
+//
+//	@Summary		Deletes a holiday calendar
+//	@Description	Deletes a holiday calendar and its holidays; the default calendar cannot be deleted
+//	@Tags			holidays
+//	@Param			calendarID	path		int		true	"Calendar ID"
+//	@Success		204			{string}	string	"Calendar deleted"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/holiday-calendars/{calendarID} [delete]
+func (app *application) deleteHolidayCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	calendarID, err := strconv.ParseInt(chi.URLParam(r, "calendarID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Holidays.DeleteCalendar(r.Context(), calendarID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrDefaultCalendar):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// importHolidayCalendarHandler godoc
+//
+//	@Summary		Imports holidays from an iCalendar file
+//	@Description	Adds the all-day events of an iCalendar (.ics) file sent as the request body to a calendar. Yearly recurring events, including ones on a weekday of a month, are expanded between the from and to dates, by default the current and the next year. Events on dates the calendar already has replace the existing holidays. Events with other recurrence rules are skipped and listed in the result.
+//	@Tags			holidays
+//	@Accept			text/calendar
+//	@Produce		json
+//	@Param			calendarID	path		int		true	"Calendar ID"
+//	@Param			from		query		string	false	"First date (YYYY-MM-DD)"
+//	@Param			to			query		string	false	"Last date (YYYY-MM-DD), at most 10 years after from"
+//	@Param			kind		query		string	false	"public (default) or closure"
+//	@Success		200			{object}	HolidayImportResult
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/holiday-calendars/{calendarID}/import [post]
+func (app *application) importHolidayCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	calendarID, err := strconv.ParseInt(chi.URLParam(r, "calendarID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	year := time.Now().Year()
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(year+1, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	qs := r.URL.Query()
+	if f := qs.Get("from"); f != "" {
+		if from, err = time.Parse(time.DateOnly, f); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if t := qs.Get("to"); t != "" {
+		if to, err = time.Parse(time.DateOnly, t); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if to.Before(from) || to.After(from.AddDate(maxImportYears, 0, 0)) {
+		app.badRequestResponse(w, r, fmt.Errorf("to must be within %d years after from", maxImportYears))
+		return
+	}
+
+	kind := qs.Get("kind")
+	switch kind {
+	case "":
+		kind = store.HolidayKindPublic
+	case store.HolidayKindPublic, store.HolidayKindClosure:
+	default:
+		app.badRequestResponse(w, r, fmt.Errorf("kind must be %q or %q", store.HolidayKindPublic, store.HolidayKindClosure))
+		return
+	}
+
+	events, skippedEvents, err := ical.Parse(http.MaxBytesReader(w, r.Body, maxCalendarBytes))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	skipped := make([]SkippedHolidayEvent, 0, len(skippedEvents))
+	for _, event := range skippedEvents {
+		app.logger.Warnw("skipped holiday event", "calendar_id", calendarID, "uid", event.UID, "summary", event.Summary, "reason", event.Reason)
+		skipped = append(skipped, SkippedHolidayEvent{UID: event.UID, Summary: event.Summary, Reason: event.Reason.Error()})
+	}
+
+	holidays := make([]store.Holiday, 0)
+	for _, event := range events {
+		name := event.Summary
+		if name == "" {
+			name = "Holiday"
+		}
+		for utf8.RuneCountInString(name) > 100 {
+			_, size := utf8.DecodeLastRuneInString(name)
+			name = name[:len(name)-size]
+		}
+
+		for _, date := range event.Dates(from, to) {
+			holidays = append(holidays, store.Holiday{
+				CalendarID: calendarID,
+				Date:       date,
+				Name:       name,
+				Kind:       kind,
+			})
+		}
+	}
+
+	added, replaced, err := app.store.Holidays.Import(r.Context(), calendarID, holidays)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	result := HolidayImportResult{
+		CalendarID: calendarID,
+		From:       from.Format(time.DateOnly),
+		To:         to.Format(time.DateOnly),
+		Added:      added,
+		Replaced:   replaced,
+		Skipped:    skipped,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// setUserHolidayCalendarHandler godoc
+//
+//	@Summary		Assigns a holiday calendar to a user
+//	@Description	Sets the holiday calendar one of the manager's reports follows instead of their site's calendar
+//	@Tags			holidays
+//	@Accept			json
+//	@Param			userID	path		int							true	"User ID"
+//	@Param			payload	body		SetHolidayCalendarPayload	true	"Calendar"
+//	@Success		204		{string}	string						"Calendar assigned"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/holiday-calendar [put]
+func (app *application) setUserHolidayCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SetHolidayCalendarPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, getUserFromContext(r), userID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	if err := app.store.Holidays.SetUserCalendar(ctx, userID, payload.CalendarID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, userID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setSiteHolidayCalendarHandler godoc
+//
+//	@Summary		Assigns a holiday calendar to a site
+//	@Description	Sets the holiday calendar the users of a site follow unless they have a calendar of their own
+//	@Tags			holidays
+//	@Accept			json
+//	@Param			siteID	path		int							true	"Site ID"
+//	@Param			payload	body		SetHolidayCalendarPayload	true	"Calendar"
+//	@Success		204		{string}	string						"Calendar assigned"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/sites/{siteID}/holiday-calendar [put]
+func (app *application) setSiteHolidayCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	siteID, err := strconv.ParseInt(chi.URLParam(r, "siteID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SetHolidayCalendarPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Holidays.SetSiteCalendar(r.Context(), siteID, payload.CalendarID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+)
+
+// idempotencyKeyHeader is the request header clients use to make retries safe.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyStore is implemented by both the Redis and the database idempotency stores.
+type idempotencyStore interface {
+	Get(context.Context, int64, string) (*store.IdempotencyRecord, error)
+	Reserve(context.Context, *store.IdempotencyRecord) (bool, error)
+	Save(context.Context, *store.IdempotencyRecord) error
+	Delete(context.Context, int64, string) error
+}
+
+// responseRecorder captures the status code and body written by a handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware godoc
+//
+//	@Summary		Idempotency Middleware
+//	@Description	Middleware that stores the first response for each (user, Idempotency-Key) pair and replays it on retries. Keys of anonymous requests are also scoped to the client IP address and the request body.
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/idempotency [post]
+func (app *application) IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > 255 {
+			app.badRequestResponse(w, r, errors.New("idempotency key must be at most 255 characters"))
+			return
+		}
+
+		// Fingerprint the request so a key cannot be reused for a different payload
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1_048_578))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+
+		// Keys are scoped per user. Anonymous clients cannot be told apart by ID, so their keys are
+		// also bound to the client IP and the payload and only ever replay identical requests.
+		var userID int64
+		if user := getUserFromContext(r); user != nil {
+			userID = user.ID
+		} else {
+			key = anonymousIdempotencyKey(clientIP(r), requestHash, key)
+		}
+
+		record := &store.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(store.IdempotencyExpTime),
+		}
+
+		ctx := r.Context()
+		idempotency := app.idempotencyStore()
+
+		existing, err := idempotency.Get(ctx, userID, key)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if existing == nil {
+			reserved, err := idempotency.Reserve(ctx, record)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if reserved {
+				app.serveIdempotent(w, r, next, idempotency, record)
+				return
+			}
+
+			// Another request claimed the key between Get and Reserve
+			existing, err = idempotency.Get(ctx, userID, key)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+		}
+
+		switch {
+		case existing == nil || existing.StatusCode == 0:
+			app.conflictResponse(w, r, errors.New("a request with this idempotency key is still being processed"))
+		case existing.Method != record.Method || existing.Path != record.Path || existing.RequestHash != record.RequestHash:
+			app.conflictResponse(w, r, errors.New("idempotency key was already used for a different request"))
+		default:
+			if existing.ContentType != "" {
+				w.Header().Set("Content-Type", existing.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(existing.StatusCode)
+			w.Write(existing.Body)
+		}
+	})
+}
+
+// serveIdempotent runs the handler for a freshly reserved key and stores its response.
+// Server errors release the key so the client can retry.
+func (app *application) serveIdempotent(w http.ResponseWriter, r *http.Request, next http.Handler, idempotency idempotencyStore, record *store.IdempotencyRecord) {
+	rec := &responseRecorder{ResponseWriter: w}
+	next.ServeHTTP(rec, r)
+
+	// Store the outcome even if the client has gone away in the meantime
+	ctx := context.WithoutCancel(r.Context())
+
+	if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+		if err := idempotency.Delete(ctx, record.UserID, record.Key); err != nil {
+			app.logger.Errorw("error releasing idempotency key", "key", record.Key, "error", err)
+		}
+		return
+	}
+
+	record.StatusCode = rec.status
+	record.ContentType = rec.Header().Get("Content-Type")
+	record.Body = rec.body.Bytes()
+
+	if err := idempotency.Save(ctx, record); err != nil {
+		app.logger.Errorw("error saving idempotent response", "key", record.Key, "error", err)
+	}
+}
+
+// anonymousIdempotencyKey derives the key an anonymous request is stored under from the client
+// IP address, the request hash and the key the client sent.
+func anonymousIdempotencyKey(ip, requestHash, key string) string {
+	hash := sha256.Sum256([]byte(ip + "\x00" + requestHash + "\x00" + key))
+	return "anon:" + hex.EncodeToString(hash[:])
+}
+
+// idempotencyStore returns the Redis store when the cache is enabled and the database store otherwise.
+func (app *application) idempotencyStore() idempotencyStore {
+	if app.config.redisCfg.enabled {
+		return app.cacheStorage.Idempotency
+	}
+
+	return app.store.Idempotency
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/invoice"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+)
+
+// invoiceConfig configures the invoice drafts.
+type invoiceConfig struct {
+	issuer     string  // Name printed as the sender of invoices
+	currency   string  // Default currency of new billing rates
+	taxPercent float64 // Tax added to the subtotal
+}
+
+// getInvoiceDraftHandler godoc
+//
+//	@Summary		Drafts a client invoice
+//	@Description	Drafts an invoice for the shifts billed to a client between two local dates, with a line per user and day priced at the rate in effect on that day
+//	@Tags			billing
+//	@Produce		json
+//	@Produce		html
+//	@Produce		application/pdf
+//	@Param			client_id	query		int		true	"Client ID"
+//	@Param			from		query		string	false	"First local date (YYYY-MM-DD), defaults to the first day of the current month"
+//	@Param			to			query		string	false	"Last local date (YYYY-MM-DD), defaults to the last day of the current month"
+//	@Param			format		query		string	false	"json (default), html or pdf"
+//	@Success		200			{object}	invoice.Invoice
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/invoices/draft [get]
+func (app *application) getInvoiceDraftHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	clientID, err := strconv.ParseInt(qs.Get("client_id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("client_id is required"))
+		return
+	}
+
+	format := qs.Get("format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "html", "pdf":
+	default:
+		app.badRequestResponse(w, r, fmt.Errorf("unknown format %q", format))
+		return
+	}
+
+	from, to, err := parseDateRange(r, getUserFromContext(r).Location())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	client, err := app.store.Projects.GetClientByID(ctx, clientID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	inv := &invoice.Invoice{
+		Issuer:     app.config.invoice.issuer,
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		From:       from.Format(time.DateOnly),
+		To:         to.Format(time.DateOnly),
+		Lines:      make([]invoice.LineItem, 0),
+		TaxPercent: app.config.invoice.taxPercent,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	userIDs, err := app.store.Billing.GetClientUsers(ctx, client.ID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		user, err := app.getUser(ctx, userID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		rates, err := app.store.Billing.GetRatesForUser(ctx, user)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		// The period is made of local dates, so it is read in each user's own time zone
+		loc := user.Location()
+		userFrom, userTo, err := parseDateRange(r, loc)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		sq := store.ShiftQuery{
+			From:     userFrom,
+			To:       userTo.AddDate(0, 0, 1),
+			Location: loc,
+		}
+
+		shifts, _, err := app.store.Timestamps.GetFinishedShifts(ctx, user.ID, sq)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		name := user.FirstName + " " + user.LastName
+		for _, shift := range shifts {
+			if shift.ClientID == nil || *shift.ClientID != client.ID {
+				continue
+			}
+
+			for _, day := range shift.Days {
+				if day.Date < inv.From || day.Date > inv.To || day.NetWorkTime <= 0 {
+					continue
+				}
+
+				rate := store.RateOn(rates, day.Date)
+				if rate == nil {
+					app.conflictResponse(w, r, fmt.Errorf("%w for user %d on %s", store.ErrNoBillingRate, user.ID, day.Date))
+					return
+				}
+
+				if inv.Currency == "" {
+					inv.Currency = rate.Currency
+				} else if inv.Currency != rate.Currency {
+					app.conflictResponse(w, r, fmt.Errorf("rates in both %s and %s apply to the invoice", inv.Currency, rate.Currency))
+					return
+				}
+
+				inv.AddLine(user.ID, name, day.Date, day.NetWorkTime/3600, rate.HourlyRate)
+			}
+		}
+	}
+
+	if inv.Currency == "" {
+		inv.Currency = app.config.invoice.currency
+	}
+	inv.Finalize()
+
+	if format == "json" {
+		if err := app.jsonResponse(w, http.StatusOK, inv); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Render into a buffer first so a template error can still be reported as a 500
+	body := new(bytes.Buffer)
+	render, contentType := invoice.RenderHTML, "text/html; charset=utf-8"
+	if format == "pdf" {
+		render, contentType = invoice.RenderPDF, "application/pdf"
+	}
+
+	if err := render(body, inv); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if format == "pdf" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invoice-%d-%s-%s.pdf"`, client.ID, inv.From, inv.To))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
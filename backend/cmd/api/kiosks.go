@@ -0,0 +1,516 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type kioskKey string
+
+// kioskCtx is a constant key used to store and retrieve the authenticated kiosk from the context.
+const kioskCtx kioskKey = "kiosk"
+
+// kioskConfig configures shared kiosks.
+type kioskConfig struct {
+	pin               store.PINPolicy // Lockout of a user's PIN after consecutive failures
+	attemptsPerMinute int             // PIN checks a single kiosk may make per minute
+}
+
+// CreateSitePayload represents the payload for creating a site.
+type CreateSitePayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CreateKioskPayload represents the payload for registering a kiosk.
+type CreateKioskPayload struct {
+	SiteID int64  `json:"site_id" validate:"required"`
+	Name   string `json:"name" validate:"required,max=100"`
+}
+
+// KioskCredentials holds the device token of a kiosk. The token is only ever shown once.
+type KioskCredentials struct {
+	Kiosk *store.Kiosk `json:"kiosk"`
+	Token string       `json:"token"`
+}
+
+// SetUserSitePayload represents the payload for assigning a user to a site. A null site removes the user from kiosks.
+type SetUserSitePayload struct {
+	SiteID *int64 `json:"site_id"`
+}
+
+// SetPINPayload represents the payload for setting the user's own kiosk PIN.
+type SetPINPayload struct {
+	Password string `json:"password" validate:"required"`
+	PIN      string `json:"pin" validate:"required,numeric,min=4,max=8"`
+}
+
+// CreateKioskTimestampPayload represents the payload for stamping at a kiosk on behalf of a user.
+type CreateKioskTimestampPayload struct {
+	UserID    int64  `json:"user_id" validate:"required"`
+	PIN       string `json:"pin" validate:"required,max=8"`
+	StampType string `json:"stamp_type" validate:"required,max=32"`
+}
+
+// createSiteHandler godoc
+//
+//	@Summary		Creates a site
+//	@Description	Creates a workplace that users and kiosks can be assigned to
+//	@Tags			kiosks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateSitePayload	true	"Site"
+//	@Success		201		{object}	store.Site
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/sites [post]
+func (app *application) createSiteHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateSitePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	site := &store.Site{Name: payload.Name}
+
+	if err := app.store.Kiosks.CreateSite(r.Context(), site); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, site); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getSitesHandler godoc
+//
+//	@Summary		Fetches all sites
+//	@Description	Fetches all sites ordered by name
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	[]store.Site
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/sites [get]
+func (app *application) getSitesHandler(w http.ResponseWriter, r *http.Request) {
+	sites, err := app.store.Kiosks.GetSites(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, sites); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createKioskHandler godoc
+//
+//	@Summary		Registers a kiosk
+//	@Description	Registers a shared kiosk for a site and returns its device token. The token is not stored and cannot be shown again.
+//	@Tags			kiosks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateKioskPayload	true	"Kiosk"
+//	@Success		201		{object}	KioskCredentials
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/kiosks [post]
+func (app *application) createKioskHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateKioskPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	kiosk := &store.Kiosk{
+		SiteID: payload.SiteID,
+		Name:   payload.Name,
+	}
+	token := uuid.New().String()
+
+	if err := app.store.Kiosks.Create(r.Context(), kiosk, token); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, KioskCredentials{Kiosk: kiosk, Token: token}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getKiosksHandler godoc
+//
+//	@Summary		Fetches all kiosks
+//	@Description	Fetches all kiosks with their site and when they were last seen
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	[]store.Kiosk
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/kiosks [get]
+func (app *application) getKiosksHandler(w http.ResponseWriter, r *http.Request) {
+	kiosks, err := app.store.Kiosks.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, kiosks); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// rotateKioskTokenHandler godoc
+//
+//	@Summary		Rotates a kiosk's device token
+//	@Description	Issues a new device token for a kiosk; the old token stops working immediately
+//	@Tags			kiosks
+//	@Produce		json
+//	@Param			kioskID	path		int	true	"Kiosk ID"
+//	@Success		200		{object}	KioskCredentials
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/kiosks/{kioskID}/token [post]
+func (app *application) rotateKioskTokenHandler(w http.ResponseWriter, r *http.Request) {
+	kioskID, err := strconv.ParseInt(chi.URLParam(r, "kioskID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	token := uuid.New().String()
+
+	kiosk, err := app.store.Kiosks.RotateToken(r.Context(), kioskID, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, KioskCredentials{Kiosk: kiosk, Token: token}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deactivateKioskHandler godoc
+//
+//	@Summary		Deactivates a kiosk
+//	@Description	Revokes a kiosk's device token, e.g. when the device is lost. Rotating the token activates it again.
+//	@Tags			kiosks
+//	@Param			kioskID	path		int		true	"Kiosk ID"
+//	@Success		204		{string}	string	"Kiosk deactivated"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/kiosks/{kioskID} [delete]
+func (app *application) deactivateKioskHandler(w http.ResponseWriter, r *http.Request) {
+	kioskID, err := strconv.ParseInt(chi.URLParam(r, "kioskID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Kiosks.Deactivate(r.Context(), kioskID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setUserSiteHandler godoc
+//
+//	@Summary		Assigns a user to a site
+//	@Description	Sets the site whose kiosks one of the manager's reports may stamp at
+//	@Tags			kiosks
+//	@Accept			json
+//	@Param			userID	path		int					true	"User ID"
+//	@Param			payload	body		SetUserSitePayload	true	"Site"
+//	@Success		204		{string}	string				"Site assigned"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/site [put]
+func (app *application) setUserSiteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SetUserSitePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, getUserFromContext(r), userID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	if err := app.store.Kiosks.SetUserSite(ctx, userID, payload.SiteID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, userID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setPINHandler godoc
+//
+//	@Summary		Sets the user's kiosk PIN
+//	@Description	Sets the 4 to 8 digit PIN the user confirms stamps at the kiosks of their site with. The user's password is required.
+//	@Tags			kiosks
+//	@Accept			json
+//	@Param			payload	body		SetPINPayload	true	"Password and new PIN"
+//	@Success		204		{string}	string			"PIN set"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pin [put]
+func (app *application) setPINHandler(w http.ResponseWriter, r *http.Request) {
+	var payload SetPINPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// Cached users do not carry their password hash, so read it from the database
+	user, err := app.store.Users.GetByID(ctx, getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := user.Password.Compare(payload.Password); err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Kiosks.SetPIN(ctx, user.ID, payload.PIN); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// KioskAuthMiddleware godoc
+//
+//	@Summary		Kiosk Auth Middleware
+//	@Description	Middleware that validates the kiosk device token in the Authorization header
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/kiosk-auth [get]
+func (app *application) KioskAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is missing"))
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Kiosk" {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
+			return
+		}
+
+		kiosk, err := app.store.Kiosks.Authenticate(r.Context(), parts[1])
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), kioskCtx, kiosk)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// getKioskFromCtx godoc
+//
+//	@Summary		Get kiosk from context
+//	@Description	Retrieves the authenticated kiosk from the request context
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	store.Kiosk
+//	@Router			/kiosks/context [get]
+func getKioskFromCtx(r *http.Request) *store.Kiosk {
+	kiosk, _ := r.Context().Value(kioskCtx).(*store.Kiosk)
+	return kiosk
+}
+
+// getKioskUsersHandler godoc
+//
+//	@Summary		Fetches the users of the kiosk's site
+//	@Description	Fetches the active users who may stamp at the authenticated kiosk, for the kiosk to show as a picker
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	[]store.KioskUser
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/kiosk/users [get]
+func (app *application) getKioskUsersHandler(w http.ResponseWriter, r *http.Request) {
+	kiosk := getKioskFromCtx(r)
+
+	users, err := app.store.Kiosks.GetSiteUsers(r.Context(), kiosk.SiteID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createKioskTimestampHandler godoc
+//
+//	@Summary		Stamps at a kiosk
+//	@Description	Creates a live timestamp for a user of the kiosk's site after checking the user's PIN. The stamp records the kiosk it was posted from. A user's PIN is locked after too many consecutive failures, and each kiosk may only check a limited number of PINs per minute.
+//	@Tags			kiosks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateKioskTimestampPayload	true	"User, PIN and stamp type"
+//	@Success		201		{object}	store.Timestamp				"Timestamp created"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/kiosk/timestamps [post]
+func (app *application) createKioskTimestampHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateKioskTimestampPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	kiosk := getKioskFromCtx(r)
+
+	if allow, retryAfter := app.kioskLimiter.Allow(fmt.Sprintf("kiosk-%d", kiosk.ID)); !allow {
+		app.rateLimitExceededResponse(w, r, retryAfter.String())
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Kiosks.CheckPIN(ctx, payload.UserID, kiosk.SiteID, payload.PIN, app.config.kiosk.pin); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidPIN):
+			app.unauthorizedErrorResponse(w, r, err)
+		case errors.Is(err, store.ErrPINLocked):
+			app.rateLimitExceededResponse(w, r, app.config.kiosk.pin.Lockout.String())
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.getUser(ctx, payload.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	timestamp := &store.Timestamp{
+		StampType: payload.StampType,
+		UserID:    user.ID,
+		KioskID:   &kiosk.ID,
+	}
+
+	if err := app.store.Timestamps.Create(ctx, timestamp); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidTransition), errors.Is(err, store.ErrPeriodLocked):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	timestamp.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusCreated, timestamp); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type leaveRequestKey string
+
+const leaveRequestCtx leaveRequestKey = "leaveRequest"
+
+// maxLeaveRequestDays limits the range of a single leave request.
+const maxLeaveRequestDays = 92
+
+// CreateLeaveTypePayload represents the payload for creating a leave type. Leave is paid unless Paid is false.
+type CreateLeaveTypePayload struct {
+	Name string `json:"name" validate:"required,max=50"`
+	Paid *bool  `json:"paid"`
+}
+
+// CreateLeavePolicyPayload represents the payload for creating a leave accrual policy.
+// Without a carry-over cap the whole balance carries over into the next year.
+type CreateLeavePolicyPayload struct {
+	LeaveTypeID  int64    `json:"leave_type_id" validate:"required,min=1"`
+	Name         string   `json:"name" validate:"required,max=100"`
+	DaysPerMonth float64  `json:"days_per_month" validate:"required,gt=0,max=31"`
+	CarryOverCap *float64 `json:"carry_over_cap" validate:"omitempty,min=0,max=999"`
+}
+
+// AssignLeavePolicyPayload represents the payload for assigning a leave accrual policy to a user.
+type AssignLeavePolicyPayload struct {
+	StartsOn string `json:"starts_on" validate:"required,datetime=2006-01-02"`
+}
+
+// CreateLeaveRequestPayload represents the payload for requesting leave on local dates, both included.
+type CreateLeaveRequestPayload struct {
+	LeaveTypeID int64  `json:"leave_type_id" validate:"required,min=1"`
+	StartDate   string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate     string `json:"end_date" validate:"required,datetime=2006-01-02"`
+	HalfDay     bool   `json:"half_day"`
+	Reason      string `json:"reason" validate:"max=255"`
+}
+
+// getLeaveTypesHandler godoc
+//
+//	@Summary		Fetches all leave types
+//	@Description	Fetches all leave types, e.g. vacation, sick leave or parental leave
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]store.LeaveType
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-types [get]
+func (app *application) getLeaveTypesHandler(w http.ResponseWriter, r *http.Request) {
+	types, err := app.store.Leave.GetTypes(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, types); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createLeaveTypeHandler godoc
+//
+//	@Summary		Creates a leave type
+//	@Description	Creates a leave type. Paid leave counts as paid non-worked time in timesheets, unpaid leave only removes the expected time.
+//	@Tags			leave
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateLeaveTypePayload	true	"Leave type"
+//	@Success		201		{object}	store.LeaveType
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-types [post]
+func (app *application) createLeaveTypeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateLeaveTypePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	leaveType := &store.LeaveType{
+		Name: payload.Name,
+		Paid: payload.Paid == nil || *payload.Paid,
+	}
+
+	if err := app.store.Leave.CreateType(r.Context(), leaveType); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, leaveType); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getLeavePoliciesHandler godoc
+//
+//	@Summary		Fetches all leave accrual policies
+//	@Description	Fetches all leave accrual policies
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]store.LeavePolicy
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-policies [get]
+func (app *application) getLeavePoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	policies, err := app.store.Leave.GetPolicies(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, policies); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createLeavePolicyHandler godoc
+//
+//	@Summary		Creates a leave accrual policy
+//	@Description	Creates a policy that accrues days of a leave type every month, e.g. 2.08 days a month with at most 5 days carried into the next year
+//	@Tags			leave
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateLeavePolicyPayload	true	"Accrual policy"
+//	@Success		201		{object}	store.LeavePolicy
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-policies [post]
+func (app *application) createLeavePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateLeavePolicyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	policy := &store.LeavePolicy{
+		LeaveTypeID:  payload.LeaveTypeID,
+		Name:         payload.Name,
+		DaysPerMonth: payload.DaysPerMonth,
+		CarryOverCap: payload.CarryOverCap,
+	}
+
+	if err := app.store.Leave.CreatePolicy(r.Context(), policy); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, policy); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// assignLeavePolicyHandler godoc
+//
+//	@Summary		Assigns a leave accrual policy to a user
+//	@Description	Makes the policy the user's policy for its leave type, replacing any earlier one. The balance accrues from starts_on.
+//	@Tags			leave
+//	@Accept			json
+//	@Param			policyID	path		int							true	"Policy ID"
+//	@Param			userID		path		int							true	"User ID"
+//	@Param			payload		body		AssignLeavePolicyPayload	true	"Accrual start"
+//	@Success		204			{string}	string						"Policy assigned"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-policies/{policyID}/users/{userID} [put]
+func (app *application) assignLeavePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policyID, err := strconv.ParseInt(chi.URLParam(r, "policyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload AssignLeavePolicyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Leave.AssignPolicy(r.Context(), policyID, userID, payload.StartsOn); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createLeaveRequestHandler godoc
+//
+//	@Summary		Requests leave
+//	@Description	Requests leave on local dates in the user's time zone. Weekends and the holidays of the user's calendar are not counted as leave days. The request waits for the manager's approval.
+//	@Tags			leave
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateLeaveRequestPayload	true	"Leave request"
+//	@Success		201		{object}	store.LeaveRequest
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-requests [post]
+func (app *application) createLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateLeaveRequestPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	loc := user.Location()
+
+	from, err := time.ParseInLocation(time.DateOnly, payload.StartDate, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	to, err := time.ParseInLocation(time.DateOnly, payload.EndDate, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	switch {
+	case to.Before(from):
+		app.badRequestResponse(w, r, errors.New("end_date must not be before start_date"))
+		return
+	case payload.HalfDay && !to.Equal(from):
+		app.badRequestResponse(w, r, errors.New("a half day must start and end on the same date"))
+		return
+	case to.Sub(from) > maxLeaveRequestDays*24*time.Hour:
+		app.badRequestResponse(w, r, fmt.Errorf("leave must not exceed %d days", maxLeaveRequestDays))
+		return
+	}
+
+	ctx := r.Context()
+
+	holidays, err := app.store.Holidays.GetForUser(ctx, user.ID, payload.StartDate, payload.EndDate)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	days := store.LeaveDates(from, to, loc, store.HolidayDates(holidays), payload.HalfDay)
+	if len(days) == 0 {
+		app.badRequestResponse(w, r, errors.New("the leave does not cover any workday"))
+		return
+	}
+
+	req := &store.LeaveRequest{
+		UserID:      user.ID,
+		LeaveTypeID: payload.LeaveTypeID,
+		StartDate:   payload.StartDate,
+		EndDate:     payload.EndDate,
+		HalfDay:     payload.HalfDay,
+		Reason:      payload.Reason,
+	}
+
+	if err := app.store.Leave.CreateRequest(ctx, req, days); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("the leave overlaps another leave request"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	req.In(loc)
+
+	if err := app.jsonResponse(w, http.StatusCreated, req); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getLeaveRequestsHandler godoc
+//
+//	@Summary		Fetches the user's leave requests
+//	@Description	Fetches all leave requests of the authenticated user, latest leave first
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]store.LeaveRequest
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-requests [get]
+func (app *application) getLeaveRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	requests, err := app.store.Leave.GetRequests(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.leaveRequestsResponse(w, r, requests)
+}
+
+// getPendingLeaveRequestsHandler godoc
+//
+//	@Summary		Fetches the pending leave requests of the manager's team
+//	@Description	Fetches the leave requests of the users managed by the authenticated user that wait for approval
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]store.LeaveRequest
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-requests/pending [get]
+func (app *application) getPendingLeaveRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	manager := getUserFromContext(r)
+
+	requests, err := app.store.Leave.GetPending(r.Context(), manager.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.leaveRequestsResponse(w, r, requests)
+}
+
+// leaveRequestsResponse writes leave requests in the time zone of the authenticated user.
+func (app *application) leaveRequestsResponse(w http.ResponseWriter, r *http.Request, requests []store.LeaveRequest) {
+	loc := getUserFromContext(r).Location()
+	for i := range requests {
+		requests[i].In(loc)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, requests); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getLeaveRequestHandler godoc
+//
+//	@Summary		Fetches a leave request
+//	@Description	Fetches a leave request by ID
+//	@Tags			leave
+//	@Produce		json
+//	@Param			leaveRequestID	path		int	true	"Leave request ID"
+//	@Success		200				{object}	store.LeaveRequest
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-requests/{leaveRequestID} [get]
+func (app *application) getLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	req := getLeaveRequestFromCtx(r)
+	req.In(getUserFromContext(r).Location())
+
+	if err := app.jsonResponse(w, http.StatusOK, req); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// approveLeaveRequestHandler godoc
+//
+//	@Summary		Approves a leave request
+//	@Description	Approves a pending leave request of one of the manager's reports. Fails if the user's balance of the leave type would be negative at the end of the leave.
+//	@Tags			leave
+//	@Produce		json
+//	@Param			leaveRequestID	path		int	true	"Leave request ID"
+//	@Success		200				{object}	store.LeaveRequest
+//	@Failure		403				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		409				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-requests/{leaveRequestID}/approve [patch]
+func (app *application) approveLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideLeaveRequest(w, r, store.LeaveStatusApproved)
+}
+
+// rejectLeaveRequestHandler godoc
+//
+//	@Summary		Rejects a leave request
+//	@Description	Rejects a pending leave request of one of the manager's reports
+//	@Tags			leave
+//	@Produce		json
+//	@Param			leaveRequestID	path		int	true	"Leave request ID"
+//	@Success		200				{object}	store.LeaveRequest
+//	@Failure		403				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		409				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-requests/{leaveRequestID}/reject [patch]
+func (app *application) rejectLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideLeaveRequest(w, r, store.LeaveStatusRejected)
+}
+
+// decideLeaveRequest records the manager's decision on the leave request in the request context.
+func (app *application) decideLeaveRequest(w http.ResponseWriter, r *http.Request, status string) {
+	req := getLeaveRequestFromCtx(r)
+	manager := getUserFromContext(r)
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, manager, req.UserID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	if err := app.store.Leave.Decide(ctx, req, status, manager.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrLeaveNotPending), errors.Is(err, store.ErrInsufficientBalance):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	req.In(manager.Location())
+
+	if err := app.jsonResponse(w, http.StatusOK, req); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// cancelLeaveRequestHandler godoc
+//
+//	@Summary		Cancels a leave request
+//	@Description	Cancels one of the user's own leave requests while it is pending, or while approved leave has not started yet
+//	@Tags			leave
+//	@Produce		json
+//	@Param			leaveRequestID	path		int	true	"Leave request ID"
+//	@Success		200				{object}	store.LeaveRequest
+//	@Failure		403				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		409				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-requests/{leaveRequestID}/cancel [patch]
+func (app *application) cancelLeaveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	req := getLeaveRequestFromCtx(r)
+	user := getUserFromContext(r)
+
+	if req.UserID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	loc := user.Location()
+	today := time.Now().In(loc).Format(time.DateOnly)
+
+	if err := app.store.Leave.Cancel(r.Context(), req, today); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrLeaveStarted):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	req.In(loc)
+
+	if err := app.jsonResponse(w, http.StatusOK, req); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getLeaveBalancesHandler godoc
+//
+//	@Summary		Fetches the user's leave balances
+//	@Description	Computes the balance of every leave type the user accrues under a policy, as of a local date
+//	@Tags			leave
+//	@Produce		json
+//	@Param			as_of	query		string	false	"Local date (YYYY-MM-DD), defaults to today"
+//	@Success		200		{object}	[]store.LeaveBalance
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-balances [get]
+func (app *application) getLeaveBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	app.leaveBalancesResponse(w, r, getUserFromContext(r))
+}
+
+// getLeaveBalancesByUserHandler godoc
+//
+//	@Summary		Fetches a user's leave balances
+//	@Description	Computes the balance of every leave type one of the manager's reports accrues under a policy, as of a local date in the report's time zone
+//	@Tags			leave
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			as_of	query		string	false	"Local date (YYYY-MM-DD), defaults to today"
+//	@Success		200		{object}	[]store.LeaveBalance
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/leave-balances/{userID} [get]
+func (app *application) getLeaveBalancesByUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.getManagedUser(r.Context(), getUserFromContext(r), userID)
+	if err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	app.leaveBalancesResponse(w, r, user)
+}
+
+// leaveBalancesResponse writes the leave balances of user on the date in the as_of query parameter.
+func (app *application) leaveBalancesResponse(w http.ResponseWriter, r *http.Request, user *store.User) {
+	asOf := time.Now().In(user.Location()).Format(time.DateOnly)
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid as_of date: %w", err))
+			return
+		}
+		asOf = v
+	}
+
+	balances, err := app.store.Leave.GetBalances(r.Context(), user.ID, asOf)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, balances); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// leaveRequestsContextMiddleware godoc
+//
+//	@Summary		Leave Requests Context Middleware
+//	@Description	Middleware that retrieves a leave request by ID and adds it to the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/leave-requests-context [get]
+func (app *application) leaveRequestsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "leaveRequestID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		req, err := app.store.Leave.GetRequestByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, leaveRequestCtx, req)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// checkLeaveRequestOwnership godoc
+//
+//	@Summary		Check Leave Request Ownership Middleware
+//	@Description	Middleware that checks if the user owns the leave request or has the required role
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/check-leave-request-ownership [get]
+func (app *application) checkLeaveRequestOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if getLeaveRequestFromCtx(r).UserID == getUserFromContext(r).ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		app.checkRolePrecedenceMiddleware(requiredRole, next).ServeHTTP(w, r)
+	})
+}
+
+// getLeaveRequestFromCtx godoc
+//
+//	@Summary		Get Leave Request from Context
+//	@Description	Retrieves the leave request from the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/get-leave-request-from-ctx [get]
+func getLeaveRequestFromCtx(r *http.Request) *store.LeaveRequest {
+	req, _ := r.Context().Value(leaveRequestCtx).(*store.LeaveRequest)
+	return req
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"expvar"
 	"runtime"
 	"time"
@@ -69,9 +70,12 @@ func main() {
 				pass: env.GetString("AUTH_BASIC_PASS", "admin"),
 			},
 			token: tokenConfig{
-				secret: env.GetString("AUTH_TOKEN_SECRET", ""),
-				exp:    time.Hour * 1, // 1 hour
-				iss:    "thymeflies",
+				exp:         time.Hour * 1, // 1 hour
+				iss:         "thymeflies",
+				refreshExp:  time.Hour * 24 * time.Duration(env.GetInt("AUTH_REFRESH_TOKEN_EXP_DAYS", 30)),
+				algorithm:   env.GetString("AUTH_TOKEN_ALGORITHM", auth.AlgEdDSA),
+				keyRotation: time.Hour * 24 * time.Duration(env.GetInt("AUTH_TOKEN_KEY_ROTATION_DAYS", 30)),
+				keyGrace:    time.Hour * time.Duration(env.GetInt("AUTH_TOKEN_KEY_GRACE_HOURS", 24)),
 			},
 		},
 		rateLimiter: ratelimiter.Config{
@@ -79,12 +83,82 @@ func main() {
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		compliance: store.ComplianceConfig{
+			MinBreakAfter: time.Minute * time.Duration(env.GetInt("COMPLIANCE_MIN_BREAK_AFTER_MINUTES", 6*60)),
+			MinBreak:      time.Minute * time.Duration(env.GetInt("COMPLIANCE_MIN_BREAK_MINUTES", 30)),
+			MinRest:       time.Minute * time.Duration(env.GetInt("COMPLIANCE_MIN_REST_MINUTES", 11*60)),
+			MaxDailyWork:  time.Minute * time.Duration(env.GetInt("COMPLIANCE_MAX_DAILY_WORK_MINUTES", 10*60)),
+		},
+		autoSignOut: autoSignOutConfig{
+			enabled:  env.GetBool("AUTO_SIGN_OUT_ENABLED", true),
+			maxShift: time.Hour * time.Duration(env.GetInt("AUTO_SIGN_OUT_MAX_SHIFT_HOURS", 12)),
+			interval: time.Minute * time.Duration(env.GetInt("AUTO_SIGN_OUT_INTERVAL_MINUTES", 15)),
+		},
+		invoice: invoiceConfig{
+			issuer:     env.GetString("INVOICE_ISSUER", "Thyme Flies"),
+			currency:   env.GetString("INVOICE_CURRENCY", "EUR"),
+			taxPercent: float64(env.GetInt("INVOICE_TAX_PERCENT", 0)),
+		},
+		punctuality: store.PunctualityGrace{
+			Late:  time.Minute * time.Duration(env.GetInt("PUNCTUALITY_LATE_GRACE_MINUTES", 5)),
+			Early: time.Minute * time.Duration(env.GetInt("PUNCTUALITY_EARLY_GRACE_MINUTES", 5)),
+		},
+		kiosk: kioskConfig{
+			pin: store.PINPolicy{
+				MaxAttempts: env.GetInt("KIOSK_PIN_MAX_ATTEMPTS", 5),
+				Lockout:     time.Minute * time.Duration(env.GetInt("KIOSK_PIN_LOCKOUT_MINUTES", 15)),
+			},
+			attemptsPerMinute: env.GetInt("KIOSK_PIN_ATTEMPTS_PER_MINUTE", 30),
+		},
+		mfa: mfaConfig{
+			issuer:       env.GetString("MFA_ISSUER", "Thyme Flies"),
+			challengeExp: time.Minute * time.Duration(env.GetInt("MFA_CHALLENGE_EXP_MINUTES", 5)),
+			maxAttempts:  env.GetInt("MFA_MAX_ATTEMPTS", 5),
+		},
+		login: loginConfig{
+			account: store.LoginPolicy{
+				FreeAttempts: env.GetInt("LOGIN_FREE_ATTEMPTS", 3),
+				MaxFailures:  env.GetInt("LOGIN_MAX_FAILURES", 10),
+				BaseDelay:    time.Second * time.Duration(env.GetInt("LOGIN_BASE_DELAY_SECONDS", 1)),
+				Lockout:      time.Minute * time.Duration(env.GetInt("LOGIN_LOCKOUT_MINUTES", 30)),
+				Window:       time.Hour * 24,
+			},
+			ip: store.LoginPolicy{
+				FreeAttempts: env.GetInt("LOGIN_IP_FREE_ATTEMPTS", 20),
+				MaxFailures:  env.GetInt("LOGIN_IP_MAX_FAILURES", 100),
+				BaseDelay:    time.Second * time.Duration(env.GetInt("LOGIN_BASE_DELAY_SECONDS", 1)),
+				Lockout:      time.Minute * time.Duration(env.GetInt("LOGIN_LOCKOUT_MINUTES", 30)),
+				Window:       time.Hour * 24,
+			},
+		},
+	}
+
+	// Replaced signing keys must outlive the tokens they signed
+	if cfg.auth.token.keyGrace < cfg.auth.token.exp {
+		cfg.auth.token.keyGrace = cfg.auth.token.exp
 	}
 
 	// Logger
 	logger := zap.Must(zap.NewProduction()).Sugar()
 	defer logger.Sync()
 
+	// Without trusted proxies every request is attributed to the address it connects from
+	trustedProxies, err := parseTrustedProxies(env.GetString("TRUSTED_PROXIES", ""))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	cfg.trustedProxies = trustedProxies
+
+	// Signing keys and TOTP secrets are stored unencrypted without a key encryption key, which is only fit for development
+	keyKEK, err := auth.ParseKeyEncryptionKey(env.GetString("AUTH_TOKEN_KEY_ENCRYPTION_KEY", ""))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if keyKEK == nil {
+		logger.Warn("AUTH_TOKEN_KEY_ENCRYPTION_KEY is not set, signing keys and TOTP secrets are stored unencrypted")
+	}
+	cfg.auth.token.keyKEK = keyKEK
+
 	// Main Database
 	db, err := db.New(
 		cfg.db.addr,
@@ -114,16 +188,26 @@ func main() {
 		cfg.rateLimiter.TimeFrame,
 	)
 
+	// PIN checks are limited per kiosk on top of the per-user lockout
+	kioskLimiter := ratelimiter.NewFixedWindowLimiter(
+		cfg.kiosk.attemptsPerMinute,
+		time.Minute,
+	)
+
 	// Mailer
 	mailer := mailer.NewSendgrid(cfg.mail.sendGrid.apiKey, cfg.mail.fromEmail)
 
 	// Authenticator
+	signingKeys := auth.NewKeySet()
 	jwtAuthenticator := auth.NewJWTAuthenticator(
-		cfg.auth.token.secret,
+		signingKeys,
 		cfg.auth.token.iss,
 		cfg.auth.token.iss,
 	)
 
+	// Compliance rules are built before the store variable shadows the package
+	complianceRules := store.NewComplianceRules(cfg.compliance)
+
 	store := store.NewStorage(db)
 	cacheStorage := cache.NewRedisStorage(rdb)
 
@@ -134,7 +218,11 @@ func main() {
 		logger:        logger,
 		mailer:        mailer,
 		authenticator: jwtAuthenticator,
+		signingKeys:   signingKeys,
 		rateLimiter:   rateLimiter,
+		kioskLimiter:  kioskLimiter,
+
+		complianceRules: complianceRules,
 	}
 
 	// Metrics collected
@@ -146,6 +234,11 @@ func main() {
 		return runtime.NumGoroutine()
 	}))
 
+	// Tokens cannot be issued until a signing key is loaded
+	if err := app.loadSigningKeys(context.Background()); err != nil {
+		logger.Fatal(err)
+	}
+
 	mux := app.mount()
 
 	logger.Fatal(app.run(mux))
@@ -0,0 +1,698 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/auth"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/totp"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// mfaConfig configures two-factor authentication.
+type mfaConfig struct {
+	issuer       string        // Account issuer shown in authenticator apps
+	challengeExp time.Duration // How long the second login step may take
+	maxAttempts  int           // Wrong codes allowed per login challenge
+}
+
+// mfaRequiredRole is the lowest role that may not log in without two-factor authentication.
+const mfaRequiredRole = "manager"
+
+// recoveryCodeCount is how many recovery codes a user gets at a time.
+const recoveryCodeCount = 10
+
+// errInvalidCode is returned when a one-time code does not match.
+var errInvalidCode = errors.New("invalid code")
+
+// MFAChallengeResponse is returned by the first login step of users with two-factor
+// authentication. EnrollmentRequired is set for users whose role requires two-factor
+// authentication but who have not set it up yet.
+type MFAChallengeResponse struct {
+	MFAToken           string    `json:"mfa_token"`
+	EnrollmentRequired bool      `json:"enrollment_required"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+// MFALoginResponse is returned when a login is completed with a one-time code. RecoveryCodes
+// are set only when the code confirmed an enrollment started during login; they are shown
+// only once.
+type MFALoginResponse struct {
+	Token         string   `json:"token"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// TOTPEnrollment holds the secret of a pending enrollment and its otpauth:// provisioning URI,
+// which clients show as a QR code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFAStatus describes the two-factor authentication of a user.
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"` // Whether the user's role requires it
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// VerifyMFAPayload represents the payload for completing a login with a one-time code or a
+// recovery code.
+type VerifyMFAPayload struct {
+	MFAToken     string `json:"mfa_token" validate:"required,max=64"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=32"`
+}
+
+// EnrollMFAPayload represents the payload for setting up TOTP during login.
+type EnrollMFAPayload struct {
+	MFAToken string `json:"mfa_token" validate:"required,max=64"`
+}
+
+// TOTPCodePayload represents the payload for confirming an action with a one-time code.
+type TOTPCodePayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// verifyMFAHandler godoc
+//
+//	@Summary		Completes a login with a one-time code
+//	@Description	Completes the login started with POST /authentication/token using a code from the user's authenticator app or an unused recovery code, and starts a session. The first code after an enrollment started during login confirms the enrollment, and the user's recovery codes are returned with the token; they are shown only once.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		VerifyMFAPayload	true	"Challenge token and code"
+//	@Success		201		{object}	MFALoginResponse
+//	@Header			201		{string}	Refresh-Token	"Refresh token of the new session"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/mfa [post]
+func (app *application) verifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var payload VerifyMFAPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	challenge, ok := app.getMFAChallenge(w, r, payload.MFAToken)
+	if !ok {
+		return
+	}
+
+	secret, err := app.getTOTP(ctx, challenge.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, errors.New("an authenticator app has to be set up first"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	var recoveryCodes []string
+
+	switch {
+	case payload.RecoveryCode != "" && secret.Enabled():
+		err = app.store.MFA.UseRecoveryCode(ctx, challenge.UserID, normalizeRecoveryCode(payload.RecoveryCode))
+	case payload.RecoveryCode != "":
+		app.badRequestResponse(w, r, errors.New("recovery codes cannot be used before the enrollment is confirmed"))
+		return
+	case secret.Enabled():
+		err = app.useTOTPCode(ctx, secret, payload.Code)
+	default:
+		// The first code confirms an enrollment started during login
+		if recoveryCodes, err = generateRecoveryCodes(); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		err = app.enableTOTP(ctx, secret, payload.Code, recoveryCodes)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidCode), errors.Is(err, store.ErrCodeUsed), errors.Is(err, store.ErrConflict):
+			if err := app.store.MFA.FailChallenge(ctx, payload.MFAToken); err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+			app.logger.Warnw("wrong multi-factor code", "user_id", challenge.UserID, "ip", r.RemoteAddr)
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Every challenge completes a single login
+	if err := app.store.MFA.CompleteChallenge(ctx, payload.MFAToken); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.getUser(ctx, challenge.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// The password attempt of the login only counts as successful now
+	if err := app.store.LoginThrottles.Succeed(ctx, user.Email, clientIP(r)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	session, err := app.startSession(w, r, user, challenge.Device)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	token, err := app.generateAccessToken(user, session.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if recoveryCodes != nil {
+		app.logger.Infow("two-factor authentication enabled", "user_id", user.ID)
+	}
+
+	response := MFALoginResponse{Token: token, RecoveryCodes: recoveryCodes}
+	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// enrollMFAChallengeHandler godoc
+//
+//	@Summary		Sets up TOTP during login
+//	@Description	Starts the TOTP enrollment of a user whose role requires two-factor authentication and who got a challenge with enrollment_required. The login is completed with a code from the app at POST /authentication/mfa.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		EnrollMFAPayload	true	"Challenge token"
+//	@Success		201		{object}	TOTPEnrollment
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/mfa/enroll [post]
+func (app *application) enrollMFAChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload EnrollMFAPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	challenge, ok := app.getMFAChallenge(w, r, payload.MFAToken)
+	if !ok {
+		return
+	}
+
+	user, err := app.getUser(r.Context(), challenge.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.totpEnrollmentResponse(w, r, user)
+}
+
+// getMFAHandler godoc
+//
+//	@Summary		Fetches the user's two-factor authentication status
+//	@Description	Reports whether the user has two-factor authentication enabled, whether their role requires it, and how many recovery codes are left
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		200	{object}	MFAStatus
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/mfa [get]
+func (app *application) getMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	secret, required, err := app.getMFAState(ctx, user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	status := MFAStatus{Enabled: secret.Enabled(), Required: required}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = app.store.MFA.CountRecoveryCodes(ctx, user.ID); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, status); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// startTOTPEnrollmentHandler godoc
+//
+//	@Summary		Starts a TOTP enrollment
+//	@Description	Generates a secret for the user's authenticator app. Two-factor authentication is enabled once a code is confirmed with POST /mfa/totp/confirm; starting again replaces an unconfirmed secret.
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		201	{object}	TOTPEnrollment
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/mfa/totp [post]
+func (app *application) startTOTPEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	app.totpEnrollmentResponse(w, r, getUserFromContext(r))
+}
+
+// confirmTOTPHandler godoc
+//
+//	@Summary		Confirms a TOTP enrollment
+//	@Description	Enables two-factor authentication with a code from the authenticator app and returns the user's recovery codes. The codes are shown only once.
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		TOTPCodePayload	true	"Code from the authenticator app"
+//	@Success		201		{object}	[]string
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/mfa/totp/confirm [post]
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TOTPCodePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	secret, err := app.getTOTP(ctx, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, errors.New("no enrollment has been started"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.enableTOTP(ctx, secret, payload.Code, codes); err != nil {
+		switch {
+		case errors.Is(err, errInvalidCode):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("two-factor authentication is already enabled"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("two-factor authentication enabled", "user_id", user.ID)
+
+	if err := app.jsonResponse(w, http.StatusCreated, codes); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// disableTOTPHandler godoc
+//
+//	@Summary		Disables TOTP
+//	@Description	Turns off two-factor authentication after checking a current code and deletes the recovery codes. An unconfirmed enrollment is cancelled without a code. Users whose role requires two-factor authentication cannot turn it off.
+//	@Tags			mfa
+//	@Accept			json
+//	@Param			payload	body		TOTPCodePayload	false	"Code from the authenticator app"
+//	@Success		204		{string}	string			"TOTP disabled"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/mfa/totp [delete]
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	secret, required, err := app.getMFAState(ctx, user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if secret == nil {
+		app.notFoundResponse(w, r, errors.New("two-factor authentication is not set up"))
+		return
+	}
+
+	if secret.Enabled() {
+		if required {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		if ok := app.checkTOTPPayload(w, r, secret); !ok {
+			return
+		}
+	}
+
+	if err := app.store.MFA.DisableTOTP(ctx, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// regenerateRecoveryCodesHandler godoc
+//
+//	@Summary		Replaces the user's recovery codes
+//	@Description	Replaces every recovery code of the user after checking a current code and returns the new ones, which are shown only once
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		TOTPCodePayload	true	"Code from the authenticator app"
+//	@Success		201		{object}	[]string
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/mfa/recovery-codes [post]
+func (app *application) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	secret, err := app.getTOTP(ctx, user.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !secret.Enabled() {
+		app.notFoundResponse(w, r, errors.New("two-factor authentication is not enabled"))
+		return
+	}
+
+	if ok := app.checkTOTPPayload(w, r, secret); !ok {
+		return
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.ReplaceRecoveryCodes(ctx, user.ID, codes); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, codes); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// resetUserMFAHandler godoc
+//
+//	@Summary		Resets a user's two-factor authentication
+//	@Description	Removes the authenticator app and recovery codes of a user who lost both. Users whose role requires two-factor authentication set it up again at their next login.
+//	@Tags			mfa
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Two-factor authentication reset"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mfa [delete]
+func (app *application) resetUserMFAHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.DisableTOTP(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("two-factor authentication reset", "user_id", userID, "by", getUserFromContext(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getMFAState returns the TOTP secret of user, or nil if there is none, and whether the user's
+// role requires two-factor authentication.
+func (app *application) getMFAState(ctx context.Context, user *store.User) (*store.TOTP, bool, error) {
+	required, err := app.checkRolePrecedence(ctx, user, mfaRequiredRole)
+	if err != nil {
+		return nil, false, err
+	}
+
+	secret, err := app.getTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, required, nil
+		}
+		return nil, false, err
+	}
+
+	return secret, required, nil
+}
+
+// mfaChallengeResponse starts the second login step of user instead of issuing a token.
+func (app *application) mfaChallengeResponse(w http.ResponseWriter, r *http.Request, user *store.User, device string, enrolled bool) {
+	token := uuid.New().String()
+	challenge := &store.MFAChallenge{
+		UserID:    user.ID,
+		Device:    device,
+		ExpiresAt: time.Now().Add(app.config.mfa.challengeExp),
+	}
+
+	if err := app.store.MFA.CreateChallenge(r.Context(), challenge, token); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := MFAChallengeResponse{
+		MFAToken:           token,
+		EnrollmentRequired: !enrolled,
+		ExpiresAt:          challenge.ExpiresAt.In(user.Location()),
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getMFAChallenge looks up the challenge of a token and writes a 401 response if it is unknown
+// or expired.
+func (app *application) getMFAChallenge(w http.ResponseWriter, r *http.Request, token string) (*store.MFAChallenge, bool) {
+	challenge, err := app.store.MFA.GetChallenge(r.Context(), token, app.config.mfa.maxAttempts)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrChallengeExpired):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return challenge, true
+}
+
+// totpEnrollmentResponse starts a TOTP enrollment of user and writes its secret.
+func (app *application) totpEnrollmentResponse(w http.ResponseWriter, r *http.Request, user *store.User) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	sealed, err := auth.SealSecret(app.config.auth.token.keyKEK, totpSecretOwner(user.ID), secret)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.SetPendingTOTP(r.Context(), user.ID, sealed); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("two-factor authentication is already enabled"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	enrollment := TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(app.config.mfa.issuer, user.Email, secret),
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getTOTP retrieves the TOTP secret of a user and opens it with the key encryption key.
+// Secrets enrolled before a key encryption key was configured are stored unencrypted.
+func (app *application) getTOTP(ctx context.Context, userID int64) (*store.TOTP, error) {
+	secret, err := app.store.MFA.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret.Secret, err = auth.OpenSecret(app.config.auth.token.keyKEK, totpSecretOwner(userID), secret.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// totpSecretOwner returns the owner a user's TOTP secret is sealed to, so that a sealed
+// secret copied to another user's row cannot be opened.
+func totpSecretOwner(userID int64) string {
+	return "totp-" + strconv.FormatInt(userID, 10)
+}
+
+// checkTOTPPayload reads a TOTPCodePayload and checks its code against secret, writing the
+// error response if it does not match.
+func (app *application) checkTOTPPayload(w http.ResponseWriter, r *http.Request, secret *store.TOTP) bool {
+	var payload TOTPCodePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return false
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return false
+	}
+
+	if err := app.useTOTPCode(r.Context(), secret, payload.Code); err != nil {
+		switch {
+		case errors.Is(err, errInvalidCode), errors.Is(err, store.ErrCodeUsed):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// useTOTPCode checks a code of an enabled secret. Every code is accepted only once.
+func (app *application) useTOTPCode(ctx context.Context, secret *store.TOTP, code string) error {
+	counter, ok := totp.Validate(secret.Secret, code, time.Now())
+	if !ok {
+		return errInvalidCode
+	}
+
+	return app.store.MFA.UseTOTP(ctx, secret.UserID, counter)
+}
+
+// enableTOTP confirms a pending secret with its first code and stores the recovery codes.
+func (app *application) enableTOTP(ctx context.Context, secret *store.TOTP, code string, recoveryCodes []string) error {
+	counter, ok := totp.Validate(secret.Secret, code, time.Now())
+	if !ok {
+		return errInvalidCode
+	}
+
+	return app.store.MFA.EnableTOTP(ctx, secret.UserID, counter, recoveryCodes)
+}
+
+// generateRecoveryCodes returns new random recovery codes formatted as xxxxx-xxxxx.
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode brings a recovery code as typed by the user into its generated form.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 10 {
+		return code
+	}
+
+	return code[:5] + "-" + code[5:]
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
 
@@ -56,7 +57,31 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Tokens issued before a password, role or account change carry an older version
+		if ver, _ := claims["ver"].(float64); int(ver) != user.TokenVersion {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+			return
+		}
+
+		// Every access token belongs to a session and is revoked with it
+		sid, ok := claims["sid"].(float64)
+		if !ok {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("token has no session"))
+			return
+		}
+
+		active, err := app.store.Sessions.IsActive(ctx, user.ID, int64(sid))
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !active {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("session has expired or was revoked"))
+			return
+		}
+
 		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, sessionCtx, int64(sid))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -222,7 +247,7 @@ func (app *application) getUser(ctx context.Context, userID int64) (*store.User,
 func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.rateLimiter.Enabled {
-			if allow, retryAfter := app.rateLimiter.Allow(r.RemoteAddr); !allow {
+			if allow, retryAfter := app.rateLimiter.Allow(clientIP(r)); !allow {
 				app.rateLimitExceededResponse(w, r, retryAfter.String())
 				return
 			}
@@ -231,3 +256,91 @@ func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RealIPMiddleware godoc
+//
+//	@Summary		Real IP Middleware
+//	@Description	Middleware that replaces the remote address of requests from a trusted proxy with the client address in X-Forwarded-For or X-Real-IP. Other requests keep the address of their connection, so clients cannot pick the address they are rate limited and throttled under.
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/real-ip [get]
+func (app *application) RealIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := app.forwardedIP(r); ok {
+			r.RemoteAddr = ip.String()
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedIP returns the client address forwarded by a trusted proxy. X-Forwarded-For is read
+// from the right, where the trusted proxies appended the addresses they received requests from.
+func (app *application) forwardedIP(r *http.Request) (netip.Addr, bool) {
+	peer, err := netip.ParseAddr(clientIP(r))
+	if err != nil || !app.isTrustedProxy(peer) {
+		return netip.Addr{}, false
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+
+		var ip netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			if ip, err = netip.ParseAddr(strings.TrimSpace(hops[i])); err != nil {
+				return netip.Addr{}, false
+			}
+			if !app.isTrustedProxy(ip) {
+				break
+			}
+		}
+
+		return ip.Unmap(), true
+	}
+
+	if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return ip.Unmap(), true
+	}
+
+	return netip.Addr{}, false
+}
+
+// isTrustedProxy reports whether ip belongs to one of the configured proxy networks.
+func (app *application) isTrustedProxy(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range app.config.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTrustedProxies parses a comma-separated list of CIDR prefixes or single addresses.
+func parseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// OvertimePolicyPayload represents the payload for creating or replacing an overtime policy.
+type OvertimePolicyPayload struct {
+	Name              string  `json:"name" validate:"required,max=100"`
+	DailyThreshold    int     `json:"daily_threshold" validate:"gte=0,lte=1440"`
+	WeeklyThreshold   int     `json:"weekly_threshold" validate:"gte=0,lte=10080"`
+	DailyMultiplier   float64 `json:"daily_multiplier" validate:"gte=0,lte=10"`
+	WeeklyMultiplier  float64 `json:"weekly_multiplier" validate:"gte=0,lte=10"`
+	RestDayMultiplier float64 `json:"rest_day_multiplier" validate:"gte=0,lte=10"`
+	HolidayMultiplier float64 `json:"holiday_multiplier" validate:"gte=0,lte=10"`
+	RestDays          []int   `json:"rest_days" validate:"max=7,dive,gte=0,lte=6"`
+}
+
+// apply copies the payload into policy.
+func (p OvertimePolicyPayload) apply(policy *store.OvertimePolicy) {
+	policy.Name = p.Name
+	policy.DailyThreshold = p.DailyThreshold
+	policy.WeeklyThreshold = p.WeeklyThreshold
+	policy.DailyMultiplier = p.DailyMultiplier
+	policy.WeeklyMultiplier = p.WeeklyMultiplier
+	policy.RestDayMultiplier = p.RestDayMultiplier
+	policy.HolidayMultiplier = p.HolidayMultiplier
+
+	policy.RestDays = make([]time.Weekday, len(p.RestDays))
+	for i, d := range p.RestDays {
+		policy.RestDays[i] = time.Weekday(d)
+	}
+}
+
+// createOvertimePolicyHandler godoc
+//
+//	@Summary		Creates an overtime policy
+//	@Description	Creates an overtime policy with daily and weekly thresholds and rest-day and holiday multipliers
+//	@Tags			overtime
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		OvertimePolicyPayload	true	"Policy"
+//	@Success		201		{object}	store.OvertimePolicy
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/overtime-policies [post]
+func (app *application) createOvertimePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload OvertimePolicyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	policy := &store.OvertimePolicy{}
+	payload.apply(policy)
+
+	if err := app.store.OvertimePolicies.Create(r.Context(), policy); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, policy); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getOvertimePoliciesHandler godoc
+//
+//	@Summary		Fetches all overtime policies
+//	@Description	Fetches all overtime policies
+//	@Tags			overtime
+//	@Produce		json
+//	@Success		200	{object}	[]store.OvertimePolicy
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/overtime-policies [get]
+func (app *application) getOvertimePoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	policies, err := app.store.OvertimePolicies.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, policies); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// updateOvertimePolicyHandler godoc
+//
+//	@Summary		Updates an overtime policy
+//	@Description	Replaces the settings of an overtime policy
+//	@Tags			overtime
+//	@Accept			json
+//	@Produce		json
+//	@Param			policyID	path		int						true	"Policy ID"
+//	@Param			payload		body		OvertimePolicyPayload	true	"Policy"
+//	@Success		200			{object}	store.OvertimePolicy
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/overtime-policies/{policyID} [patch]
+func (app *application) updateOvertimePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policyID, err := strconv.ParseInt(chi.URLParam(r, "policyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	policy, err := app.store.OvertimePolicies.GetByID(r.Context(), policyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	var payload OvertimePolicyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.apply(policy)
+
+	if err := app.store.OvertimePolicies.Update(r.Context(), policy); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, policy); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteOvertimePolicyHandler godoc
+//
+//	@Summary		Deletes an overtime policy
+//	@Description	Deletes an overtime policy and its assignments
+//	@Tags			overtime
+//	@Param			policyID	path		int		true	"Policy ID"
+//	@Success		204			{string}	string	"Policy deleted"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/overtime-policies/{policyID} [delete]
+func (app *application) deleteOvertimePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policyID, err := strconv.ParseInt(chi.URLParam(r, "policyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.OvertimePolicies.Delete(r.Context(), policyID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// assignOvertimePolicyToUserHandler godoc
+//
+//	@Summary		Assigns an overtime policy to a user
+//	@Description	Assigns an overtime policy to a user; it takes precedence over the policy of the user's role
+//	@Tags			overtime
+//	@Param			policyID	path		int		true	"Policy ID"
+//	@Param			userID		path		int		true	"User ID"
+//	@Success		204			{string}	string	"Policy assigned"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/overtime-policies/{policyID}/users/{userID} [put]
+func (app *application) assignOvertimePolicyToUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.assignOvertimePolicy(w, r, "userID", app.store.OvertimePolicies.AssignToUser)
+}
+
+// assignOvertimePolicyToRoleHandler godoc
+//
+//	@Summary		Assigns an overtime policy to a role
+//	@Description	Assigns an overtime policy to every user with the role who has no policy of their own
+//	@Tags			overtime
+//	@Param			policyID	path		int		true	"Policy ID"
+//	@Param			roleID		path		int		true	"Role ID"
+//	@Success		204			{string}	string	"Policy assigned"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/overtime-policies/{policyID}/roles/{roleID} [put]
+func (app *application) assignOvertimePolicyToRoleHandler(w http.ResponseWriter, r *http.Request) {
+	app.assignOvertimePolicy(w, r, "roleID", app.store.OvertimePolicies.AssignToRole)
+}
+
+// assignOvertimePolicy assigns the policy in the URL to the user or role named by param.
+func (app *application) assignOvertimePolicy(w http.ResponseWriter, r *http.Request, param string, assign func(context.Context, int64, int64) error) {
+	policyID, err := strconv.ParseInt(chi.URLParam(r, "policyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	targetID, err := strconv.ParseInt(chi.URLParam(r, param), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.store.OvertimePolicies.GetByID(r.Context(), policyID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := assign(r.Context(), policyID, targetID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type payPeriodKey string
+
+const payPeriodCtx payPeriodKey = "payPeriod"
+
+// CreatePayPeriodPayload represents the payload for opening a timesheet period.
+// The dates are local dates in the user's time zone; both are included in the period.
+type CreatePayPeriodPayload struct {
+	From string `json:"from" validate:"required,datetime=2006-01-02"`
+	To   string `json:"to" validate:"required,datetime=2006-01-02"`
+}
+
+// PayPeriodTransitionPayload represents the optional body of a pay period status change.
+// Returning a period to draft and reopening a locked period require a reason.
+type PayPeriodTransitionPayload struct {
+	Reason string `json:"reason" validate:"max=255"`
+}
+
+// PayPeriodDetails represents a pay period together with its status history.
+type PayPeriodDetails struct {
+	*store.PayPeriod
+	Transitions []store.PayPeriodTransition `json:"transitions"`
+}
+
+// createPayPeriodHandler godoc
+//
+//	@Summary		Creates a timesheet period
+//	@Description	Opens a draft timesheet period for the user between two local dates
+//	@Tags			pay-periods
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreatePayPeriodPayload	true	"Period dates"
+//	@Success		201		{object}	store.PayPeriod
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods [post]
+func (app *application) createPayPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreatePayPeriodPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	loc := user.Location()
+
+	from, err := time.ParseInLocation(time.DateOnly, payload.From, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	to, err := time.ParseInLocation(time.DateOnly, payload.To, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if to.Before(from) {
+		app.badRequestResponse(w, r, errors.New("to must not be before from"))
+		return
+	}
+
+	period := &store.PayPeriod{
+		UserID:   user.ID,
+		StartsAt: from.UTC(),
+		EndsAt:   time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc).UTC(),
+	}
+
+	if err := app.store.PayPeriods.Create(r.Context(), period); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	period.In(loc)
+
+	if err := app.jsonResponse(w, http.StatusCreated, period); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getPayPeriodsHandler godoc
+//
+//	@Summary		Fetches the user's timesheet periods
+//	@Description	Fetches all timesheet periods of the user, newest first
+//	@Tags			pay-periods
+//	@Produce		json
+//	@Success		200	{object}	[]store.PayPeriod
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods [get]
+func (app *application) getPayPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	app.payPeriodsResponse(w, r, getUserFromContext(r))
+}
+
+// getPayPeriodsByUserHandler godoc
+//
+//	@Summary		Fetches a user's timesheet periods
+//	@Description	Fetches all timesheet periods of one of the manager's reports, newest first
+//	@Tags			pay-periods
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	[]store.PayPeriod
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods/users/{userID} [get]
+func (app *application) getPayPeriodsByUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.getManagedUser(r.Context(), getUserFromContext(r), userID)
+	if err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	app.payPeriodsResponse(w, r, user)
+}
+
+// payPeriodsResponse writes the periods of user in the user's time zone.
+func (app *application) payPeriodsResponse(w http.ResponseWriter, r *http.Request, user *store.User) {
+	periods, err := app.store.PayPeriods.GetByUser(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range periods {
+		periods[i].In(user.Location())
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, periods); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getPayPeriodHandler godoc
+//
+//	@Summary		Fetches a timesheet period
+//	@Description	Fetches a timesheet period with all of its status transitions
+//	@Tags			pay-periods
+//	@Produce		json
+//	@Param			periodID	path		int	true	"Pay period ID"
+//	@Success		200			{object}	PayPeriodDetails
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods/{periodID} [get]
+func (app *application) getPayPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	period := getPayPeriodFromCtx(r)
+	loc := getUserFromContext(r).Location()
+
+	transitions, err := app.store.PayPeriods.GetTransitions(r.Context(), period.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range transitions {
+		transitions[i].CreatedAt = transitions[i].CreatedAt.In(loc)
+	}
+	period.In(loc)
+
+	if err := app.jsonResponse(w, http.StatusOK, PayPeriodDetails{PayPeriod: period, Transitions: transitions}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// submitPayPeriodHandler godoc
+//
+//	@Summary		Submits a timesheet period
+//	@Description	Submits a draft timesheet period for approval
+//	@Tags			pay-periods
+//	@Accept			json
+//	@Produce		json
+//	@Param			periodID	path		int							true	"Pay period ID"
+//	@Param			payload		body		PayPeriodTransitionPayload	false	"Optional comment"
+//	@Success		200			{object}	store.PayPeriod
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods/{periodID}/submit [patch]
+func (app *application) submitPayPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	app.transitionPayPeriod(w, r, store.PayPeriodStatusSubmitted)
+}
+
+// approvePayPeriodHandler godoc
+//
+//	@Summary		Approves a timesheet period
+//	@Description	Approves a submitted timesheet period
+//	@Tags			pay-periods
+//	@Accept			json
+//	@Produce		json
+//	@Param			periodID	path		int							true	"Pay period ID"
+//	@Param			payload		body		PayPeriodTransitionPayload	false	"Optional comment"
+//	@Success		200			{object}	store.PayPeriod
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods/{periodID}/approve [patch]
+func (app *application) approvePayPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	app.transitionPayPeriod(w, r, store.PayPeriodStatusApproved)
+}
+
+// returnPayPeriodHandler godoc
+//
+//	@Summary		Returns a timesheet period to draft
+//	@Description	Sends a submitted timesheet period back to the user for corrections
+//	@Tags			pay-periods
+//	@Accept			json
+//	@Produce		json
+//	@Param			periodID	path		int							true	"Pay period ID"
+//	@Param			payload		body		PayPeriodTransitionPayload	true	"Reason"
+//	@Success		200			{object}	store.PayPeriod
+//	@Failure		400			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods/{periodID}/return [patch]
+func (app *application) returnPayPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	if getPayPeriodFromCtx(r).Status != store.PayPeriodStatusSubmitted {
+		app.conflictResponse(w, r, store.ErrInvalidPeriodTransition)
+		return
+	}
+
+	app.transitionPayPeriod(w, r, store.PayPeriodStatusDraft)
+}
+
+// lockPayPeriodHandler godoc
+//
+//	@Summary		Locks a timesheet period
+//	@Description	Locks an approved timesheet period so that its stamps can no longer be created, changed or deleted
+//	@Tags			pay-periods
+//	@Accept			json
+//	@Produce		json
+//	@Param			periodID	path		int							true	"Pay period ID"
+//	@Param			payload		body		PayPeriodTransitionPayload	false	"Optional comment"
+//	@Success		200			{object}	store.PayPeriod
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods/{periodID}/lock [patch]
+func (app *application) lockPayPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	app.transitionPayPeriod(w, r, store.PayPeriodStatusLocked)
+}
+
+// reopenPayPeriodHandler godoc
+//
+//	@Summary		Reopens a locked timesheet period
+//	@Description	Moves a locked timesheet period back to draft; the reason is kept in the period's history
+//	@Tags			pay-periods
+//	@Accept			json
+//	@Produce		json
+//	@Param			periodID	path		int							true	"Pay period ID"
+//	@Param			payload		body		PayPeriodTransitionPayload	true	"Reason"
+//	@Success		200			{object}	store.PayPeriod
+//	@Failure		400			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pay-periods/{periodID}/reopen [patch]
+func (app *application) reopenPayPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	if getPayPeriodFromCtx(r).Status != store.PayPeriodStatusLocked {
+		app.conflictResponse(w, r, store.ErrInvalidPeriodTransition)
+		return
+	}
+
+	app.transitionPayPeriod(w, r, store.PayPeriodStatusDraft)
+}
+
+// transitionPayPeriod moves the period in the request context to status and records the actor.
+func (app *application) transitionPayPeriod(w http.ResponseWriter, r *http.Request, status string) {
+	var payload PayPeriodTransitionPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	period := getPayPeriodFromCtx(r)
+	user := getUserFromContext(r)
+
+	if err := app.store.PayPeriods.Transition(r.Context(), period, status, user.ID, payload.Reason); err != nil {
+		switch {
+		case errors.Is(err, store.ErrReasonRequired):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrInvalidPeriodTransition):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	period.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusOK, period); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// payPeriodsContextMiddleware godoc
+//
+//	@Summary		Pay Periods Context Middleware
+//	@Description	Middleware that retrieves a pay period by ID and adds it to the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/pay-periods-context [get]
+func (app *application) payPeriodsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "periodID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		period, err := app.store.PayPeriods.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, payPeriodCtx, period)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// checkPayPeriodOwnership godoc
+//
+//	@Summary		Check Pay Period Ownership Middleware
+//	@Description	Middleware that checks if the user owns the pay period or has the required role
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/check-pay-period-ownership [get]
+func (app *application) checkPayPeriodOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if getPayPeriodFromCtx(r).UserID == getUserFromContext(r).ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		app.checkRolePrecedenceMiddleware(requiredRole, next).ServeHTTP(w, r)
+	})
+}
+
+// getPayPeriodFromCtx godoc
+//
+//	@Summary		Get Pay Period from Context
+//	@Description	Retrieves the pay period from the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/get-pay-period-from-ctx [get]
+func getPayPeriodFromCtx(r *http.Request) *store.PayPeriod {
+	period, _ := r.Context().Value(payPeriodCtx).(*store.PayPeriod)
+	return period
+}
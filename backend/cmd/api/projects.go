@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type projectKey string
+
+const projectCtx projectKey = "project"
+
+// CreateClientPayload represents the payload for creating a client.
+type CreateClientPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CreateProjectPayload represents the payload for creating a project.
+type CreateProjectPayload struct {
+	ClientID int64  `json:"client_id" validate:"required,min=1"`
+	Name     string `json:"name" validate:"required,max=100"`
+}
+
+// CreateTaskPayload represents the payload for creating a task in a project.
+type CreateTaskPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// getClientsHandler godoc
+//
+//	@Summary		Fetches all clients
+//	@Description	Fetches all clients ordered by name
+//	@Tags			projects
+//	@Produce		json
+//	@Success		200	{object}	[]store.Client
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/clients [get]
+func (app *application) getClientsHandler(w http.ResponseWriter, r *http.Request) {
+	clients, err := app.store.Projects.GetClients(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, clients); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createClientHandler godoc
+//
+//	@Summary		Creates a client
+//	@Description	Creates a client that projects can be assigned to
+//	@Tags			projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateClientPayload	true	"Client"
+//	@Success		201		{object}	store.Client
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/clients [post]
+func (app *application) createClientHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateClientPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	client := &store.Client{Name: payload.Name}
+
+	if err := app.store.Projects.CreateClient(r.Context(), client); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, client); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getProjectsHandler godoc
+//
+//	@Summary		Fetches projects
+//	@Description	Fetches all projects, or those of one client
+//	@Tags			projects
+//	@Produce		json
+//	@Param			client_id	query		int	false	"Only projects of this client"
+//	@Success		200			{object}	[]store.Project
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/projects [get]
+func (app *application) getProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	var clientID int64
+	if c := r.URL.Query().Get("client_id"); c != "" {
+		id, err := strconv.ParseInt(c, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		clientID = id
+	}
+
+	projects, err := app.store.Projects.GetProjects(r.Context(), clientID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, projects); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createProjectHandler godoc
+//
+//	@Summary		Creates a project
+//	@Description	Creates a project for a client
+//	@Tags			projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateProjectPayload	true	"Project"
+//	@Success		201		{object}	store.Project
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/projects [post]
+func (app *application) createProjectHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateProjectPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	project := &store.Project{ClientID: payload.ClientID, Name: payload.Name}
+
+	if err := app.store.Projects.CreateProject(r.Context(), project); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, project); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getProjectHandler godoc
+//
+//	@Summary		Fetches a project
+//	@Description	Fetches a project by ID
+//	@Tags			projects
+//	@Produce		json
+//	@Param			projectID	path		int	true	"Project ID"
+//	@Success		200			{object}	store.Project
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/projects/{projectID} [get]
+func (app *application) getProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getProjectFromCtx(r)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getTasksHandler godoc
+//
+//	@Summary		Fetches the tasks of a project
+//	@Description	Fetches the tasks of a project ordered by name
+//	@Tags			projects
+//	@Produce		json
+//	@Param			projectID	path		int	true	"Project ID"
+//	@Success		200			{object}	[]store.Task
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/projects/{projectID}/tasks [get]
+func (app *application) getTasksHandler(w http.ResponseWriter, r *http.Request) {
+	project := getProjectFromCtx(r)
+
+	tasks, err := app.store.Projects.GetTasks(r.Context(), project.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tasks); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createTaskHandler godoc
+//
+//	@Summary		Creates a task
+//	@Description	Creates a task in a project that users can record time against
+//	@Tags			projects
+//	@Accept			json
+//	@Produce		json
+//	@Param			projectID	path		int					true	"Project ID"
+//	@Param			payload		body		CreateTaskPayload	true	"Task"
+//	@Success		201			{object}	store.Task
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/projects/{projectID}/tasks [post]
+func (app *application) createTaskHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateTaskPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	task := &store.Task{ProjectID: getProjectFromCtx(r).ID, Name: payload.Name}
+
+	if err := app.store.Projects.CreateTask(r.Context(), task); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, task); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// projectsContextMiddleware godoc
+//
+//	@Summary		Projects Context Middleware
+//	@Description	Middleware that retrieves a project by ID and adds it to the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/projects-context [get]
+func (app *application) projectsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		project, err := app.store.Projects.GetProjectByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, projectCtx, project)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// getProjectFromCtx godoc
+//
+//	@Summary		Get Project from Context
+//	@Description	Retrieves the project from the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/get-project-from-ctx [get]
+func getProjectFromCtx(r *http.Request) *store.Project {
+	project, _ := r.Context().Value(projectCtx).(*store.Project)
+	return project
+}
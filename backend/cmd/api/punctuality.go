@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetWorkPatternPayload represents the payload for setting a user's work pattern.
+// Omitted grace periods fall back to the configured defaults.
+type SetWorkPatternPayload struct {
+	Weekdays          []time.Weekday `json:"weekdays" validate:"required,min=1,max=7,dive,min=0,max=6"`
+	StartTime         string         `json:"start_time" validate:"required,datetime=15:04"`
+	EndTime           string         `json:"end_time" validate:"required,datetime=15:04"`
+	LateGraceMinutes  *int           `json:"late_grace_minutes" validate:"omitempty,min=0,max=240"`
+	EarlyGraceMinutes *int           `json:"early_grace_minutes" validate:"omitempty,min=0,max=240"`
+}
+
+// TeamMemberPunctuality is the punctuality report of one team member.
+type TeamMemberPunctuality struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	*store.PunctualityReport
+}
+
+// getWorkPatternHandler godoc
+//
+//	@Summary		Fetches a user's work pattern
+//	@Description	Fetches the weekly work pattern a user is expected to keep
+//	@Tags			punctuality
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	store.WorkPattern
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/work-patterns/{userID} [get]
+func (app *application) getWorkPatternHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	pattern, err := app.store.WorkPatterns.Get(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, pattern); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// setWorkPatternHandler godoc
+//
+//	@Summary		Sets a user's work pattern
+//	@Description	Creates or replaces the weekly work pattern of one of the manager's reports, e.g. Monday to Friday 08:30-17:00 in the user's time zone. Weekdays are numbered from 0 for Sunday.
+//	@Tags			punctuality
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int						true	"User ID"
+//	@Param			payload	body		SetWorkPatternPayload	true	"Work pattern"
+//	@Success		200		{object}	store.WorkPattern
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/work-patterns/{userID} [put]
+func (app *application) setWorkPatternHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SetWorkPatternPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, getUserFromContext(r), userID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	pattern := &store.WorkPattern{
+		UserID:            userID,
+		Weekdays:          payload.Weekdays,
+		StartTime:         payload.StartTime,
+		EndTime:           payload.EndTime,
+		LateGraceMinutes:  payload.LateGraceMinutes,
+		EarlyGraceMinutes: payload.EarlyGraceMinutes,
+	}
+
+	if err := app.store.WorkPatterns.Set(ctx, pattern); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, pattern); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteWorkPatternHandler godoc
+//
+//	@Summary		Deletes a user's work pattern
+//	@Description	Deletes the work pattern of one of the manager's reports, who is then no longer checked for punctuality
+//	@Tags			punctuality
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Work pattern deleted"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/work-patterns/{userID} [delete]
+func (app *application) deleteWorkPatternHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, getUserFromContext(r), userID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	if err := app.store.WorkPatterns.Delete(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getPunctualityHandler godoc
+//
+//	@Summary		Fetches the user's punctuality report
+//	@Description	Compares the user's finished shifts with their work pattern and reports late arrivals, early departures, missing workdays and unscheduled work
+//	@Tags			punctuality
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD), defaults to the first day of the current month"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD), defaults to the last day of the current month"
+//	@Success		200		{object}	store.PunctualityReport
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/punctuality [get]
+func (app *application) getPunctualityHandler(w http.ResponseWriter, r *http.Request) {
+	app.punctualityResponse(w, r, getUserFromContext(r))
+}
+
+// getPunctualityByUserHandler godoc
+//
+//	@Summary		Fetches a user's punctuality report
+//	@Description	Compares the finished shifts of one of the manager's reports with their work pattern in the report's time zone
+//	@Tags			punctuality
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD)"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD)"
+//	@Success		200		{object}	store.PunctualityReport
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/punctuality/{userID} [get]
+func (app *application) getPunctualityByUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.getManagedUser(r.Context(), getUserFromContext(r), userID)
+	if err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	app.punctualityResponse(w, r, user)
+}
+
+// getTeamPunctualityHandler godoc
+//
+//	@Summary		Fetches the punctuality report of the manager's team
+//	@Description	Compares the finished shifts of every user managed by the authenticated user with their work pattern. Users without a work pattern are left out.
+//	@Tags			punctuality
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD), defaults to the first day of the current month"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD), defaults to the last day of the current month"
+//	@Success		200		{object}	[]TeamMemberPunctuality
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/punctuality/team [get]
+func (app *application) getTeamPunctualityHandler(w http.ResponseWriter, r *http.Request) {
+	manager := getUserFromContext(r)
+
+	team, err := app.store.Users.GetByManager(r.Context(), manager.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	reports := make([]TeamMemberPunctuality, 0, len(team))
+	for _, member := range team {
+		from, to, err := parseDateRange(r, member.Location())
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		report, err := app.buildPunctualityReport(r.Context(), member, from, to)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		reports = append(reports, TeamMemberPunctuality{
+			FirstName:         member.FirstName,
+			LastName:          member.LastName,
+			PunctualityReport: report,
+		})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, reports); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// punctualityResponse writes the punctuality report of user for the requested dates.
+func (app *application) punctualityResponse(w http.ResponseWriter, r *http.Request, user *store.User) {
+	from, to, err := parseDateRange(r, user.Location())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.buildPunctualityReport(r.Context(), user, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, errors.New("the user has no work pattern"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// buildPunctualityReport compares the user's finished shifts on the local dates from..to with the
+// user's work pattern. It returns store.ErrNotFound if the user has no work pattern.
+func (app *application) buildPunctualityReport(ctx context.Context, user *store.User, from, to time.Time) (*store.PunctualityReport, error) {
+	pattern, err := app.store.WorkPatterns.Get(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sq := store.ShiftQuery{
+		From:     from,
+		To:       to.AddDate(0, 0, 1),
+		Location: user.Location(),
+	}
+
+	shifts, _, err := app.store.Timestamps.GetFinishedShifts(ctx, user.ID, sq)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays, err := app.store.Holidays.GetForUser(ctx, user.ID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	leave, err := app.store.Leave.GetDays(ctx, user.ID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	// Days on approved leave are not workdays either
+	daysOff := store.HolidayDates(holidays)
+	for _, l := range leave {
+		daysOff[l.Date] = true
+	}
+
+	return store.ComparePattern(user, pattern, app.config.punctuality, from, to, shifts, daysOff, time.Now())
+}
@@ -0,0 +1,725 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type plannedShiftKey string
+
+const plannedShiftCtx plannedShiftKey = "plannedShift"
+
+const (
+	maxPlannedShift     = 24 * time.Hour // Longest shift that can be planned
+	maxUpcomingDays     = 90             // Furthest ahead upcoming shifts can be fetched
+	maxTemplateDays     = 92             // Longest period a template can be applied to at once
+	defaultUpcomingDays = 14
+)
+
+// errNotManager is returned when the authenticated user neither manages a user nor is an admin.
+var errNotManager = errors.New("not the user's manager")
+
+// CreatePlannedShiftPayload represents the payload for planning a shift.
+type CreatePlannedShiftPayload struct {
+	UserID   int64  `json:"user_id" validate:"required,min=1"`
+	StartsAt string `json:"starts_at" validate:"required"`
+	EndsAt   string `json:"ends_at" validate:"required"`
+	Location string `json:"location" validate:"max=100"`
+	Role     string `json:"role" validate:"max=50"`
+}
+
+// UpdatePlannedShiftPayload represents the payload for changing a planned shift. Omitted fields are kept.
+type UpdatePlannedShiftPayload struct {
+	StartsAt *string `json:"starts_at"`
+	EndsAt   *string `json:"ends_at"`
+	Location *string `json:"location" validate:"omitempty,max=100"`
+	Role     *string `json:"role" validate:"omitempty,max=50"`
+}
+
+// PublishRosterPayload represents the payload for publishing the roster of a week.
+type PublishRosterPayload struct {
+	WeekStart string `json:"week_start" validate:"required,datetime=2006-01-02"`
+}
+
+// PublishRosterResponse reports how many planned shifts a roster publication made visible.
+type PublishRosterResponse struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Published int64     `json:"published"`
+}
+
+// CreateShiftTemplatePayload represents the payload for creating a recurring shift template.
+type CreateShiftTemplatePayload struct {
+	Name      string         `json:"name" validate:"required,max=100"`
+	Weekdays  []time.Weekday `json:"weekdays" validate:"required,min=1,max=7,dive,min=0,max=6"`
+	StartTime string         `json:"start_time" validate:"required,datetime=15:04"`
+	EndTime   string         `json:"end_time" validate:"required,datetime=15:04"`
+	Location  string         `json:"location" validate:"max=100"`
+	Role      string         `json:"role" validate:"max=50"`
+}
+
+// ApplyShiftTemplatePayload represents the payload for generating planned shifts from a template.
+// The dates are local dates in each user's time zone; both are included.
+type ApplyShiftTemplatePayload struct {
+	UserIDs []int64 `json:"user_ids" validate:"required,min=1,max=100,dive,min=1"`
+	From    string  `json:"from" validate:"required,datetime=2006-01-02"`
+	To      string  `json:"to" validate:"required,datetime=2006-01-02"`
+}
+
+// ApplyShiftTemplateResponse lists the planned shifts generated from a template and how many
+// occurrences were skipped because they overlap shifts that were already planned.
+type ApplyShiftTemplateResponse struct {
+	Created []store.PlannedShift `json:"created"`
+	Skipped int                  `json:"skipped"`
+}
+
+// getUpcomingShiftsHandler godoc
+//
+//	@Summary		Fetches the user's upcoming shifts
+//	@Description	Fetches the published planned shifts of the authenticated user that have not ended yet
+//	@Tags			schedule
+//	@Produce		json
+//	@Param			days	query		int	false	"How many days ahead to look, 14 by default and at most 90"
+//	@Success		200		{object}	[]store.PlannedShift
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/planned-shifts/upcoming [get]
+func (app *application) getUpcomingShiftsHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultUpcomingDays
+	if d := r.URL.Query().Get("days"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 || n > maxUpcomingDays {
+			app.badRequestResponse(w, r, fmt.Errorf("days must be between 1 and %d", maxUpcomingDays))
+			return
+		}
+		days = n
+	}
+
+	user := getUserFromContext(r)
+	now := time.Now()
+
+	shifts, err := app.store.Schedules.GetUpcoming(r.Context(), user.ID, now, now.AddDate(0, 0, days))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	loc := user.Location()
+	for i := range shifts {
+		shifts[i].In(loc)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, shifts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getTeamScheduleHandler godoc
+//
+//	@Summary		Fetches the schedule of the manager's team
+//	@Description	Fetches the published and unpublished planned shifts of the manager's reports that start between two local dates
+//	@Tags			schedule
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD), defaults to the first day of the current month"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD), defaults to the last day of the current month"
+//	@Param			user_id	query		int		false	"Only the shifts of this user"
+//	@Success		200		{object}	[]store.PlannedShift
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/planned-shifts [get]
+func (app *application) getTeamScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	manager := getUserFromContext(r)
+	loc := manager.Location()
+
+	from, to, err := parseDateRange(r, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var userID int64
+	if u := r.URL.Query().Get("user_id"); u != "" {
+		if userID, err = strconv.ParseInt(u, 10, 64); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	shifts, err := app.store.Schedules.GetForTeam(r.Context(), manager.ID, userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range shifts {
+		shifts[i].In(loc)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, shifts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createPlannedShiftHandler godoc
+//
+//	@Summary		Plans a shift
+//	@Description	Plans an unpublished shift for one of the manager's reports. It must not overlap another planned shift of the user.
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreatePlannedShiftPayload	true	"Planned shift"
+//	@Success		201		{object}	store.PlannedShift
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/planned-shifts [post]
+func (app *application) createPlannedShiftHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreatePlannedShiftPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	startsAt, endsAt, err := parseShiftTimes(payload.StartsAt, payload.EndsAt)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	manager := getUserFromContext(r)
+
+	if _, err := app.getManagedUser(ctx, manager, payload.UserID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	shift := &store.PlannedShift{
+		UserID:    payload.UserID,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		Location:  payload.Location,
+		Role:      payload.Role,
+		CreatedBy: manager.ID,
+	}
+
+	if err := app.store.Schedules.Create(ctx, shift); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrScheduleOverlap):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	shift.In(manager.Location())
+
+	if err := app.jsonResponse(w, http.StatusCreated, shift); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// updatePlannedShiftHandler godoc
+//
+//	@Summary		Changes a planned shift
+//	@Description	Changes the time, location or role of a planned shift of one of the manager's reports. A published shift stays published.
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Param			plannedShiftID	path		int							true	"Planned shift ID"
+//	@Param			payload			body		UpdatePlannedShiftPayload	true	"Changes"
+//	@Success		200				{object}	store.PlannedShift
+//	@Failure		400				{object}	error
+//	@Failure		403				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		409				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/planned-shifts/{plannedShiftID} [patch]
+func (app *application) updatePlannedShiftHandler(w http.ResponseWriter, r *http.Request) {
+	shift := getPlannedShiftFromCtx(r)
+
+	var payload UpdatePlannedShiftPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	startsAt := shift.StartsAt.Format(time.RFC3339)
+	if payload.StartsAt != nil {
+		startsAt = *payload.StartsAt
+	}
+	endsAt := shift.EndsAt.Format(time.RFC3339)
+	if payload.EndsAt != nil {
+		endsAt = *payload.EndsAt
+	}
+
+	var err error
+	if shift.StartsAt, shift.EndsAt, err = parseShiftTimes(startsAt, endsAt); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Location != nil {
+		shift.Location = *payload.Location
+	}
+	if payload.Role != nil {
+		shift.Role = *payload.Role
+	}
+
+	ctx := r.Context()
+	manager := getUserFromContext(r)
+
+	if _, err := app.getManagedUser(ctx, manager, shift.UserID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	if err := app.store.Schedules.Update(ctx, shift); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrScheduleOverlap):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	shift.In(manager.Location())
+
+	if err := app.jsonResponse(w, http.StatusOK, shift); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deletePlannedShiftHandler godoc
+//
+//	@Summary		Deletes a planned shift
+//	@Description	Deletes a planned shift of one of the manager's reports
+//	@Tags			schedule
+//	@Param			plannedShiftID	path		int		true	"Planned shift ID"
+//	@Success		204				{string}	string	"Planned shift deleted"
+//	@Failure		403				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/planned-shifts/{plannedShiftID} [delete]
+func (app *application) deletePlannedShiftHandler(w http.ResponseWriter, r *http.Request) {
+	shift := getPlannedShiftFromCtx(r)
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, getUserFromContext(r), shift.UserID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	if err := app.store.Schedules.Delete(ctx, shift.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishRosterHandler godoc
+//
+//	@Summary		Publishes a weekly roster
+//	@Description	Publishes the planned shifts of the manager's reports that start in the seven local days from week_start, so the users see them as upcoming shifts
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		PublishRosterPayload	true	"First day of the week"
+//	@Success		200		{object}	PublishRosterResponse
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/planned-shifts/publish [post]
+func (app *application) publishRosterHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PublishRosterPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	manager := getUserFromContext(r)
+	loc := manager.Location()
+
+	from, err := time.ParseInLocation(time.DateOnly, payload.WeekStart, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	to := from.AddDate(0, 0, 7)
+
+	published, err := app.store.Schedules.Publish(r.Context(), manager.ID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, PublishRosterResponse{From: from, To: to, Published: published}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getShiftTemplatesHandler godoc
+//
+//	@Summary		Fetches the shift templates
+//	@Description	Fetches all recurring shift templates ordered by name
+//	@Tags			schedule
+//	@Produce		json
+//	@Success		200	{object}	[]store.ShiftTemplate
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shift-templates [get]
+func (app *application) getShiftTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := app.store.Schedules.GetTemplates(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, templates); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createShiftTemplateHandler godoc
+//
+//	@Summary		Creates a shift template
+//	@Description	Creates a recurring shift template such as Monday to Friday 09:00-17:00. Weekdays are numbered from 0 for Sunday.
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateShiftTemplatePayload	true	"Shift template"
+//	@Success		201		{object}	store.ShiftTemplate
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shift-templates [post]
+func (app *application) createShiftTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateShiftTemplatePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tmpl := &store.ShiftTemplate{
+		Name:      payload.Name,
+		Weekdays:  payload.Weekdays,
+		StartTime: payload.StartTime,
+		EndTime:   payload.EndTime,
+		Location:  payload.Location,
+		Role:      payload.Role,
+		CreatedBy: getUserFromContext(r).ID,
+	}
+
+	if err := app.store.Schedules.CreateTemplate(r.Context(), tmpl); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tmpl); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteShiftTemplateHandler godoc
+//
+//	@Summary		Deletes a shift template
+//	@Description	Deletes a shift template; shifts already generated from it are kept
+//	@Tags			schedule
+//	@Param			templateID	path		int		true	"Shift template ID"
+//	@Success		204			{string}	string	"Shift template deleted"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shift-templates/{templateID} [delete]
+func (app *application) deleteShiftTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "templateID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Schedules.DeleteTemplate(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyShiftTemplateHandler godoc
+//
+//	@Summary		Plans shifts from a template
+//	@Description	Plans unpublished shifts from a template for some of the manager's reports on the matching weekdays between two local dates. Occurrences that overlap already planned shifts are skipped.
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Param			templateID	path		int							true	"Shift template ID"
+//	@Param			payload		body		ApplyShiftTemplatePayload	true	"Users and dates"
+//	@Success		201			{object}	ApplyShiftTemplateResponse
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shift-templates/{templateID}/apply [post]
+func (app *application) applyShiftTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "templateID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload ApplyShiftTemplatePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	tmpl, err := app.store.Schedules.GetTemplateByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	manager := getUserFromContext(r)
+	tmpl.CreatedBy = manager.ID
+
+	shifts := make([]store.PlannedShift, 0)
+	for _, userID := range payload.UserIDs {
+		user, err := app.getManagedUser(ctx, manager, userID)
+		if err != nil {
+			app.managedUserError(w, r, err)
+			return
+		}
+
+		// The dates are local to each user, like the clock times of the template
+		loc := user.Location()
+		from, err := time.ParseInLocation(time.DateOnly, payload.From, loc)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		to, err := time.ParseInLocation(time.DateOnly, payload.To, loc)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if to.Before(from) {
+			app.badRequestResponse(w, r, errors.New("to must not be before from"))
+			return
+		}
+		if to.Sub(from) > maxTemplateDays*24*time.Hour {
+			app.badRequestResponse(w, r, fmt.Errorf("date range must not exceed %d days", maxTemplateDays))
+			return
+		}
+
+		occurrences, err := tmpl.Occurrences(user.ID, from, to, loc)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		shifts = append(shifts, occurrences...)
+	}
+
+	created, skipped, err := app.store.Schedules.CreateBatch(ctx, shifts)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	loc := manager.Location()
+	for i := range created {
+		created[i].In(loc)
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, ApplyShiftTemplateResponse{Created: created, Skipped: skipped}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// parseShiftTimes parses the RFC 3339 start and end of a planned shift and checks its length.
+func parseShiftTimes(start, end string) (time.Time, time.Time, error) {
+	startsAt, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid starts_at: %w", err)
+	}
+
+	endsAt, err := time.Parse(time.RFC3339, end)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid ends_at: %w", err)
+	}
+
+	if !endsAt.After(startsAt) {
+		return time.Time{}, time.Time{}, errors.New("ends_at must be after starts_at")
+	}
+
+	if endsAt.Sub(startsAt) > maxPlannedShift {
+		return time.Time{}, time.Time{}, fmt.Errorf("a planned shift must not be longer than %s", maxPlannedShift)
+	}
+
+	return startsAt.UTC(), endsAt.UTC(), nil
+}
+
+// getManagedUser loads the user with userID if manager is their manager or an admin,
+// and returns errNotManager otherwise.
+func (app *application) getManagedUser(ctx context.Context, manager *store.User, userID int64) (*store.User, error) {
+	user, err := app.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ManagerID == manager.ID {
+		return user, nil
+	}
+
+	isAdmin, err := app.checkRolePrecedence(ctx, manager, "admin")
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, errNotManager
+	}
+
+	return user, nil
+}
+
+// managedUserError responds to an error from getManagedUser.
+func (app *application) managedUserError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundResponse(w, r, err)
+	case errors.Is(err, errNotManager):
+		app.forbiddenResponse(w, r)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// plannedShiftsContextMiddleware godoc
+//
+//	@Summary		Planned Shifts Context Middleware
+//	@Description	Middleware that retrieves a planned shift by ID and adds it to the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/planned-shifts-context [get]
+func (app *application) plannedShiftsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "plannedShiftID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		shift, err := app.store.Schedules.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, plannedShiftCtx, shift)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// getPlannedShiftFromCtx godoc
+//
+//	@Summary		Get Planned Shift from Context
+//	@Description	Retrieves the planned shift from the request context
+//	@Tags			middleware
+//	@Produce		json
+//	@Router			/middleware/get-planned-shift-from-ctx [get]
+func getPlannedShiftFromCtx(r *http.Request) *store.PlannedShift {
+	shift, _ := r.Context().Value(plannedShiftCtx).(*store.PlannedShift)
+	return shift
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type sessionKey string
+
+// sessionCtx holds the ID of the session the access token of the request was issued for.
+const sessionCtx sessionKey = "session"
+
+// refreshTokenHeader is the response header a new refresh token is returned in.
+const refreshTokenHeader = "Refresh-Token"
+
+// RefreshTokenPayload represents the payload for refreshing an access token.
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=64"`
+}
+
+// refreshTokenHandler godoc
+//
+//	@Summary		Refreshes a token
+//	@Description	Exchanges a refresh token for a new access token and a new refresh token, returned in the Refresh-Token header. Every refresh token works once; presenting a used one revokes its session.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		201		{string}	string				"Token"
+//	@Header			201		{string}	Refresh-Token		"Refresh token replacing the one sent"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/refresh [post]
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	refreshToken := uuid.New().String()
+
+	session, err := app.store.Sessions.Rotate(ctx, payload.RefreshToken, refreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRefreshTokenReused):
+			app.logger.Warnw("refresh token reused, session revoked", "method", r.Method, "path", r.URL.Path, "ip", r.RemoteAddr)
+			app.unauthorizedErrorResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrSessionExpired):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.getUser(ctx, session.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Sessions started before two-factor authentication became mandatory for the user's role end here
+	secret, required, err := app.getMFAState(ctx, user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if required && !secret.Enabled() {
+		app.unauthorizedErrorResponse(w, r, errors.New("two-factor authentication has to be set up"))
+		return
+	}
+
+	token, err := app.generateAccessToken(user, session.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set(refreshTokenHeader, refreshToken)
+
+	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getSessionsHandler godoc
+//
+//	@Summary		Fetches the user's sessions
+//	@Description	Fetches the sessions of the authenticated user that have neither expired nor been revoked. The session of the request is marked as current.
+//	@Tags			sessions
+//	@Produce		json
+//	@Success		200	{object}	[]store.Session
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/sessions [get]
+func (app *application) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	sessions, err := app.store.Sessions.GetActive(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	current, _ := r.Context().Value(sessionCtx).(int64)
+	loc := user.Location()
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+		sessions[i].In(loc)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, sessions); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// revokeSessionHandler godoc
+//
+//	@Summary		Revokes one of the user's sessions
+//	@Description	Revokes a session of the authenticated user, e.g. on a lost device. Its refresh token and access tokens stop working. Changing the password revokes every session and access token.
+//	@Tags			sessions
+//	@Param			sessionID	path		int		true	"Session ID"
+//	@Success		204			{string}	string	"Session revoked"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/sessions/{sessionID} [delete]
+func (app *application) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "sessionID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Sessions.Revoke(r.Context(), getUserFromContext(r).ID, sessionID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeUserSessionsHandler godoc
+//
+//	@Summary		Revokes all sessions of a user
+//	@Description	Revokes every session and access token of a user, who has to log in again right away
+//	@Tags			sessions
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Sessions revoked"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/sessions [delete]
+func (app *application) revokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getUser(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	revoked, err := app.store.Sessions.RevokeAll(ctx, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.logger.Infow("sessions revoked", "user_id", userID, "count", revoked, "by", getUserFromContext(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startSession starts a session of user on the device the request comes from and returns its
+// refresh token in the Refresh-Token header.
+func (app *application) startSession(w http.ResponseWriter, r *http.Request, user *store.User, device string) (*store.Session, error) {
+	ip := clientIP(r)
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	session := &store.Session{
+		UserID:    user.ID,
+		Device:    device,
+		UserAgent: userAgent,
+		IPAddress: ip,
+		ExpiresAt: time.Now().Add(app.config.auth.token.refreshExp),
+	}
+
+	refreshToken := uuid.New().String()
+	if err := app.store.Sessions.Create(r.Context(), session, refreshToken); err != nil {
+		return nil, err
+	}
+
+	w.Header().Set(refreshTokenHeader, refreshToken)
+
+	return session, nil
+}
+
+// clientIP returns the IP address a request comes from, without its port.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// generateAccessToken issues a short-lived access token of user for a session.
+func (app *application) generateAccessToken(user *store.User, sessionID int64) (string, error) {
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"sub": user.ID,
+		"sid": sessionID,
+		"ver": user.TokenVersion,
+		"exp": now.Add(app.config.auth.token.exp).Unix(),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+	}
+
+	return app.authenticator.GenerateToken(claims)
+}
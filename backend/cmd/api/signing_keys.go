@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/auth"
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/google/uuid"
+)
+
+// signingKeyRefresh is how often every instance reloads the signing keys from the database and
+// how long clients may cache the JWKS.
+const signingKeyRefresh = time.Minute
+
+// signingKeyPublishAhead is how long a new key is published before it signs tokens, so that
+// every instance and JWKS consumer knows it by then.
+const signingKeyPublishAhead = 2 * signingKeyRefresh
+
+// jwksHandler godoc
+//
+//	@Summary		Fetches the public signing keys
+//	@Description	Serves the public keys that verify access tokens as a JSON Web Key Set. Tokens name their key in the kid header. Keys are published ahead of use and stay listed until the tokens signed with them have expired.
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		200	{object}	auth.JWKSet
+//	@Router			/.well-known/jwks.json [get]
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(signingKeyRefresh.Seconds())))
+
+	if err := writeJSON(w, http.StatusOK, app.signingKeys.JWKS(time.Now())); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// runKeyRotation reloads the signing keys and rotates them when due until ctx is cancelled.
+func (app *application) runKeyRotation(ctx context.Context) {
+	ticker := time.NewTicker(signingKeyRefresh)
+	defer ticker.Stop()
+
+	app.logger.Infow("signing key rotation started", "algorithm", app.config.auth.token.algorithm, "rotation", app.config.auth.token.keyRotation.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			app.logger.Infow("signing key rotation stopped")
+			return
+		case <-ticker.C:
+			if err := app.loadSigningKeys(ctx); err != nil {
+				app.logger.Errorw("loading signing keys failed", "error", err)
+			}
+		}
+	}
+}
+
+// loadSigningKeys loads the signing keys into the key set, first creating a key if there is none
+// or the current one is older than the rotation interval.
+func (app *application) loadSigningKeys(ctx context.Context) error {
+	now := time.Now()
+	due := now.Add(-app.config.auth.token.keyRotation)
+
+	keys, err := app.store.SigningKeys.GetValid(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	// Keys are sorted newest first; the very first key signs right away
+	if len(keys) == 0 || !keys[0].ActivatesAt.After(due) {
+		activatesAt := now
+		if len(keys) > 0 {
+			activatesAt = now.Add(signingKeyPublishAhead)
+		}
+
+		if err := app.rotateSigningKey(ctx, activatesAt, due); err != nil {
+			return err
+		}
+
+		if keys, err = app.store.SigningKeys.GetValid(ctx, now); err != nil {
+			return err
+		}
+	}
+
+	signingKeys := make([]auth.SigningKey, 0, len(keys))
+	for _, key := range keys {
+		der, err := auth.OpenPrivateKey(app.config.auth.token.keyKEK, key.ID, key.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", key.ID, err)
+		}
+
+		private, err := auth.ParsePrivateKey(key.Algorithm, der)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", key.ID, err)
+		}
+
+		signingKeys = append(signingKeys, auth.SigningKey{
+			ID:          key.ID,
+			Algorithm:   key.Algorithm,
+			Private:     private,
+			ActivatesAt: key.ActivatesAt,
+			ExpiresAt:   key.ExpiresAt,
+		})
+	}
+
+	app.signingKeys.Set(signingKeys)
+
+	return nil
+}
+
+// rotateSigningKey generates a key that signs tokens from activatesAt on and replaces the current
+// key with it unless another instance already did.
+func (app *application) rotateSigningKey(ctx context.Context, activatesAt, due time.Time) error {
+	alg := app.config.auth.token.algorithm
+
+	private, err := auth.GenerateKey(alg)
+	if err != nil {
+		return err
+	}
+
+	der, err := auth.MarshalPrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	kid := uuid.New().String()
+
+	sealed, err := auth.SealPrivateKey(app.config.auth.token.keyKEK, kid, der)
+	if err != nil {
+		return err
+	}
+
+	key := &store.SigningKey{
+		ID:          kid,
+		Algorithm:   alg,
+		PrivateKey:  sealed,
+		ActivatesAt: activatesAt,
+	}
+
+	if err := app.store.SigningKeys.Rotate(ctx, key, due, app.config.auth.token.keyGrace); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return nil
+		}
+		return err
+	}
+
+	app.logger.Infow("signing key rotated", "kid", key.ID, "algorithm", alg, "activates_at", activatesAt)
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+)
+
+// StampTypePayload represents one stamp type of the stamp graph.
+type StampTypePayload struct {
+	Name        string `json:"name" validate:"required,max=32"`
+	Category    string `json:"category" validate:"required,oneof=start end work paid-break unpaid-break"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+// StampTransitionPayload represents one allowed transition of the stamp graph.
+type StampTransitionPayload struct {
+	From string `json:"from" validate:"required,max=32"`
+	To   string `json:"to" validate:"required,max=32"`
+}
+
+// UpdateStampGraphPayload represents the payload for replacing the stamp graph.
+type UpdateStampGraphPayload struct {
+	Types       []StampTypePayload       `json:"types" validate:"required,min=2,dive"`
+	Transitions []StampTransitionPayload `json:"transitions" validate:"required,min=2,dive"`
+}
+
+// getStampGraphHandler godoc
+//
+//	@Summary		Fetches the stamp graph
+//	@Description	Fetches the stamp types users can record and the transitions allowed between them
+//	@Tags			stamp-types
+//	@Produce		json
+//	@Success		200	{object}	store.StampGraph
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/stamp-graph [get]
+func (app *application) getStampGraphHandler(w http.ResponseWriter, r *http.Request) {
+	graph, err := app.store.StampTypes.GetGraph(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, graph); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// updateStampGraphHandler godoc
+//
+//	@Summary		Replaces the stamp graph
+//	@Description	Replaces all stamp types and transitions. The graph must have a start and an end type, every type must be reachable from a start type and every shift must be able to end. Types that were stamped cannot be removed or change their category.
+//	@Tags			stamp-types
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateStampGraphPayload	true	"Stamp graph"
+//	@Success		200		{object}	store.StampGraph
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/stamp-graph [put]
+func (app *application) updateStampGraphHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateStampGraphPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	types := make([]store.StampType, len(payload.Types))
+	for i, t := range payload.Types {
+		types[i] = store.StampType{Name: t.Name, Category: t.Category, Description: t.Description}
+	}
+
+	transitions := make([]store.StampTransition, len(payload.Transitions))
+	for i, t := range payload.Transitions {
+		transitions[i] = store.StampTransition{From: t.From, To: t.To}
+	}
+
+	graph := store.NewStampGraph(types, transitions)
+
+	if err := app.store.StampTypes.ReplaceGraph(r.Context(), graph); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidStampGraph):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrStampTypeInUse):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, graph); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateTaskEntryPayload represents the payload for logging time spent on a task.
+type CreateTaskEntryPayload struct {
+	TaskID    int64  `json:"task_id" validate:"required,min=1"`
+	StartedAt string `json:"started_at" validate:"required"`
+	EndedAt   string `json:"ended_at" validate:"required"`
+	Note      string `json:"note" validate:"max=255"`
+}
+
+// StartTaskTimerPayload represents the payload for starting a task timer.
+type StartTaskTimerPayload struct {
+	TaskID int64  `json:"task_id" validate:"required,min=1"`
+	Note   string `json:"note" validate:"max=255"`
+}
+
+// getTaskEntriesHandler godoc
+//
+//	@Summary		Fetches the user's task entries
+//	@Description	Fetches the task entries of the authenticated user between two local dates, by default those of the current month
+//	@Tags			tasks
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD)"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD)"
+//	@Success		200		{object}	[]store.TaskEntry
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/task-entries [get]
+func (app *application) getTaskEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	loc := user.Location()
+
+	from, to, err := parseDateRange(r, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries, err := app.store.TaskEntries.GetByUser(r.Context(), user.ID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range entries {
+		entries[i].In(loc)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, entries); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// createTaskEntryHandler godoc
+//
+//	@Summary		Logs time spent on a task
+//	@Description	Logs a finished task entry for the authenticated user. The time must lie within one of the user's shifts and must not overlap other entries.
+//	@Tags			tasks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateTaskEntryPayload	true	"Task entry"
+//	@Success		201		{object}	store.TaskEntry
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/task-entries [post]
+func (app *application) createTaskEntryHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateTaskEntryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	startedAt, err := time.Parse(time.RFC3339, payload.StartedAt)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	endedAt, err := time.Parse(time.RFC3339, payload.EndedAt)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !endedAt.After(startedAt) {
+		app.badRequestResponse(w, r, errors.New("ended_at must be after started_at"))
+		return
+	}
+
+	if endedAt.After(time.Now()) {
+		app.badRequestResponse(w, r, errors.New("ended_at must not be in the future"))
+		return
+	}
+
+	ctx := r.Context()
+
+	task, err := app.store.Projects.GetTaskByID(ctx, payload.TaskID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user := getUserFromContext(r)
+	endedAt = endedAt.UTC()
+
+	entry := &store.TaskEntry{
+		UserID:    user.ID,
+		TaskID:    task.ID,
+		TaskName:  task.Name,
+		ProjectID: task.ProjectID,
+		StartedAt: startedAt.UTC(),
+		EndedAt:   &endedAt,
+		Note:      payload.Note,
+	}
+
+	if err := app.store.TaskEntries.Create(ctx, entry); err != nil {
+		switch {
+		case errors.Is(err, store.ErrOutsideShift), errors.Is(err, store.ErrTaskOverlap):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	entry.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusCreated, entry); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteTaskEntryHandler godoc
+//
+//	@Summary		Deletes a task entry
+//	@Description	Deletes a task entry of the authenticated user; managers may delete any entry
+//	@Tags			tasks
+//	@Param			entryID	path		int		true	"Task entry ID"
+//	@Success		204		{string}	string	"Task entry deleted"
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/task-entries/{entryID} [delete]
+func (app *application) deleteTaskEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entryID, err := strconv.ParseInt(chi.URLParam(r, "entryID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	entry, err := app.store.TaskEntries.GetByID(ctx, entryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	del := func(w http.ResponseWriter, r *http.Request) {
+		if err := app.store.TaskEntries.Delete(r.Context(), entry.ID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	if entry.UserID == getUserFromContext(r).ID {
+		del(w, r)
+		return
+	}
+
+	app.checkRolePrecedenceMiddleware("manager", del).ServeHTTP(w, r)
+}
+
+// getTaskTimerHandler godoc
+//
+//	@Summary		Fetches the running task timer
+//	@Description	Fetches the running task timer of the authenticated user, or null if there is none
+//	@Tags			tasks
+//	@Produce		json
+//	@Success		200	{object}	store.TaskEntry
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/task-entries/timer [get]
+func (app *application) getTaskTimerHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	entry, err := app.store.TaskEntries.GetRunning(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if entry != nil {
+		entry.In(user.Location())
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, entry); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// startTaskTimerHandler godoc
+//
+//	@Summary		Starts a task timer
+//	@Description	Starts a timer on a task for the authenticated user, who must be signed in and have no other timer running
+//	@Tags			tasks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		StartTaskTimerPayload	true	"Task"
+//	@Success		201		{object}	store.TaskEntry
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/task-entries/timer [post]
+func (app *application) startTaskTimerHandler(w http.ResponseWriter, r *http.Request) {
+	var payload StartTaskTimerPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	task, err := app.store.Projects.GetTaskByID(ctx, payload.TaskID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	entry := &store.TaskEntry{
+		UserID:    user.ID,
+		TaskID:    task.ID,
+		TaskName:  task.Name,
+		ProjectID: task.ProjectID,
+		Note:      payload.Note,
+	}
+
+	if err := app.store.TaskEntries.Start(ctx, entry); err != nil {
+		switch {
+		case errors.Is(err, store.ErrOutsideShift), errors.Is(err, store.ErrTimerRunning):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	entry.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusCreated, entry); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// stopTaskTimerHandler godoc
+//
+//	@Summary		Stops the task timer
+//	@Description	Stops the running task timer of the authenticated user. A timer that outlived its shift ends at the end of the shift.
+//	@Tags			tasks
+//	@Produce		json
+//	@Success		200	{object}	store.TaskEntry
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/task-entries/timer/stop [post]
+func (app *application) stopTaskTimerHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	entry, err := app.store.TaskEntries.Stop(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNoTimerRunning):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	entry.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusOK, entry); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getTaskHoursReportHandler godoc
+//
+//	@Summary		Reports task hours
+//	@Description	Sums the task time between two local dates per project, per task and per user
+//	@Tags			tasks
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD), defaults to the first day of the current month"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD), defaults to the last day of the current month"
+//	@Param			user_id	query		int		false	"Only the task time of this user"
+//	@Success		200		{object}	store.TaskHoursReport
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/task-entries/report [get]
+func (app *application) getTaskHoursReportHandler(w http.ResponseWriter, r *http.Request) {
+	loc := getUserFromContext(r).Location()
+
+	from, to, err := parseDateRange(r, loc)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var userID int64
+	if u := r.URL.Query().Get("user_id"); u != "" {
+		if userID, err = strconv.ParseInt(u, 10, 64); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	report, err := app.store.TaskEntries.GetHours(r.Context(), from, to.AddDate(0, 0, 1), userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
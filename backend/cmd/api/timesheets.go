@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxTimesheetDays limits the range of a single timesheet request.
+const maxTimesheetDays = 366
+
+// getTimesheetHandler godoc
+//
+//	@Summary		Fetches the user's timesheet
+//	@Description	Fetches per-day, per-ISO-week and per-month totals of the user's finished shifts and compares them with the contracted hours
+//	@Tags			timesheets
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD), defaults to the first day of the current month"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD), defaults to the last day of the current month"
+//	@Success		200		{object}	store.Timesheet
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timesheets [get]
+func (app *application) getTimesheetHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	from, to, err := parseDateRange(r, user.Location())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	timesheet, err := app.buildTimesheet(r.Context(), user, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, timesheet); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getTimesheetByUserHandler godoc
+//
+//	@Summary		Fetches a user's timesheet
+//	@Description	Fetches per-day, per-ISO-week and per-month totals of the finished shifts of one of the manager's reports in the report's time zone
+//	@Tags			timesheets
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD)"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD)"
+//	@Success		200		{object}	store.Timesheet
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timesheets/{userID} [get]
+func (app *application) getTimesheetByUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.getManagedUser(r.Context(), getUserFromContext(r), userID)
+	if err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r, user.Location())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	timesheet, err := app.buildTimesheet(r.Context(), user, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, timesheet); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// getTeamTimesheetsHandler godoc
+//
+//	@Summary		Fetches the timesheets of the manager's team
+//	@Description	Fetches a timesheet for every user managed by the authenticated user, each in the member's own time zone
+//	@Tags			timesheets
+//	@Produce		json
+//	@Param			from	query		string	false	"First local date (YYYY-MM-DD)"
+//	@Param			to		query		string	false	"Last local date (YYYY-MM-DD)"
+//	@Success		200		{object}	[]store.Timesheet
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timesheets/team [get]
+func (app *application) getTeamTimesheetsHandler(w http.ResponseWriter, r *http.Request) {
+	manager := getUserFromContext(r)
+
+	team, err := app.store.Users.GetByManager(r.Context(), manager.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	timesheets := make([]*store.Timesheet, 0, len(team))
+	for _, member := range team {
+		from, to, err := parseDateRange(r, member.Location())
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		timesheet, err := app.buildTimesheet(r.Context(), member, from, to)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		timesheets = append(timesheets, timesheet)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, timesheets); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// buildTimesheet loads the user's finished shifts and approved leave that overlap the local
+// dates from..to and aggregates them into a timesheet.
+func (app *application) buildTimesheet(ctx context.Context, user *store.User, from, to time.Time) (*store.Timesheet, error) {
+	sq := store.ShiftQuery{
+		From:     from,
+		To:       to.AddDate(0, 0, 1),
+		Location: user.Location(),
+	}
+
+	shifts, _, err := app.store.Timestamps.GetFinishedShifts(ctx, user.ID, sq)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.evaluateShifts(ctx, user, shifts); err != nil {
+		return nil, err
+	}
+
+	holidays, err := app.store.Holidays.GetForUser(ctx, user.ID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	leave, err := app.store.Leave.GetDays(ctx, user.ID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	return store.NewTimesheet(user, from, to, shifts, holidays, leave), nil
+}
+
+// parseDateRange reads the from and to query parameters as local dates in loc.
+// Both default to the bounds of the current month.
+func parseDateRange(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 1, -1)
+
+	qs := r.URL.Query()
+
+	if f := qs.Get("from"); f != "" {
+		t, err := time.ParseInLocation(time.DateOnly, f, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from date: %w", err)
+		}
+		from = t
+	}
+
+	if t := qs.Get("to"); t != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, t, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to date: %w", err)
+		}
+		to = parsed
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("to must not be before from")
+	}
+
+	if to.Sub(from) > maxTimesheetDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range must not exceed %d days", maxTimesheetDays)
+	}
+
+	return from, to, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -18,19 +19,22 @@ const timestampCtx timestampKey = "timestamp"
 // CreateTimestampPayload represents the payload for creating a new timestamp.
 type CreateTimestampPayload struct {
 	StampType string `json:"stamp_type" validate:"required"`
-	StampTime string `json:"stamp_time" validate:"required"`
+	// StampTime is ignored; live stamps are recorded at the server time and backdated ones are
+	// created with POST /timestamps/manual. It is still accepted so that existing clients keep working.
+	StampTime string `json:"stamp_time"`
 }
 
 // createTimestampHandler godoc
 //
 //	@Summary		Creates a timestamp
-//	@Description	Creates a timestamp for a user
+//	@Description	Creates a timestamp for a user at the current server time. Any stamp_time in the payload is ignored.
 //	@Tags			timestamps
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateTimestampPayload	true	"Timestamp information"
 //	@Success		201		{object}	store.Timestamp			"Timestamp created"
 //	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/timestamps [post]
@@ -47,28 +51,196 @@ func (app *application) createTimestampHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	//user := getUserFromContext(r)
+	user := getUserFromContext(r)
+
+	timestamp := &store.Timestamp{
+		StampType: payload.StampType,
+		UserID:    user.ID,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Timestamps.Create(ctx, timestamp); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidTransition), errors.Is(err, store.ErrPeriodLocked):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	timestamp.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusCreated, timestamp); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// CreateManualTimestampPayload represents the payload for creating a backdated timestamp.
+type CreateManualTimestampPayload struct {
+	StampType string `json:"stamp_type" validate:"required,max=32"`
+	StampTime string `json:"stamp_time" validate:"required"`
+	Reason    string `json:"reason" validate:"required,max=255"`
+}
 
-	parsedTime, err := time.ParseInLocation(time.RFC3339, payload.StampTime, time.Local)
+// createManualTimestampHandler godoc
+//
+//	@Summary		Creates a manual timestamp
+//	@Description	Creates a backdated timestamp for the user that stays pending until a manager approves it
+//	@Tags			timestamps
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateManualTimestampPayload	true	"Timestamp information"
+//	@Success		201		{object}	store.Timestamp					"Timestamp created"
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timestamps/manual [post]
+func (app *application) createManualTimestampHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateManualTimestampPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	parsedTime, err := time.Parse(time.RFC3339, payload.StampTime)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
+	user := getUserFromContext(r)
+
 	timestamp := &store.Timestamp{
 		StampType: payload.StampType,
-		UserID:    getUserFromContext(r).ID,
-		StampTime: parsedTime,
+		UserID:    user.ID,
+		StampTime: parsedTime.UTC(),
+		Reason:    payload.Reason,
 	}
 
-	ctx := r.Context()
+	if err := app.store.Timestamps.CreateManual(r.Context(), timestamp); err != nil {
+		switch {
+		case errors.Is(err, store.ErrFutureTimestamp), errors.Is(err, store.ErrUnknownStampType):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrPeriodLocked):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
 
-	if err := app.store.Timestamps.Create(ctx, timestamp); err != nil {
+	timestamp.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusCreated, timestamp); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+}
 
-	if err := app.jsonResponse(w, http.StatusCreated, timestamp); err != nil {
+// getPendingTimestampsHandler godoc
+//
+//	@Summary		Fetches pending timestamps
+//	@Description	Fetches the manual timestamps of the users managed by the authenticated user that wait for approval
+//	@Tags			timestamps
+//	@Produce		json
+//	@Success		200	{object}	[]store.Timestamp
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timestamps/pending [get]
+func (app *application) getPendingTimestampsHandler(w http.ResponseWriter, r *http.Request) {
+	manager := getUserFromContext(r)
+
+	pending, err := app.store.Timestamps.GetPending(r.Context(), manager.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	loc := manager.Location()
+	for i := range pending {
+		pending[i].In(loc)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, pending); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// approveTimestampHandler godoc
+//
+//	@Summary		Approves a pending timestamp
+//	@Description	Approves a manual timestamp of one of the manager's reports so it counts towards finished shifts
+//	@Tags			timestamps
+//	@Produce		json
+//	@Param			id	path		int	true	"Timestamp ID"
+//	@Success		200	{object}	store.Timestamp
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timestamps/{id}/approve [patch]
+func (app *application) approveTimestampHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideTimestamp(w, r, store.TimestampStatusApproved)
+}
+
+// rejectTimestampHandler godoc
+//
+//	@Summary		Rejects a pending timestamp
+//	@Description	Rejects a manual timestamp of one of the manager's reports so it never counts towards finished shifts
+//	@Tags			timestamps
+//	@Produce		json
+//	@Param			id	path		int	true	"Timestamp ID"
+//	@Success		200	{object}	store.Timestamp
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timestamps/{id}/reject [patch]
+func (app *application) rejectTimestampHandler(w http.ResponseWriter, r *http.Request) {
+	app.decideTimestamp(w, r, store.TimestampStatusRejected)
+}
+
+// decideTimestamp records the manager's decision on the timestamp in the request context.
+func (app *application) decideTimestamp(w http.ResponseWriter, r *http.Request, status string) {
+	timestamp := getTimestampFromCtx(r)
+	manager := getUserFromContext(r)
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, manager, timestamp.UserID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	if err := app.store.Timestamps.Decide(ctx, timestamp, status, manager.ID); err != nil {
+		var ledgerErr *store.LedgerError
+		switch {
+		case errors.Is(err, store.ErrSelfDecision):
+			app.forbiddenResponse(w, r)
+		case errors.As(err, &ledgerErr):
+			app.ledgerConflictResponse(w, r, ledgerErr)
+		case errors.Is(err, store.ErrNotPending), errors.Is(err, store.ErrPeriodLocked):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	timestamp.In(manager.Location())
+
+	if err := app.jsonResponse(w, http.StatusOK, timestamp); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -90,6 +262,7 @@ func (app *application) createTimestampHandler(w http.ResponseWriter, r *http.Re
 //	@Router			/timestamps/{id} [get]
 func (app *application) getTimestampHandler(w http.ResponseWriter, r *http.Request) {
 	timestamp := getTimestampFromCtx(r)
+	timestamp.In(getUserFromContext(r).Location())
 
 	if err := app.jsonResponse(w, http.StatusOK, timestamp); err != nil {
 		app.internalServerError(w, r, err)
@@ -117,6 +290,10 @@ func (app *application) getLatestTimestampHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	if timestamp != nil {
+		timestamp.In(user.Location())
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, timestamp); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -126,24 +303,47 @@ func (app *application) getLatestTimestampHandler(w http.ResponseWriter, r *http
 // getFinishedShiftsHandler godoc
 //
 //	@Summary		Fetches finished shifts
-//	@Description	Fetches finished shifts for a user
+//	@Description	Fetches finished shifts for a user, optionally limited to a time window and paginated
 //	@Tags			shifts
 //	@Accept			json
 //	@Produce		json
+//	@Param			from	query		string	false	"Only shifts ending after this time (RFC 3339)"
+//	@Param			to		query		string	false	"Only shifts starting before this time (RFC 3339)"
+//	@Param			limit	query		int		false	"Page size, the next page is linked in the Link header"
+//	@Param			cursor	query		string	false	"Cursor of the page to fetch"
 //	@Success		200	{object}	[]store.Shift
+//	@Failure		400	{object}	error
 //	@Failure		500	{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/shifts [get]
 func (app *application) getFinishedShiftsHandler(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 
-	shifts, err := app.store.Timestamps.GetFinishedShifts(r.Context(), user.ID)
+	app.finishedShiftsResponse(w, r, user)
+}
+
+// getCurrentShiftHandler godoc
+//
+//	@Summary		Fetches the current shift
+//	@Description	Fetches the open shift of the user, its state, elapsed work and break seconds and the allowed next stamp types
+//	@Tags			shifts
+//	@Produce		json
+//	@Success		200	{object}	store.CurrentShift
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shifts/current [get]
+func (app *application) getCurrentShiftHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	current, err := app.store.Timestamps.GetCurrentShift(r.Context(), user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, shifts); err != nil {
+	current.In(user.Location())
+
+	if err := app.jsonResponse(w, http.StatusOK, current); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -152,12 +352,19 @@ func (app *application) getFinishedShiftsHandler(w http.ResponseWriter, r *http.
 // getFinishedShiftsByUserHandler godoc
 //
 //	@Summary		Fetches finished shifts by user ID
-//	@Description	Fetches finished shifts for a specific user by their ID
+//	@Description	Fetches finished shifts of one of the manager's reports by their ID
 //	@Tags			shifts
 //	@Accept			json
 //	@Produce		json
 //	@Param			userID	path		int	true	"User ID"
+//	@Param			from	query		string	false	"Only shifts ending after this time (RFC 3339)"
+//	@Param			to		query		string	false	"Only shifts starting before this time (RFC 3339)"
+//	@Param			limit	query		int		false	"Page size, the next page is linked in the Link header"
+//	@Param			cursor	query		string	false	"Cursor of the page to fetch"
 //	@Success		200	{object}	[]store.Shift
+//	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/shifts/{userID} [get]
@@ -170,18 +377,171 @@ func (app *application) getFinishedShiftsByUserHandler(w http.ResponseWriter, r
 		return
 	}
 
-	shifts, err := app.store.Timestamps.GetFinishedShifts(r.Context(), id)
+	user, err := app.getManagedUser(r.Context(), getUserFromContext(r), id)
+	if err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	app.finishedShiftsResponse(w, r, user)
+}
+
+// finishedShiftsResponse writes one page of the user's finished shifts for the window
+// given in the query string, split into days in the user's time zone.
+// The next page is advertised through a Link header.
+func (app *application) finishedShiftsResponse(w http.ResponseWriter, r *http.Request, user *store.User) {
+	sq, err := store.ShiftQuery{Location: user.Location()}.Parse(r)
 	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	shifts, nextCursor, err := app.store.Timestamps.GetFinishedShifts(r.Context(), user.ID, sq)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.evaluateShifts(r.Context(), user, shifts); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	if nextCursor != "" {
+		next := *r.URL
+		qs := next.Query()
+		qs.Set("cursor", nextCursor)
+		next.RawQuery = qs.Encode()
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, shifts); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
+// evaluateShifts flags work on the user's holidays and applies the user's overtime policy and the
+// compliance rules to shifts, which must be localized and in chronological order. The shifts of the
+// week before the first shift, and at least one day, are loaded as well so that weekly thresholds and
+// rest periods are judged with their context.
+func (app *application) evaluateShifts(ctx context.Context, user *store.User, shifts []store.Shift) error {
+	if len(shifts) == 0 {
+		return nil
+	}
+
+	loc := user.Location()
+	first := shifts[0].SignIn
+	weekStart := time.Date(first.Year(), first.Month(), first.Day()-(int(first.Weekday())+6)%7, 0, 0, 0, 0, loc)
+
+	prior, _, err := app.store.Timestamps.GetFinishedShifts(ctx, user.ID, store.ShiftQuery{
+		From:     earliestTime(weekStart, first.AddDate(0, 0, -1)),
+		To:       first,
+		Location: loc,
+	})
+	if err != nil {
+		return err
+	}
+
+	all := append(prior, shifts...)
+
+	holidays, err := app.store.Holidays.GetForUser(
+		ctx,
+		user.ID,
+		all[0].SignIn.Format(time.DateOnly),
+		all[len(all)-1].SignOut.Format(time.DateOnly),
+	)
+	if err != nil {
+		return err
+	}
+
+	store.MarkHolidays(all, holidays)
+
+	policy, err := app.store.OvertimePolicies.GetForUser(ctx, user)
+	switch {
+	case err == nil:
+		policy.Apply(all, store.HolidayDates(holidays))
+	case !errors.Is(err, store.ErrNotFound):
+		return err
+	}
+
+	store.CheckCompliance(app.complianceRules, all)
+
+	copy(shifts, all[len(prior):])
+	return nil
+}
+
+// earliestTime returns the earlier of a and b.
+func earliestTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// LedgerReport represents the result of replaying a user's stamps through the transition table.
+type LedgerReport struct {
+	UserID     int64                   `json:"user_id"`
+	Valid      bool                    `json:"valid"`
+	Violations []store.LedgerViolation `json:"violations"`
+}
+
+// getLedgerReportHandler godoc
+//
+//	@Summary		Lints a user's ledger
+//	@Description	Replays all approved stamps of one of the manager's reports and lists the positions that break the sign-in/sign-out sequence
+//	@Tags			timestamps
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	LedgerReport
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/timestamps/lint/{userID} [get]
+func (app *application) getLedgerReportHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getManagedUser(ctx, getUserFromContext(r), userID); err != nil {
+		app.managedUserError(w, r, err)
+		return
+	}
+
+	violations, err := app.store.Timestamps.ValidateLedger(ctx, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report := LedgerReport{
+		UserID:     userID,
+		Valid:      len(violations) == 0,
+		Violations: violations,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
 // deleteTimestampHandler godoc
 //
 //	@Summary		Deletes a timestamp
@@ -191,6 +551,7 @@ func (app *application) getFinishedShiftsByUserHandler(w http.ResponseWriter, r
 //	@Param			id	path		int	true	"Timestamp ID"
 //	@Success		204	{string}	string	"Timestamp deleted"
 //	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
 //	@Failure		500	{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/timestamps/{id} [delete]
@@ -206,7 +567,12 @@ func (app *application) deleteTimestampHandler(w http.ResponseWriter, r *http.Re
 	ctx := r.Context()
 
 	if err := app.store.Timestamps.Delete(ctx, id); err != nil {
+		var ledgerErr *store.LedgerError
 		switch {
+		case errors.As(err, &ledgerErr):
+			app.ledgerConflictResponse(w, r, ledgerErr)
+		case errors.Is(err, store.ErrPeriodLocked):
+			app.conflictResponse(w, r, err)
 		case errors.Is(err, store.ErrNotFound):
 			app.notFoundResponse(w, r, err)
 		default:
@@ -236,6 +602,7 @@ type UpdateTimestampPayload struct {
 //	@Success		200		{object}	store.Timestamp
 //	@Failure		400		{object}	error
 //	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/timestamps/{id} [patch]
@@ -260,15 +627,31 @@ func (app *application) updateTimestampHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	previousUserID := timestamp.UserID
 	timestamp.StampType = payload.StampType
-	timestamp.StampTime = parsedTime
+	timestamp.StampTime = parsedTime.UTC()
 
 	ctx := r.Context()
 
-	if err := app.updateTimestamp(ctx, timestamp); err != nil {
-		app.internalServerError(w, r, err)
+	if err := app.updateTimestamp(ctx, timestamp, previousUserID); err != nil {
+		var ledgerErr *store.LedgerError
+		switch {
+		case errors.As(err, &ledgerErr):
+			app.ledgerConflictResponse(w, r, ledgerErr)
+		case errors.Is(err, store.ErrUnknownStampType):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrPeriodLocked):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
+	timestamp.In(getUserFromContext(r).Location())
+
 	if err := app.jsonResponse(w, http.StatusOK, timestamp); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -324,15 +707,20 @@ func getTimestampFromCtx(r *http.Request) *store.Timestamp {
 // updateTimestamp godoc
 //
 //	@Summary		Update Timestamp
-//	@Description	Updates a timestamp in the store and deletes the cache entry
+//	@Description	Updates a timestamp in the store and deletes the cache entries of its owner and, if the stamp was moved to another user, of its previous owner
 //	@Tags			timestamps
 //	@Produce		json
 //	@Router			/timestamps/update [patch]
-func (app *application) updateTimestamp(ctx context.Context, timestamp *store.Timestamp) error {
+func (app *application) updateTimestamp(ctx context.Context, timestamp *store.Timestamp, previousUserID int64) error {
 	if err := app.store.Timestamps.Update(ctx, timestamp); err != nil {
 		return err
 	}
 
-	app.cacheStorage.Users.Delete(ctx, timestamp.ID)
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, timestamp.UserID)
+		if previousUserID != timestamp.UserID {
+			app.cacheStorage.Users.Delete(ctx, previousUserID)
+		}
+	}
 	return nil
 }
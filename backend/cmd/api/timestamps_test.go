@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+)
+
+// signInOnceStore accepts a single sign-in like the transition check of the real store. The
+// accepted stamp is held back until release is closed, so that every other request is answered
+// while it is still being processed.
+type signInOnceStore struct {
+	store.MockTimestampStore
+
+	mu       sync.Mutex
+	calls    int
+	signedIn bool
+	release  chan struct{}
+}
+
+func (s *signInOnceStore) Create(ctx context.Context, t *store.Timestamp) error {
+	s.mu.Lock()
+	s.calls++
+	if s.signedIn {
+		s.mu.Unlock()
+		return store.ErrInvalidTransition
+	}
+	s.signedIn = true
+	s.mu.Unlock()
+
+	<-s.release
+	t.ID = 1
+	t.StampTime = time.Now().UTC()
+	return nil
+}
+
+func TestCreateTimestampConcurrently(t *testing.T) {
+	const requests = 10
+
+	tests := []struct {
+		name  string
+		key   func(i int) string // Idempotency-Key of the ith request; empty sends none
+		calls int                // Requests that reach the store
+	}{
+		{name: "same key", key: func(int) string { return "double-tap" }, calls: 1},
+		{name: "different keys", key: func(i int) string { return fmt.Sprintf("tap-%d", i) }, calls: requests},
+		{name: "without keys", key: func(int) string { return "" }, calls: requests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+			timestamps := &signInOnceStore{release: make(chan struct{})}
+			app.store.Timestamps = timestamps
+			mux := app.mount()
+			token := testToken(t, app, 1)
+
+			responses := make(chan *httptest.ResponseRecorder, requests)
+			for i := range requests {
+				req := httptest.NewRequest(http.MethodPost, "/v1/timestamps", strings.NewReader(`{"stamp_type":"sign-in"}`))
+				req.Header.Set("Authorization", "Bearer "+token)
+				if key := tt.key(i); key != "" {
+					req.Header.Set(idempotencyKeyHeader, key)
+				}
+
+				go func() { responses <- executeRequest(req, mux) }()
+			}
+
+			statuses := make(map[int]int)
+			for i := range requests {
+				// All but the accepted request are answered while it is held back
+				if i == requests-1 {
+					close(timestamps.release)
+				}
+
+				select {
+				case rr := <-responses:
+					statuses[rr.Code]++
+				case <-time.After(5 * time.Second):
+					t.Fatalf("got %d of %d responses, statuses %v", i, requests, statuses)
+				}
+			}
+
+			if statuses[http.StatusCreated] != 1 {
+				t.Errorf("got %d created, want 1; statuses %v", statuses[http.StatusCreated], statuses)
+			}
+			if rejected := statuses[http.StatusConflict] + statuses[http.StatusBadRequest]; rejected != requests-1 {
+				t.Errorf("got %d conflicts or bad requests, want %d; statuses %v", rejected, requests-1, statuses)
+			}
+			if timestamps.calls != tt.calls {
+				t.Errorf("store was called %d times, want %d", timestamps.calls, tt.calls)
+			}
+		})
+	}
+}
@@ -159,6 +159,10 @@ func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(r.Context(), user.ID)
+	}
+
 	if err := app.jsonResponse(w, http.StatusNoContent, ""); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -171,6 +175,9 @@ type UpdateUserPayload struct {
 	Email     string `json:"email"`
 	ManagerID int64  `json:"manager_id"`
 	RoleID    int64  `json:"role_id"`
+	TimeZone  string `json:"time_zone" validate:"omitempty,timezone"`
+	// ContractedHours are the weekly contracted work hours; omitted keeps the current value
+	ContractedHours *float64 `json:"contracted_hours" validate:"omitempty,gte=0,lte=168"`
 }
 
 // updateUserHandler godoc
@@ -229,14 +236,74 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 	user.LastName = payload.LastName
 	user.Email = payload.Email
 	user.ManagerID = (payload.ManagerID)
-	user.RoleID = (payload.RoleID)
+	if payload.RoleID != 0 {
+		user.RoleID = payload.RoleID
+	}
 	user.IsActive = 1
+	if payload.TimeZone != "" {
+		user.TimeZone = payload.TimeZone
+	}
+	if payload.ContractedHours != nil {
+		user.ContractedHours = *payload.ContractedHours
+	}
 
 	if err := app.store.Users.Update(r.Context(), user); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	// A changed role must not be served from the cache
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(r.Context(), user.ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateTimeZonePayload represents the payload for changing the user's own time zone.
+type UpdateTimeZonePayload struct {
+	TimeZone string `json:"time_zone" validate:"required,timezone"`
+}
+
+// updateTimeZoneHandler godoc
+//
+//	@Summary		Updates the user's time zone
+//	@Description	Sets the IANA time zone used to attribute the user's shifts to local workdays
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateTimeZonePayload	true	"IANA time zone, e.g. Europe/Stockholm"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/time-zone [patch]
+func (app *application) updateTimeZoneHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateTimeZonePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	user.TimeZone = payload.TimeZone
+
+	if err := app.store.Users.UpdateTimeZone(r.Context(), user.ID, user.TimeZone); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(r.Context(), user.ID)
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -275,6 +342,11 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// The deleted user's tokens fail as soon as the user is no longer cached
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, id)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
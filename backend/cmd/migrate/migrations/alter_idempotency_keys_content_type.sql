@@ -0,0 +1,3 @@
+ALTER TABLE
+    idempotency_keys
+ADD COLUMN content_type varchar(255) NOT NULL DEFAULT '' AFTER status_code;
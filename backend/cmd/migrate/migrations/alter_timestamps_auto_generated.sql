@@ -0,0 +1,3 @@
+ALTER TABLE
+    timestamps
+ADD COLUMN is_auto_generated tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE
+    timestamps
+ADD COLUMN kiosk_id int(11) DEFAULT NULL,
+ADD CONSTRAINT fk_timestamps_kiosk FOREIGN KEY (kiosk_id) REFERENCES kiosks (id);
@@ -0,0 +1,8 @@
+ALTER TABLE
+    timestamps
+ADD COLUMN status varchar(16) NOT NULL DEFAULT 'approved',
+ADD COLUMN is_manual tinyint(1) NOT NULL DEFAULT 0,
+ADD COLUMN reason varchar(255) DEFAULT NULL,
+ADD COLUMN decided_by int(11) DEFAULT NULL,
+ADD COLUMN decided_at timestamp NULL DEFAULT NULL,
+ADD KEY status_idx (status);
@@ -0,0 +1,3 @@
+ALTER TABLE
+    timestamps
+ADD KEY user_type_time_idx (user_id, stamp_type, status, time);
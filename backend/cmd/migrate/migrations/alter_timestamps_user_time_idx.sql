@@ -0,0 +1,3 @@
+ALTER TABLE
+    timestamps
+ADD KEY user_status_time_idx (user_id, status, time);
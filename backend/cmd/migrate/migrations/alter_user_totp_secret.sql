@@ -0,0 +1,3 @@
+ALTER TABLE
+    user_totp
+MODIFY COLUMN secret varchar(255) NOT NULL;
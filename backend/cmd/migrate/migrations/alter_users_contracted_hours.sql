@@ -0,0 +1,3 @@
+ALTER TABLE
+    users
+ADD COLUMN contracted_hours decimal(5,2) NOT NULL DEFAULT 40.00;
@@ -0,0 +1,8 @@
+ALTER TABLE
+    users
+ADD COLUMN site_id int(11) DEFAULT NULL,
+ADD COLUMN pin_hash varbinary(60) DEFAULT NULL,
+ADD COLUMN pin_failed_attempts int(11) NOT NULL DEFAULT 0,
+ADD COLUMN pin_locked_until datetime DEFAULT NULL,
+ADD KEY site_idx (site_id),
+ADD CONSTRAINT fk_users_site FOREIGN KEY (site_id) REFERENCES sites (id) ON DELETE SET NULL;
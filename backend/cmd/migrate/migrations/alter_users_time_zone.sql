@@ -0,0 +1,3 @@
+ALTER TABLE
+    users
+ADD COLUMN time_zone varchar(64) NOT NULL DEFAULT 'UTC';
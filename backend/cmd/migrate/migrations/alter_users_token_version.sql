@@ -0,0 +1,3 @@
+ALTER TABLE
+    users
+ADD COLUMN token_version int(11) NOT NULL DEFAULT 0;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS billing_rates (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    user_id int(11) DEFAULT NULL,
+    role_id int(11) DEFAULT NULL,
+    hourly_rate decimal(10,2) NOT NULL,
+    currency char(3) NOT NULL,
+    effective_from date NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY user_effective_idx (user_id, effective_from),
+    KEY role_effective_idx (role_id, effective_from),
+    CONSTRAINT fk_billing_rates_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_billing_rates_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shift_clients (
+    sign_in_id int(11) NOT NULL,
+    client_id int(11) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (sign_in_id),
+    KEY client_id_idx (client_id),
+    CONSTRAINT fk_shift_clients_sign_in FOREIGN KEY (sign_in_id) REFERENCES timestamps (id) ON DELETE CASCADE,
+    CONSTRAINT fk_shift_clients_client FOREIGN KEY (client_id) REFERENCES clients (id) ON DELETE CASCADE
+);
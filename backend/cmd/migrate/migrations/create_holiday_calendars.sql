@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS holiday_calendars (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    name varchar(100) NOT NULL,
+    region varchar(20) NOT NULL DEFAULT '',
+    is_default tinyint(1) NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    UNIQUE KEY name_UNIQUE (name)
+);
+
+INSERT INTO holiday_calendars (name, is_default) VALUES ('Default', 1);
+
+ALTER TABLE
+    public_holidays
+ADD COLUMN calendar_id int(11) DEFAULT NULL AFTER id,
+ADD COLUMN kind varchar(16) NOT NULL DEFAULT 'public';
+
+UPDATE public_holidays
+SET calendar_id = (SELECT id FROM holiday_calendars WHERE is_default = 1);
+
+ALTER TABLE
+    public_holidays
+MODIFY calendar_id int(11) NOT NULL,
+DROP INDEX date_UNIQUE,
+ADD UNIQUE KEY calendar_date_UNIQUE (calendar_id, date),
+ADD CONSTRAINT fk_public_holidays_calendar FOREIGN KEY (calendar_id) REFERENCES holiday_calendars (id) ON DELETE CASCADE;
+
+ALTER TABLE
+    sites
+ADD COLUMN holiday_calendar_id int(11) DEFAULT NULL,
+ADD CONSTRAINT fk_sites_holiday_calendar FOREIGN KEY (holiday_calendar_id) REFERENCES holiday_calendars (id) ON DELETE SET NULL;
+
+ALTER TABLE
+    users
+ADD COLUMN holiday_calendar_id int(11) DEFAULT NULL,
+ADD CONSTRAINT fk_users_holiday_calendar FOREIGN KEY (holiday_calendar_id) REFERENCES holiday_calendars (id) ON DELETE SET NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id int(11) NOT NULL,
+    idempotency_key varchar(255) NOT NULL,
+    method varchar(10) NOT NULL,
+    path varchar(255) NOT NULL,
+    request_hash char(64) NOT NULL,
+    status_code int(11) NOT NULL DEFAULT 0,
+    body mediumblob DEFAULT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    expires_at timestamp NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key),
+    KEY expires_at_idx (expires_at)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS sites (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    name varchar(100) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    UNIQUE KEY name_UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS kiosks (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    site_id int(11) NOT NULL,
+    name varchar(100) NOT NULL,
+    token_hash char(64) NOT NULL,
+    is_active tinyint(1) NOT NULL DEFAULT 1,
+    last_seen_at datetime DEFAULT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    UNIQUE KEY token_hash_UNIQUE (token_hash),
+    CONSTRAINT fk_kiosks_site FOREIGN KEY (site_id) REFERENCES sites (id)
+);
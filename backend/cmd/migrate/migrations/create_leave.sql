@@ -0,0 +1,58 @@
+CREATE TABLE IF NOT EXISTS leave_types (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    name varchar(50) NOT NULL,
+    paid tinyint(1) NOT NULL DEFAULT 1,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    UNIQUE KEY name_UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS leave_policies (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    leave_type_id int(11) NOT NULL,
+    name varchar(100) NOT NULL,
+    days_per_month decimal(5,2) NOT NULL,
+    carry_over_cap decimal(5,2) DEFAULT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    UNIQUE KEY name_UNIQUE (name),
+    CONSTRAINT fk_leave_policies_type FOREIGN KEY (leave_type_id) REFERENCES leave_types (id)
+);
+
+CREATE TABLE IF NOT EXISTS leave_policy_assignments (
+    user_id int(11) NOT NULL,
+    leave_type_id int(11) NOT NULL,
+    policy_id int(11) NOT NULL,
+    starts_on date NOT NULL,
+    PRIMARY KEY (user_id, leave_type_id),
+    CONSTRAINT fk_leave_assignments_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_leave_assignments_type FOREIGN KEY (leave_type_id) REFERENCES leave_types (id),
+    CONSTRAINT fk_leave_assignments_policy FOREIGN KEY (policy_id) REFERENCES leave_policies (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS leave_requests (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    user_id int(11) NOT NULL,
+    leave_type_id int(11) NOT NULL,
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    half_day tinyint(1) NOT NULL DEFAULT 0,
+    days decimal(5,2) NOT NULL,
+    reason varchar(255) NOT NULL DEFAULT '',
+    status varchar(16) NOT NULL DEFAULT 'pending',
+    decided_by int(11) DEFAULT NULL,
+    decided_at datetime DEFAULT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY user_dates_idx (user_id, start_date, end_date),
+    CONSTRAINT fk_leave_requests_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_leave_requests_type FOREIGN KEY (leave_type_id) REFERENCES leave_types (id)
+);
+
+CREATE TABLE IF NOT EXISTS leave_request_days (
+    request_id int(11) NOT NULL,
+    date date NOT NULL,
+    amount decimal(3,2) NOT NULL DEFAULT 1.00,
+    PRIMARY KEY (request_id, date),
+    CONSTRAINT fk_leave_days_request FOREIGN KEY (request_id) REFERENCES leave_requests (id) ON DELETE CASCADE
+);
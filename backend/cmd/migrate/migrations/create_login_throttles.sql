@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope varchar(10) NOT NULL,
+    subject varchar(255) NOT NULL,
+    failures int(11) NOT NULL DEFAULT 0,
+    last_failed_at datetime NOT NULL,
+    locked_until datetime DEFAULT NULL,
+    PRIMARY KEY (scope, subject)
+);
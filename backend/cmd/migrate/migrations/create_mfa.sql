@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id int(11) NOT NULL,
+    secret varchar(64) NOT NULL,
+    last_counter bigint NOT NULL DEFAULT 0,
+    created_at datetime NOT NULL,
+    enabled_at datetime DEFAULT NULL,
+    PRIMARY KEY (user_id),
+    CONSTRAINT fk_user_totp_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    user_id int(11) NOT NULL,
+    code_hash char(64) NOT NULL,
+    created_at datetime NOT NULL,
+    used_at datetime DEFAULT NULL,
+    PRIMARY KEY (user_id, code_hash),
+    CONSTRAINT fk_mfa_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    token_hash char(64) NOT NULL,
+    user_id int(11) NOT NULL,
+    device varchar(100) NOT NULL DEFAULT '',
+    attempts int(11) NOT NULL DEFAULT 0,
+    created_at datetime NOT NULL,
+    expires_at datetime NOT NULL,
+    PRIMARY KEY (token_hash),
+    KEY user_idx (user_id),
+    CONSTRAINT fk_mfa_challenges_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS overtime_policies (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    name varchar(100) NOT NULL,
+    daily_threshold int(11) NOT NULL DEFAULT 0,
+    weekly_threshold int(11) NOT NULL DEFAULT 0,
+    daily_multiplier decimal(4,2) NOT NULL DEFAULT 1.00,
+    weekly_multiplier decimal(4,2) NOT NULL DEFAULT 1.00,
+    rest_day_multiplier decimal(4,2) NOT NULL DEFAULT 1.00,
+    holiday_multiplier decimal(4,2) NOT NULL DEFAULT 1.00,
+    rest_days varchar(20) NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS overtime_policy_assignments (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    policy_id int(11) NOT NULL,
+    user_id int(11) DEFAULT NULL,
+    role_id int(11) DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY user_id_UNIQUE (user_id),
+    UNIQUE KEY role_id_UNIQUE (role_id),
+    CONSTRAINT fk_overtime_assignments_policy FOREIGN KEY (policy_id) REFERENCES overtime_policies (id) ON DELETE CASCADE,
+    CONSTRAINT fk_overtime_assignments_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_overtime_assignments_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS public_holidays (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    date date NOT NULL,
+    name varchar(100) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY date_UNIQUE (date)
+);
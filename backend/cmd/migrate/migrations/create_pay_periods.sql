@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS pay_periods (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    user_id int(11) NOT NULL,
+    starts_at datetime NOT NULL,
+    ends_at datetime NOT NULL,
+    status varchar(16) NOT NULL DEFAULT 'draft',
+    version int(11) NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    updated_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY user_status_range_idx (user_id, status, starts_at, ends_at),
+    CONSTRAINT fk_pay_periods_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS pay_period_transitions (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    period_id int(11) NOT NULL,
+    from_status varchar(16) NOT NULL,
+    to_status varchar(16) NOT NULL,
+    actor_id int(11) NOT NULL,
+    reason varchar(255) NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY period_idx (period_id),
+    CONSTRAINT fk_pay_period_transitions_period FOREIGN KEY (period_id) REFERENCES pay_periods (id) ON DELETE CASCADE
+);
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS clients (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    name varchar(100) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    UNIQUE KEY name_UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS projects (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    client_id int(11) NOT NULL,
+    name varchar(100) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY client_id_idx (client_id),
+    CONSTRAINT fk_projects_client FOREIGN KEY (client_id) REFERENCES clients (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    project_id int(11) NOT NULL,
+    name varchar(100) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY project_id_idx (project_id),
+    CONSTRAINT fk_tasks_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS task_entries (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    user_id int(11) NOT NULL,
+    task_id int(11) NOT NULL,
+    started_at datetime NOT NULL,
+    ended_at datetime DEFAULT NULL,
+    note varchar(255) NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY user_started_idx (user_id, started_at),
+    KEY task_id_idx (task_id),
+    CONSTRAINT fk_task_entries_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_entries_task FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE
+);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS shift_templates (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    name varchar(100) NOT NULL,
+    weekdays tinyint(3) unsigned NOT NULL,
+    start_time time NOT NULL,
+    end_time time NOT NULL,
+    location varchar(100) NOT NULL DEFAULT '',
+    role varchar(50) NOT NULL DEFAULT '',
+    created_by int(11) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    UNIQUE KEY name_UNIQUE (name),
+    CONSTRAINT fk_shift_templates_creator FOREIGN KEY (created_by) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS planned_shifts (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    user_id int(11) NOT NULL,
+    starts_at datetime NOT NULL,
+    ends_at datetime NOT NULL,
+    location varchar(100) NOT NULL DEFAULT '',
+    role varchar(50) NOT NULL DEFAULT '',
+    template_id int(11) DEFAULT NULL,
+    published_at datetime DEFAULT NULL,
+    created_by int(11) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (id),
+    KEY user_starts_idx (user_id, starts_at),
+    KEY starts_idx (starts_at),
+    CONSTRAINT fk_planned_shifts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_planned_shifts_template FOREIGN KEY (template_id) REFERENCES shift_templates (id) ON DELETE SET NULL,
+    CONSTRAINT fk_planned_shifts_creator FOREIGN KEY (created_by) REFERENCES users (id)
+);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id int(11) NOT NULL AUTO_INCREMENT,
+    user_id int(11) NOT NULL,
+    device varchar(100) NOT NULL DEFAULT '',
+    user_agent varchar(255) NOT NULL DEFAULT '',
+    ip_address varchar(45) NOT NULL DEFAULT '',
+    created_at datetime NOT NULL,
+    last_used_at datetime NOT NULL,
+    expires_at datetime NOT NULL,
+    revoked_at datetime DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY user_idx (user_id),
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash char(64) NOT NULL,
+    session_id int(11) NOT NULL,
+    created_at datetime NOT NULL,
+    used_at datetime DEFAULT NULL,
+    PRIMARY KEY (token_hash),
+    KEY session_idx (session_id),
+    CONSTRAINT fk_refresh_tokens_session FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
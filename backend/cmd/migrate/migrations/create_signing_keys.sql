@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid varchar(36) NOT NULL,
+    algorithm varchar(10) NOT NULL,
+    private_key varbinary(2048) NOT NULL,
+    created_at datetime NOT NULL,
+    activates_at datetime NOT NULL,
+    expires_at datetime DEFAULT NULL,
+    PRIMARY KEY (kid),
+    KEY activates_at_idx (activates_at)
+);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS stamp_types (
+    name varchar(32) NOT NULL,
+    category varchar(16) NOT NULL,
+    description varchar(255) NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (name)
+);
+
+CREATE TABLE IF NOT EXISTS stamp_transitions (
+    from_type varchar(32) NOT NULL,
+    to_type varchar(32) NOT NULL,
+    PRIMARY KEY (from_type, to_type),
+    CONSTRAINT fk_stamp_transitions_from FOREIGN KEY (from_type) REFERENCES stamp_types (name) ON DELETE CASCADE,
+    CONSTRAINT fk_stamp_transitions_to FOREIGN KEY (to_type) REFERENCES stamp_types (name) ON DELETE CASCADE
+);
+
+INSERT IGNORE INTO stamp_types (name, category, description) VALUES
+    ('sign-in', 'start', 'Starts a shift'),
+    ('sign-out', 'end', 'Ends a shift'),
+    ('start-break', 'unpaid-break', 'Starts an unpaid break'),
+    ('end-break', 'work', 'Ends a break');
+
+INSERT IGNORE INTO stamp_transitions (from_type, to_type) VALUES
+    ('sign-out', 'sign-in'),
+    ('sign-in', 'sign-out'),
+    ('sign-in', 'start-break'),
+    ('start-break', 'end-break'),
+    ('end-break', 'sign-out'),
+    ('end-break', 'start-break');
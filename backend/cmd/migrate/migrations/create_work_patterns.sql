@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS work_patterns (
+    user_id int(11) NOT NULL,
+    weekdays tinyint(3) unsigned NOT NULL,
+    start_time time NOT NULL,
+    end_time time NOT NULL,
+    late_grace_minutes int(11) DEFAULT NULL,
+    early_grace_minutes int(11) DEFAULT NULL,
+    updated_at datetime NOT NULL,
+    PRIMARY KEY (user_id),
+    CONSTRAINT fk_work_patterns_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -2,27 +2,36 @@ package auth
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWTAuthenticator is a struct that holds the secret, audience, and issuer for JWT authentication.
+// JWTAuthenticator signs and validates JWT tokens with the asymmetric keys of a KeySet, so that
+// other services can verify tokens with the published public keys.
 type JWTAuthenticator struct {
-	secret string
-	aud    string
-	iss    string
+	keys *KeySet
+	aud  string
+	iss  string
 }
 
-// NewJWTAuthenticator creates a new JWTAuthenticator with the given secret, audience, and issuer.
-func NewJWTAuthenticator(secret, aud, iss string) *JWTAuthenticator {
-	return &JWTAuthenticator{secret, iss, aud}
+// NewJWTAuthenticator creates a new JWTAuthenticator with the given keys, audience, and issuer.
+func NewJWTAuthenticator(keys *KeySet, aud, iss string) *JWTAuthenticator {
+	return &JWTAuthenticator{keys, aud, iss}
 }
 
-// GenerateToken generates a JWT token with the given claims.
+// GenerateToken generates a JWT token with the given claims, signed with the current key and
+// carrying its kid in the header.
 func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key, err := a.keys.current(time.Now())
+	if err != nil {
+		return "", err
+	}
 
-	tokenString, err := token.SignedString([]byte(a.secret))
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -33,15 +42,31 @@ func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
 // ValidateToken validates the given JWT token and returns the parsed token.
 func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := a.keys.lookup(kid, time.Now())
+		if !ok {
+			return nil, fmt.Errorf("unknown or expired signing key %q", kid)
+		}
+
+		if t.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
 		}
 
-		return []byte(a.secret), nil
+		return key.Private.Public(), nil
 	},
 		jwt.WithExpirationRequired(),
 		jwt.WithAudience(a.aud),
-		jwt.WithIssuer(a.aud),
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithIssuer(a.iss),
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
 	)
 }
+
+// signingMethod returns the JWT signing method of a supported algorithm.
+func signingMethod(alg string) jwt.SigningMethod {
+	if alg == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+
+	return jwt.SigningMethodRS256
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Supported signing algorithms.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys.
+const rsaKeyBits = 2048
+
+// ErrNoSigningKey is returned when a token is to be signed before any key has become active.
+var ErrNoSigningKey = errors.New("no active signing key")
+
+// SigningKey is a private key that access tokens are signed with, identified by its kid.
+type SigningKey struct {
+	ID          string        // kid
+	Algorithm   string        // RS256 or EdDSA
+	Private     crypto.Signer // *rsa.PrivateKey or ed25519.PrivateKey
+	ActivatesAt time.Time     // Tokens are signed with the key from this time on
+	ExpiresAt   *time.Time    // Tokens signed with the key are rejected from this time on; nil while it is current
+}
+
+// KeySet holds the signing keys that are currently published. The newest active key signs new
+// tokens; every key that has not expired verifies them.
+type KeySet struct {
+	sync.RWMutex
+	keys []SigningKey
+}
+
+// NewKeySet creates an empty KeySet.
+func NewKeySet() *KeySet {
+	return &KeySet{}
+}
+
+// Set replaces the keys of the set.
+func (s *KeySet) Set(keys []SigningKey) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.keys = keys
+}
+
+// current returns the most recently activated key that may sign tokens at now.
+func (s *KeySet) current(now time.Time) (*SigningKey, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	var current *SigningKey
+	for i, key := range s.keys {
+		if key.ActivatesAt.After(now) || key.expired(now) {
+			continue
+		}
+		if current == nil || key.ActivatesAt.After(current.ActivatesAt) {
+			current = &s.keys[i]
+		}
+	}
+
+	if current == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return current, nil
+}
+
+// lookup returns the key with the given kid if tokens signed with it are still accepted at now.
+func (s *KeySet) lookup(kid string, now time.Time) (*SigningKey, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for i, key := range s.keys {
+		if key.ID == kid && !key.expired(now) {
+			return &s.keys[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// JWKS returns the public keys that verify tokens at now, including keys that are published
+// ahead of their activation.
+func (s *KeySet) JWKS(now time.Time) JWKSet {
+	s.RLock()
+	defer s.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		if key.expired(now) {
+			continue
+		}
+
+		jwk, err := key.jwk()
+		if err != nil {
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func (k *SigningKey) expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Curve of an OKP key
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is a JSON Web Key Set as served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk returns the public half of the key as a JWK.
+func (k *SigningKey) jwk() (JWK, error) {
+	jwk := JWK{Kid: k.ID, Alg: k.Algorithm, Use: "sig"}
+
+	switch pub := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", pub)
+	}
+
+	return jwk, nil
+}
+
+// GenerateKey generates a new private key for the given algorithm.
+func GenerateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case AlgRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+// MarshalPrivateKey encodes a private key as PKCS #8 DER for storage.
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(key)
+}
+
+// ParsePrivateKey decodes a PKCS #8 DER private key and checks that it fits the algorithm.
+func ParsePrivateKey(alg string, der []byte) (crypto.Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if alg == AlgRS256 {
+			return key, nil
+		}
+	case ed25519.PrivateKey:
+		if alg == AlgEdDSA {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%T is not a %s key", key, alg)
+}
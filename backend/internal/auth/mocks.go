@@ -1,24 +1,13 @@
 package auth
 
-import (
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-)
+import "github.com/golang-jwt/jwt/v5"
 
 type TestAuthenticator struct{}
 
 const secret = "test"
 
-var testClaims = jwt.MapClaims{
-	"aud": "test-aud",
-	"iss": "test-aud",
-	"sub": int64(1),
-	"exp": time.Now().Add(time.Hour).Unix(),
-}
-
 func (a *TestAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, _ := token.SignedString([]byte(secret))
 	return tokenString, nil
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks a private key encrypted with SealPrivateKey. PKCS #8 DER always starts with
+// 0x30, so keys stored before encryption was configured are told apart from sealed ones.
+var sealedPrefix = []byte("kek1")
+
+// sealedSecretPrefix marks a secret encrypted with SealSecret. It cannot occur in the base32
+// TOTP secrets stored before encryption was configured.
+const sealedSecretPrefix = "kek1:"
+
+// ErrNoKeyEncryptionKey is returned when a sealed private key or secret is opened without a key
+// encryption key.
+var ErrNoKeyEncryptionKey = errors.New("data is encrypted but no key encryption key is configured")
+
+// ParseKeyEncryptionKey decodes a base64 encoded 256 bit key encryption key. An empty string
+// returns a nil key, which leaves private keys unencrypted.
+func ParseKeyEncryptionKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	kek, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("key encryption key: %w", err)
+	}
+	if len(kek) != 32 {
+		return nil, fmt.Errorf("key encryption key: got %d bytes, want 32", len(kek))
+	}
+
+	return kek, nil
+}
+
+// SealPrivateKey encrypts a PKCS #8 DER private key with AES-256-GCM under kek for storage.
+// The kid is authenticated with it, so a sealed key cannot be moved to another kid. A nil kek
+// returns der as it is.
+func SealPrivateKey(kek []byte, kid string, der []byte) ([]byte, error) {
+	if kek == nil {
+		return der, nil
+	}
+
+	sealed, err := seal(kek, kid, der)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(bytes.Clone(sealedPrefix), sealed...), nil
+}
+
+// OpenPrivateKey returns the PKCS #8 DER of a private key stored with SealPrivateKey. Keys
+// stored without encryption are returned as they are.
+func OpenPrivateKey(kek []byte, kid string, stored []byte) ([]byte, error) {
+	if !bytes.HasPrefix(stored, sealedPrefix) {
+		return stored, nil
+	}
+	if kek == nil {
+		return nil, ErrNoKeyEncryptionKey
+	}
+
+	return open(kek, kid, stored[len(sealedPrefix):])
+}
+
+// SealSecret encrypts a short secret, such as a TOTP secret, with AES-256-GCM under kek for
+// storage in a text column. The owner is authenticated with it, so a sealed secret cannot be
+// moved to another owner. A nil kek returns secret as it is.
+func SealSecret(kek []byte, owner, secret string) (string, error) {
+	if kek == nil {
+		return secret, nil
+	}
+
+	sealed, err := seal(kek, owner, []byte(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return sealedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret returns a secret stored with SealSecret. Secrets stored without encryption are
+// returned as they are.
+func OpenSecret(kek []byte, owner, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedSecretPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if kek == nil {
+		return "", ErrNoKeyEncryptionKey
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("sealed secret: %w", err)
+	}
+
+	secret, err := open(kek, owner, sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// seal encrypts plaintext under kek with a random nonce, which it returns in front of the
+// ciphertext. aad is authenticated but not encrypted.
+func seal(kek []byte, aad string, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, []byte(aad)), nil
+}
+
+// open decrypts the output of seal.
+func open(kek []byte, aad string, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed data is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(aad))
+}
+
+func newAEAD(kek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
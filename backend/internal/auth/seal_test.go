@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSealPrivateKey(t *testing.T) {
+	kek := bytes.Repeat([]byte{7}, 32)
+
+	key, err := GenerateKey(AlgEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := MarshalPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := SealPrivateKey(kek, "kid-1", der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, der) {
+		t.Fatal("sealed key contains the plain key")
+	}
+
+	opened, err := OpenPrivateKey(kek, "kid-1", sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, der) {
+		t.Fatal("opened key differs from the sealed one")
+	}
+
+	if _, err := OpenPrivateKey(kek, "kid-2", sealed); err == nil {
+		t.Error("opened a key under another kid")
+	}
+	if _, err := OpenPrivateKey(bytes.Repeat([]byte{8}, 32), "kid-1", sealed); err == nil {
+		t.Error("opened a key with another key encryption key")
+	}
+	if _, err := OpenPrivateKey(nil, "kid-1", sealed); !errors.Is(err, ErrNoKeyEncryptionKey) {
+		t.Errorf("got %v, want ErrNoKeyEncryptionKey", err)
+	}
+
+	// Keys stored before a key encryption key was configured still load
+	if opened, err := OpenPrivateKey(kek, "kid-1", der); err != nil || !bytes.Equal(opened, der) {
+		t.Errorf("unencrypted key: got %v", err)
+	}
+}
+
+func TestSealPrivateKeyWithoutKEK(t *testing.T) {
+	der := []byte{0x30, 1, 2, 3}
+
+	sealed, err := SealPrivateKey(nil, "kid-1", der)
+	if err != nil || !bytes.Equal(sealed, der) {
+		t.Errorf("got %x, %v, want the key unchanged", sealed, err)
+	}
+}
+
+func TestSealSecret(t *testing.T) {
+	kek := bytes.Repeat([]byte{7}, 32)
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	sealed, err := SealSecret(kek, "totp-1", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, secret) || len(sealed) > 255 {
+		t.Fatalf("got sealed secret %q", sealed)
+	}
+
+	opened, err := OpenSecret(kek, "totp-1", sealed)
+	if err != nil || opened != secret {
+		t.Fatalf("got %q, %v, want the secret", opened, err)
+	}
+
+	if _, err := OpenSecret(kek, "totp-2", sealed); err == nil {
+		t.Error("opened a secret of another owner")
+	}
+	if _, err := OpenSecret(nil, "totp-1", sealed); !errors.Is(err, ErrNoKeyEncryptionKey) {
+		t.Errorf("got %v, want ErrNoKeyEncryptionKey", err)
+	}
+
+	// Secrets stored before a key encryption key was configured still load
+	if opened, err := OpenSecret(kek, "totp-1", secret); err != nil || opened != secret {
+		t.Errorf("unencrypted secret: got %q, %v", opened, err)
+	}
+
+	if unsealed, err := SealSecret(nil, "totp-1", secret); err != nil || unsealed != secret {
+		t.Errorf("without a key encryption key: got %q, %v, want the secret unchanged", unsealed, err)
+	}
+}
+
+func TestParseKeyEncryptionKey(t *testing.T) {
+	if kek, err := ParseKeyEncryptionKey(""); kek != nil || err != nil {
+		t.Errorf("empty: got %x, %v", kek, err)
+	}
+	if kek, err := ParseKeyEncryptionKey("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="); len(kek) != 32 || err != nil {
+		t.Errorf("32 bytes: got %x, %v", kek, err)
+	}
+	if _, err := ParseKeyEncryptionKey("AAAAAAAAAAAAAAAAAAAAAA=="); err == nil {
+		t.Error("accepted a 16 byte key")
+	}
+	if _, err := ParseKeyEncryptionKey("not base64!"); err == nil {
+		t.Error("accepted invalid base64")
+	}
+}
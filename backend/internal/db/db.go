@@ -5,7 +5,7 @@ import (
 	"database/sql"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // New godoc
@@ -22,7 +22,19 @@ import (
 //	@Failure		500				{object}	error	"Internal server error"
 //	@Router			/database/new [get]
 func New(addr string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", addr)
+	cfg, err := mysql.ParseDSN(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store and read every timestamp in UTC regardless of the server and session settings
+	cfg.Loc = time.UTC
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	cfg.Params["time_zone"] = "'+00:00'"
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,8 @@
 package env
 
 import (
+	"errors"
+	"io/fs"
 	"log"
 	"os"
 	"strconv"
@@ -9,8 +11,9 @@ import (
 )
 
 func init() {
+	// Without a .env file, such as in tests, only the process environment is used
 	err := godotenv.Load(".env")
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal("Error loading .env file")
 	}
 }
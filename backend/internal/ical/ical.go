@@ -0,0 +1,411 @@
+// Package ical reads the all-day events of iCalendar (RFC 5545) files, such as published
+// public holiday calendars. Times of day are ignored; every event covers whole dates.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed       = errors.New("ical: malformed calendar")
+	ErrUnsupportedRule = errors.New("ical: unsupported recurrence rule")
+)
+
+// Event is a VEVENT of a calendar. Start and End are dates at midnight UTC; End is exclusive.
+type Event struct {
+	UID      string
+	Summary  string
+	Start    time.Time
+	End      time.Time
+	Rule     *Rule           // Yearly recurrence, if any
+	Excluded map[string]bool // Dates (YYYY-MM-DD) of occurrences removed with EXDATE
+}
+
+// SkippedEvent is an event that was left out because its recurrence is not supported.
+type SkippedEvent struct {
+	UID     string
+	Summary string
+	Reason  error
+}
+
+// Rule is a yearly recurrence rule. Zero Count and Until mean the event recurs forever.
+// Without Months, MonthDays and Weekdays the event recurs on the date of its start.
+type Rule struct {
+	Interval  int
+	Count     int
+	Until     time.Time
+	Months    []time.Month  // BYMONTH; defaults to every month with MonthDays, else the month of the start
+	MonthDays []int         // BYMONTHDAY; negative days count back from the end of the month
+	Weekdays  []RuleWeekday // BYDAY, within each month of the rule
+}
+
+// RuleWeekday is a BYDAY entry such as MO, 4TH or -1MO. N is the occurrence of the weekday within
+// the month, counted from the end if negative; zero means every such weekday.
+type RuleWeekday struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Parse reads the events of a calendar. Events with recurrence rules that are not supported are
+// returned as skipped instead of failing the whole calendar.
+func Parse(r io.Reader) ([]Event, []SkippedEvent, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make([]Event, 0)
+	skipped := make([]SkippedEvent, 0)
+	var event *Event
+	var unsupported error // Why the current event is skipped, if it is
+
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		name, value, ok := splitLine(line)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: line %d", ErrMalformed, n+1)
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &Event{Excluded: make(map[string]bool)}
+			unsupported = nil
+		case event == nil:
+			// Properties of the calendar itself and of other components are not needed
+		case name == "END" && value == "VEVENT":
+			if event.Start.IsZero() {
+				return nil, nil, fmt.Errorf("%w: event %q has no start date", ErrMalformed, event.Summary)
+			}
+			if !event.End.After(event.Start) {
+				event.End = event.Start.AddDate(0, 0, 1)
+			}
+			if unsupported != nil {
+				skipped = append(skipped, SkippedEvent{UID: event.UID, Summary: event.Summary, Reason: unsupported})
+			} else {
+				events = append(events, *event)
+			}
+			event = nil
+		case name == "UID":
+			event.UID = value
+		case name == "SUMMARY":
+			event.Summary = unescape(value)
+		case name == "DTSTART":
+			if event.Start, err = parseDate(value); err != nil {
+				return nil, nil, fmt.Errorf("%w: line %d", ErrMalformed, n+1)
+			}
+		case name == "DTEND":
+			end, err := parseDate(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: line %d", ErrMalformed, n+1)
+			}
+			// A DTEND with a time of day ends during that date, which therefore still belongs to the event
+			if len(value) >= 15 && value[9:15] != "000000" {
+				end = end.AddDate(0, 0, 1)
+			}
+			event.End = end
+		case name == "RRULE":
+			event.Rule, err = parseRule(value)
+			switch {
+			case errors.Is(err, ErrUnsupportedRule):
+				unsupported = err
+			case err != nil:
+				return nil, nil, fmt.Errorf("event %q: %w", event.Summary, err)
+			}
+		case name == "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				date, err := parseDate(v)
+				if err != nil {
+					return nil, nil, fmt.Errorf("%w: line %d", ErrMalformed, n+1)
+				}
+				event.Excluded[date.Format(time.DateOnly)] = true
+			}
+		}
+	}
+
+	if event != nil {
+		return nil, nil, fmt.Errorf("%w: unterminated event %q", ErrMalformed, event.Summary)
+	}
+
+	return events, skipped, nil
+}
+
+// Dates returns the dates (YYYY-MM-DD) from..to (inclusive) on which the event takes place, in order.
+func (e Event) Dates(from, to time.Time) []string {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(e.End.Sub(e.Start).Hours()/24 + 0.5)
+
+	dates := make([]string, 0)
+	seen := make(map[string]bool)
+
+	add := func(start time.Time) {
+		if e.Excluded[start.Format(time.DateOnly)] {
+			return
+		}
+		for i := 0; i < days; i++ {
+			d := start.AddDate(0, 0, i)
+			date := d.Format(time.DateOnly)
+			if d.Before(from) || d.After(to) || seen[date] {
+				continue
+			}
+			seen[date] = true
+			dates = append(dates, date)
+		}
+	}
+
+	if e.Rule == nil {
+		add(e.Start)
+		return dates
+	}
+
+	count := 0
+	for year := e.Start.Year(); time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).Compare(to) <= 0; year += e.Rule.Interval {
+		for _, start := range e.Rule.occurrences(year, e.Start) {
+			// Occurrences before the start, e.g. earlier in its year, do not count
+			if start.Before(e.Start) {
+				continue
+			}
+			if start.After(to) || (!e.Rule.Until.IsZero() && start.After(e.Rule.Until)) {
+				return dates
+			}
+
+			count++
+			if e.Rule.Count > 0 && count > e.Rule.Count {
+				return dates
+			}
+
+			add(start)
+		}
+	}
+
+	return dates
+}
+
+// occurrences returns the dates of year the rule selects, in order. Dates that do not exist,
+// e.g. February 29 in common years, are never selected.
+func (r *Rule) occurrences(year int, start time.Time) []time.Time {
+	// Days of the month without months select from every month, otherwise the month of the start is kept
+	months := r.Months
+	switch {
+	case len(months) > 0:
+	case len(r.MonthDays) > 0:
+		months = []time.Month{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	default:
+		months = []time.Month{start.Month()}
+	}
+
+	dates := make([]time.Time, 0)
+	for _, month := range months {
+		daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for day := 1; day <= daysInMonth; day++ {
+			date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+			if r.selects(date, daysInMonth, start) {
+				dates = append(dates, date)
+			}
+		}
+	}
+
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+
+	return slices.Compact(dates)
+}
+
+// selects reports whether the rule selects date, a day of a month with daysInMonth days.
+func (r *Rule) selects(date time.Time, daysInMonth int, start time.Time) bool {
+	day := date.Day()
+
+	if len(r.MonthDays) > 0 && !slices.ContainsFunc(r.MonthDays, func(d int) bool {
+		return d == day || d == day-daysInMonth-1
+	}) {
+		return false
+	}
+
+	if len(r.Weekdays) > 0 {
+		return slices.ContainsFunc(r.Weekdays, func(w RuleWeekday) bool {
+			switch {
+			case w.Weekday != date.Weekday():
+				return false
+			case w.N > 0:
+				return (day-1)/7+1 == w.N
+			case w.N < 0:
+				return (daysInMonth-day)/7+1 == -w.N
+			default:
+				return true
+			}
+		})
+	}
+
+	// Without BYMONTHDAY and BYDAY the event recurs on the day of the month it started on
+	return len(r.MonthDays) > 0 || day == start.Day()
+}
+
+// unfold reads the content lines of a calendar, joining lines that were folded onto several lines.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lines := make([]string, 0)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if n := len(lines); n > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[n-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// splitLine splits a content line into its upper-cased name and its value. Parameters are dropped.
+func splitLine(line string) (name, value string, ok bool) {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ':' && !quoted:
+			name, _, _ = strings.Cut(line[:i], ";")
+			return strings.ToUpper(name), line[i+1:], true
+		}
+	}
+
+	return "", "", false
+}
+
+// parseDate reads the date of a DATE or DATE-TIME value. The time of day and time zone are ignored.
+func parseDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, ErrMalformed
+	}
+
+	return time.Parse("20060102", value[:8])
+}
+
+// weekdays maps the two-letter weekdays of BYDAY.
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// parseRule reads a RRULE value. Yearly rules with BYMONTH, BYMONTHDAY and BYDAY within months
+// are supported, which covers fixed-date holidays as well as ones like the fourth Thursday of
+// November.
+func parseRule(value string) (*Rule, error) {
+	rule := &Rule{Interval: 1}
+	freq := ""
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq = strings.ToUpper(val)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+			if err == nil && rule.Interval < 1 {
+				err = ErrMalformed
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+		case "UNTIL":
+			rule.Until, err = parseDate(val)
+		case "BYMONTH":
+			for _, v := range strings.Split(val, ",") {
+				var month int
+				if month, err = strconv.Atoi(v); err == nil && (month < 1 || month > 12) {
+					err = ErrMalformed
+				}
+				if err != nil {
+					break
+				}
+				rule.Months = append(rule.Months, time.Month(month))
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(val, ",") {
+				var day int
+				if day, err = strconv.Atoi(v); err == nil && (day == 0 || day < -31 || day > 31) {
+					err = ErrMalformed
+				}
+				if err != nil {
+					break
+				}
+				rule.MonthDays = append(rule.MonthDays, day)
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(strings.ToUpper(val), ",") {
+				var w RuleWeekday
+				if w, err = parseRuleWeekday(v); err != nil {
+					break
+				}
+				rule.Weekdays = append(rule.Weekdays, w)
+			}
+		case "WKST", "BYHOUR", "BYMINUTE", "BYSECOND":
+			// Only affect weeks and times of day, neither of which changes the dates of a yearly rule
+		default:
+			// BYWEEKNO, BYYEARDAY and BYSETPOS select days across the year
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedRule, strings.ToUpper(key))
+		}
+		if err != nil {
+			return nil, ErrMalformed
+		}
+	}
+
+	if freq != "YEARLY" {
+		return nil, fmt.Errorf("%w: only yearly rules are supported", ErrUnsupportedRule)
+	}
+
+	// Without months, BYDAY would select weekdays of the whole year
+	if len(rule.Weekdays) > 0 && len(rule.Months) == 0 {
+		return nil, fmt.Errorf("%w: BYDAY without BYMONTH", ErrUnsupportedRule)
+	}
+
+	return rule, nil
+}
+
+// parseRuleWeekday reads a BYDAY entry such as MO, 4TH or -1MO.
+func parseRuleWeekday(value string) (RuleWeekday, error) {
+	if len(value) < 2 {
+		return RuleWeekday{}, ErrMalformed
+	}
+
+	weekday, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return RuleWeekday{}, ErrMalformed
+	}
+
+	w := RuleWeekday{Weekday: weekday}
+	if n := value[:len(value)-2]; n != "" {
+		var err error
+		if w.N, err = strconv.Atoi(n); err != nil || w.N == 0 || w.N < -5 || w.N > 5 {
+			return RuleWeekday{}, ErrMalformed
+		}
+	}
+
+	return w, nil
+}
+
+// unescape decodes the escaped characters of a TEXT value.
+func unescape(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, `;`, `\,`, `,`, `\n`, " ", `\N`, " ").Replace(value)
+}
@@ -0,0 +1,274 @@
+package ical
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar wraps the lines of one event into a calendar.
+func calendar(event ...string) string {
+	lines := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "BEGIN:VEVENT"}, event...)
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func date(value string) time.Time {
+	d, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestEventDates(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    []string
+		from, to string
+		want     []string
+	}{
+		{
+			name:  "single all-day event",
+			event: []string{"SUMMARY:Founders Day", "DTSTART;VALUE=DATE:20240315"},
+			from:  "2024-01-01", to: "2025-12-31",
+			want: []string{"2024-03-15"},
+		},
+		{
+			name:  "multi-day event with exclusive end",
+			event: []string{"SUMMARY:Summer closure", "DTSTART;VALUE=DATE:20240729", "DTEND;VALUE=DATE:20240802"},
+			from:  "2024-01-01", to: "2024-12-31",
+			want: []string{"2024-07-29", "2024-07-30", "2024-07-31", "2024-08-01"},
+		},
+		{
+			name:  "event clipped to the window",
+			event: []string{"SUMMARY:Summer closure", "DTSTART;VALUE=DATE:20240729", "DTEND;VALUE=DATE:20240802"},
+			from:  "2024-07-31", to: "2024-12-31",
+			want: []string{"2024-07-31", "2024-08-01"},
+		},
+		{
+			name:  "time of day on the end date",
+			event: []string{"SUMMARY:Offsite", "DTSTART:20240510T090000Z", "DTEND:20240511T120000Z"},
+			from:  "2024-01-01", to: "2024-12-31",
+			want: []string{"2024-05-10", "2024-05-11"},
+		},
+		{
+			name:  "yearly on the start date",
+			event: []string{"SUMMARY:New Year", "DTSTART;VALUE=DATE:20200101", "RRULE:FREQ=YEARLY"},
+			from:  "2024-01-01", to: "2025-12-31",
+			want: []string{"2024-01-01", "2025-01-01"},
+		},
+		{
+			name:  "yearly with BYMONTH and BYMONTHDAY",
+			event: []string{"SUMMARY:Christmas Day", "DTSTART;VALUE=DATE:20101225", "RRULE:FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25"},
+			from:  "2024-01-01", to: "2025-12-31",
+			want: []string{"2024-12-25", "2025-12-25"},
+		},
+		{
+			name:  "fourth Thursday of November",
+			event: []string{"SUMMARY:Thanksgiving", "DTSTART;VALUE=DATE:20101125", "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH"},
+			from:  "2024-01-01", to: "2025-12-31",
+			want: []string{"2024-11-28", "2025-11-27"},
+		},
+		{
+			name:  "last Monday of May",
+			event: []string{"SUMMARY:Memorial Day", "DTSTART;VALUE=DATE:20100531", "RRULE:FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO"},
+			from:  "2024-01-01", to: "2025-12-31",
+			want: []string{"2024-05-27", "2025-05-26"},
+		},
+		{
+			name: "weekday within days of the month",
+			event: []string{
+				"SUMMARY:Midsummer Eve", "DTSTART;VALUE=DATE:20100625",
+				"RRULE:FREQ=YEARLY;BYMONTH=6;BYMONTHDAY=19,20,21,22,23,24,25;BYDAY=FR",
+			},
+			from: "2024-01-01", to: "2025-12-31",
+			want: []string{"2024-06-21", "2025-06-20"},
+		},
+		{
+			name:  "last day of February",
+			event: []string{"SUMMARY:Month end", "DTSTART;VALUE=DATE:20230228", "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"},
+			from:  "2024-01-01", to: "2025-12-31",
+			want: []string{"2024-02-29", "2025-02-28"},
+		},
+		{
+			name:  "several months",
+			event: []string{"SUMMARY:Quarter start", "DTSTART;VALUE=DATE:20240101", "RRULE:FREQ=YEARLY;BYMONTH=7,1,4,10;BYMONTHDAY=1"},
+			from:  "2024-01-01", to: "2024-12-31",
+			want: []string{"2024-01-01", "2024-04-01", "2024-07-01", "2024-10-01"},
+		},
+		{
+			name:  "February 29 only in leap years",
+			event: []string{"SUMMARY:Leap day", "DTSTART;VALUE=DATE:20200229", "RRULE:FREQ=YEARLY"},
+			from:  "2023-01-01", to: "2028-12-31",
+			want: []string{"2024-02-29", "2028-02-29"},
+		},
+		{
+			name:  "interval",
+			event: []string{"SUMMARY:Biennial", "DTSTART;VALUE=DATE:20200601", "RRULE:FREQ=YEARLY;INTERVAL=2"},
+			from:  "2020-01-01", to: "2025-12-31",
+			want: []string{"2020-06-01", "2022-06-01", "2024-06-01"},
+		},
+		{
+			name:  "count includes occurrences before the window",
+			event: []string{"SUMMARY:Anniversary", "DTSTART;VALUE=DATE:20200601", "RRULE:FREQ=YEARLY;COUNT=5"},
+			from:  "2023-01-01", to: "2030-12-31",
+			want: []string{"2023-06-01", "2024-06-01"},
+		},
+		{
+			name:  "until",
+			event: []string{"SUMMARY:Old holiday", "DTSTART;VALUE=DATE:20200601", "RRULE:FREQ=YEARLY;UNTIL=20230601T000000Z"},
+			from:  "2022-01-01", to: "2030-12-31",
+			want: []string{"2022-06-01", "2023-06-01"},
+		},
+		{
+			name:  "no occurrences before the start",
+			event: []string{"SUMMARY:Thanksgiving", "DTSTART;VALUE=DATE:20241128", "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=TH"},
+			from:  "2024-01-01", to: "2024-12-31",
+			want: []string{"2024-11-28"},
+		},
+		{
+			name:  "excluded occurrence",
+			event: []string{"SUMMARY:Christmas Day", "DTSTART;VALUE=DATE:20201225", "RRULE:FREQ=YEARLY", "EXDATE;VALUE=DATE:20241225"},
+			from:  "2024-01-01", to: "2025-12-31",
+			want: []string{"2025-12-25"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, skipped, err := Parse(strings.NewReader(calendar(tt.event...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 || len(skipped) != 0 {
+				t.Fatalf("got %d events and %d skipped, want 1 event", len(events), len(skipped))
+			}
+
+			got := events[0].Dates(date(tt.from), date(tt.to))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:1",
+		"SUMMARY:Christmas Day\\, observed",
+		"DTSTART;VALUE=DATE:20241225",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:2",
+		"SUMMARY:Monthly",
+		"DTSTART;VALUE=DATE:20240101",
+		"RRULE:FREQ=MONTHLY",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:3",
+		"SUMMARY:A holiday with a long",
+		"  name",
+		"DTSTART;VALUE=DATE:20240101",
+		"RRULE:FREQ=YEARLY;BYWEEKNO=1",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, skipped, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].UID != "1" || events[0].Summary != "Christmas Day, observed" {
+		t.Errorf("got events %+v, want only the unescaped Christmas Day", events)
+	}
+
+	if len(skipped) != 2 {
+		t.Fatalf("got %d skipped events, want 2", len(skipped))
+	}
+	if skipped[1].Summary != "A holiday with a long name" {
+		t.Errorf("got summary %q of a folded line", skipped[1].Summary)
+	}
+	for _, s := range skipped {
+		if !errors.Is(s.Reason, ErrUnsupportedRule) {
+			t.Errorf("event %s skipped for %v, want an unsupported rule", s.UID, s.Reason)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    *Rule
+		wantErr error
+	}{
+		{rule: "FREQ=YEARLY", want: &Rule{Interval: 1}},
+		{rule: "FREQ=YEARLY;INTERVAL=2;COUNT=3", want: &Rule{Interval: 2, Count: 3}},
+		{rule: "FREQ=YEARLY;UNTIL=20301231", want: &Rule{Interval: 1, Until: date("2030-12-31")}},
+		{rule: "FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25", want: &Rule{Interval: 1, Months: []time.Month{12}, MonthDays: []int{25}}},
+		{
+			rule: "freq=yearly;bymonth=11;byday=4th,+1FR,-1SU,MO",
+			want: &Rule{Interval: 1, Months: []time.Month{11}, Weekdays: []RuleWeekday{
+				{Weekday: time.Thursday, N: 4},
+				{Weekday: time.Friday, N: 1},
+				{Weekday: time.Sunday, N: -1},
+				{Weekday: time.Monday},
+			}},
+		},
+		{rule: "FREQ=YEARLY;WKST=MO;BYHOUR=0", want: &Rule{Interval: 1}},
+		{rule: "FREQ=MONTHLY", wantErr: ErrUnsupportedRule},
+		{rule: "FREQ=YEARLY;BYDAY=MO", wantErr: ErrUnsupportedRule},
+		{rule: "FREQ=YEARLY;BYYEARDAY=100", wantErr: ErrUnsupportedRule},
+		{rule: "FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO;BYSETPOS=1", wantErr: ErrUnsupportedRule},
+		{rule: "FREQ=YEARLY;INTERVAL=0", wantErr: ErrMalformed},
+		{rule: "FREQ=YEARLY;BYMONTH=13,5", wantErr: ErrMalformed},
+		{rule: "FREQ=YEARLY;BYMONTHDAY=0", wantErr: ErrMalformed},
+		{rule: "FREQ=YEARLY;BYMONTH=11;BYDAY=6TH", wantErr: ErrMalformed},
+		{rule: "FREQ=YEARLY;BYMONTH=11;BYDAY=XX", wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := parseRule(tt.rule)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Interval != tt.want.Interval || got.Count != tt.want.Count || !got.Until.Equal(tt.want.Until) ||
+				!slices.Equal(got.Months, tt.want.Months) || !slices.Equal(got.MonthDays, tt.want.MonthDays) ||
+				!slices.Equal(got.Weekdays, tt.want.Weekdays) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := map[string]string{
+		"missing start":        calendar("SUMMARY:No start"),
+		"invalid start":        calendar("SUMMARY:Bad", "DTSTART;VALUE=DATE:2024"),
+		"malformed rule":       calendar("SUMMARY:Bad", "DTSTART;VALUE=DATE:20240101", "RRULE:FREQ=YEARLY;COUNT=x"),
+		"line without value":   calendar("SUMMARY"),
+		"unterminated event":   "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20240101\r\n",
+		"invalid excluded day": calendar("SUMMARY:Bad", "DTSTART;VALUE=DATE:20240101", "EXDATE:2024"),
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := Parse(strings.NewReader(input)); !errors.Is(err, ErrMalformed) {
+				t.Errorf("got %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package invoice
+
+import (
+	"embed"
+	"math"
+	"time"
+)
+
+const (
+	// HTMLTemplate is the template file for the HTML rendering of an invoice.
+	HTMLTemplate = "invoice.html.tmpl"
+	// PDFTemplate is the template file for the text layout of the PDF rendering of an invoice.
+	PDFTemplate = "invoice.txt.tmpl"
+)
+
+//go:embed "templates"
+var FS embed.FS
+
+// Invoice is a draft invoice for the work done for a client in a period.
+// Amounts are in Currency and rounded to cents.
+type Invoice struct {
+	Issuer     string     `json:"issuer"`
+	ClientID   int64      `json:"client_id"`
+	ClientName string     `json:"client_name"`
+	From       string     `json:"from"` // First date of the period (YYYY-MM-DD)
+	To         string     `json:"to"`   // Last date of the period (YYYY-MM-DD)
+	Currency   string     `json:"currency"`
+	Lines      []LineItem `json:"lines"`
+	Hours      float64    `json:"hours"`
+	Subtotal   float64    `json:"subtotal"`
+	TaxPercent float64    `json:"tax_percent"`
+	Tax        float64    `json:"tax"`
+	Total      float64    `json:"total"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// LineItem is the work of one user on one day.
+type LineItem struct {
+	UserID   int64   `json:"user_id"`
+	UserName string  `json:"user_name"`
+	Date     string  `json:"date"` // YYYY-MM-DD
+	Hours    float64 `json:"hours"`
+	Rate     float64 `json:"rate"`
+	Amount   float64 `json:"amount"`
+}
+
+// AddLine adds the work of a user on a day, merging it into an existing line for the same user and day.
+func (inv *Invoice) AddLine(userID int64, userName, date string, hours, rate float64) {
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		if line.UserID == userID && line.Date == date && line.Rate == rate {
+			line.Hours += hours
+			line.Amount = roundCents(line.Hours * rate)
+			return
+		}
+	}
+
+	inv.Lines = append(inv.Lines, LineItem{
+		UserID:   userID,
+		UserName: userName,
+		Date:     date,
+		Hours:    hours,
+		Rate:     rate,
+		Amount:   roundCents(hours * rate),
+	})
+}
+
+// Finalize rounds the hours of every line and computes the subtotal, tax and total.
+// Line amounts are recomputed from the rounded hours so that every line adds up as printed.
+func (inv *Invoice) Finalize() {
+	inv.Hours = 0
+	inv.Subtotal = 0
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		line.Hours = roundCents(line.Hours)
+		line.Amount = roundCents(line.Hours * line.Rate)
+		inv.Hours += line.Hours
+		inv.Subtotal += line.Amount
+	}
+
+	inv.Hours = roundCents(inv.Hours)
+	inv.Subtotal = roundCents(inv.Subtotal)
+	inv.Tax = roundCents(inv.Subtotal * inv.TaxPercent / 100)
+	inv.Total = roundCents(inv.Subtotal + inv.Tax)
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,46 @@
+package invoice
+
+import "testing"
+
+func TestFinalizeUsesRoundedHours(t *testing.T) {
+	inv := Invoice{TaxPercent: 25}
+
+	// 7h 59m 42s and 20 minutes, as they come out of the shifts
+	inv.AddLine(1, "Jane Doe", "2024-01-08", 7.995, 100)
+	inv.AddLine(2, "John Doe", "2024-01-08", 1.0/3, 90)
+	inv.Finalize()
+
+	want := []LineItem{
+		{UserID: 1, UserName: "Jane Doe", Date: "2024-01-08", Hours: 8, Rate: 100, Amount: 800},
+		{UserID: 2, UserName: "John Doe", Date: "2024-01-08", Hours: 0.33, Rate: 90, Amount: 29.7},
+	}
+	for i, line := range inv.Lines {
+		if line != want[i] {
+			t.Errorf("line %d: got %+v, want %+v", i, line, want[i])
+		}
+	}
+
+	if inv.Hours != 8.33 || inv.Subtotal != 829.7 || inv.Tax != 207.43 || inv.Total != 1037.13 {
+		t.Errorf("got hours %v, subtotal %v, tax %v, total %v", inv.Hours, inv.Subtotal, inv.Tax, inv.Total)
+	}
+}
+
+func TestAddLineMergesSameDayAndRate(t *testing.T) {
+	inv := Invoice{}
+
+	inv.AddLine(1, "Jane Doe", "2024-01-08", 4, 100)
+	inv.AddLine(1, "Jane Doe", "2024-01-08", 3.5, 100)
+	inv.AddLine(1, "Jane Doe", "2024-01-08", 1, 150)
+	inv.AddLine(1, "Jane Doe", "2024-01-09", 2, 100)
+	inv.Finalize()
+
+	if len(inv.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(inv.Lines))
+	}
+	if inv.Lines[0].Hours != 7.5 || inv.Lines[0].Amount != 750 {
+		t.Errorf("got merged line %+v", inv.Lines[0])
+	}
+	if inv.Subtotal != 1100 {
+		t.Errorf("got subtotal %v, want 1100", inv.Subtotal)
+	}
+}
@@ -0,0 +1,36 @@
+package invoice
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// RenderHTML writes the invoice as an HTML document.
+func RenderHTML(w io.Writer, inv *Invoice) error {
+	tmpl, err := htmltemplate.ParseFS(FS, "templates/"+HTMLTemplate)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, inv)
+}
+
+// RenderPDF writes the invoice as a PDF document. The page text is laid out by the
+// PDF template and set in a monospaced font, so columns line up as in the template.
+func RenderPDF(w io.Writer, inv *Invoice) error {
+	tmpl, err := template.ParseFS(FS, "templates/"+PDFTemplate)
+	if err != nil {
+		return err
+	}
+
+	text := new(bytes.Buffer)
+	if err := tmpl.Execute(text, inv); err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(text.String(), "\n"), "\n")
+	return writePDF(w, lines)
+}
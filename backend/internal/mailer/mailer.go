@@ -11,6 +11,10 @@ const (
 	UserWelcomeTemplate = "user_invitation.tmpl"
 	// PasswordResetTemplate is the template file for the password reset email.
 	PasswordResetTemplate = "password_reset.tmpl"
+	// AutoSignOutTemplate is the template file for the notice that a forgotten shift was closed.
+	AutoSignOutTemplate = "auto_sign_out.tmpl"
+	// AccountLockedTemplate is the template file for the notice that an account was locked after failed logins.
+	AccountLockedTemplate = "account_locked.tmpl"
 )
 
 //go:embed "templates"
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ClosedShift describes a forgotten shift that was closed by AutoSignOut.
+type ClosedShift struct {
+	UserID  int64
+	SignIn  time.Time
+	SignOut time.Time
+	Stamps  []Timestamp // The auto-generated stamps, in order
+}
+
+// GetOpenShifts godoc
+//
+//	@Summary		Retrieves forgotten open shifts
+//	@Description	Retrieves the latest stamp of every user whose shift was opened before the cutoff and is still open
+//	@Tags			timestamps
+//	@Produce		json
+//	@Success		200	{object}	[]Timestamp
+//	@Failure		500	{object}	error
+func (s *TimestampStore) GetOpenShifts(ctx context.Context, openedBefore time.Time) ([]Timestamp, error) {
+	query := `
+		SELECT t.id, t.user_id, t.stamp_type, t.time, t.created_at, t.updated_at, t.version,
+			t.status, t.is_manual, t.reason, t.decided_by, t.decided_at, t.is_auto_generated, t.kiosk_id
+		FROM timestamps t
+		WHERE t.status = 'approved'
+			AND t.stamp_type NOT IN (SELECT name FROM stamp_types WHERE category = 'end')
+			AND t.id = (
+				SELECT l.id
+				FROM timestamps l
+				WHERE l.user_id = t.user_id AND l.status = 'approved'
+				ORDER BY l.time DESC, l.id DESC
+				LIMIT 1
+			)
+			AND (
+				SELECT MAX(si.time)
+				FROM timestamps si
+				WHERE si.user_id = t.user_id AND si.status = 'approved'
+					AND si.stamp_type IN (SELECT name FROM stamp_types WHERE category = 'start')
+			) < ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, openedBefore.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	open := make([]Timestamp, 0)
+	for rows.Next() {
+		var t Timestamp
+		if err := scanTimestamp(rows, &t); err != nil {
+			return nil, err
+		}
+
+		open = append(open, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return open, nil
+}
+
+// AutoSignOut godoc
+//
+//	@Summary		Closes a forgotten shift
+//	@Description	Inserts the auto-generated stamps on the shortest path to an end type, e.g. end-break and sign-out, for a shift open longer than maxShift
+//	@Tags			timestamps
+//	@Produce		json
+//	@Success		200	{object}	ClosedShift
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+func (s *TimestampStore) AutoSignOut(ctx context.Context, userID int64, maxShift time.Duration) (*ClosedShift, error) {
+	var closed *ClosedShift
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		latest, err := getLatestTimestamp(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		graph, err := getStampGraph(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if latest == nil || graph.Category(latest.StampType) == StampCategoryEnd {
+			// The user signed out since the open shifts were listed
+			return nil
+		}
+
+		types := graph.PathToEnd(latest.StampType)
+		if types == nil {
+			return fmt.Errorf("%w: no end type can be reached from %s", ErrInvalidTransition, latest.StampType)
+		}
+
+		signIn, err := getShiftStart(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		// Close the shift at the limit, unless the user stamped after it
+		closeAt := signIn.Add(maxShift)
+		if time.Now().Before(closeAt) {
+			return nil
+		}
+		if latest.StampTime.After(closeAt) {
+			closeAt = latest.StampTime
+		}
+
+		if err := checkPeriodsOpen(ctx, tx, userID, closeAt); err != nil {
+			return err
+		}
+
+		closed = &ClosedShift{UserID: userID, SignIn: signIn, SignOut: closeAt.UTC()}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		for _, stampType := range types {
+			stamp := Timestamp{
+				UserID:          userID,
+				StampType:       stampType,
+				StampTime:       closeAt.UTC(),
+				Status:          TimestampStatusApproved,
+				IsAutoGenerated: true,
+			}
+
+			result, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO timestamps (user_id, stamp_type, time, status, is_auto_generated) VALUES (?, ?, ?, ?, ?)`,
+				stamp.UserID,
+				stamp.StampType,
+				stamp.StampTime,
+				stamp.Status,
+				stamp.IsAutoGenerated,
+			)
+			if err != nil {
+				return err
+			}
+
+			if stamp.ID, err = result.LastInsertId(); err != nil {
+				return err
+			}
+
+			closed.Stamps = append(closed.Stamps, stamp)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return closed, nil
+}
+
+// getShiftStart returns the time of the user's latest approved shift start.
+func getShiftStart(ctx context.Context, q querier, userID int64) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var raw []byte
+	err := q.QueryRowContext(
+		ctx,
+		`SELECT MAX(time) FROM timestamps WHERE user_id = ? AND status = 'approved'
+			AND stamp_type IN (SELECT name FROM stamp_types WHERE category = 'start')`,
+		userID,
+	).Scan(&raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if raw == nil {
+		return time.Time{}, ErrNotFound
+	}
+
+	return parseDBTime(string(raw))
+}
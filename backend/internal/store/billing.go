@@ -0,0 +1,313 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrNoBillingRate is returned when no billing rate is in effect for a user on a date.
+var ErrNoBillingRate = errors.New("no billing rate")
+
+// BillingRate is the hourly rate billed for a user or for every user of a role, effective from a date
+// until the next rate of the same user or role. A user rate overrides the rate of the user's role.
+type BillingRate struct {
+	ID            int64     `json:"id"`
+	UserID        *int64    `json:"user_id"`
+	RoleID        *int64    `json:"role_id"`
+	HourlyRate    float64   `json:"hourly_rate"`
+	Currency      string    `json:"currency"`       // ISO 4217 code, e.g. EUR
+	EffectiveFrom string    `json:"effective_from"` // YYYY-MM-DD
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RateOn returns the rate in effect on date (YYYY-MM-DD), preferring user rates over role rates,
+// or nil if there is none. rates must be ordered by EffectiveFrom, newest first.
+func RateOn(rates []BillingRate, date string) *BillingRate {
+	var roleRate *BillingRate
+	for i := range rates {
+		if rates[i].EffectiveFrom > date {
+			continue
+		}
+		if rates[i].UserID != nil {
+			return &rates[i]
+		}
+		if roleRate == nil {
+			roleRate = &rates[i]
+		}
+	}
+
+	return roleRate
+}
+
+// BillingStore provides methods for managing billing rates and the clients shifts are billed to.
+type BillingStore struct {
+	db *sql.DB
+}
+
+// CreateRate godoc
+//
+//	@Summary		Creates a billing rate
+//	@Description	Inserts a billing rate for a user or a role; there can be one rate per user or role and date
+//	@Tags			billing
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	BillingRate
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/billing-rates [post]
+func (s *BillingStore) CreateRate(ctx context.Context, rate *BillingRate) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var found bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT (? IS NULL OR EXISTS (SELECT 1 FROM users WHERE id = ?))
+			AND (? IS NULL OR EXISTS (SELECT 1 FROM roles WHERE id = ?))`,
+		rate.UserID,
+		rate.UserID,
+		rate.RoleID,
+		rate.RoleID,
+	).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM billing_rates WHERE (user_id = ? OR role_id = ?) AND effective_from = ?`,
+		rate.UserID,
+		rate.RoleID,
+		rate.EffectiveFrom,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO billing_rates (user_id, role_id, hourly_rate, currency, effective_from) VALUES (?, ?, ?, ?, ?)`,
+		rate.UserID,
+		rate.RoleID,
+		rate.HourlyRate,
+		rate.Currency,
+		rate.EffectiveFrom,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rate.ID = id
+	rate.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// GetRates godoc
+//
+//	@Summary		Retrieves the rate history of a user or role
+//	@Description	Retrieves the billing rates of a user, or of a role if userID is 0, newest first
+//	@Tags			billing
+//	@Produce		json
+//	@Success		200	{object}	[]BillingRate
+//	@Failure		500	{object}	error
+//	@Router			/billing-rates [get]
+func (s *BillingStore) GetRates(ctx context.Context, userID, roleID int64) ([]BillingRate, error) {
+	query := `
+		SELECT id, user_id, role_id, hourly_rate, currency, effective_from, created_at
+		FROM billing_rates
+		WHERE user_id = ? OR (? = 0 AND role_id = ?)
+		ORDER BY effective_from DESC
+	`
+
+	return s.queryRates(ctx, query, userID, userID, roleID)
+}
+
+// GetRatesForUser godoc
+//
+//	@Summary		Retrieves the rates that apply to a user
+//	@Description	Retrieves the billing rates of the user and of the user's role, newest first
+//	@Tags			billing
+//	@Produce		json
+//	@Success		200	{object}	[]BillingRate
+//	@Failure		500	{object}	error
+func (s *BillingStore) GetRatesForUser(ctx context.Context, user *User) ([]BillingRate, error) {
+	query := `
+		SELECT id, user_id, role_id, hourly_rate, currency, effective_from, created_at
+		FROM billing_rates
+		WHERE user_id = ? OR role_id = ?
+		ORDER BY effective_from DESC
+	`
+
+	return s.queryRates(ctx, query, user.ID, user.Role.ID)
+}
+
+// AssignShiftClient godoc
+//
+//	@Summary		Assigns a client to a shift
+//	@Description	Bills the shift opened by the sign-in stamp signInID to a client, replacing any earlier assignment
+//	@Tags			billing
+//	@Produce		json
+//	@Success		204	{string}	string	"Client assigned"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/shift-clients/{signInID} [put]
+func (s *BillingStore) AssignShiftClient(ctx context.Context, signInID, clientID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM timestamps WHERE id = ?
+			AND stamp_type IN (SELECT name FROM stamp_types WHERE category = 'start'))
+		AND EXISTS (SELECT 1 FROM clients WHERE id = ?)`,
+		signInID,
+		clientID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`INSERT INTO shift_clients (sign_in_id, client_id) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE client_id = VALUES(client_id)`,
+		signInID,
+		clientID,
+	)
+	return err
+}
+
+// UnassignShiftClient godoc
+//
+//	@Summary		Removes the client of a shift
+//	@Description	Removes the client assignment of the shift opened by the sign-in stamp signInID
+//	@Tags			billing
+//	@Produce		json
+//	@Success		204	{string}	string	"Client removed"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/shift-clients/{signInID} [delete]
+func (s *BillingStore) UnassignShiftClient(ctx context.Context, signInID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM shift_clients WHERE sign_in_id = ?`, signInID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetClientUsers godoc
+//
+//	@Summary		Retrieves the users who worked for a client
+//	@Description	Retrieves the IDs of the users with shifts billed to the client that may overlap [from, to)
+//	@Tags			billing
+//	@Produce		json
+//	@Success		200	{object}	[]int64
+//	@Failure		500	{object}	error
+func (s *BillingStore) GetClientUsers(ctx context.Context, clientID int64, from, to time.Time) ([]int64, error) {
+	// A shift that started the day before the window can still run into it
+	query := `
+		SELECT DISTINCT t.user_id
+		FROM shift_clients sc
+		JOIN timestamps t ON t.id = sc.sign_in_id
+		WHERE sc.client_id = ? AND t.status = 'approved' AND t.time >= ? AND t.time < ?
+		ORDER BY t.user_id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, clientID, from.AddDate(0, 0, -1).UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// queryRates runs a query selecting the billing_rates column list.
+func (s *BillingStore) queryRates(ctx context.Context, query string, args ...any) ([]BillingRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make([]BillingRate, 0)
+	for rows.Next() {
+		var r BillingRate
+		var userID, roleID sql.NullInt64
+		var effectiveFrom, createdAt []byte
+
+		if err := rows.Scan(&r.ID, &userID, &roleID, &r.HourlyRate, &r.Currency, &effectiveFrom, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if userID.Valid {
+			r.UserID = &userID.Int64
+		}
+		if roleID.Valid {
+			r.RoleID = &roleID.Int64
+		}
+		r.EffectiveFrom = string(effectiveFrom)
+
+		if r.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+			return nil, err
+		}
+
+		rates = append(rates, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/store"
+	"github.com/go-redis/redis/v8"
+)
+
+type IdempotencyStore struct {
+	rdb *redis.Client
+}
+
+func idempotencyCacheKey(userID int64, key string) string {
+	return fmt.Sprintf("idempotency-%d-%s", userID, key)
+}
+
+func (s *IdempotencyStore) Get(ctx context.Context, userID int64, key string) (*store.IdempotencyRecord, error) {
+	data, err := s.rdb.Get(ctx, idempotencyCacheKey(userID, key)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var record store.IdempotencyRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (s *IdempotencyStore) Reserve(ctx context.Context, record *store.IdempotencyRecord) (bool, error) {
+	json, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+
+	return s.rdb.SetNX(ctx, idempotencyCacheKey(record.UserID, record.Key), json, time.Until(record.ExpiresAt)).Result()
+}
+
+func (s *IdempotencyStore) Save(ctx context.Context, record *store.IdempotencyRecord) error {
+	json, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.SetEX(ctx, idempotencyCacheKey(record.UserID, record.Key), json, time.Until(record.ExpiresAt)).Err()
+}
+
+func (s *IdempotencyStore) Delete(ctx context.Context, userID int64, key string) error {
+	return s.rdb.Del(ctx, idempotencyCacheKey(userID, key)).Err()
+}
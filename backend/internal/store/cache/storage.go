@@ -13,10 +13,17 @@ type Storage struct {
 		Set(context.Context, *store.User) error
 		Delete(context.Context, int64)
 	}
+	Idempotency interface {
+		Get(context.Context, int64, string) (*store.IdempotencyRecord, error)
+		Reserve(context.Context, *store.IdempotencyRecord) (bool, error)
+		Save(context.Context, *store.IdempotencyRecord) error
+		Delete(context.Context, int64, string) error
+	}
 }
 
 func NewRedisStorage(rbd *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rdb: rbd},
+		Users:       &UserStore{rdb: rbd},
+		Idempotency: &IdempotencyStore{rdb: rbd},
 	}
 }
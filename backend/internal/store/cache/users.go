@@ -16,6 +16,12 @@ type UserStore struct {
 
 const UserExpTime = time.Minute
 
+// cachedUser keeps the token version, which is hidden from API responses, in the cache.
+type cachedUser struct {
+	*store.User
+	TokenVersion int `json:"token_version"`
+}
+
 func (s *UserStore) Get(ctx context.Context, userID int64) (*store.User, error) {
 	cacheKey := fmt.Sprintf("user-%d", userID)
 
@@ -26,21 +32,22 @@ func (s *UserStore) Get(ctx context.Context, userID int64) (*store.User, error)
 		return nil, err
 	}
 
-	var user store.User
+	user := cachedUser{User: &store.User{}}
 	if data != "" {
 		err := json.Unmarshal([]byte(data), &user)
 		if err != nil {
 			return nil, err
 		}
 	}
+	user.User.TokenVersion = user.TokenVersion
 
-	return &user, nil
+	return user.User, nil
 }
 
 func (s *UserStore) Set(ctx context.Context, user *store.User) error {
 	cacheKey := fmt.Sprintf("user-%d", user.ID)
 
-	json, err := json.Marshal(user)
+	json, err := json.Marshal(cachedUser{User: user, TokenVersion: user.TokenVersion})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// ComplianceViolation describes a shift that breaks a working-time rule.
+// Actual and Limit are in minutes.
+type ComplianceViolation struct {
+	Rule     string  `json:"rule"`
+	SignInID int64   `json:"sign_in_id"` // The shift the violation is attached to
+	Date     string  `json:"date"`       // Local date (YYYY-MM-DD) the violation happened on
+	Actual   float64 `json:"actual"`
+	Limit    float64 `json:"limit"`
+	Message  string  `json:"message"`
+}
+
+// ComplianceRule checks localized shifts in chronological order and returns the violations it finds.
+// Rules see the whole sequence, so they can look at single shifts, gaps between shifts or whole days.
+type ComplianceRule interface {
+	Name() string
+	Check(shifts []Shift) []ComplianceViolation
+}
+
+// ComplianceConfig configures the built-in rules. A zero duration disables the rule.
+type ComplianceConfig struct {
+	MinBreakAfter time.Duration // Net work after which a break is required
+	MinBreak      time.Duration // Minimum total break once MinBreakAfter is exceeded
+	MinRest       time.Duration // Minimum rest between the end of one shift and the start of the next
+	MaxDailyWork  time.Duration // Maximum net work per local day
+}
+
+// NewComplianceRules returns the built-in rules enabled in cfg.
+func NewComplianceRules(cfg ComplianceConfig) []ComplianceRule {
+	rules := make([]ComplianceRule, 0, 3)
+
+	if cfg.MinBreakAfter > 0 && cfg.MinBreak > 0 {
+		rules = append(rules, MinBreakRule{After: cfg.MinBreakAfter, MinBreak: cfg.MinBreak})
+	}
+	if cfg.MinRest > 0 {
+		rules = append(rules, MinRestRule{MinRest: cfg.MinRest})
+	}
+	if cfg.MaxDailyWork > 0 {
+		rules = append(rules, MaxDailyWorkRule{Max: cfg.MaxDailyWork})
+	}
+
+	return rules
+}
+
+// CheckCompliance runs rules over shifts, attaches each violation to the shift it names
+// and returns all violations in rule order.
+func CheckCompliance(rules []ComplianceRule, shifts []Shift) []ComplianceViolation {
+	bySignIn := make(map[int64]*Shift, len(shifts))
+	for i := range shifts {
+		shifts[i].Violations = nil
+		bySignIn[shifts[i].SignInID] = &shifts[i]
+	}
+
+	violations := make([]ComplianceViolation, 0)
+	for _, rule := range rules {
+		for _, v := range rule.Check(shifts) {
+			if shift, ok := bySignIn[v.SignInID]; ok {
+				shift.Violations = append(shift.Violations, v)
+			}
+			violations = append(violations, v)
+		}
+	}
+
+	return violations
+}
+
+// MinBreakRule requires a minimum amount of break in shifts with more net work than After.
+type MinBreakRule struct {
+	After    time.Duration
+	MinBreak time.Duration
+}
+
+func (r MinBreakRule) Name() string { return "min-break" }
+
+func (r MinBreakRule) Check(shifts []Shift) []ComplianceViolation {
+	violations := make([]ComplianceViolation, 0)
+
+	for _, shift := range shifts {
+		work := time.Duration(shift.NetWorkTime * float64(time.Second))
+		breaks := time.Duration(shift.TotalBreakTime * float64(time.Second))
+
+		if work > r.After && breaks < r.MinBreak {
+			violations = append(violations, ComplianceViolation{
+				Rule:     r.Name(),
+				SignInID: shift.SignInID,
+				Date:     shift.Workday,
+				Actual:   breaks.Minutes(),
+				Limit:    r.MinBreak.Minutes(),
+				Message:  fmt.Sprintf("%.0f minutes of break after %.1f hours of work, %.0f required", breaks.Minutes(), work.Hours(), r.MinBreak.Minutes()),
+			})
+		}
+	}
+
+	return violations
+}
+
+// MinRestRule requires a minimum rest between consecutive shifts. The violation is attached to the later shift.
+type MinRestRule struct {
+	MinRest time.Duration
+}
+
+func (r MinRestRule) Name() string { return "min-rest" }
+
+func (r MinRestRule) Check(shifts []Shift) []ComplianceViolation {
+	violations := make([]ComplianceViolation, 0)
+
+	for i := 1; i < len(shifts); i++ {
+		rest := shifts[i].SignIn.Sub(shifts[i-1].SignOut)
+
+		if rest < r.MinRest {
+			violations = append(violations, ComplianceViolation{
+				Rule:     r.Name(),
+				SignInID: shifts[i].SignInID,
+				Date:     shifts[i].Workday,
+				Actual:   rest.Minutes(),
+				Limit:    r.MinRest.Minutes(),
+				Message:  fmt.Sprintf("%.1f hours of rest before the shift, %.1f required", rest.Hours(), r.MinRest.Hours()),
+			})
+		}
+	}
+
+	return violations
+}
+
+// MaxDailyWorkRule limits the net work per local day across all shifts. The violation is attached
+// to the shift that crossed the limit.
+type MaxDailyWorkRule struct {
+	Max time.Duration
+}
+
+func (r MaxDailyWorkRule) Name() string { return "max-daily-work" }
+
+func (r MaxDailyWorkRule) Check(shifts []Shift) []ComplianceViolation {
+	violations := make([]ComplianceViolation, 0)
+
+	worked := make(map[string]time.Duration)
+	reported := make(map[string]bool)
+
+	for _, shift := range shifts {
+		for _, day := range shift.Days {
+			worked[day.Date] += time.Duration(day.NetWorkTime * float64(time.Second))
+
+			if worked[day.Date] > r.Max && !reported[day.Date] {
+				reported[day.Date] = true
+				violations = append(violations, ComplianceViolation{
+					Rule:     r.Name(),
+					SignInID: shift.SignInID,
+					Date:     day.Date,
+					Limit:    r.Max.Minutes(),
+				})
+			}
+		}
+	}
+
+	// Report the day's full total, which is only known after all shifts were seen
+	for i := range violations {
+		total := worked[violations[i].Date]
+		violations[i].Actual = total.Minutes()
+		violations[i].Message = fmt.Sprintf("%.1f hours of work on %s, at most %.1f allowed", total.Hours(), violations[i].Date, r.Max.Hours())
+	}
+
+	return violations
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckCompliance(t *testing.T) {
+	cfg := ComplianceConfig{
+		MinBreakAfter: 6 * time.Hour,
+		MinBreak:      30 * time.Minute,
+		MinRest:       11 * time.Hour,
+		MaxDailyWork:  10 * time.Hour,
+	}
+
+	type shift struct {
+		start     string
+		length    time.Duration
+		breakAt   time.Duration // Offset of the break from the start of the shift
+		breakTime time.Duration
+	}
+
+	// Only the rule, shift, date and amounts are compared; shifts are numbered from 1
+	type violation struct {
+		rule     string
+		signInID int64
+		date     string
+		actual   float64
+		limit    float64
+	}
+
+	// 2024-01-08 is a Monday
+	tests := []struct {
+		name   string
+		cfg    *ComplianceConfig // Defaults to cfg
+		shifts []shift
+		want   []violation
+	}{
+		{
+			name:   "compliant day",
+			shifts: []shift{{"2024-01-08 08:00", 9 * time.Hour, 4 * time.Hour, 30 * time.Minute}},
+		},
+		{
+			name:   "six hours need no break",
+			shifts: []shift{{"2024-01-08 08:00", 6 * time.Hour, 0, 0}},
+		},
+		{
+			name:   "no break after six hours",
+			shifts: []shift{{"2024-01-08 08:00", 7 * time.Hour, 0, 0}},
+			want:   []violation{{"min-break", 1, "2024-01-08", 0, 30}},
+		},
+		{
+			name:   "break too short",
+			shifts: []shift{{"2024-01-08 08:00", 7 * time.Hour, 3 * time.Hour, 15 * time.Minute}},
+			want:   []violation{{"min-break", 1, "2024-01-08", 15, 30}},
+		},
+		{
+			name:   "missing break on a shift crossing midnight is reported on its workday",
+			shifts: []shift{{"2024-01-08 22:00", 7 * time.Hour, 0, 0}},
+			want:   []violation{{"min-break", 1, "2024-01-08", 0, 30}},
+		},
+		{
+			name: "eleven hours of rest",
+			shifts: []shift{
+				{"2024-01-08 16:00", 6 * time.Hour, 0, 0},
+				{"2024-01-09 09:00", 6 * time.Hour, 0, 0},
+			},
+		},
+		{
+			name: "short rest is reported on the later shift",
+			shifts: []shift{
+				{"2024-01-08 16:00", 6 * time.Hour, 0, 0},
+				{"2024-01-09 06:00", 6 * time.Hour, 0, 0},
+			},
+			want: []violation{{"min-rest", 2, "2024-01-09", 8 * 60, 11 * 60}},
+		},
+		{
+			name: "rest after a shift crossing midnight",
+			shifts: []shift{
+				{"2024-01-08 20:00", 6 * time.Hour, 0, 0},
+				{"2024-01-09 12:00", 6 * time.Hour, 0, 0},
+			},
+			want: []violation{{"min-rest", 2, "2024-01-09", 10 * 60, 11 * 60}},
+		},
+		{
+			name: "rest across a month boundary",
+			shifts: []shift{
+				{"2024-01-31 16:00", 6 * time.Hour, 0, 0},
+				{"2024-02-01 06:00", 6 * time.Hour, 0, 0},
+			},
+			want: []violation{{"min-rest", 2, "2024-02-01", 8 * 60, 11 * 60}},
+		},
+		{
+			name: "rest across a year boundary",
+			shifts: []shift{
+				{"2023-12-31 18:00", 6 * time.Hour, 0, 0},
+				{"2024-01-01 08:00", 6 * time.Hour, 0, 0},
+			},
+			want: []violation{{"min-rest", 2, "2024-01-01", 8 * 60, 11 * 60}},
+		},
+		{
+			name:   "long day",
+			shifts: []shift{{"2024-01-08 07:00", 11 * time.Hour, 5 * time.Hour, 30 * time.Minute}},
+			want:   []violation{{"max-daily-work", 1, "2024-01-08", 630, 600}},
+		},
+		{
+			name: "second shift of a day crosses the daily max",
+			shifts: []shift{
+				{"2024-01-08 06:00", 6 * time.Hour, 0, 0},
+				{"2024-01-08 14:00", 5 * time.Hour, 0, 0},
+			},
+			want: []violation{
+				{"min-rest", 2, "2024-01-08", 2 * 60, 11 * 60},
+				{"max-daily-work", 2, "2024-01-08", 11 * 60, 600},
+			},
+		},
+		{
+			name:   "night shift split at midnight stays below the daily max",
+			shifts: []shift{{"2024-01-08 18:00", 12 * time.Hour, 4 * time.Hour, time.Hour}},
+		},
+		{
+			name: "night shift counts towards the next day",
+			shifts: []shift{
+				{"2024-01-08 20:00", 8 * time.Hour, 3 * time.Hour, 30 * time.Minute},
+				{"2024-01-09 15:00", 7 * time.Hour, 3 * time.Hour, 30 * time.Minute},
+			},
+			// 4 hours after midnight and 6.5 in the afternoon
+			want: []violation{{"max-daily-work", 2, "2024-01-09", 630, 600}},
+		},
+		{
+			name: "zero durations disable the rules",
+			cfg:  &ComplianceConfig{},
+			shifts: []shift{
+				{"2024-01-08 06:00", 12 * time.Hour, 0, 0},
+				{"2024-01-08 20:00", 4 * time.Hour, 0, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			if tt.cfg != nil {
+				c = *tt.cfg
+			}
+
+			shifts := make([]Shift, 0, len(tt.shifts))
+			for i, s := range tt.shifts {
+				shift := testShift(t, s.start, s.length)
+				shift.SignInID = int64(i + 1)
+				if s.breakTime > 0 {
+					start := shift.SignIn.Add(s.breakAt)
+					shift.Breaks = [][]time.Time{{start, start.Add(s.breakTime)}}
+					shift.TotalBreakTime = s.breakTime.Seconds()
+					shift.NetWorkTime = (s.length - s.breakTime).Seconds()
+					shift.localize(time.UTC)
+				}
+				shifts = append(shifts, shift)
+			}
+
+			violations := CheckCompliance(NewComplianceRules(c), shifts)
+
+			if len(violations) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", violations, tt.want)
+			}
+			for i, v := range violations {
+				got := violation{v.Rule, v.SignInID, v.Date, v.Actual, v.Limit}
+				if got != tt.want[i] {
+					t.Errorf("violation %d: got %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+
+			// Every violation is attached to the shift it names
+			attached := 0
+			for _, shift := range shifts {
+				for _, v := range shift.Violations {
+					if v.SignInID != shift.SignInID {
+						t.Errorf("violation %+v is attached to shift %d", v, shift.SignInID)
+					}
+					attached++
+				}
+			}
+			if attached != len(violations) {
+				t.Errorf("got %d attached violations, want %d", attached, len(violations))
+			}
+		})
+	}
+}
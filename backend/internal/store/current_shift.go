@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// States a user can be in with respect to their current shift.
+const (
+	ShiftStateOff     = "off"
+	ShiftStateWorking = "working"
+	ShiftStateOnBreak = "on-break"
+)
+
+// CurrentShift represents the open shift of a user, if any, with running totals up to AsOf.
+type CurrentShift struct {
+	Open             bool          `json:"open"`
+	State            string        `json:"state"`
+	SignIn           *time.Time    `json:"sign_in"`
+	BreakStart       *time.Time    `json:"break_start"`
+	Breaks           [][]time.Time `json:"breaks"` // Unpaid breaks
+	WorkSeconds      float64       `json:"work_seconds"`
+	BreakSeconds     float64       `json:"break_seconds"`      // Unpaid break time, deducted from work
+	PaidBreakSeconds float64       `json:"paid_break_seconds"` // Paid break time, included in work
+	LastStamp        *Timestamp    `json:"last_stamp"`
+	AllowedNext      []string      `json:"allowed_next"`
+	AsOf             time.Time     `json:"as_of"`
+}
+
+// In renders every time of the current shift in loc.
+func (c *CurrentShift) In(loc *time.Location) {
+	c.AsOf = c.AsOf.In(loc)
+	if c.SignIn != nil {
+		signIn := c.SignIn.In(loc)
+		c.SignIn = &signIn
+	}
+	if c.BreakStart != nil {
+		breakStart := c.BreakStart.In(loc)
+		c.BreakStart = &breakStart
+	}
+	for _, b := range c.Breaks {
+		for i := range b {
+			b[i] = b[i].In(loc)
+		}
+	}
+	if c.LastStamp != nil {
+		c.LastStamp.In(loc)
+	}
+}
+
+// GetCurrentShift godoc
+//
+//	@Summary		Retrieves the current shift
+//	@Description	Retrieves the open shift of a user with elapsed work and break time so far
+//	@Tags			shifts
+//	@Produce		json
+//	@Param			userID	query		int	true	"User ID"
+//	@Success		200		{object}	CurrentShift
+//	@Failure		500		{object}	error
+//	@Router			/shifts/current [get]
+func (s *TimestampStore) GetCurrentShift(ctx context.Context, userID int64) (*CurrentShift, error) {
+	graph, err := getStampGraph(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the stamps since the latest shift start can belong to an open shift
+	query := `
+		SELECT id, user_id, stamp_type, time, created_at, updated_at, version,
+			status, is_manual, reason, decided_by, decided_at, is_auto_generated, kiosk_id
+		FROM timestamps
+		WHERE user_id = ? AND status = 'approved' AND time >= (
+			SELECT MAX(time)
+			FROM timestamps
+			WHERE user_id = ? AND status = 'approved' AND stamp_type IN (
+				SELECT name FROM stamp_types WHERE category = 'start'
+			)
+		)
+		ORDER BY time ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stamps []Timestamp
+	for rows.Next() {
+		var t Timestamp
+		if err := scanTimestamp(rows, &t); err != nil {
+			return nil, err
+		}
+
+		stamps = append(stamps, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buildCurrentShift(graph, stamps, time.Now()), nil
+}
+
+// buildCurrentShift replays the stamps since the latest shift start and computes the running totals at now.
+// The category of each stamp in graph decides how the time until the next stamp is counted.
+func buildCurrentShift(graph *StampGraph, stamps []Timestamp, now time.Time) *CurrentShift {
+	current := &CurrentShift{
+		State:  ShiftStateOff,
+		Breaks: make([][]time.Time, 0),
+		AsOf:   now,
+	}
+
+	// endBreak closes the running break at end
+	var breakCategory string
+	endBreak := func(end time.Time) {
+		if current.BreakStart == nil {
+			return
+		}
+
+		seconds := end.Sub(*current.BreakStart).Seconds()
+		if breakCategory == StampCategoryUnpaidBreak {
+			current.Breaks = append(current.Breaks, []time.Time{*current.BreakStart, end})
+			current.BreakSeconds += seconds
+		} else {
+			current.PaidBreakSeconds += seconds
+		}
+		current.BreakStart = nil
+	}
+
+	for i := range stamps {
+		stamp := stamps[i]
+		current.LastStamp = &stamp
+
+		category := graph.Category(stamp.StampType)
+		if category == StampCategoryStart {
+			current.Open = true
+			current.State = ShiftStateWorking
+			current.SignIn = &stamp.StampTime
+			continue
+		}
+		if !current.Open {
+			continue
+		}
+
+		endBreak(stamp.StampTime)
+
+		switch category {
+		case StampCategoryWork:
+			current.State = ShiftStateWorking
+
+		case StampCategoryPaidBreak, StampCategoryUnpaidBreak:
+			current.State = ShiftStateOnBreak
+			current.BreakStart = &stamp.StampTime
+			breakCategory = category
+
+		case StampCategoryEnd:
+			// The latest shift is already finished
+			current.Open = false
+			current.State = ShiftStateOff
+			current.SignIn = nil
+			current.Breaks = make([][]time.Time, 0)
+			current.BreakSeconds = 0
+			current.PaidBreakSeconds = 0
+		}
+	}
+
+	if current.Open {
+		// Include the break that is still running
+		if current.BreakStart != nil {
+			seconds := now.Sub(*current.BreakStart).Seconds()
+			if breakCategory == StampCategoryUnpaidBreak {
+				current.BreakSeconds += seconds
+			} else {
+				current.PaidBreakSeconds += seconds
+			}
+		}
+
+		current.WorkSeconds = now.Sub(*current.SignIn).Seconds() - current.BreakSeconds
+	}
+
+	previousType := ""
+	if current.LastStamp != nil {
+		previousType = current.LastStamp.StampType
+	}
+	current.AllowedNext = graph.Next(previousType)
+
+	return current
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuildCurrentShift(t *testing.T) {
+	graph := NewStampGraph(
+		append(slices.Clone(testStampGraph().Types), StampType{Name: "coffee", Category: StampCategoryPaidBreak}),
+		append(slices.Clone(testStampGraph().Transitions),
+			StampTransition{From: "sign-in", To: "coffee"},
+			StampTransition{From: "coffee", To: "end-break"},
+		),
+	)
+
+	type stamp struct {
+		stampType string
+		at        string // 2006-01-02 15:04 UTC
+	}
+
+	tests := []struct {
+		name        string
+		stamps      []stamp
+		now         string
+		state       string
+		work        time.Duration
+		breakTime   time.Duration
+		paidBreak   time.Duration
+		breaks      int
+		onBreak     bool
+		allowedNext []string
+	}{
+		{
+			name:        "no stamps",
+			now:         "2024-01-08 12:00",
+			state:       ShiftStateOff,
+			allowedNext: []string{"sign-in"},
+		},
+		{
+			name:        "working",
+			stamps:      []stamp{{"sign-in", "2024-01-08 08:00"}},
+			now:         "2024-01-08 12:00",
+			state:       ShiftStateWorking,
+			work:        4 * time.Hour,
+			allowedNext: []string{"coffee", "sign-out", "start-break"},
+		},
+		{
+			name:        "on an unpaid break",
+			stamps:      []stamp{{"sign-in", "2024-01-08 08:00"}, {"start-break", "2024-01-08 11:30"}},
+			now:         "2024-01-08 12:00",
+			state:       ShiftStateOnBreak,
+			work:        3*time.Hour + 30*time.Minute,
+			breakTime:   30 * time.Minute,
+			onBreak:     true,
+			allowedNext: []string{"end-break"},
+		},
+		{
+			name: "back from an unpaid break",
+			stamps: []stamp{
+				{"sign-in", "2024-01-08 08:00"},
+				{"start-break", "2024-01-08 10:00"},
+				{"end-break", "2024-01-08 10:15"},
+			},
+			now:         "2024-01-08 12:00",
+			state:       ShiftStateWorking,
+			work:        3*time.Hour + 45*time.Minute,
+			breakTime:   15 * time.Minute,
+			breaks:      1,
+			allowedNext: []string{"sign-out", "start-break"},
+		},
+		{
+			name:        "on a paid break",
+			stamps:      []stamp{{"sign-in", "2024-01-08 08:00"}, {"coffee", "2024-01-08 11:50"}},
+			now:         "2024-01-08 12:00",
+			state:       ShiftStateOnBreak,
+			work:        4 * time.Hour,
+			paidBreak:   10 * time.Minute,
+			onBreak:     true,
+			allowedNext: []string{"end-break"},
+		},
+		{
+			name: "back from a paid break",
+			stamps: []stamp{
+				{"sign-in", "2024-01-08 08:00"},
+				{"coffee", "2024-01-08 10:00"},
+				{"end-break", "2024-01-08 10:15"},
+			},
+			now:         "2024-01-08 12:00",
+			state:       ShiftStateWorking,
+			work:        4 * time.Hour,
+			paidBreak:   15 * time.Minute,
+			allowedNext: []string{"sign-out", "start-break"},
+		},
+		{
+			name: "finished shift",
+			stamps: []stamp{
+				{"sign-in", "2024-01-08 08:00"},
+				{"start-break", "2024-01-08 10:00"},
+				{"end-break", "2024-01-08 10:15"},
+				{"sign-out", "2024-01-08 16:00"},
+			},
+			now:         "2024-01-08 18:00",
+			state:       ShiftStateOff,
+			allowedNext: []string{"sign-in"},
+		},
+		{
+			name:        "stamps before the start are ignored",
+			stamps:      []stamp{{"end-break", "2024-01-08 07:00"}, {"sign-in", "2024-01-08 08:00"}},
+			now:         "2024-01-08 09:00",
+			state:       ShiftStateWorking,
+			work:        time.Hour,
+			allowedNext: []string{"coffee", "sign-out", "start-break"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stamps := make([]Timestamp, len(tt.stamps))
+			for i, s := range tt.stamps {
+				stamps[i] = testStamp(t, int64(i+1), s.stampType, s.at, time.UTC)
+			}
+			now, err := time.Parse("2006-01-02 15:04", tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			current := buildCurrentShift(graph, stamps, now)
+
+			if current.State != tt.state || current.Open != (tt.state != ShiftStateOff) {
+				t.Errorf("got state %s, open %v, want %s", current.State, current.Open, tt.state)
+			}
+			if current.WorkSeconds != tt.work.Seconds() || current.BreakSeconds != tt.breakTime.Seconds() || current.PaidBreakSeconds != tt.paidBreak.Seconds() {
+				t.Errorf("got work %v, break %v, paid break %v seconds, want %v, %v, %v",
+					current.WorkSeconds, current.BreakSeconds, current.PaidBreakSeconds, tt.work, tt.breakTime, tt.paidBreak)
+			}
+			if len(current.Breaks) != tt.breaks || (current.BreakStart != nil) != tt.onBreak {
+				t.Errorf("got %d finished breaks and break start %v", len(current.Breaks), current.BreakStart)
+			}
+			if !slices.Equal(current.AllowedNext, tt.allowedNext) {
+				t.Errorf("got allowed next %v, want %v", current.AllowedNext, tt.allowedNext)
+			}
+
+			if len(stamps) > 0 && current.LastStamp.ID != int64(len(stamps)) {
+				t.Errorf("got last stamp %d, want %d", current.LastStamp.ID, len(stamps))
+			}
+			if current.Open && !current.SignIn.Equal(stamps[slices.IndexFunc(stamps, func(s Timestamp) bool { return s.StampType == "sign-in" })].StampTime) {
+				t.Errorf("got sign-in %v", current.SignIn)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdmFjalar/CS301.3-Time-Tracker/internal/db"
+)
+
+// newTestDB connects to the database in TEST_DB_ADDR, which has to hold the schema of
+// Create-database-and-tables.sql. Tests needing a database are skipped without it.
+func newTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		tb.Skip("TEST_DB_ADDR is not set")
+	}
+
+	conn, err := db.New(addr, 30, 30, "15m")
+	if err != nil {
+		tb.Fatalf("connecting to the test database: %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// createTestUser inserts an active user and deletes it together with its stamps after the test.
+func createTestUser(tb testing.TB, conn *sql.DB) int64 {
+	tb.Helper()
+
+	ctx := context.Background()
+
+	if _, err := conn.ExecContext(ctx, `INSERT IGNORE INTO roles (name, level, description) VALUES ('user', 1, 'Employee')`); err != nil {
+		tb.Fatalf("creating role: %v", err)
+	}
+
+	res, err := conn.ExecContext(
+		ctx,
+		`INSERT INTO users (email, passhash, is_active, role_id) SELECT ?, '', 1, MIN(id) FROM roles`,
+		fmt.Sprintf("test-%d@example.com", time.Now().UnixNano()),
+	)
+	if err != nil {
+		tb.Fatalf("creating user: %v", err)
+	}
+
+	userID, err := res.LastInsertId()
+	if err != nil {
+		tb.Fatalf("creating user: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		conn.ExecContext(ctx, `DELETE FROM timestamps WHERE user_id = ?`, userID)
+		conn.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, userID)
+	})
+
+	return userID
+}
+
+// insertTestStamps inserts approved stamps of a user as they are, without validating transitions.
+func insertTestStamps(tb testing.TB, conn *sql.DB, userID int64, stamps []Timestamp) {
+	tb.Helper()
+
+	// Rows are inserted in batches to keep the statements below max_allowed_packet
+	const batchSize = 1000
+
+	for len(stamps) > 0 {
+		batch := stamps[:min(batchSize, len(stamps))]
+		stamps = stamps[len(batch):]
+
+		values := make([]string, 0, len(batch))
+		args := make([]any, 0, 3*len(batch))
+		for _, stamp := range batch {
+			values = append(values, "(?, ?, ?, 'approved')")
+			args = append(args, userID, stamp.StampType, stamp.StampTime)
+		}
+
+		_, err := conn.Exec(`INSERT INTO timestamps (user_id, stamp_type, time, status) VALUES `+strings.Join(values, ", "), args...)
+		if err != nil {
+			tb.Fatalf("inserting stamps: %v", err)
+		}
+	}
+}
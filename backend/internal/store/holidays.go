@@ -0,0 +1,494 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrDefaultCalendar = errors.New("the default holiday calendar cannot be deleted")
+
+// Kinds of holidays. Both are days off; closures are days the company itself is closed.
+const (
+	HolidayKindPublic  = "public"
+	HolidayKindClosure = "closure"
+)
+
+// HolidayCalendar is a set of holidays, typically those of one region. Users follow the calendar
+// assigned to them, then that of their site, then the default calendar.
+type HolidayCalendar struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Region    string    `json:"region"` // e.g. "SE" or "US-CA"
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Holiday is a public holiday or company closure on a calendar date.
+type Holiday struct {
+	ID         int64  `json:"id"`
+	CalendarID int64  `json:"calendar_id"`
+	Date       string `json:"date"` // YYYY-MM-DD
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+}
+
+// HolidayStore provides methods for managing holiday calendars and their holidays in the database.
+type HolidayStore struct {
+	db *sql.DB
+}
+
+// calendarOfUser selects the ID of the holiday calendar a user follows.
+const calendarOfUser = `
+	SELECT COALESCE(
+		u.holiday_calendar_id,
+		s.holiday_calendar_id,
+		(SELECT id FROM holiday_calendars WHERE is_default = 1 LIMIT 1)
+	)
+	FROM users u
+	LEFT JOIN sites s ON s.id = u.site_id
+	WHERE u.id = ?
+`
+
+// GetCalendars godoc
+//
+//	@Summary		Retrieves all holiday calendars
+//	@Description	Retrieves all holiday calendars ordered by name
+//	@Tags			holidays
+//	@Produce		json
+//	@Success		200	{object}	[]HolidayCalendar
+//	@Failure		500	{object}	error
+//	@Router			/holiday-calendars [get]
+func (s *HolidayStore) GetCalendars(ctx context.Context) ([]HolidayCalendar, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, region, is_default, created_at FROM holiday_calendars ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calendars := make([]HolidayCalendar, 0)
+	for rows.Next() {
+		var c HolidayCalendar
+		if err := scanHolidayCalendar(rows, &c); err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return calendars, nil
+}
+
+// CreateCalendar godoc
+//
+//	@Summary		Creates a holiday calendar
+//	@Description	Inserts a holiday calendar; calendar names are unique. A new default calendar replaces the previous one.
+//	@Tags			holidays
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	HolidayCalendar
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/holiday-calendars [post]
+func (s *HolidayStore) CreateCalendar(ctx context.Context, calendar *HolidayCalendar) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var exists int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM holiday_calendars WHERE name = ?`, calendar.Name).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrConflict
+		}
+
+		if calendar.IsDefault {
+			if _, err := tx.ExecContext(ctx, `UPDATE holiday_calendars SET is_default = 0 WHERE is_default = 1`); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO holiday_calendars (name, region, is_default) VALUES (?, ?, ?)`,
+			calendar.Name,
+			calendar.Region,
+			calendar.IsDefault,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		calendar.ID = id
+		calendar.CreatedAt = time.Now().UTC()
+
+		return nil
+	})
+}
+
+// DeleteCalendar godoc
+//
+//	@Summary		Deletes a holiday calendar
+//	@Description	Deletes a holiday calendar and its holidays. Users and sites that followed it fall back to the next calendar in line. The default calendar cannot be deleted.
+//	@Tags			holidays
+//	@Param			id	path		int		true	"Calendar ID"
+//	@Success		204	{string}	string	"Calendar deleted"
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/holiday-calendars/{id} [delete]
+func (s *HolidayStore) DeleteCalendar(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var isDefault bool
+	err := s.db.QueryRowContext(ctx, `SELECT is_default FROM holiday_calendars WHERE id = ?`, id).Scan(&isDefault)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+	if isDefault {
+		return ErrDefaultCalendar
+	}
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM holiday_calendars WHERE id = ?`, id)
+	return err
+}
+
+// GetForUser godoc
+//
+//	@Summary		Retrieves the holidays of a user
+//	@Description	Retrieves the holidays between two dates (inclusive) of the calendar the user follows
+//	@Tags			holidays
+//	@Produce		json
+//	@Success		200	{object}	[]Holiday
+//	@Failure		500	{object}	error
+//	@Router			/holidays [get]
+func (s *HolidayStore) GetForUser(ctx context.Context, userID int64, from, to string) ([]Holiday, error) {
+	query := `
+		SELECT id, calendar_id, date, name, kind
+		FROM public_holidays
+		WHERE calendar_id = (` + calendarOfUser + `) AND date BETWEEN ? AND ?
+		ORDER BY date ASC
+	`
+
+	return s.query(ctx, query, userID, from, to)
+}
+
+// GetBetween godoc
+//
+//	@Summary		Retrieves the holidays of a calendar
+//	@Description	Retrieves the holidays of a calendar between two dates (inclusive)
+//	@Tags			holidays
+//	@Produce		json
+//	@Success		200	{object}	[]Holiday
+//	@Failure		500	{object}	error
+//	@Router			/holidays [get]
+func (s *HolidayStore) GetBetween(ctx context.Context, calendarID int64, from, to string) ([]Holiday, error) {
+	query := `
+		SELECT id, calendar_id, date, name, kind
+		FROM public_holidays
+		WHERE calendar_id = ? AND date BETWEEN ? AND ?
+		ORDER BY date ASC
+	`
+
+	return s.query(ctx, query, calendarID, from, to)
+}
+
+// query runs a query selecting holidays.
+func (s *HolidayStore) query(ctx context.Context, query string, args ...any) ([]Holiday, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := make([]Holiday, 0)
+	for rows.Next() {
+		var h Holiday
+		if err := rows.Scan(&h.ID, &h.CalendarID, &h.Date, &h.Name, &h.Kind); err != nil {
+			return nil, err
+		}
+
+		holidays = append(holidays, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return holidays, nil
+}
+
+// Create godoc
+//
+//	@Summary		Creates a holiday
+//	@Description	Inserts a holiday into a calendar, the default calendar if none is given; a calendar has one holiday per date
+//	@Tags			holidays
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	Holiday
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/holidays [post]
+func (s *HolidayStore) Create(ctx context.Context, holiday *Holiday) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `SELECT id FROM holiday_calendars WHERE id = ?`
+	args := []any{holiday.CalendarID}
+	if holiday.CalendarID == 0 {
+		query = `SELECT id FROM holiday_calendars WHERE is_default = 1 LIMIT 1`
+		args = nil
+	}
+
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&holiday.CalendarID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM public_holidays WHERE calendar_id = ? AND date = ?`,
+		holiday.CalendarID,
+		holiday.Date,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	if holiday.Kind == "" {
+		holiday.Kind = HolidayKindPublic
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO public_holidays (calendar_id, date, name, kind) VALUES (?, ?, ?, ?)`,
+		holiday.CalendarID,
+		holiday.Date,
+		holiday.Name,
+		holiday.Kind,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	holiday.ID = id
+
+	return nil
+}
+
+// Delete godoc
+//
+//	@Summary		Deletes a public holiday
+//	@Description	Removes a public holiday by its ID
+//	@Tags			holidays
+//	@Produce		json
+//	@Param			id	path		int	true	"Holiday ID"
+//	@Success		204	{string}	string	"Holiday deleted"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/holidays/{id} [delete]
+func (s *HolidayStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM public_holidays WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Import godoc
+//
+//	@Summary		Imports holidays into a calendar
+//	@Description	Inserts holidays into a calendar. A holiday on a date the calendar already has replaces the existing one. It returns how many holidays were added and how many were replaced.
+//	@Tags			holidays
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/holiday-calendars/{id}/import [post]
+func (s *HolidayStore) Import(ctx context.Context, calendarID int64, holidays []Holiday) (int, int, error) {
+	query := `
+		INSERT INTO public_holidays (calendar_id, date, name, kind)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE name = VALUES(name), kind = VALUES(kind)
+	`
+
+	added, replaced := 0, 0
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var id int64
+		err := tx.QueryRowContext(ctx, `SELECT id FROM holiday_calendars WHERE id = ? FOR UPDATE`, calendarID).Scan(&id)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		for _, h := range holidays {
+			res, err := tx.ExecContext(ctx, query, calendarID, h.Date, h.Name, h.Kind)
+			if err != nil {
+				return err
+			}
+
+			// MySQL reports 1 for an insert, 2 for an update and 0 for an unchanged row
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			switch rows {
+			case 1:
+				added++
+			case 2:
+				replaced++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return added, replaced, nil
+}
+
+// SetUserCalendar godoc
+//
+//	@Summary		Assigns a holiday calendar to a user
+//	@Description	Sets the holiday calendar a user follows; a nil calendar makes the user follow their site's calendar or the default one
+//	@Tags			holidays
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/users/{id}/holiday-calendar [put]
+func (s *HolidayStore) SetUserCalendar(ctx context.Context, userID int64, calendarID *int64) error {
+	return s.setCalendar(ctx, `users`, userID, calendarID)
+}
+
+// SetSiteCalendar godoc
+//
+//	@Summary		Assigns a holiday calendar to a site
+//	@Description	Sets the holiday calendar the users of a site follow unless they have their own; a nil calendar makes them follow the default one
+//	@Tags			holidays
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/sites/{id}/holiday-calendar [put]
+func (s *HolidayStore) SetSiteCalendar(ctx context.Context, siteID int64, calendarID *int64) error {
+	return s.setCalendar(ctx, `sites`, siteID, calendarID)
+}
+
+// setCalendar sets the holiday calendar of a row of table, which is either users or sites.
+func (s *HolidayStore) setCalendar(ctx context.Context, table string, id int64, calendarID *int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var found bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = ?)
+			AND (? IS NULL OR EXISTS (SELECT 1 FROM holiday_calendars WHERE id = ?))`,
+		id,
+		calendarID,
+		calendarID,
+	).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	_, err = s.db.ExecContext(ctx, `UPDATE `+table+` SET holiday_calendar_id = ? WHERE id = ?`, calendarID, id)
+	return err
+}
+
+// scanHolidayCalendar scans a holiday calendar.
+func scanHolidayCalendar(row scanner, c *HolidayCalendar) error {
+	var createdAt []byte
+	if err := row.Scan(&c.ID, &c.Name, &c.Region, &c.IsDefault, &createdAt); err != nil {
+		return err
+	}
+
+	var err error
+	c.CreatedAt, err = parseDBTime(string(createdAt))
+	return err
+}
+
+// HolidayDates returns the dates of holidays as a set.
+func HolidayDates(holidays []Holiday) map[string]bool {
+	dates := make(map[string]bool, len(holidays))
+	for _, h := range holidays {
+		dates[h.Date] = true
+	}
+	return dates
+}
+
+// MarkHolidays flags the days of localized shifts that fall on holidays and totals the work done on them.
+func MarkHolidays(shifts []Shift, holidays []Holiday) {
+	names := make(map[string]string, len(holidays))
+	for _, h := range holidays {
+		names[h.Date] = h.Name
+	}
+
+	for i := range shifts {
+		sh := &shifts[i]
+		sh.HolidayTime = 0
+		for j := range sh.Days {
+			day := &sh.Days[j]
+			day.Holiday = names[day.Date]
+			if day.Holiday != "" {
+				sh.HolidayTime += day.NetWorkTime
+			}
+		}
+	}
+}
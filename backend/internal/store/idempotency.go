@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IdempotencyExpTime is how long a stored response is replayed for the same key.
+const IdempotencyExpTime = 24 * time.Hour
+
+// IdempotencyRecord holds the first response sent for a (user, Idempotency-Key) pair.
+// A StatusCode of zero means the original request is still being processed.
+type IdempotencyRecord struct {
+	UserID      int64     `json:"user_id"`
+	Key         string    `json:"key"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// IdempotencyStore persists idempotency records in the database.
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+func (s *IdempotencyStore) Get(ctx context.Context, userID int64, key string) (*IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, idempotency_key, method, path, request_hash, status_code, content_type, body, expires_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ? AND expires_at > ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	record := &IdempotencyRecord{}
+	var rawExpiresAt []byte
+
+	err := s.db.QueryRowContext(ctx, query, userID, key, time.Now()).Scan(
+		&record.UserID,
+		&record.Key,
+		&record.Method,
+		&record.Path,
+		&record.RequestHash,
+		&record.StatusCode,
+		&record.ContentType,
+		&record.Body,
+		&rawExpiresAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	record.ExpiresAt, err = parseDBTime(string(rawExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Reserve claims the key for an in-flight request. It returns false if another
+// request already holds an unexpired record for the same key.
+func (s *IdempotencyStore) Reserve(ctx context.Context, record *IdempotencyRecord) (bool, error) {
+	reserved := false
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// Expired records no longer protect their key
+		_, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND expires_at <= ?`,
+			record.UserID, record.Key, time.Now(),
+		)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT IGNORE INTO idempotency_keys (user_id, idempotency_key, method, path, request_hash, status_code, expires_at)
+			VALUES (?, ?, ?, ?, ?, 0, ?)
+		`
+
+		res, err := tx.ExecContext(ctx, query, record.UserID, record.Key, record.Method, record.Path, record.RequestHash, record.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		reserved = rows == 1
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return reserved, nil
+}
+
+// Save stores the final response of a reserved key.
+func (s *IdempotencyStore) Save(ctx context.Context, record *IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = ?, content_type = ?, body = ?
+		WHERE user_id = ? AND idempotency_key = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, record.StatusCode, record.ContentType, record.Body, record.UserID, record.Key)
+	return err
+}
+
+// Delete releases a key so that the request can be retried.
+func (s *IdempotencyStore) Delete(ctx context.Context, userID int64, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, key)
+	return err
+}
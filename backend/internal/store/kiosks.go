@@ -0,0 +1,560 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidPIN = errors.New("invalid user or PIN")
+	ErrPINLocked  = errors.New("too many failed PIN attempts")
+)
+
+// Site is a workplace that users and shared kiosks belong to, e.g. a warehouse.
+type Site struct {
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	HolidayCalendarID *int64    `json:"holiday_calendar_id"` // Holiday calendar of the site's users
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Kiosk is a shared device that posts stamps on behalf of the users of its site.
+// It authenticates with a device token of which only the hash is stored.
+type Kiosk struct {
+	ID         int64      `json:"id"`
+	SiteID     int64      `json:"site_id"`
+	SiteName   string     `json:"site_name"`
+	Name       string     `json:"name"`
+	IsActive   bool       `json:"is_active"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// KioskUser is a user as listed on the kiosks of their site.
+type KioskUser struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	HasPIN    bool   `json:"has_pin"`
+}
+
+// PINPolicy throttles PIN guessing: after MaxAttempts consecutive failures the PIN is locked for Lockout.
+type PINPolicy struct {
+	MaxAttempts int
+	Lockout     time.Duration
+}
+
+// KioskStore provides methods for managing sites, kiosks and the PINs users sign in with at kiosks.
+type KioskStore struct {
+	db *sql.DB
+}
+
+// hashToken returns the hex encoded SHA-256 hash a device or refresh token is stored as.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateSite godoc
+//
+//	@Summary		Creates a site
+//	@Description	Inserts a site; site names are unique
+//	@Tags			kiosks
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	Site
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/sites [post]
+func (s *KioskStore) CreateSite(ctx context.Context, site *Site) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sites WHERE name = ?`, site.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	result, err := s.db.ExecContext(ctx, `INSERT INTO sites (name) VALUES (?)`, site.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	site.ID = id
+	site.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// GetSites godoc
+//
+//	@Summary		Retrieves all sites
+//	@Description	Retrieves all sites ordered by name
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	[]Site
+//	@Failure		500	{object}	error
+//	@Router			/sites [get]
+func (s *KioskStore) GetSites(ctx context.Context) ([]Site, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, holiday_calendar_id, created_at FROM sites ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sites := make([]Site, 0)
+	for rows.Next() {
+		var site Site
+		var holidayCalendarID sql.NullInt64
+		var createdAt []byte
+		if err := rows.Scan(&site.ID, &site.Name, &holidayCalendarID, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if holidayCalendarID.Valid {
+			site.HolidayCalendarID = &holidayCalendarID.Int64
+		}
+
+		if site.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+			return nil, err
+		}
+
+		sites = append(sites, site)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sites, nil
+}
+
+// Create godoc
+//
+//	@Summary		Creates a kiosk
+//	@Description	Inserts a kiosk for a site that authenticates with the given device token
+//	@Tags			kiosks
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	Kiosk
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/kiosks [post]
+func (s *KioskStore) Create(ctx context.Context, kiosk *Kiosk, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `SELECT name FROM sites WHERE id = ?`, kiosk.SiteID).Scan(&kiosk.SiteName)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO kiosks (site_id, name, token_hash) VALUES (?, ?, ?)`,
+		kiosk.SiteID,
+		kiosk.Name,
+		hashToken(token),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	kiosk.ID = id
+	kiosk.IsActive = true
+	kiosk.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// GetAll godoc
+//
+//	@Summary		Retrieves all kiosks
+//	@Description	Retrieves all kiosks ordered by site and name
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	[]Kiosk
+//	@Failure		500	{object}	error
+//	@Router			/kiosks [get]
+func (s *KioskStore) GetAll(ctx context.Context) ([]Kiosk, error) {
+	query := `
+		SELECT k.id, k.site_id, s.name, k.name, k.is_active, k.last_seen_at, k.created_at
+		FROM kiosks k
+		JOIN sites s ON s.id = k.site_id
+		ORDER BY s.name ASC, k.name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	kiosks := make([]Kiosk, 0)
+	for rows.Next() {
+		var k Kiosk
+		if err := scanKiosk(rows, &k); err != nil {
+			return nil, err
+		}
+		kiosks = append(kiosks, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return kiosks, nil
+}
+
+// Authenticate godoc
+//
+//	@Summary		Authenticates a kiosk
+//	@Description	Retrieves the active kiosk with the given device token and records that it was seen
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	Kiosk
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+func (s *KioskStore) Authenticate(ctx context.Context, token string) (*Kiosk, error) {
+	query := `
+		SELECT k.id, k.site_id, s.name, k.name, k.is_active, k.last_seen_at, k.created_at
+		FROM kiosks k
+		JOIN sites s ON s.id = k.site_id
+		WHERE k.token_hash = ? AND k.is_active = 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var kiosk Kiosk
+	if err := scanKiosk(s.db.QueryRowContext(ctx, query, hashToken(token)), &kiosk); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	seenAt := time.Now().UTC().Truncate(time.Second)
+	if _, err := s.db.ExecContext(ctx, `UPDATE kiosks SET last_seen_at = ? WHERE id = ?`, seenAt, kiosk.ID); err != nil {
+		return nil, err
+	}
+	kiosk.LastSeenAt = &seenAt
+
+	return &kiosk, nil
+}
+
+// RotateToken godoc
+//
+//	@Summary		Rotates the device token of a kiosk
+//	@Description	Replaces the device token of a kiosk, which signs out the device until it is set up with the new token. A deactivated kiosk is activated again.
+//	@Tags			kiosks
+//	@Produce		json
+//	@Param			id	path		int	true	"Kiosk ID"
+//	@Success		200	{object}	Kiosk
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/kiosks/{id}/token [post]
+func (s *KioskStore) RotateToken(ctx context.Context, id int64, token string) (*Kiosk, error) {
+	query := `
+		SELECT k.id, k.site_id, s.name, k.name, k.is_active, k.last_seen_at, k.created_at
+		FROM kiosks k
+		JOIN sites s ON s.id = k.site_id
+		WHERE k.id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE kiosks SET token_hash = ?, is_active = 1 WHERE id = ?`, hashToken(token), id); err != nil {
+		return nil, err
+	}
+
+	var kiosk Kiosk
+	if err := scanKiosk(s.db.QueryRowContext(ctx, query, id), &kiosk); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &kiosk, nil
+}
+
+// Deactivate godoc
+//
+//	@Summary		Deactivates a kiosk
+//	@Description	Revokes the device token of a kiosk; stamps it posted keep referring to it
+//	@Tags			kiosks
+//	@Param			id	path		int		true	"Kiosk ID"
+//	@Success		204	{string}	string	"Kiosk deactivated"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/kiosks/{id} [delete]
+func (s *KioskStore) Deactivate(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM kiosks WHERE id = ?`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+
+	_, err := s.db.ExecContext(ctx, `UPDATE kiosks SET is_active = 0 WHERE id = ?`, id)
+	return err
+}
+
+// SetUserSite godoc
+//
+//	@Summary		Assigns a user to a site
+//	@Description	Sets the site whose kiosks the user may stamp at; a nil site removes the user from kiosks
+//	@Tags			kiosks
+//	@Param			id	path		int		true	"User ID"
+//	@Success		204	{string}	string	"Site assigned"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/users/{id}/site [put]
+func (s *KioskStore) SetUserSite(ctx context.Context, userID int64, siteID *int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var found bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)
+			AND (? IS NULL OR EXISTS (SELECT 1 FROM sites WHERE id = ?))`,
+		userID,
+		siteID,
+		siteID,
+	).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	_, err = s.db.ExecContext(ctx, `UPDATE users SET site_id = ? WHERE id = ?`, siteID, userID)
+	return err
+}
+
+// GetSiteUsers godoc
+//
+//	@Summary		Retrieves the users of a site
+//	@Description	Retrieves the active users assigned to a site ordered by name
+//	@Tags			kiosks
+//	@Produce		json
+//	@Success		200	{object}	[]KioskUser
+//	@Failure		500	{object}	error
+//	@Router			/kiosk/users [get]
+func (s *KioskStore) GetSiteUsers(ctx context.Context, siteID int64) ([]KioskUser, error) {
+	query := `
+		SELECT id, first_name, last_name, pin_hash IS NOT NULL
+		FROM users
+		WHERE site_id = ? AND is_active = 1
+		ORDER BY first_name ASC, last_name ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, siteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]KioskUser, 0)
+	for rows.Next() {
+		var u KioskUser
+		var firstName, lastName sql.NullString
+		if err := rows.Scan(&u.ID, &firstName, &lastName, &u.HasPIN); err != nil {
+			return nil, err
+		}
+		u.FirstName = firstName.String
+		u.LastName = lastName.String
+
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// SetPIN godoc
+//
+//	@Summary		Sets the kiosk PIN of a user
+//	@Description	Hashes and stores the PIN a user confirms kiosk stamps with and lifts any PIN lockout
+//	@Tags			kiosks
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/pin [put]
+func (s *KioskStore) SetPIN(ctx context.Context, userID int64, pin string) error {
+	var hashed password
+	if err := hashed.Set(pin); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET pin_hash = ?, pin_failed_attempts = 0, pin_locked_until = NULL WHERE id = ?`,
+		hashed.hash,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// CheckPIN verifies the PIN of an active user assigned to the site. It returns ErrInvalidPIN if the user
+// is not at the site, has no PIN or the PIN is wrong, and ErrPINLocked while the PIN is locked after
+// too many consecutive failures. Failures are counted even though an error is returned.
+func (s *KioskStore) CheckPIN(ctx context.Context, userID, siteID int64, pin string, policy PINPolicy) error {
+	var result error
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var userSiteID sql.NullInt64
+		var hashed password
+		var failedAttempts int
+		var rawLockedUntil []byte
+
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT site_id, pin_hash, pin_failed_attempts, pin_locked_until FROM users WHERE id = ? AND is_active = 1 FOR UPDATE`,
+			userID,
+		).Scan(&userSiteID, &hashed.hash, &failedAttempts, &rawLockedUntil)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				result = ErrInvalidPIN
+				return nil
+			default:
+				return err
+			}
+		}
+
+		if !userSiteID.Valid || userSiteID.Int64 != siteID {
+			result = ErrInvalidPIN
+			return nil
+		}
+
+		now := time.Now().UTC()
+
+		if rawLockedUntil != nil {
+			lockedUntil, err := parseDBTime(string(rawLockedUntil))
+			if err != nil {
+				return err
+			}
+			if now.Before(lockedUntil) {
+				result = ErrPINLocked
+				return nil
+			}
+		}
+
+		if hashed.hash != nil && hashed.Compare(pin) == nil {
+			_, err := tx.ExecContext(ctx, `UPDATE users SET pin_failed_attempts = 0, pin_locked_until = NULL WHERE id = ?`, userID)
+			return err
+		}
+
+		failedAttempts++
+		result = ErrInvalidPIN
+
+		var lockedUntil *time.Time
+		if failedAttempts >= policy.MaxAttempts {
+			until := now.Add(policy.Lockout).Truncate(time.Second)
+			lockedUntil = &until
+			failedAttempts = 0
+			result = ErrPINLocked
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE users SET pin_failed_attempts = ?, pin_locked_until = ? WHERE id = ?`,
+			failedAttempts,
+			lockedUntil,
+			userID,
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+// scanKiosk scans a kiosk joined with the name of its site.
+func scanKiosk(row scanner, k *Kiosk) error {
+	var lastSeenAt, createdAt []byte
+
+	err := row.Scan(&k.ID, &k.SiteID, &k.SiteName, &k.Name, &k.IsActive, &lastSeenAt, &createdAt)
+	if err != nil {
+		return err
+	}
+
+	if lastSeenAt != nil {
+		seenAt, err := parseDBTime(string(lastSeenAt))
+		if err != nil {
+			return err
+		}
+		k.LastSeenAt = &seenAt
+	}
+
+	if k.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return err
+	}
+
+	return nil
+}
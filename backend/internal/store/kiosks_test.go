@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestKioskStoreCheckPIN(t *testing.T) {
+	conn := newTestDB(t)
+	s := &KioskStore{conn}
+	ctx := context.Background()
+
+	site := &Site{Name: fmt.Sprintf("test-%d", time.Now().UnixNano())}
+	if err := s.CreateSite(ctx, site); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.ExecContext(ctx, `DELETE FROM sites WHERE id = ?`, site.ID) })
+
+	userID := createTestUser(t, conn)
+	if err := s.SetUserSite(ctx, userID, &site.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := PINPolicy{MaxAttempts: 3, Lockout: time.Hour}
+
+	check := func(siteID int64, pin string, want error) {
+		t.Helper()
+
+		if err := s.CheckPIN(ctx, userID, siteID, pin, policy); !errors.Is(err, want) {
+			t.Fatalf("checking %s at site %d: got %v, want %v", pin, siteID, err, want)
+		}
+	}
+
+	// Without a PIN nothing matches
+	check(site.ID, "", ErrInvalidPIN)
+
+	if err := s.SetPIN(ctx, userID, "4711"); err != nil {
+		t.Fatal(err)
+	}
+
+	check(site.ID, "4711", nil)
+	check(site.ID+1, "4711", ErrInvalidPIN)
+
+	// A success resets the count of consecutive failures
+	check(site.ID, "0000", ErrInvalidPIN)
+	check(site.ID, "0000", ErrInvalidPIN)
+	check(site.ID, "4711", nil)
+	check(site.ID, "0000", ErrInvalidPIN)
+	check(site.ID, "0000", ErrInvalidPIN)
+
+	// The third failure in a row locks the PIN, which then refuses the right PIN too
+	check(site.ID, "0000", ErrPINLocked)
+	check(site.ID, "4711", ErrPINLocked)
+
+	// The lockout expires
+	if _, err := conn.ExecContext(ctx, `UPDATE users SET pin_locked_until = ? WHERE id = ?`, time.Now().UTC().Add(-time.Minute), userID); err != nil {
+		t.Fatal(err)
+	}
+	check(site.ID, "4711", nil)
+
+	// Setting a new PIN lifts a lockout
+	for range policy.MaxAttempts {
+		s.CheckPIN(ctx, userID, site.ID, "0000", policy)
+	}
+	check(site.ID, "4711", ErrPINLocked)
+	if err := s.SetPIN(ctx, userID, "1234"); err != nil {
+		t.Fatal(err)
+	}
+	check(site.ID, "1234", nil)
+
+	// Inactive users cannot sign in at kiosks
+	if _, err := conn.ExecContext(ctx, `UPDATE users SET is_active = 0 WHERE id = ?`, userID); err != nil {
+		t.Fatal(err)
+	}
+	check(site.ID, "1234", ErrInvalidPIN)
+}
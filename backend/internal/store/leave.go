@@ -0,0 +1,961 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// Leave request statuses. A request is pending until a manager approves or rejects it;
+// the user can cancel it while it is pending or before approved leave has started.
+const (
+	LeaveStatusPending   = "pending"
+	LeaveStatusApproved  = "approved"
+	LeaveStatusRejected  = "rejected"
+	LeaveStatusCancelled = "cancelled"
+)
+
+var (
+	// ErrLeaveNotPending is returned when a leave request that has already been decided is approved or rejected.
+	ErrLeaveNotPending = errors.New("leave request is not pending approval")
+	// ErrLeaveStarted is returned when leave is cancelled after it has started or been rejected.
+	ErrLeaveStarted = errors.New("leave request can no longer be cancelled")
+	// ErrInsufficientBalance is returned when approving a request would leave a negative leave balance.
+	ErrInsufficientBalance = errors.New("insufficient leave balance")
+)
+
+// LeaveType is a kind of absence, e.g. vacation, sick leave or parental leave.
+// Paid leave counts as paid non-worked time; unpaid leave only excuses the absence.
+type LeaveType struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Paid      bool      `json:"paid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LeavePolicy accrues days of a leave type every month. At the start of every year the balance is
+// capped at CarryOverCap and the excess is forfeited; a nil cap carries over the whole balance.
+type LeavePolicy struct {
+	ID           int64     `json:"id"`
+	LeaveTypeID  int64     `json:"leave_type_id"`
+	Name         string    `json:"name"`
+	DaysPerMonth float64   `json:"days_per_month"` // e.g. 2.08 for 25 days a year
+	CarryOverCap *float64  `json:"carry_over_cap"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LeaveRequest is a request for leave on the local dates StartDate..EndDate (inclusive).
+// Days counts the workdays in the range that are not holidays, halved for a half day.
+type LeaveRequest struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	LeaveTypeID int64      `json:"leave_type_id"`
+	LeaveType   string     `json:"leave_type"`
+	StartDate   string     `json:"start_date"`
+	EndDate     string     `json:"end_date"`
+	HalfDay     bool       `json:"half_day"`
+	Days        float64    `json:"days"`
+	Reason      string     `json:"reason"`
+	Status      string     `json:"status"`
+	DecidedBy   *int64     `json:"decided_by"`
+	DecidedAt   *time.Time `json:"decided_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// In converts the times of the request to loc for display.
+func (l *LeaveRequest) In(loc *time.Location) {
+	l.CreatedAt = l.CreatedAt.In(loc)
+	if l.DecidedAt != nil {
+		decidedAt := l.DecidedAt.In(loc)
+		l.DecidedAt = &decidedAt
+	}
+}
+
+// LeaveDay is one local date of a leave request.
+type LeaveDay struct {
+	Date        string  `json:"date"` // YYYY-MM-DD in the user's time zone
+	RequestID   int64   `json:"request_id"`
+	LeaveTypeID int64   `json:"leave_type_id"`
+	LeaveType   string  `json:"leave_type"`
+	Paid        bool    `json:"paid"`
+	Amount      float64 `json:"amount"` // 1 for a full day, 0.5 for a half day
+}
+
+// LeaveBalance is the balance of a leave type under the user's policy on a local date.
+// Balance is Accrued minus Forfeited minus Taken; pending and booked days are not deducted.
+type LeaveBalance struct {
+	LeaveTypeID  int64    `json:"leave_type_id"`
+	LeaveType    string   `json:"leave_type"`
+	PolicyID     int64    `json:"policy_id"`
+	StartsOn     string   `json:"starts_on"` // Date from which the policy accrues
+	AsOf         string   `json:"as_of"`
+	Accrued      float64  `json:"accrued"`
+	Forfeited    float64  `json:"forfeited"` // Days above the carry-over cap at the start of a year
+	Taken        float64  `json:"taken"`     // Approved days up to and including AsOf
+	Booked       float64  `json:"booked"`    // Approved days after AsOf
+	Pending      float64  `json:"pending"`   // Days of requests waiting for approval
+	Balance      float64  `json:"balance"`
+	CarryOverCap *float64 `json:"carry_over_cap"`
+}
+
+// LeaveDates lists the dates of leave on the local dates from..to in loc. Weekends and holidays
+// are skipped, as no time is expected on them. A half day is only possible on a single date.
+func LeaveDates(from, to time.Time, loc *time.Location, holidays map[string]bool, halfDay bool) []LeaveDay {
+	amount := 1.0
+	if halfDay {
+		amount = 0.5
+	}
+
+	days := make([]LeaveDay, 0)
+	for d := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc); !d.After(to); d = time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc) {
+		date := d.Format(time.DateOnly)
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || holidays[date] {
+			continue
+		}
+
+		days = append(days, LeaveDay{Date: date, Amount: amount})
+	}
+
+	return days
+}
+
+// ComputeLeaveBalance works out the balance of policy on the date asOf (YYYY-MM-DD) for an assignment
+// starting on startsOn. DaysPerMonth is credited on the first day of every month from startsOn on, and
+// the carry-over cap is applied at the start of every following year before that year's first credit.
+// days holds the user's approved and pending leave days of the policy's leave type.
+func ComputeLeaveBalance(policy *LeavePolicy, startsOn, asOf string, days []LeaveDay, pending map[int64]bool) (*LeaveBalance, error) {
+	start, err := time.Parse(time.DateOnly, startsOn)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(time.DateOnly, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &LeaveBalance{
+		LeaveTypeID:  policy.LeaveTypeID,
+		PolicyID:     policy.ID,
+		StartsOn:     startsOn,
+		AsOf:         asOf,
+		CarryOverCap: policy.CarryOverCap,
+	}
+
+	// Leave taken per year; leave before the assignment started counts against its first year
+	takenByYear := make(map[int]float64)
+	for _, d := range days {
+		switch {
+		case pending[d.RequestID]:
+			balance.Pending += d.Amount
+		case d.Date > asOf:
+			balance.Booked += d.Amount
+		default:
+			year := start.Year()
+			if d.Date >= startsOn {
+				t, err := time.Parse(time.DateOnly, d.Date)
+				if err != nil {
+					return nil, err
+				}
+				year = t.Year()
+			}
+			takenByYear[year] += d.Amount
+			balance.Taken += d.Amount
+		}
+	}
+
+	last := end.Year()
+	if last < start.Year() {
+		last = start.Year()
+	}
+
+	current := 0.0
+	for year := start.Year(); year <= last; year++ {
+		if year > start.Year() && policy.CarryOverCap != nil && current > *policy.CarryOverCap {
+			balance.Forfeited += current - *policy.CarryOverCap
+			current = *policy.CarryOverCap
+		}
+
+		// Credits on the first of the months of this year that fall within startsOn..asOf
+		credits := 0
+		for m := time.January; m <= time.December; m++ {
+			first := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+			if !first.Before(start) && !first.After(end) {
+				credits++
+			}
+		}
+
+		accrued := float64(credits) * policy.DaysPerMonth
+		balance.Accrued += accrued
+		current += accrued - takenByYear[year]
+	}
+
+	balance.Balance = current
+	balance.round()
+
+	return balance, nil
+}
+
+// round rounds the day counts of the balance to hundredths of a day.
+func (b *LeaveBalance) round() {
+	for _, v := range []*float64{&b.Accrued, &b.Forfeited, &b.Taken, &b.Booked, &b.Pending, &b.Balance} {
+		*v = math.Round(*v*100) / 100
+	}
+}
+
+// LeaveStore provides methods for managing leave types, accrual policies and leave requests.
+type LeaveStore struct {
+	db *sql.DB
+}
+
+const leaveRequestSelect = `
+	SELECT lr.id, lr.user_id, lr.leave_type_id, lt.name, lr.start_date, lr.end_date, lr.half_day,
+		lr.days, lr.reason, lr.status, lr.decided_by, lr.decided_at, lr.created_at
+	FROM leave_requests lr
+	JOIN leave_types lt ON lt.id = lr.leave_type_id
+`
+
+// CreateType godoc
+//
+//	@Summary		Creates a leave type
+//	@Description	Inserts a leave type with a unique name
+//	@Tags			leave
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	LeaveType
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/leave-types [post]
+func (s *LeaveStore) CreateType(ctx context.Context, leaveType *LeaveType) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM leave_types WHERE name = ?`, leaveType.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	result, err := s.db.ExecContext(ctx, `INSERT INTO leave_types (name, paid) VALUES (?, ?)`, leaveType.Name, leaveType.Paid)
+	if err != nil {
+		return err
+	}
+
+	if leaveType.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+	leaveType.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	return nil
+}
+
+// GetTypes godoc
+//
+//	@Summary		Retrieves all leave types
+//	@Description	Retrieves all leave types ordered by name
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]LeaveType
+//	@Failure		500	{object}	error
+//	@Router			/leave-types [get]
+func (s *LeaveStore) GetTypes(ctx context.Context) ([]LeaveType, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, paid, created_at FROM leave_types ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make([]LeaveType, 0)
+	for rows.Next() {
+		var t LeaveType
+		var createdAt []byte
+		if err := rows.Scan(&t.ID, &t.Name, &t.Paid, &createdAt); err != nil {
+			return nil, err
+		}
+		if t.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+			return nil, err
+		}
+
+		types = append(types, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+// CreatePolicy godoc
+//
+//	@Summary		Creates a leave accrual policy
+//	@Description	Inserts an accrual policy with a unique name for an existing leave type
+//	@Tags			leave
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	LeavePolicy
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/leave-policies [post]
+func (s *LeaveStore) CreatePolicy(ctx context.Context, policy *LeavePolicy) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var found bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM leave_types WHERE id = ?)`, policy.LeaveTypeID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM leave_policies WHERE name = ?`, policy.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO leave_policies (leave_type_id, name, days_per_month, carry_over_cap) VALUES (?, ?, ?, ?)`,
+		policy.LeaveTypeID,
+		policy.Name,
+		policy.DaysPerMonth,
+		policy.CarryOverCap,
+	)
+	if err != nil {
+		return err
+	}
+
+	if policy.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+	policy.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	return nil
+}
+
+// GetPolicies godoc
+//
+//	@Summary		Retrieves all leave accrual policies
+//	@Description	Retrieves all leave accrual policies ordered by name
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]LeavePolicy
+//	@Failure		500	{object}	error
+//	@Router			/leave-policies [get]
+func (s *LeaveStore) GetPolicies(ctx context.Context) ([]LeavePolicy, error) {
+	query := `
+		SELECT id, leave_type_id, name, days_per_month, carry_over_cap, created_at
+		FROM leave_policies
+		ORDER BY name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]LeavePolicy, 0)
+	for rows.Next() {
+		var p LeavePolicy
+		if err := scanLeavePolicy(rows, &p); err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// AssignPolicy godoc
+//
+//	@Summary		Assigns a leave accrual policy to a user
+//	@Description	Replaces the user's policy for the policy's leave type; accrual starts on startsOn
+//	@Tags			leave
+//	@Produce		json
+//	@Success		204	{string}	string	"Policy assigned"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/leave-policies/{id}/users/{userID} [put]
+func (s *LeaveStore) AssignPolicy(ctx context.Context, policyID, userID int64, startsOn string) error {
+	query := `
+		INSERT INTO leave_policy_assignments (user_id, leave_type_id, policy_id, starts_on)
+		SELECT ?, leave_type_id, id, ? FROM leave_policies WHERE id = ?
+		ON DUPLICATE KEY UPDATE policy_id = VALUES(policy_id), starts_on = VALUES(starts_on)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var found bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = ?) AND EXISTS (SELECT 1 FROM leave_policies WHERE id = ?)`,
+		userID,
+		policyID,
+	).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	_, err = s.db.ExecContext(ctx, query, userID, startsOn, policyID)
+	return err
+}
+
+// CreateRequest godoc
+//
+//	@Summary		Creates a leave request
+//	@Description	Inserts a pending leave request with its days; it must not overlap another pending or approved request of the user
+//	@Tags			leave
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	LeaveRequest
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/leave-requests [post]
+func (s *LeaveStore) CreateRequest(ctx context.Context, req *LeaveRequest, days []LeaveDay) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, req.UserID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, `SELECT name FROM leave_types WHERE id = ?`, req.LeaveTypeID).Scan(&req.LeaveType)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		var overlapping int
+		err = tx.QueryRowContext(
+			ctx,
+			`SELECT COUNT(*) FROM leave_requests
+			WHERE user_id = ? AND status IN (?, ?) AND start_date <= ? AND end_date >= ?`,
+			req.UserID,
+			LeaveStatusPending,
+			LeaveStatusApproved,
+			req.EndDate,
+			req.StartDate,
+		).Scan(&overlapping)
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrConflict
+		}
+
+		req.Days = 0
+		for _, d := range days {
+			req.Days += d.Amount
+		}
+		req.Status = LeaveStatusPending
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO leave_requests (user_id, leave_type_id, start_date, end_date, half_day, days, reason, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			req.UserID,
+			req.LeaveTypeID,
+			req.StartDate,
+			req.EndDate,
+			req.HalfDay,
+			req.Days,
+			req.Reason,
+			req.Status,
+		)
+		if err != nil {
+			return err
+		}
+
+		if req.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		for _, d := range days {
+			_, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO leave_request_days (request_id, date, amount) VALUES (?, ?, ?)`,
+				req.ID,
+				d.Date,
+				d.Amount,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		req.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+		return nil
+	})
+}
+
+// GetRequestByID godoc
+//
+//	@Summary		Retrieves a leave request by ID
+//	@Description	Retrieves a leave request by its ID
+//	@Tags			leave
+//	@Produce		json
+//	@Param			id	path		int	true	"Leave request ID"
+//	@Success		200	{object}	LeaveRequest
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/leave-requests/{id} [get]
+func (s *LeaveStore) GetRequestByID(ctx context.Context, id int64) (*LeaveRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var req LeaveRequest
+	if err := scanLeaveRequest(s.db.QueryRowContext(ctx, leaveRequestSelect+` WHERE lr.id = ?`, id), &req); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &req, nil
+}
+
+// GetRequests godoc
+//
+//	@Summary		Retrieves the leave requests of a user
+//	@Description	Retrieves all leave requests of a user, latest leave first
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]LeaveRequest
+//	@Failure		500	{object}	error
+//	@Router			/leave-requests [get]
+func (s *LeaveStore) GetRequests(ctx context.Context, userID int64) ([]LeaveRequest, error) {
+	query := leaveRequestSelect + `
+		WHERE lr.user_id = ?
+		ORDER BY lr.start_date DESC, lr.id DESC
+	`
+
+	return s.queryRequests(ctx, query, userID)
+}
+
+// GetPending godoc
+//
+//	@Summary		Retrieves the pending leave requests of a team
+//	@Description	Retrieves the leave requests of a manager's reports that wait for approval, earliest leave first
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]LeaveRequest
+//	@Failure		500	{object}	error
+//	@Router			/leave-requests/pending [get]
+func (s *LeaveStore) GetPending(ctx context.Context, managerID int64) ([]LeaveRequest, error) {
+	query := leaveRequestSelect + `
+		JOIN users u ON u.id = lr.user_id
+		WHERE u.manager_id = ? AND lr.status = ?
+		ORDER BY lr.start_date ASC, lr.id ASC
+	`
+
+	return s.queryRequests(ctx, query, managerID, LeaveStatusPending)
+}
+
+// Decide godoc
+//
+//	@Summary		Approves or rejects a leave request
+//	@Description	Records a manager's decision on a pending leave request. Approval fails if the user's balance of the leave type would be negative at the end of the leave.
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	LeaveRequest
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/leave-requests/{id}/approve [patch]
+func (s *LeaveStore) Decide(ctx context.Context, req *LeaveRequest, status string, actorID int64) error {
+	if status != LeaveStatusApproved && status != LeaveStatusRejected {
+		return errors.New("invalid leave decision")
+	}
+
+	decidedAt := time.Now().UTC().Truncate(time.Second)
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, req.UserID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var current string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM leave_requests WHERE id = ? FOR UPDATE`, req.ID).Scan(&current)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+		if current != LeaveStatusPending {
+			return ErrLeaveNotPending
+		}
+
+		if status == LeaveStatusApproved {
+			balance, err := leaveBalance(ctx, tx, req.UserID, req.LeaveTypeID, req.EndDate)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			// Without a policy the leave type has no balance to respect, e.g. sick leave
+			if balance != nil && balance.Balance-req.Days < 0 {
+				return ErrInsufficientBalance
+			}
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE leave_requests SET status = ?, decided_by = ?, decided_at = ? WHERE id = ?`,
+			status,
+			actorID,
+			decidedAt,
+			req.ID,
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	req.Status = status
+	req.DecidedBy = &actorID
+	req.DecidedAt = &decidedAt
+
+	return nil
+}
+
+// Cancel godoc
+//
+//	@Summary		Cancels a leave request
+//	@Description	Cancels a pending leave request, or an approved one whose first day is after today
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	LeaveRequest
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/leave-requests/{id}/cancel [patch]
+func (s *LeaveStore) Cancel(ctx context.Context, req *LeaveRequest, today string) error {
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var current string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM leave_requests WHERE id = ? FOR UPDATE`, req.ID).Scan(&current)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		switch {
+		case current == LeaveStatusPending:
+		case current == LeaveStatusApproved && req.StartDate > today:
+		default:
+			return ErrLeaveStarted
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE leave_requests SET status = ? WHERE id = ?`, LeaveStatusCancelled, req.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	req.Status = LeaveStatusCancelled
+
+	return nil
+}
+
+// GetBalances godoc
+//
+//	@Summary		Retrieves the leave balances of a user
+//	@Description	Computes the balance on the local date asOf of every leave type the user has an accrual policy for
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]LeaveBalance
+//	@Failure		500	{object}	error
+//	@Router			/leave-balances [get]
+func (s *LeaveStore) GetBalances(ctx context.Context, userID int64, asOf string) ([]LeaveBalance, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT leave_type_id FROM leave_policy_assignments WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	typeIDs := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		typeIDs = append(typeIDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	balances := make([]LeaveBalance, 0, len(typeIDs))
+	for _, id := range typeIDs {
+		balance, err := leaveBalance(ctx, s.db, userID, id, asOf)
+		if err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, *balance)
+	}
+
+	return balances, nil
+}
+
+// GetDays godoc
+//
+//	@Summary		Retrieves the approved leave days of a user
+//	@Description	Retrieves the approved leave days of a user on the local dates from..to
+//	@Tags			leave
+//	@Produce		json
+//	@Success		200	{object}	[]LeaveDay
+//	@Failure		500	{object}	error
+//	@Router			/timesheets [get]
+func (s *LeaveStore) GetDays(ctx context.Context, userID int64, from, to string) ([]LeaveDay, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	days, _, err := queryLeaveDays(ctx, s.db, userID, 0, from, to, LeaveStatusApproved)
+	return days, err
+}
+
+// queryRequests runs a query selecting leaveRequestSelect columns.
+func (s *LeaveStore) queryRequests(ctx context.Context, query string, args ...any) ([]LeaveRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]LeaveRequest, 0)
+	for rows.Next() {
+		var req LeaveRequest
+		if err := scanLeaveRequest(rows, &req); err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, req)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// leaveBalance computes the user's balance of a leave type on asOf. It returns ErrNotFound if the
+// user has no accrual policy for the leave type.
+func leaveBalance(ctx context.Context, q querier, userID, leaveTypeID int64, asOf string) (*LeaveBalance, error) {
+	query := `
+		SELECT lp.id, lp.leave_type_id, lp.name, lp.days_per_month, lp.carry_over_cap, lp.created_at,
+			lpa.starts_on, lt.name
+		FROM leave_policy_assignments lpa
+		JOIN leave_policies lp ON lp.id = lpa.policy_id
+		JOIN leave_types lt ON lt.id = lpa.leave_type_id
+		WHERE lpa.user_id = ? AND lpa.leave_type_id = ?
+	`
+
+	var policy LeavePolicy
+	var startsOn, leaveType string
+	var createdAt []byte
+	var carryOverCap sql.NullFloat64
+
+	err := q.QueryRowContext(ctx, query, userID, leaveTypeID).Scan(
+		&policy.ID,
+		&policy.LeaveTypeID,
+		&policy.Name,
+		&policy.DaysPerMonth,
+		&carryOverCap,
+		&createdAt,
+		&startsOn,
+		&leaveType,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if carryOverCap.Valid {
+		policy.CarryOverCap = &carryOverCap.Float64
+	}
+
+	days, pending, err := queryLeaveDays(ctx, q, userID, leaveTypeID, "", "", LeaveStatusApproved, LeaveStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := ComputeLeaveBalance(&policy, startsOn, asOf, days, pending)
+	if err != nil {
+		return nil, err
+	}
+	balance.LeaveType = leaveType
+
+	return balance, nil
+}
+
+// queryLeaveDays fetches the user's leave days of requests with one of statuses, in date order.
+// A zero leaveTypeID matches all leave types and empty dates leave the range open. The returned
+// map holds the IDs of the pending requests among them.
+func queryLeaveDays(ctx context.Context, q querier, userID, leaveTypeID int64, from, to string, statuses ...string) ([]LeaveDay, map[int64]bool, error) {
+	query := `
+		SELECT d.date, d.request_id, lr.leave_type_id, lt.name, lt.paid, d.amount, lr.status
+		FROM leave_request_days d
+		JOIN leave_requests lr ON lr.id = d.request_id
+		JOIN leave_types lt ON lt.id = lr.leave_type_id
+		WHERE lr.user_id = ? AND (? = 0 OR lr.leave_type_id = ?)
+			AND (? = '' OR d.date >= ?) AND (? = '' OR d.date <= ?)
+			AND lr.status IN (?` + strings.Repeat(", ?", len(statuses)-1) + `)
+		ORDER BY d.date ASC
+	`
+
+	args := []any{userID, leaveTypeID, leaveTypeID, from, from, to, to}
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	days := make([]LeaveDay, 0)
+	pending := make(map[int64]bool)
+	for rows.Next() {
+		var d LeaveDay
+		var status string
+		if err := rows.Scan(&d.Date, &d.RequestID, &d.LeaveTypeID, &d.LeaveType, &d.Paid, &d.Amount, &status); err != nil {
+			return nil, nil, err
+		}
+
+		if status == LeaveStatusPending {
+			pending[d.RequestID] = true
+		}
+		days = append(days, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return days, pending, nil
+}
+
+// scanLeavePolicy scans a row of the leave_policies column list into p.
+func scanLeavePolicy(row scanner, p *LeavePolicy) error {
+	var carryOverCap sql.NullFloat64
+	var createdAt []byte
+
+	err := row.Scan(&p.ID, &p.LeaveTypeID, &p.Name, &p.DaysPerMonth, &carryOverCap, &createdAt)
+	if err != nil {
+		return err
+	}
+
+	if carryOverCap.Valid {
+		p.CarryOverCap = &carryOverCap.Float64
+	}
+
+	if p.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// scanLeaveRequest scans a row selected with leaveRequestSelect into req.
+func scanLeaveRequest(row scanner, req *LeaveRequest) error {
+	var decidedBy sql.NullInt64
+	var decidedAt, createdAt []byte
+
+	err := row.Scan(
+		&req.ID,
+		&req.UserID,
+		&req.LeaveTypeID,
+		&req.LeaveType,
+		&req.StartDate,
+		&req.EndDate,
+		&req.HalfDay,
+		&req.Days,
+		&req.Reason,
+		&req.Status,
+		&decidedBy,
+		&decidedAt,
+		&createdAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if decidedBy.Valid {
+		req.DecidedBy = &decidedBy.Int64
+	}
+
+	if decidedAt != nil {
+		t, err := parseDBTime(string(decidedAt))
+		if err != nil {
+			return err
+		}
+		req.DecidedAt = &t
+	}
+
+	if req.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeLeaveBalance(t *testing.T) {
+	capAt := func(days float64) *float64 { return &days }
+
+	// taken returns approved full days of request 1
+	taken := func(dates ...string) []LeaveDay {
+		days := make([]LeaveDay, len(dates))
+		for i, d := range dates {
+			days[i] = LeaveDay{Date: d, RequestID: 1, Amount: 1}
+		}
+		return days
+	}
+
+	tests := []struct {
+		name     string
+		policy   LeavePolicy
+		startsOn string
+		asOf     string
+		days     []LeaveDay
+		pending  map[int64]bool
+		want     LeaveBalance // Only the day counts are compared
+		wantErr  bool
+	}{
+		{
+			name:     "credits from the month after a mid-month start",
+			policy:   LeavePolicy{DaysPerMonth: 2},
+			startsOn: "2024-03-15",
+			asOf:     "2024-06-30",
+			want:     LeaveBalance{Accrued: 6, Balance: 6},
+		},
+		{
+			name:     "credit on the first day of the assignment",
+			policy:   LeavePolicy{DaysPerMonth: 2},
+			startsOn: "2024-03-01",
+			asOf:     "2024-03-01",
+			want:     LeaveBalance{Accrued: 2, Balance: 2},
+		},
+		{
+			name:     "before the assignment starts",
+			policy:   LeavePolicy{DaysPerMonth: 2},
+			startsOn: "2024-03-01",
+			asOf:     "2024-02-01",
+		},
+		{
+			name:     "fractional credits are rounded",
+			policy:   LeavePolicy{DaysPerMonth: 2.08},
+			startsOn: "2024-01-01",
+			asOf:     "2024-12-31",
+			want:     LeaveBalance{Accrued: 24.96, Balance: 24.96},
+		},
+		{
+			name:     "whole balance carries over without a cap",
+			policy:   LeavePolicy{DaysPerMonth: 2},
+			startsOn: "2023-01-01",
+			asOf:     "2024-02-15",
+			days:     taken("2023-07-03", "2023-07-04", "2023-07-05", "2023-07-06"),
+			want:     LeaveBalance{Accrued: 28, Taken: 4, Balance: 24},
+		},
+		{
+			name:     "excess above the cap is forfeited at the start of the year",
+			policy:   LeavePolicy{DaysPerMonth: 2, CarryOverCap: capAt(5)},
+			startsOn: "2023-01-01",
+			asOf:     "2024-02-15",
+			days:     taken("2023-07-03", "2023-07-04", "2023-07-05", "2023-07-06"),
+			want:     LeaveBalance{Accrued: 28, Forfeited: 15, Taken: 4, Balance: 9},
+		},
+		{
+			name:     "leave in the new year does not count against the old balance",
+			policy:   LeavePolicy{DaysPerMonth: 2, CarryOverCap: capAt(5)},
+			startsOn: "2023-01-01",
+			asOf:     "2024-02-15",
+			days:     taken("2024-01-10", "2024-01-11"),
+			want:     LeaveBalance{Accrued: 28, Forfeited: 19, Taken: 2, Balance: 7},
+		},
+		{
+			name:     "cap applies every year",
+			policy:   LeavePolicy{DaysPerMonth: 1, CarryOverCap: capAt(3)},
+			startsOn: "2022-01-01",
+			asOf:     "2024-01-01",
+			want:     LeaveBalance{Accrued: 25, Forfeited: 21, Balance: 4},
+		},
+		{
+			name:     "zero cap forfeits everything",
+			policy:   LeavePolicy{DaysPerMonth: 2, CarryOverCap: capAt(0)},
+			startsOn: "2023-01-01",
+			asOf:     "2024-01-01",
+			want:     LeaveBalance{Accrued: 26, Forfeited: 24, Balance: 2},
+		},
+		{
+			name:     "negative balance is carried over",
+			policy:   LeavePolicy{DaysPerMonth: 1, CarryOverCap: capAt(5)},
+			startsOn: "2023-11-01",
+			asOf:     "2024-01-01",
+			days:     taken("2023-12-04", "2023-12-05", "2023-12-06"),
+			want:     LeaveBalance{Accrued: 3, Taken: 3, Balance: 0},
+		},
+		{
+			name:     "leave before the start counts against the first year",
+			policy:   LeavePolicy{DaysPerMonth: 2},
+			startsOn: "2024-01-01",
+			asOf:     "2024-01-01",
+			days:     taken("2023-12-29"),
+			want:     LeaveBalance{Accrued: 2, Taken: 1, Balance: 1},
+		},
+		{
+			name:     "pending and booked days are not deducted",
+			policy:   LeavePolicy{DaysPerMonth: 2},
+			startsOn: "2024-01-01",
+			asOf:     "2024-02-15",
+			days: []LeaveDay{
+				{Date: "2024-02-01", RequestID: 1, Amount: 1},
+				{Date: "2024-02-16", RequestID: 1, Amount: 1},
+				{Date: "2024-02-20", RequestID: 2, Amount: 0.5},
+				{Date: "2024-01-20", RequestID: 2, Amount: 1},
+			},
+			pending: map[int64]bool{2: true},
+			want:    LeaveBalance{Accrued: 4, Taken: 1, Booked: 1, Pending: 1.5, Balance: 3},
+		},
+		{
+			name:     "invalid date",
+			policy:   LeavePolicy{DaysPerMonth: 2},
+			startsOn: "2024-13-01",
+			asOf:     "2024-02-15",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ComputeLeaveBalance(&tt.policy, tt.startsOn, tt.asOf, tt.days, tt.pending)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Accrued != tt.want.Accrued || got.Forfeited != tt.want.Forfeited || got.Taken != tt.want.Taken ||
+				got.Booked != tt.want.Booked || got.Pending != tt.want.Pending || got.Balance != tt.want.Balance {
+				t.Errorf("got accrued %v, forfeited %v, taken %v, booked %v, pending %v, balance %v, want %+v",
+					got.Accrued, got.Forfeited, got.Taken, got.Booked, got.Pending, got.Balance, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaveDates(t *testing.T) {
+	from := time.Date(2024, time.December, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.December, 27, 0, 0, 0, 0, time.UTC)
+	holidays := map[string]bool{"2024-12-25": true, "2024-12-26": true}
+
+	days := LeaveDates(from, to, time.UTC, holidays, false)
+
+	want := []string{"2024-12-20", "2024-12-23", "2024-12-24", "2024-12-27"}
+	if len(days) != len(want) {
+		t.Fatalf("got %v, want %v", days, want)
+	}
+	for i, d := range days {
+		if d.Date != want[i] || d.Amount != 1 {
+			t.Errorf("day %d: got %+v, want %s", i, d, want[i])
+		}
+	}
+
+	if half := LeaveDates(from, from, time.UTC, nil, true); len(half) != 1 || half[0].Amount != 0.5 {
+		t.Errorf("got half day %+v", half)
+	}
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTransition is returned when a stamp does not fit the user's stamp sequence.
+var ErrInvalidTransition = errors.New("invalid timestamp transition")
+
+// LedgerViolation describes a stamp that breaks the sign-in/sign-out sequence
+// when the user's approved stamps are replayed in time order.
+type LedgerViolation struct {
+	Position     int    `json:"position"` // zero-based index in the time-ordered ledger
+	TimestampID  int64  `json:"timestamp_id"`
+	StampType    string `json:"stamp_type"`
+	PreviousType string `json:"previous_type"`
+	Message      string `json:"message"`
+}
+
+// LedgerError is returned when a change would leave the user's ledger in an invalid state.
+type LedgerError struct {
+	Violations []LedgerViolation
+}
+
+func (e *LedgerError) Error() string {
+	positions := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		positions[i] = fmt.Sprint(v.Position)
+	}
+
+	return fmt.Sprintf("invalid timestamp sequence at positions %s", strings.Join(positions, ", "))
+}
+
+// checkTransition validates that a stamp of type next may follow a stamp of type prev in graph.
+// An empty prev means the user has no previous stamps.
+func checkTransition(graph *StampGraph, prev, next string) error {
+	if !graph.Has(next) {
+		return fmt.Errorf("%w: invalid stamp type", ErrInvalidTransition)
+	}
+
+	// Handle case where no previous timestamps exist (first action must start a shift)
+	if prev == "" {
+		if !graph.Allows(prev, next) {
+			return fmt.Errorf("%w: first action must start a shift", ErrInvalidTransition)
+		}
+		return nil
+	}
+
+	// Ensure no duplicate consecutive timestamps
+	if prev == next {
+		return fmt.Errorf("%w: duplicate timestamp", ErrInvalidTransition)
+	}
+
+	if !graph.Allows(prev, next) {
+		return fmt.Errorf("%w: invalid transition from %s to %s", ErrInvalidTransition, prev, next)
+	}
+
+	return nil
+}
+
+// CheckLedger replays a time-ordered stamp sequence through the transition
+// graph and returns every position that breaks it.
+func CheckLedger(graph *StampGraph, stamps []Timestamp) []LedgerViolation {
+	violations := make([]LedgerViolation, 0)
+
+	prev := ""
+	for i, stamp := range stamps {
+		if err := checkTransition(graph, prev, stamp.StampType); err != nil {
+			violations = append(violations, LedgerViolation{
+				Position:     i,
+				TimestampID:  stamp.ID,
+				StampType:    stamp.StampType,
+				PreviousType: prev,
+				Message:      err.Error(),
+			})
+		}
+
+		prev = stamp.StampType
+	}
+
+	return violations
+}
+
+// introducedViolations returns the violations in after that were not already present in before,
+// so that edits are not blocked by unrelated problems further back in the ledger.
+func introducedViolations(before, after []LedgerViolation) []LedgerViolation {
+	existing := make(map[string]bool, len(before))
+	for _, v := range before {
+		existing[fmt.Sprintf("%d:%s", v.TimestampID, v.PreviousType)] = true
+	}
+
+	introduced := make([]LedgerViolation, 0)
+	for _, v := range after {
+		if !existing[fmt.Sprintf("%d:%s", v.TimestampID, v.PreviousType)] {
+			introduced = append(introduced, v)
+		}
+	}
+
+	return introduced
+}
+
+// ValidateLedger godoc
+//
+//	@Summary		Validates a user's ledger
+//	@Description	Replays all approved stamps of a user and returns the positions that break the sequence
+//	@Tags			timestamps
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	[]LedgerViolation
+//	@Failure		500		{object}	error
+//	@Router			/timestamps/lint/{userID} [get]
+func (s *TimestampStore) ValidateLedger(ctx context.Context, userID int64) ([]LedgerViolation, error) {
+	var violations []LedgerViolation
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		graph, err := getStampGraph(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		stamps, err := s.getLedger(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		violations = CheckLedger(graph, stamps)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return violations, nil
+}
+
+// getLedger loads the approved stamps of a user in time order within the given transaction.
+func (s *TimestampStore) getLedger(ctx context.Context, tx *sql.Tx, userID int64) ([]Timestamp, error) {
+	query := `
+		SELECT id, user_id, stamp_type, time, created_at, updated_at, version,
+			status, is_manual, reason, decided_by, decided_at, is_auto_generated, kiosk_id
+		FROM timestamps
+		WHERE user_id = ? AND status = 'approved'
+		ORDER BY time ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stamps []Timestamp
+	for rows.Next() {
+		var t Timestamp
+		if err := scanTimestamp(rows, &t); err != nil {
+			return nil, err
+		}
+
+		stamps = append(stamps, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stamps, nil
+}
+
+// revalidate locks the user, runs change inside tx and fails with a *LedgerError if it introduces
+// new violations into the user's ledger.
+func (s *TimestampStore) revalidate(ctx context.Context, tx *sql.Tx, userID int64, change func() error) error {
+	if err := lockUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	graph, err := getStampGraph(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	before, err := s.getLedger(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	after, err := s.getLedger(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	introduced := introducedViolations(CheckLedger(graph, before), CheckLedger(graph, after))
+	if len(introduced) > 0 {
+		return &LedgerError{Violations: introduced}
+	}
+
+	return nil
+}
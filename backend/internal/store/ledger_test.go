@@ -0,0 +1,120 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+// testLedger returns stamps of the given types with IDs counting up from 1.
+func testLedger(types ...string) []Timestamp {
+	stamps := make([]Timestamp, len(types))
+	for i, t := range types {
+		stamps[i] = Timestamp{ID: int64(i + 1), StampType: t}
+	}
+	return stamps
+}
+
+func TestCheckLedger(t *testing.T) {
+	graph := testStampGraph()
+
+	tests := []struct {
+		name  string
+		types []string
+		want  []int // Positions of the violations
+	}{
+		{name: "empty", types: nil, want: []int{}},
+		{name: "single shift", types: []string{"sign-in", "sign-out"}, want: []int{}},
+		{name: "open shift", types: []string{"sign-out", "sign-in", "start-break"}, want: []int{0}},
+		{
+			name:  "breaks",
+			types: []string{"sign-in", "start-break", "end-break", "start-break", "end-break", "sign-out"},
+			want:  []int{},
+		},
+		{name: "first stamp is not a start", types: []string{"start-break", "end-break", "sign-out"}, want: []int{0}},
+		{name: "duplicate", types: []string{"sign-in", "sign-in", "sign-out"}, want: []int{1}},
+		{name: "missing sign-out", types: []string{"sign-in", "sign-out", "start-break", "sign-in"}, want: []int{2, 3}},
+		{name: "unknown type", types: []string{"sign-in", "lunch", "sign-out"}, want: []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := CheckLedger(graph, testLedger(tt.types...))
+
+			got := make([]int, len(violations))
+			for i, v := range violations {
+				got[i] = v.Position
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got violations at %v, want %v", got, tt.want)
+			}
+
+			for _, v := range violations {
+				if v.TimestampID != int64(v.Position+1) || v.StampType != tt.types[v.Position] {
+					t.Errorf("violation %+v does not describe its stamp", v)
+				}
+				if v.Position > 0 && v.PreviousType != tt.types[v.Position-1] {
+					t.Errorf("violation %+v has the wrong previous type", v)
+				}
+			}
+		})
+	}
+}
+
+func TestIntroducedViolations(t *testing.T) {
+	graph := testStampGraph()
+
+	in := func(id int64) Timestamp { return Timestamp{ID: id, StampType: "sign-in"} }
+	out := func(id int64) Timestamp { return Timestamp{ID: id, StampType: "sign-out"} }
+	brk := func(id int64) Timestamp { return Timestamp{ID: id, StampType: "start-break"} }
+
+	tests := []struct {
+		name          string
+		before, after []Timestamp
+		want          []int64 // IDs of the stamps with introduced violations
+	}{
+		{
+			name:   "valid ledger stays valid",
+			before: []Timestamp{in(1), out(2)},
+			after:  []Timestamp{in(1), out(2), in(3)},
+			want:   []int64{},
+		},
+		{
+			name:   "deleting a sign-out",
+			before: []Timestamp{in(1), out(2), in(3)},
+			after:  []Timestamp{in(1), in(3)},
+			want:   []int64{3},
+		},
+		{
+			name:   "old violation is not blamed on the edit",
+			before: []Timestamp{in(1), in(2), out(3)},
+			after:  []Timestamp{in(1), in(2), out(3), in(4)},
+			want:   []int64{},
+		},
+		{
+			name:   "old violation of a stamp with a new predecessor",
+			before: []Timestamp{in(1), in(2), out(3)},
+			after:  []Timestamp{in(1), brk(4), in(2), out(3)},
+			want:   []int64{2},
+		},
+		{
+			name:   "fixing a violation",
+			before: []Timestamp{in(1), in(2), out(3)},
+			after:  []Timestamp{in(1), out(3)},
+			want:   []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			introduced := introducedViolations(CheckLedger(graph, tt.before), CheckLedger(graph, tt.after))
+
+			got := make([]int64, len(introduced))
+			for i, v := range introduced {
+				got[i] = v.TimestampID
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got introduced violations of %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Scopes of login throttles.
+const (
+	LoginScopeAccount = "account"
+	LoginScopeIP      = "ip"
+)
+
+// LoginPolicy throttles password guessing. Every attempt is counted as a failure until it
+// succeeds. Beyond FreeAttempts consecutive failures the wait before the next attempt doubles,
+// starting at BaseDelay, and MaxFailures failures lock for Lockout. Failures are forgotten once
+// none happened for Window.
+type LoginPolicy struct {
+	FreeAttempts int
+	MaxFailures  int
+	BaseDelay    time.Duration
+	Lockout      time.Duration
+	Window       time.Duration
+}
+
+// delay returns how long the next attempt has to wait after the given number of consecutive failures.
+func (p LoginPolicy) delay(failures int) time.Duration {
+	switch {
+	case failures >= p.MaxFailures:
+		return p.Lockout
+	case failures <= p.FreeAttempts:
+		return 0
+	}
+
+	delay := p.BaseDelay << (failures - p.FreeAttempts - 1)
+	if delay <= 0 || delay > p.Lockout {
+		return p.Lockout
+	}
+
+	return delay
+}
+
+// LoginAttempt is the outcome of counting a login attempt.
+type LoginAttempt struct {
+	RetryAfter time.Duration // Non-zero if the attempt was refused because the account or IP address has to wait
+	Locks      bool          // Whether the account is locked if this attempt fails
+}
+
+// LoginThrottle is the failed-login state of an account or IP address.
+type LoginThrottle struct {
+	Scope        string
+	Subject      string // Email address or IP address
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  *time.Time
+}
+
+// LoginThrottleStore provides methods for tracking failed logins per account and IP address.
+type LoginThrottleStore struct {
+	db *sql.DB
+}
+
+// Attempt godoc
+//
+//	@Summary		Counts a login attempt
+//	@Description	Counts a login attempt for an email address and an IP address, before the password is checked so that parallel guesses are throttled too. Attempts while either has to wait are refused and not counted.
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		201	{string}	string	"Token"
+//	@Failure		429	{object}	error
+//	@Failure		500	{object}	error
+func (s *LoginThrottleStore) Attempt(ctx context.Context, email, ip string, account, network LoginPolicy) (*LoginAttempt, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	attempt := &LoginAttempt{}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// Rows are always locked account first to avoid deadlocks
+		accountThrottle, err := getLoginThrottle(ctx, tx, LoginScopeAccount, normalizeEmail(email), account, now)
+		if err != nil {
+			return err
+		}
+
+		ipThrottle, err := getLoginThrottle(ctx, tx, LoginScopeIP, ip, network, now)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range []*LoginThrottle{accountThrottle, ipThrottle} {
+			if t.LockedUntil != nil && t.LockedUntil.After(now) && t.LockedUntil.Sub(now) > attempt.RetryAfter {
+				attempt.RetryAfter = t.LockedUntil.Sub(now)
+			}
+		}
+		if attempt.RetryAfter > 0 {
+			return nil
+		}
+
+		accountThrottle.Failures++
+		ipThrottle.Failures++
+		attempt.Locks = accountThrottle.Failures == account.MaxFailures
+
+		if err := saveLoginThrottle(ctx, tx, accountThrottle, account.delay(accountThrottle.Failures), now); err != nil {
+			return err
+		}
+
+		return saveLoginThrottle(ctx, tx, ipThrottle, network.delay(ipThrottle.Failures), now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
+// Succeed godoc
+//
+//	@Summary		Records a successful login
+//	@Description	Clears the failures of an account and takes the successful attempt back from the IP address, whose earlier failures stay counted
+//	@Tags			authentication
+//	@Success		201	{string}	string	"Token"
+//	@Failure		500	{object}	error
+func (s *LoginThrottleStore) Succeed(ctx context.Context, email, ip string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM login_throttles WHERE scope = ? AND subject = ?`,
+			LoginScopeAccount,
+			normalizeEmail(email),
+		); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			`UPDATE login_throttles SET failures = GREATEST(failures - 1, 0) WHERE scope = ? AND subject = ?`,
+			LoginScopeIP,
+			ip,
+		)
+		return err
+	})
+}
+
+// Unlock godoc
+//
+//	@Summary		Unlocks an account
+//	@Description	Clears the failed logins of an account. Returns ErrNotFound if it has none.
+//	@Tags			users
+//	@Success		204	{string}	string	"Account unlocked"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+func (s *LoginThrottleStore) Unlock(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM login_throttles WHERE scope = ? AND subject = ?`,
+		LoginScopeAccount,
+		normalizeEmail(email),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// getLoginThrottle selects and locks the throttle of a subject, starting over if its last failure
+// is older than the policy window.
+func getLoginThrottle(ctx context.Context, tx *sql.Tx, scope, subject string, policy LoginPolicy, now time.Time) (*LoginThrottle, error) {
+	t := &LoginThrottle{Scope: scope, Subject: subject}
+	var lastFailedAt, lockedUntil []byte
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT failures, last_failed_at, locked_until FROM login_throttles WHERE scope = ? AND subject = ? FOR UPDATE`,
+		scope,
+		subject,
+	).Scan(&t.Failures, &lastFailedAt, &lockedUntil)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return t, nil
+		default:
+			return nil, err
+		}
+	}
+
+	if t.LastFailedAt, err = parseDBTime(string(lastFailedAt)); err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		until, err := parseDBTime(string(lockedUntil))
+		if err != nil {
+			return nil, err
+		}
+		t.LockedUntil = &until
+	}
+
+	// A lock is waited out before its failures are forgotten
+	if now.Sub(t.LastFailedAt) > policy.Window && (t.LockedUntil == nil || !t.LockedUntil.After(now)) {
+		t.Failures = 0
+		t.LockedUntil = nil
+	}
+
+	return t, nil
+}
+
+// saveLoginThrottle stores a failure of t, locking it for delay if it is non-zero.
+func saveLoginThrottle(ctx context.Context, tx *sql.Tx, t *LoginThrottle, delay time.Duration, now time.Time) error {
+	t.LastFailedAt = now
+	t.LockedUntil = nil
+	if delay > 0 {
+		until := now.Add(delay)
+		t.LockedUntil = &until
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO login_throttles (scope, subject, failures, last_failed_at, locked_until) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE failures = VALUES(failures), last_failed_at = VALUES(last_failed_at), locked_until = VALUES(locked_until)`,
+		t.Scope,
+		t.Subject,
+		t.Failures,
+		t.LastFailedAt,
+		t.LockedUntil,
+	)
+	return err
+}
+
+// normalizeEmail returns the form an email address is throttled under.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginPolicyDelay(t *testing.T) {
+	policy := LoginPolicy{
+		FreeAttempts: 3,
+		MaxFailures:  10,
+		BaseDelay:    time.Second,
+		Lockout:      30 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		policy   *LoginPolicy // Defaults to policy
+		failures int
+		want     time.Duration
+	}{
+		{name: "no failures", failures: 0, want: 0},
+		{name: "last free attempt", failures: 3, want: 0},
+		{name: "first delayed attempt", failures: 4, want: time.Second},
+		{name: "delay doubles", failures: 5, want: 2 * time.Second},
+		{name: "last attempt before the lockout", failures: 9, want: 32 * time.Second},
+		{name: "lockout", failures: 10, want: 30 * time.Minute},
+		{name: "after the lockout", failures: 25, want: 30 * time.Minute},
+		{
+			name:     "delay is capped at the lockout",
+			policy:   &LoginPolicy{MaxFailures: 1000, BaseDelay: time.Second, Lockout: time.Hour},
+			failures: 13,
+			want:     time.Hour,
+		},
+		{
+			name:     "overflowing delay",
+			policy:   &LoginPolicy{MaxFailures: 1000, BaseDelay: time.Second, Lockout: time.Hour},
+			failures: 100,
+			want:     time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := policy
+			if tt.policy != nil {
+				p = *tt.policy
+			}
+
+			if got := p.delay(tt.failures); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
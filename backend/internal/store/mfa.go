@@ -0,0 +1,446 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrCodeUsed is returned when a one-time code or recovery code was already used.
+	ErrCodeUsed = errors.New("code was already used")
+	// ErrChallengeExpired is returned when an MFA challenge has expired or ran out of attempts.
+	ErrChallengeExpired = errors.New("multi-factor challenge has expired")
+)
+
+// TOTP is the authenticator app secret of a user. Enrollment is pending until the user confirms
+// a first code, which sets EnabledAt.
+type TOTP struct {
+	UserID      int64
+	Secret      string // Base32 secret, sealed with the key encryption key if one is configured
+	LastCounter int64  // Time step of the last accepted code, which may not be used again
+	CreatedAt   time.Time
+	EnabledAt   *time.Time
+}
+
+// Enabled reports whether the user has confirmed the enrollment.
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// MFAChallenge is the second login step of a user who entered the right password. It is
+// completed with a one-time code within its lifetime and a limited number of attempts.
+type MFAChallenge struct {
+	UserID    int64
+	Device    string // Device name the session is started with once the challenge is completed
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+// MFAStore provides methods for managing TOTP secrets, recovery codes and login challenges.
+type MFAStore struct {
+	db *sql.DB
+}
+
+// GetTOTP godoc
+//
+//	@Summary		Retrieves a user's TOTP secret
+//	@Description	Retrieves the pending or enabled TOTP secret of a user
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		200	{object}	TOTP
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/mfa [get]
+func (s *MFAStore) GetTOTP(ctx context.Context, userID int64) (*TOTP, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return getTOTP(ctx, s.db, userID, "")
+}
+
+// SetPendingTOTP godoc
+//
+//	@Summary		Starts a TOTP enrollment
+//	@Description	Stores a new secret for a user who has not enabled TOTP yet, replacing an unconfirmed one. Returns ErrConflict if TOTP is already enabled.
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		201	{string}	string	"Enrollment started"
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/mfa/totp [post]
+func (s *MFAStore) SetPendingTOTP(ctx context.Context, userID int64, secret string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		current, err := getTOTP(ctx, tx, userID, " FOR UPDATE")
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if current.Enabled() {
+			return ErrConflict
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`REPLACE INTO user_totp (user_id, secret, last_counter, created_at) VALUES (?, ?, 0, ?)`,
+			userID,
+			secret,
+			time.Now().UTC(),
+		)
+		return err
+	})
+}
+
+// EnableTOTP godoc
+//
+//	@Summary		Confirms a TOTP enrollment
+//	@Description	Enables the pending TOTP secret of a user after the code of time step counter was confirmed, and replaces the user's recovery codes
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		201	{object}	[]string
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/mfa/totp/confirm [post]
+func (s *MFAStore) EnableTOTP(ctx context.Context, userID, counter int64, recoveryCodes []string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		current, err := getTOTP(ctx, tx, userID, " FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		if current.Enabled() {
+			return ErrConflict
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE user_totp SET enabled_at = ?, last_counter = ? WHERE user_id = ?`,
+			time.Now().UTC(),
+			counter,
+			userID,
+		); err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	})
+}
+
+// UseTOTP godoc
+//
+//	@Summary		Records a used TOTP code
+//	@Description	Records that the code of time step counter was accepted. Returns ErrCodeUsed if it or a later code was accepted before.
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		200	{string}	string	"Code accepted"
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/authentication/mfa [post]
+func (s *MFAStore) UseTOTP(ctx context.Context, userID, counter int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE user_totp SET last_counter = ? WHERE user_id = ? AND last_counter < ? AND enabled_at IS NOT NULL`,
+		counter,
+		userID,
+		counter,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrCodeUsed
+	}
+
+	return nil
+}
+
+// DisableTOTP godoc
+//
+//	@Summary		Disables TOTP
+//	@Description	Deletes the TOTP secret and the recovery codes of a user
+//	@Tags			mfa
+//	@Success		204	{string}	string	"TOTP disabled"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/mfa/totp [delete]
+func (s *MFAStore) DisableTOTP(ctx context.Context, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = ?`, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID)
+		return err
+	})
+}
+
+// ReplaceRecoveryCodes godoc
+//
+//	@Summary		Replaces a user's recovery codes
+//	@Description	Replaces every recovery code of a user with new ones, which are stored hashed
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		201	{object}	[]string
+//	@Failure		500	{object}	error
+//	@Router			/mfa/recovery-codes [post]
+func (s *MFAStore) ReplaceRecoveryCodes(ctx context.Context, userID int64, codes []string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		return replaceRecoveryCodes(ctx, tx, userID, codes)
+	})
+}
+
+// CountRecoveryCodes godoc
+//
+//	@Summary		Counts a user's recovery codes
+//	@Description	Counts the recovery codes of a user that have not been used
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		200	{integer}	int
+//	@Failure		500	{object}	error
+//	@Router			/mfa [get]
+func (s *MFAStore) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL`,
+		userID,
+	).Scan(&count)
+
+	return count, err
+}
+
+// UseRecoveryCode godoc
+//
+//	@Summary		Uses a recovery code
+//	@Description	Marks an unused recovery code of a user as used. Returns ErrCodeUsed if the code is unknown or was used before.
+//	@Tags			mfa
+//	@Produce		json
+//	@Success		200	{string}	string	"Code accepted"
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/authentication/mfa [post]
+func (s *MFAStore) UseRecoveryCode(ctx context.Context, userID int64, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE mfa_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now().UTC(),
+		userID,
+		hashToken(code),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrCodeUsed
+	}
+
+	return nil
+}
+
+// CreateChallenge godoc
+//
+//	@Summary		Creates an MFA challenge
+//	@Description	Stores the hash of a challenge token that completes the login of a user until challenge.ExpiresAt
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		202	{object}	MFAChallenge
+//	@Failure		500	{object}	error
+//	@Router			/authentication/token [post]
+func (s *MFAStore) CreateChallenge(ctx context.Context, challenge *MFAChallenge, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO mfa_challenges (token_hash, user_id, device, attempts, created_at, expires_at) VALUES (?, ?, ?, 0, ?, ?)`,
+		hashToken(token),
+		challenge.UserID,
+		challenge.Device,
+		time.Now().UTC(),
+		challenge.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+// GetChallenge godoc
+//
+//	@Summary		Retrieves an MFA challenge
+//	@Description	Retrieves the challenge of a token. Returns ErrChallengeExpired once it expired or maxAttempts codes were wrong.
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		200	{object}	MFAChallenge
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/authentication/mfa [post]
+func (s *MFAStore) GetChallenge(ctx context.Context, token string, maxAttempts int) (*MFAChallenge, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var challenge MFAChallenge
+	var expiresAt []byte
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT user_id, device, attempts, expires_at FROM mfa_challenges WHERE token_hash = ?`,
+		hashToken(token),
+	).Scan(&challenge.UserID, &challenge.Device, &challenge.Attempts, &expiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if challenge.ExpiresAt, err = parseDBTime(string(expiresAt)); err != nil {
+		return nil, err
+	}
+
+	if !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= maxAttempts {
+		return nil, ErrChallengeExpired
+	}
+
+	return &challenge, nil
+}
+
+// FailChallenge godoc
+//
+//	@Summary		Records a wrong code
+//	@Description	Counts a wrong code entered for a challenge
+//	@Tags			authentication
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/authentication/mfa [post]
+func (s *MFAStore) FailChallenge(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash = ?`, hashToken(token))
+	return err
+}
+
+// CompleteChallenge godoc
+//
+//	@Summary		Completes an MFA challenge
+//	@Description	Deletes a challenge, together with the expired challenges of every user. Returns ErrNotFound if it was completed already.
+//	@Tags			authentication
+//	@Success		201	{string}	string	"Token"
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/authentication/mfa [post]
+func (s *MFAStore) CompleteChallenge(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE token_hash = ?`, hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE expires_at < ?`, time.Now().UTC())
+	return err
+}
+
+// getTOTP selects the TOTP secret of a user, with suffix appended to the query (e.g. FOR UPDATE).
+func getTOTP(ctx context.Context, q querier, userID int64, suffix string) (*TOTP, error) {
+	totp := &TOTP{UserID: userID}
+	var createdAt, enabledAt []byte
+
+	err := q.QueryRowContext(
+		ctx,
+		`SELECT secret, last_counter, created_at, enabled_at FROM user_totp WHERE user_id = ?`+suffix,
+		userID,
+	).Scan(&totp.Secret, &totp.LastCounter, &createdAt, &enabledAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if totp.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return nil, err
+	}
+	if enabledAt != nil {
+		t, err := parseDBTime(string(enabledAt))
+		if err != nil {
+			return nil, err
+		}
+		totp.EnabledAt = &t
+	}
+
+	return totp, nil
+}
+
+// replaceRecoveryCodes replaces the recovery codes of a user with the hashes of codes.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64, codes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, code := range codes {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)`,
+			userID,
+			hashToken(code),
+			now,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -3,12 +3,17 @@ package store
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sync"
 	"time"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:       &MockUserStore{},
+		Timestamps:  &MockTimestampStore{},
+		Sessions:    &MockSessionStore{},
+		Idempotency: NewMockIdempotencyStore(),
 	}
 }
 
@@ -30,6 +35,10 @@ func (m *MockUserStore) GetByEmail(context.Context, string) (*User, error) {
 	return &User{}, nil
 }
 
+func (m *MockUserStore) GetByManager(ctx context.Context, managerID int64) ([]*User, error) {
+	return []*User{}, nil
+}
+
 func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
 	return nil
 }
@@ -42,6 +51,10 @@ func (m *MockUserStore) Update(ctx context.Context, u *User) error {
 	return nil
 }
 
+func (m *MockUserStore) UpdateTimeZone(ctx context.Context, userID int64, timeZone string) error {
+	return nil
+}
+
 func (m *MockUserStore) ChangePassword(ctx context.Context, u *User) error {
 	return nil
 }
@@ -57,3 +70,140 @@ func (m *MockUserStore) RequestPasswordAndEmailReset(ctx context.Context, user *
 func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
+
+type MockTimestampStore struct{}
+
+func (m *MockTimestampStore) GetByID(ctx context.Context, id int64) (*Timestamp, error) {
+	return &Timestamp{ID: id}, nil
+}
+
+func (m *MockTimestampStore) Create(ctx context.Context, t *Timestamp) error {
+	return nil
+}
+
+func (m *MockTimestampStore) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockTimestampStore) Update(ctx context.Context, t *Timestamp) error {
+	return nil
+}
+
+func (m *MockTimestampStore) GetUserFeed(ctx context.Context, userID int64, q Query) ([]Timestamp, error) {
+	return []Timestamp{}, nil
+}
+
+func (m *MockTimestampStore) GetLatestTimestamp(ctx context.Context, userID int64) (*Timestamp, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockTimestampStore) GetFinishedShifts(ctx context.Context, userID int64, q ShiftQuery) ([]Shift, string, error) {
+	return []Shift{}, "", nil
+}
+
+func (m *MockTimestampStore) CreateManual(ctx context.Context, t *Timestamp) error {
+	return nil
+}
+
+func (m *MockTimestampStore) GetPending(ctx context.Context, managerID int64) ([]Timestamp, error) {
+	return []Timestamp{}, nil
+}
+
+func (m *MockTimestampStore) Decide(ctx context.Context, t *Timestamp, status string, managerID int64) error {
+	return nil
+}
+
+func (m *MockTimestampStore) ValidateLedger(ctx context.Context, userID int64) ([]LedgerViolation, error) {
+	return []LedgerViolation{}, nil
+}
+
+func (m *MockTimestampStore) GetCurrentShift(ctx context.Context, userID int64) (*CurrentShift, error) {
+	return &CurrentShift{}, nil
+}
+
+func (m *MockTimestampStore) GetOpenShifts(ctx context.Context, before time.Time) ([]Timestamp, error) {
+	return []Timestamp{}, nil
+}
+
+func (m *MockTimestampStore) AutoSignOut(ctx context.Context, userID int64, maxShift time.Duration) (*ClosedShift, error) {
+	return nil, ErrNotFound
+}
+
+type MockSessionStore struct{}
+
+func (m *MockSessionStore) Create(ctx context.Context, session *Session, refreshToken string) error {
+	return nil
+}
+
+func (m *MockSessionStore) Rotate(ctx context.Context, refreshToken, newRefreshToken string) (*Session, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockSessionStore) GetActive(ctx context.Context, userID int64) ([]Session, error) {
+	return []Session{}, nil
+}
+
+func (m *MockSessionStore) IsActive(ctx context.Context, userID, sessionID int64) (bool, error) {
+	return true, nil
+}
+
+func (m *MockSessionStore) Revoke(ctx context.Context, userID, sessionID int64) error {
+	return nil
+}
+
+func (m *MockSessionStore) RevokeAll(ctx context.Context, userID int64) (int64, error) {
+	return 0, nil
+}
+
+// MockIdempotencyStore keeps idempotency records in memory.
+type MockIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func NewMockIdempotencyStore() *MockIdempotencyStore {
+	return &MockIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+func (m *MockIdempotencyStore) Get(ctx context.Context, userID int64, key string) (*IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[mockIdempotencyKey(userID, key)]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (m *MockIdempotencyStore) Reserve(ctx context.Context, record *IdempotencyRecord) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := mockIdempotencyKey(record.UserID, record.Key)
+	if _, ok := m.records[key]; ok {
+		return false, nil
+	}
+	m.records[key] = *record
+	return true, nil
+}
+
+func (m *MockIdempotencyStore) Save(ctx context.Context, record *IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[mockIdempotencyKey(record.UserID, record.Key)] = *record
+	return nil
+}
+
+func (m *MockIdempotencyStore) Delete(ctx context.Context, userID int64, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, mockIdempotencyKey(userID, key))
+	return nil
+}
+
+func mockIdempotencyKey(userID int64, key string) string {
+	return fmt.Sprintf("%d-%s", userID, key)
+}
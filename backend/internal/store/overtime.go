@@ -0,0 +1,126 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// OvertimePolicy configures how worked time is split into regular time and overtime.
+// Thresholds are in minutes of net work time; a zero threshold disables that rule.
+type OvertimePolicy struct {
+	ID                int64          `json:"id"`
+	Name              string         `json:"name"`
+	DailyThreshold    int            `json:"daily_threshold"`  // Minutes per local day before daily overtime starts
+	WeeklyThreshold   int            `json:"weekly_threshold"` // Regular minutes per ISO week before weekly overtime starts
+	DailyMultiplier   float64        `json:"daily_multiplier"`
+	WeeklyMultiplier  float64        `json:"weekly_multiplier"`
+	RestDayMultiplier float64        `json:"rest_day_multiplier"`
+	HolidayMultiplier float64        `json:"holiday_multiplier"`
+	RestDays          []time.Weekday `json:"rest_days"` // 0 = Sunday ... 6 = Saturday
+	CreatedAt         time.Time      `json:"created_at"`
+}
+
+// OvertimeBreakdown splits net work time into regular time and the kinds of overtime.
+// Every worked minute is counted in exactly one bucket, in the order holiday, rest day,
+// daily and weekly overtime, so the buckets add up to the net work time.
+type OvertimeBreakdown struct {
+	RegularMinutes        float64 `json:"regular_minutes"`
+	DailyOvertimeMinutes  float64 `json:"daily_overtime_minutes"`
+	WeeklyOvertimeMinutes float64 `json:"weekly_overtime_minutes"`
+	RestDayMinutes        float64 `json:"rest_day_minutes"`
+	HolidayMinutes        float64 `json:"holiday_minutes"`
+	OvertimeMinutes       float64 `json:"overtime_minutes"` // All minutes outside the regular bucket
+	WeightedMinutes       float64 `json:"weighted_minutes"` // Minutes multiplied by the rate of their bucket
+}
+
+// Add adds the minutes of o to b.
+func (b *OvertimeBreakdown) Add(o OvertimeBreakdown) {
+	b.RegularMinutes += o.RegularMinutes
+	b.DailyOvertimeMinutes += o.DailyOvertimeMinutes
+	b.WeeklyOvertimeMinutes += o.WeeklyOvertimeMinutes
+	b.RestDayMinutes += o.RestDayMinutes
+	b.HolidayMinutes += o.HolidayMinutes
+	b.OvertimeMinutes += o.OvertimeMinutes
+	b.WeightedMinutes += o.WeightedMinutes
+}
+
+// isRestDay reports whether day is one of the policy's rest days.
+func (p *OvertimePolicy) isRestDay(day time.Weekday) bool {
+	for _, d := range p.RestDays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the overtime breakdown of every shift and of each of its days.
+// The shifts must be localized and in chronological order; holidays holds local dates (YYYY-MM-DD).
+// Pass the earlier shifts of the first shift's ISO week as well so that the weekly threshold is met
+// at the right point.
+func (p *OvertimePolicy) Apply(shifts []Shift, holidays map[string]bool) {
+	dayMinutes := make(map[string]float64)         // Minutes worked per local date
+	weekRegularMinutes := make(map[string]float64) // Regular minutes per ISO week
+
+	for i := range shifts {
+		shift := &shifts[i]
+		total := OvertimeBreakdown{}
+
+		for j := range shift.Days {
+			day := &shift.Days[j]
+			minutes := day.NetWorkTime / 60
+
+			date, err := time.Parse(time.DateOnly, day.Date)
+			if err != nil {
+				continue
+			}
+			year, week := date.ISOWeek()
+			weekKey := fmt.Sprintf("%04d-W%02d", year, week)
+
+			var b OvertimeBreakdown
+			switch {
+			case holidays[day.Date]:
+				b.HolidayMinutes = minutes
+			case p.isRestDay(date.Weekday()):
+				b.RestDayMinutes = minutes
+			default:
+				regular := minutes
+				if p.DailyThreshold > 0 {
+					regular = clamp(float64(p.DailyThreshold)-dayMinutes[day.Date], 0, minutes)
+				}
+				b.DailyOvertimeMinutes = minutes - regular
+
+				if p.WeeklyThreshold > 0 {
+					b.WeeklyOvertimeMinutes = clamp(weekRegularMinutes[weekKey]+regular-float64(p.WeeklyThreshold), 0, regular)
+				}
+				b.RegularMinutes = regular - b.WeeklyOvertimeMinutes
+
+				weekRegularMinutes[weekKey] += b.RegularMinutes
+			}
+			dayMinutes[day.Date] += minutes
+
+			b.OvertimeMinutes = b.DailyOvertimeMinutes + b.WeeklyOvertimeMinutes + b.RestDayMinutes + b.HolidayMinutes
+			b.WeightedMinutes = b.RegularMinutes +
+				b.DailyOvertimeMinutes*p.DailyMultiplier +
+				b.WeeklyOvertimeMinutes*p.WeeklyMultiplier +
+				b.RestDayMinutes*p.RestDayMultiplier +
+				b.HolidayMinutes*p.HolidayMultiplier
+
+			day.Overtime = &b
+			total.Add(b)
+		}
+
+		shift.Overtime = &total
+	}
+}
+
+// clamp limits v to [lo, hi].
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,346 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OvertimePolicyStore provides methods for managing overtime policies and their assignments.
+type OvertimePolicyStore struct {
+	db *sql.DB
+}
+
+// Create godoc
+//
+//	@Summary		Creates an overtime policy
+//	@Description	Inserts a new overtime policy into the database
+//	@Tags			overtime
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	OvertimePolicy
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies [post]
+func (s *OvertimePolicyStore) Create(ctx context.Context, policy *OvertimePolicy) error {
+	query := `
+		INSERT INTO overtime_policies (name, daily_threshold, weekly_threshold, daily_multiplier,
+			weekly_multiplier, rest_day_multiplier, holiday_multiplier, rest_days)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(
+		ctx,
+		query,
+		policy.Name,
+		policy.DailyThreshold,
+		policy.WeeklyThreshold,
+		policy.DailyMultiplier,
+		policy.WeeklyMultiplier,
+		policy.RestDayMultiplier,
+		policy.HolidayMultiplier,
+		formatWeekdays(policy.RestDays),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	policy.ID = id
+
+	return nil
+}
+
+// GetAll godoc
+//
+//	@Summary		Retrieves all overtime policies
+//	@Description	Retrieves all overtime policies ordered by name
+//	@Tags			overtime
+//	@Produce		json
+//	@Success		200	{object}	[]OvertimePolicy
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies [get]
+func (s *OvertimePolicyStore) GetAll(ctx context.Context) ([]OvertimePolicy, error) {
+	query := `
+		SELECT id, name, daily_threshold, weekly_threshold, daily_multiplier, weekly_multiplier,
+			rest_day_multiplier, holiday_multiplier, rest_days, created_at
+		FROM overtime_policies
+		ORDER BY name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]OvertimePolicy, 0)
+	for rows.Next() {
+		var p OvertimePolicy
+		if err := scanOvertimePolicy(rows, &p); err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// GetByID godoc
+//
+//	@Summary		Retrieves an overtime policy by ID
+//	@Description	Retrieves an overtime policy by its ID
+//	@Tags			overtime
+//	@Produce		json
+//	@Param			id	path		int	true	"Policy ID"
+//	@Success		200	{object}	OvertimePolicy
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies/{id} [get]
+func (s *OvertimePolicyStore) GetByID(ctx context.Context, id int64) (*OvertimePolicy, error) {
+	query := `
+		SELECT id, name, daily_threshold, weekly_threshold, daily_multiplier, weekly_multiplier,
+			rest_day_multiplier, holiday_multiplier, rest_days, created_at
+		FROM overtime_policies
+		WHERE id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var policy OvertimePolicy
+	if err := scanOvertimePolicy(s.db.QueryRowContext(ctx, query, id), &policy); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &policy, nil
+}
+
+// GetForUser godoc
+//
+//	@Summary		Retrieves the overtime policy of a user
+//	@Description	Retrieves the policy assigned to the user, or else the policy assigned to the user's role
+//	@Tags			overtime
+//	@Produce		json
+//	@Success		200	{object}	OvertimePolicy
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies/users/{userID} [get]
+func (s *OvertimePolicyStore) GetForUser(ctx context.Context, user *User) (*OvertimePolicy, error) {
+	// A user assignment overrides the assignment of the user's role
+	query := `
+		SELECT p.id, p.name, p.daily_threshold, p.weekly_threshold, p.daily_multiplier, p.weekly_multiplier,
+			p.rest_day_multiplier, p.holiday_multiplier, p.rest_days, p.created_at
+		FROM overtime_policy_assignments a
+		JOIN overtime_policies p ON p.id = a.policy_id
+		WHERE a.user_id = ? OR a.role_id = ?
+		ORDER BY a.user_id IS NULL ASC
+		LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var policy OvertimePolicy
+	if err := scanOvertimePolicy(s.db.QueryRowContext(ctx, query, user.ID, user.Role.ID), &policy); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &policy, nil
+}
+
+// Update godoc
+//
+//	@Summary		Updates an overtime policy
+//	@Description	Modifies an existing overtime policy
+//	@Tags			overtime
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Policy ID"
+//	@Success		200	{object}	OvertimePolicy
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies/{id} [patch]
+func (s *OvertimePolicyStore) Update(ctx context.Context, policy *OvertimePolicy) error {
+	query := `
+		UPDATE overtime_policies
+		SET name = ?, daily_threshold = ?, weekly_threshold = ?, daily_multiplier = ?,
+			weekly_multiplier = ?, rest_day_multiplier = ?, holiday_multiplier = ?, rest_days = ?
+		WHERE id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		policy.Name,
+		policy.DailyThreshold,
+		policy.WeeklyThreshold,
+		policy.DailyMultiplier,
+		policy.WeeklyMultiplier,
+		policy.RestDayMultiplier,
+		policy.HolidayMultiplier,
+		formatWeekdays(policy.RestDays),
+		policy.ID,
+	)
+	return err
+}
+
+// Delete godoc
+//
+//	@Summary		Deletes an overtime policy
+//	@Description	Removes an overtime policy and all of its assignments
+//	@Tags			overtime
+//	@Produce		json
+//	@Param			id	path		int	true	"Policy ID"
+//	@Success		204	{string}	string	"Policy deleted"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies/{id} [delete]
+func (s *OvertimePolicyStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM overtime_policies WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// AssignToUser godoc
+//
+//	@Summary		Assigns an overtime policy to a user
+//	@Description	Replaces the user's overtime policy assignment
+//	@Tags			overtime
+//	@Produce		json
+//	@Success		204	{string}	string	"Policy assigned"
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies/{id}/users/{userID} [put]
+func (s *OvertimePolicyStore) AssignToUser(ctx context.Context, policyID, userID int64) error {
+	return s.assign(ctx, policyID, "user_id", userID)
+}
+
+// AssignToRole godoc
+//
+//	@Summary		Assigns an overtime policy to a role
+//	@Description	Replaces the role's overtime policy assignment
+//	@Tags			overtime
+//	@Produce		json
+//	@Success		204	{string}	string	"Policy assigned"
+//	@Failure		500	{object}	error
+//	@Router			/overtime-policies/{id}/roles/{roleID} [put]
+func (s *OvertimePolicyStore) AssignToRole(ctx context.Context, policyID, roleID int64) error {
+	return s.assign(ctx, policyID, "role_id", roleID)
+}
+
+// assign replaces the assignment of the user or role in column (user_id or role_id).
+func (s *OvertimePolicyStore) assign(ctx context.Context, policyID int64, column string, id int64) error {
+	query := `
+		INSERT INTO overtime_policy_assignments (policy_id, ` + column + `)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE policy_id = VALUES(policy_id)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, policyID, id)
+	return err
+}
+
+// scanOvertimePolicy scans a row of the overtime_policies column list into p.
+func scanOvertimePolicy(row scanner, p *OvertimePolicy) error {
+	var restDays string
+	var createdAt []byte
+
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.DailyThreshold,
+		&p.WeeklyThreshold,
+		&p.DailyMultiplier,
+		&p.WeeklyMultiplier,
+		&p.RestDayMultiplier,
+		&p.HolidayMultiplier,
+		&restDays,
+		&createdAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if p.RestDays, err = parseWeekdays(restDays); err != nil {
+		return err
+	}
+
+	if p.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// formatWeekdays stores weekdays as a comma-separated list, e.g. "0,6".
+func formatWeekdays(days []time.Weekday) string {
+	parts := make([]string, len(days))
+	for i, d := range days {
+		parts[i] = strconv.Itoa(int(d))
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseWeekdays reads a list written by formatWeekdays.
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	days := make([]time.Weekday, 0)
+	if s == "" {
+		return days, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		d, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, time.Weekday(d))
+	}
+
+	return days, nil
+}
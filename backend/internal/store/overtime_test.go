@@ -0,0 +1,199 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// testShift returns a shift without breaks starting at start (YYYY-MM-DD hh:mm, UTC) localized in UTC.
+func testShift(t *testing.T, start string, length time.Duration) Shift {
+	t.Helper()
+
+	signIn, err := time.Parse("2006-01-02 15:04", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shift := Shift{SignIn: signIn, SignOut: signIn.Add(length)}
+	shift.NetWorkTime = length.Seconds()
+	shift.localize(time.UTC)
+
+	return shift
+}
+
+func TestOvertimePolicyApply(t *testing.T) {
+	policy := OvertimePolicy{
+		DailyThreshold:    8 * 60,
+		WeeklyThreshold:   40 * 60,
+		DailyMultiplier:   1.5,
+		WeeklyMultiplier:  1.25,
+		RestDayMultiplier: 2,
+		HolidayMultiplier: 2.5,
+		RestDays:          []time.Weekday{time.Sunday},
+	}
+
+	type shift struct {
+		start  string
+		length time.Duration
+	}
+
+	// 2024-01-08 is a Monday
+	tests := []struct {
+		name     string
+		policy   *OvertimePolicy // Defaults to policy
+		shifts   []shift
+		holidays []string
+		want     []OvertimeBreakdown
+	}{
+		{
+			name:   "below the daily threshold",
+			shifts: []shift{{"2024-01-08 08:00", 7 * time.Hour}},
+			want:   []OvertimeBreakdown{{RegularMinutes: 420, WeightedMinutes: 420}},
+		},
+		{
+			name:   "daily threshold",
+			shifts: []shift{{"2024-01-08 08:00", 10 * time.Hour}},
+			want: []OvertimeBreakdown{
+				{RegularMinutes: 480, DailyOvertimeMinutes: 120, OvertimeMinutes: 120, WeightedMinutes: 480 + 120*1.5},
+			},
+		},
+		{
+			name: "second shift of a day crosses the daily threshold",
+			shifts: []shift{
+				{"2024-01-08 06:00", 4 * time.Hour},
+				{"2024-01-08 12:00", 6 * time.Hour},
+			},
+			want: []OvertimeBreakdown{
+				{RegularMinutes: 240, WeightedMinutes: 240},
+				{RegularMinutes: 240, DailyOvertimeMinutes: 120, OvertimeMinutes: 120, WeightedMinutes: 240 + 120*1.5},
+			},
+		},
+		{
+			name: "shift crossing midnight counts towards each day",
+			shifts: []shift{
+				{"2024-01-08 14:00", 12 * time.Hour},
+			},
+			want: []OvertimeBreakdown{
+				// 600 minutes on Monday, 120 on Tuesday
+				{RegularMinutes: 600, DailyOvertimeMinutes: 120, OvertimeMinutes: 120, WeightedMinutes: 600 + 120*1.5},
+			},
+		},
+		{
+			name: "shift crossing the weekly threshold",
+			shifts: []shift{
+				{"2024-01-08 08:00", 8 * time.Hour},
+				{"2024-01-09 08:00", 8 * time.Hour},
+				{"2024-01-10 08:00", 8 * time.Hour},
+				{"2024-01-11 08:00", 8 * time.Hour},
+				{"2024-01-12 08:00", 7 * time.Hour},
+				{"2024-01-13 08:00", 6 * time.Hour},
+			},
+			want: []OvertimeBreakdown{
+				{RegularMinutes: 480, WeightedMinutes: 480},
+				{RegularMinutes: 480, WeightedMinutes: 480},
+				{RegularMinutes: 480, WeightedMinutes: 480},
+				{RegularMinutes: 480, WeightedMinutes: 480},
+				{RegularMinutes: 420, WeightedMinutes: 420},
+				{RegularMinutes: 60, WeeklyOvertimeMinutes: 300, OvertimeMinutes: 300, WeightedMinutes: 60 + 300*1.25},
+			},
+		},
+		{
+			name: "daily overtime does not count towards the weekly threshold",
+			shifts: []shift{
+				{"2024-01-08 08:00", 10 * time.Hour},
+				{"2024-01-09 08:00", 10 * time.Hour},
+				{"2024-01-10 08:00", 10 * time.Hour},
+				{"2024-01-11 08:00", 10 * time.Hour},
+				{"2024-01-12 08:00", 8 * time.Hour},
+			},
+			want: []OvertimeBreakdown{
+				{RegularMinutes: 480, DailyOvertimeMinutes: 120, OvertimeMinutes: 120, WeightedMinutes: 480 + 120*1.5},
+				{RegularMinutes: 480, DailyOvertimeMinutes: 120, OvertimeMinutes: 120, WeightedMinutes: 480 + 120*1.5},
+				{RegularMinutes: 480, DailyOvertimeMinutes: 120, OvertimeMinutes: 120, WeightedMinutes: 480 + 120*1.5},
+				{RegularMinutes: 480, DailyOvertimeMinutes: 120, OvertimeMinutes: 120, WeightedMinutes: 480 + 120*1.5},
+				{RegularMinutes: 480, WeightedMinutes: 480},
+			},
+		},
+		{
+			name:   "weekly threshold restarts each ISO week",
+			policy: &OvertimePolicy{WeeklyThreshold: 10 * 60, WeeklyMultiplier: 1.25},
+			shifts: []shift{
+				{"2024-01-14 08:00", 8 * time.Hour},
+				{"2024-01-15 08:00", 8 * time.Hour},
+			},
+			want: []OvertimeBreakdown{
+				{RegularMinutes: 480, WeightedMinutes: 480},
+				{RegularMinutes: 480, WeightedMinutes: 480},
+			},
+		},
+		{
+			name:   "rest day",
+			shifts: []shift{{"2024-01-14 08:00", 4 * time.Hour}},
+			want:   []OvertimeBreakdown{{RestDayMinutes: 240, OvertimeMinutes: 240, WeightedMinutes: 240 * 2}},
+		},
+		{
+			name:     "holiday takes precedence over the daily threshold",
+			shifts:   []shift{{"2024-01-08 08:00", 10 * time.Hour}},
+			holidays: []string{"2024-01-08"},
+			want:     []OvertimeBreakdown{{HolidayMinutes: 600, OvertimeMinutes: 600, WeightedMinutes: 600 * 2.5}},
+		},
+		{
+			name:     "holiday on a rest day",
+			shifts:   []shift{{"2024-01-14 08:00", 4 * time.Hour}},
+			holidays: []string{"2024-01-14"},
+			want:     []OvertimeBreakdown{{HolidayMinutes: 240, OvertimeMinutes: 240, WeightedMinutes: 240 * 2.5}},
+		},
+		{
+			name:     "shift running into a holiday",
+			shifts:   []shift{{"2024-12-24 20:00", 8 * time.Hour}},
+			holidays: []string{"2024-12-25"},
+			want: []OvertimeBreakdown{
+				{RegularMinutes: 240, HolidayMinutes: 240, OvertimeMinutes: 240, WeightedMinutes: 240 + 240*2.5},
+			},
+		},
+		{
+			name:   "zero thresholds count everything as regular",
+			policy: &OvertimePolicy{DailyMultiplier: 1.5, WeeklyMultiplier: 1.25},
+			shifts: []shift{{"2024-01-08 06:00", 14 * time.Hour}},
+			want:   []OvertimeBreakdown{{RegularMinutes: 840, WeightedMinutes: 840}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := policy
+			if tt.policy != nil {
+				p = *tt.policy
+			}
+
+			shifts := make([]Shift, 0, len(tt.shifts))
+			for _, s := range tt.shifts {
+				shifts = append(shifts, testShift(t, s.start, s.length))
+			}
+
+			holidays := make(map[string]bool)
+			for _, h := range tt.holidays {
+				holidays[h] = true
+			}
+
+			p.Apply(shifts, holidays)
+
+			for i, shift := range shifts {
+				if shift.Overtime == nil {
+					t.Fatalf("shift %d has no overtime breakdown", i)
+				}
+				if *shift.Overtime != tt.want[i] {
+					t.Errorf("shift %d: got %+v, want %+v", i, *shift.Overtime, tt.want[i])
+				}
+
+				var days OvertimeBreakdown
+				for _, day := range shift.Days {
+					days.Add(*day.Overtime)
+				}
+				if days != *shift.Overtime {
+					t.Errorf("shift %d: days add up to %+v, want %+v", i, days, *shift.Overtime)
+				}
+			}
+		})
+	}
+}
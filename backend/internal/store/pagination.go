@@ -69,3 +69,49 @@ func parseTime(s string) string {
 
 	return t.Format(time.DateTime)
 }
+
+// ShiftQuery narrows finished shifts to a time window and a page.
+// Shifts are rendered and split into days in Location, which defaults to UTC.
+type ShiftQuery struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Limit    int            `json:"limit" validate:"gte=0,lte=500"`
+	Cursor   string         `json:"cursor" validate:"max=255"`
+	Location *time.Location `json:"-"`
+}
+
+// Parse reads from, to (RFC 3339), limit and cursor from the query string.
+func (sq ShiftQuery) Parse(r *http.Request) (ShiftQuery, error) {
+	qs := r.URL.Query()
+
+	if from := qs.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return sq, err
+		}
+
+		sq.From = t
+	}
+
+	if to := qs.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return sq, err
+		}
+
+		sq.To = t
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return sq, err
+		}
+
+		sq.Limit = l
+	}
+
+	sq.Cursor = qs.Get("cursor")
+
+	return sq, nil
+}
@@ -0,0 +1,391 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Pay period statuses. A period moves draft -> submitted -> approved -> locked;
+// a submitted period can be returned to draft and a locked period can be reopened.
+const (
+	PayPeriodStatusDraft     = "draft"
+	PayPeriodStatusSubmitted = "submitted"
+	PayPeriodStatusApproved  = "approved"
+	PayPeriodStatusLocked    = "locked"
+)
+
+var (
+	// ErrPeriodLocked is returned when a stamp change falls into a locked pay period.
+	ErrPeriodLocked = errors.New("timesheet period is locked")
+	// ErrInvalidPeriodTransition is returned when a pay period cannot move to the requested status.
+	ErrInvalidPeriodTransition = errors.New("invalid timesheet period transition")
+	// ErrReasonRequired is returned when a pay period is returned or reopened without a reason.
+	ErrReasonRequired = errors.New("a reason is required")
+)
+
+// payPeriodTransitions maps each status to the statuses it may move to.
+var payPeriodTransitions = map[string][]string{
+	PayPeriodStatusDraft:     {PayPeriodStatusSubmitted},
+	PayPeriodStatusSubmitted: {PayPeriodStatusApproved, PayPeriodStatusDraft},
+	PayPeriodStatusApproved:  {PayPeriodStatusLocked},
+	PayPeriodStatusLocked:    {PayPeriodStatusDraft},
+}
+
+// PayPeriod is one timesheet period of a user that goes through submission and approval.
+// StartsAt is inclusive and EndsAt exclusive, both in UTC.
+type PayPeriod struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Status    string    `json:"status"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// In converts the times of the period to loc for display.
+func (p *PayPeriod) In(loc *time.Location) {
+	p.StartsAt = p.StartsAt.In(loc)
+	p.EndsAt = p.EndsAt.In(loc)
+	p.CreatedAt = p.CreatedAt.In(loc)
+	p.UpdatedAt = p.UpdatedAt.In(loc)
+}
+
+// PayPeriodTransition records a status change of a pay period.
+type PayPeriodTransition struct {
+	ID         int64     `json:"id"`
+	PeriodID   int64     `json:"period_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    int64     `json:"actor_id"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PayPeriodStore provides methods for managing pay periods in the database.
+type PayPeriodStore struct {
+	db *sql.DB
+}
+
+// Create godoc
+//
+//	@Summary		Creates a pay period
+//	@Description	Inserts a draft pay period that must not overlap another period of the same user
+//	@Tags			pay-periods
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	PayPeriod
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/pay-periods [post]
+func (s *PayPeriodStore) Create(ctx context.Context, period *PayPeriod) error {
+	if !period.EndsAt.After(period.StartsAt) {
+		return errors.New("period must end after it starts")
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, period.UserID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var overlapping int
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT COUNT(*) FROM pay_periods WHERE user_id = ? AND starts_at < ? AND ends_at > ?`,
+			period.UserID,
+			period.EndsAt.UTC(),
+			period.StartsAt.UTC(),
+		).Scan(&overlapping)
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrConflict
+		}
+
+		period.Status = PayPeriodStatusDraft
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO pay_periods (user_id, starts_at, ends_at, status) VALUES (?, ?, ?, ?)`,
+			period.UserID,
+			period.StartsAt.UTC(),
+			period.EndsAt.UTC(),
+			period.Status,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		period.ID = id
+
+		return nil
+	})
+}
+
+// GetByID godoc
+//
+//	@Summary		Retrieves a pay period by ID
+//	@Description	Retrieves a pay period by its ID
+//	@Tags			pay-periods
+//	@Produce		json
+//	@Param			id	path		int	true	"Pay period ID"
+//	@Success		200	{object}	PayPeriod
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/pay-periods/{id} [get]
+func (s *PayPeriodStore) GetByID(ctx context.Context, id int64) (*PayPeriod, error) {
+	query := `
+		SELECT id, user_id, starts_at, ends_at, status, version, created_at, updated_at
+		FROM pay_periods
+		WHERE id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var period PayPeriod
+	if err := scanPayPeriod(s.db.QueryRowContext(ctx, query, id), &period); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &period, nil
+}
+
+// GetByUser godoc
+//
+//	@Summary		Retrieves the pay periods of a user
+//	@Description	Retrieves all pay periods of a user, newest first
+//	@Tags			pay-periods
+//	@Produce		json
+//	@Success		200	{object}	[]PayPeriod
+//	@Failure		500	{object}	error
+//	@Router			/pay-periods [get]
+func (s *PayPeriodStore) GetByUser(ctx context.Context, userID int64) ([]PayPeriod, error) {
+	query := `
+		SELECT id, user_id, starts_at, ends_at, status, version, created_at, updated_at
+		FROM pay_periods
+		WHERE user_id = ?
+		ORDER BY starts_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := make([]PayPeriod, 0)
+	for rows.Next() {
+		var p PayPeriod
+		if err := scanPayPeriod(rows, &p); err != nil {
+			return nil, err
+		}
+
+		periods = append(periods, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return periods, nil
+}
+
+// Transition godoc
+//
+//	@Summary		Changes the status of a pay period
+//	@Description	Moves a pay period to a new status and records the transition with its actor and reason
+//	@Tags			pay-periods
+//	@Produce		json
+//	@Success		200	{object}	PayPeriod
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/pay-periods/{id}/submit [patch]
+func (s *PayPeriodStore) Transition(ctx context.Context, period *PayPeriod, to string, actorID int64, reason string) error {
+	// Moving back to draft undoes someone's work, so it has to be explained
+	if to == PayPeriodStatusDraft && reason == "" {
+		return ErrReasonRequired
+	}
+
+	var updatedAt time.Time
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Serialise with stamp changes of the user so nothing slips in while the period is locked
+		if err := lockUser(ctx, tx, period.UserID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var from string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM pay_periods WHERE id = ? FOR UPDATE`, period.ID).Scan(&from)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		// The caller decided on the status it loaded; refuse if someone moved the period meanwhile
+		if from != period.Status || !contains(payPeriodTransitions[from], to) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidPeriodTransition, from, to)
+		}
+
+		updatedAt = time.Now().UTC()
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE pay_periods SET status = ?, version = version + 1, updated_at = ? WHERE id = ?`,
+			to,
+			updatedAt,
+			period.ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO pay_period_transitions (period_id, from_status, to_status, actor_id, reason) VALUES (?, ?, ?, ?, ?)`,
+			period.ID,
+			from,
+			to,
+			actorID,
+			reason,
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	period.Status = to
+	period.UpdatedAt = updatedAt
+	period.Version++
+
+	return nil
+}
+
+// GetTransitions godoc
+//
+//	@Summary		Retrieves the history of a pay period
+//	@Description	Retrieves all status transitions of a pay period in the order they happened
+//	@Tags			pay-periods
+//	@Produce		json
+//	@Param			id	path		int	true	"Pay period ID"
+//	@Success		200	{object}	[]PayPeriodTransition
+//	@Failure		500	{object}	error
+//	@Router			/pay-periods/{id}/transitions [get]
+func (s *PayPeriodStore) GetTransitions(ctx context.Context, periodID int64) ([]PayPeriodTransition, error) {
+	query := `
+		SELECT id, period_id, from_status, to_status, actor_id, reason, created_at
+		FROM pay_period_transitions
+		WHERE period_id = ?
+		ORDER BY created_at ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, periodID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := make([]PayPeriodTransition, 0)
+	for rows.Next() {
+		var t PayPeriodTransition
+		var createdAt []byte
+		if err := rows.Scan(&t.ID, &t.PeriodID, &t.FromStatus, &t.ToStatus, &t.ActorID, &t.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if t.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+			return nil, err
+		}
+
+		transitions = append(transitions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
+// scanPayPeriod scans a row of the pay_periods column list into p.
+func scanPayPeriod(row scanner, p *PayPeriod) error {
+	var startsAt, endsAt, createdAt, updatedAt []byte
+
+	err := row.Scan(&p.ID, &p.UserID, &startsAt, &endsAt, &p.Status, &p.Version, &createdAt, &updatedAt)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range []struct {
+		raw []byte
+		dst *time.Time
+	}{
+		{startsAt, &p.StartsAt},
+		{endsAt, &p.EndsAt},
+		{createdAt, &p.CreatedAt},
+		{updatedAt, &p.UpdatedAt},
+	} {
+		if *f.dst, err = parseDBTime(string(f.raw)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPeriodsOpen fails with ErrPeriodLocked if any of times falls into a locked pay period of the user.
+// Inside a transaction it must run after the user has been locked.
+func checkPeriodsOpen(ctx context.Context, q querier, userID int64, times ...time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	for _, t := range times {
+		var locked int
+		err := q.QueryRowContext(
+			ctx,
+			`SELECT COUNT(*) FROM pay_periods WHERE user_id = ? AND status = 'locked' AND starts_at <= ? AND ends_at > ?`,
+			userID,
+			t.UTC(),
+			t.UTC(),
+		).Scan(&locked)
+		if err != nil {
+			return err
+		}
+		if locked > 0 {
+			return ErrPeriodLocked
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,387 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Client is a customer that projects are done for.
+type Client struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Project groups the tasks done for a client.
+type Project struct {
+	ID         int64     `json:"id"`
+	ClientID   int64     `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Task is a unit of work within a project that users record time against.
+type Task struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProjectStore provides methods for managing clients, projects and tasks.
+type ProjectStore struct {
+	db *sql.DB
+}
+
+// CreateClient godoc
+//
+//	@Summary		Creates a client
+//	@Description	Inserts a client; client names are unique
+//	@Tags			projects
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	Client
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/clients [post]
+func (s *ProjectStore) CreateClient(ctx context.Context, client *Client) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM clients WHERE name = ?`, client.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	result, err := s.db.ExecContext(ctx, `INSERT INTO clients (name) VALUES (?)`, client.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	client.ID = id
+	client.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// GetClients godoc
+//
+//	@Summary		Retrieves all clients
+//	@Description	Retrieves all clients ordered by name
+//	@Tags			projects
+//	@Produce		json
+//	@Success		200	{object}	[]Client
+//	@Failure		500	{object}	error
+//	@Router			/clients [get]
+func (s *ProjectStore) GetClients(ctx context.Context) ([]Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, created_at FROM clients ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := make([]Client, 0)
+	for rows.Next() {
+		var c Client
+		var createdAt []byte
+		if err := rows.Scan(&c.ID, &c.Name, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if c.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+			return nil, err
+		}
+
+		clients = append(clients, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// GetClientByID godoc
+//
+//	@Summary		Retrieves a client by ID
+//	@Description	Retrieves a client by its ID
+//	@Tags			projects
+//	@Produce		json
+//	@Param			id	path		int	true	"Client ID"
+//	@Success		200	{object}	Client
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+func (s *ProjectStore) GetClientByID(ctx context.Context, id int64) (*Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var client Client
+	var createdAt []byte
+	err := s.db.QueryRowContext(ctx, `SELECT id, name, created_at FROM clients WHERE id = ?`, id).Scan(&client.ID, &client.Name, &createdAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if client.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// CreateProject godoc
+//
+//	@Summary		Creates a project
+//	@Description	Inserts a project for an existing client
+//	@Tags			projects
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	Project
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/projects [post]
+func (s *ProjectStore) CreateProject(ctx context.Context, project *Project) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `SELECT name FROM clients WHERE id = ?`, project.ClientID).Scan(&project.ClientName)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, `INSERT INTO projects (client_id, name) VALUES (?, ?)`, project.ClientID, project.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	project.ID = id
+	project.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// GetProjects godoc
+//
+//	@Summary		Retrieves projects
+//	@Description	Retrieves the projects of a client, or of all clients if clientID is 0
+//	@Tags			projects
+//	@Produce		json
+//	@Success		200	{object}	[]Project
+//	@Failure		500	{object}	error
+//	@Router			/projects [get]
+func (s *ProjectStore) GetProjects(ctx context.Context, clientID int64) ([]Project, error) {
+	query := `
+		SELECT p.id, p.client_id, c.name, p.name, p.created_at
+		FROM projects p
+		JOIN clients c ON c.id = p.client_id
+		WHERE ? = 0 OR p.client_id = ?
+		ORDER BY c.name ASC, p.name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, clientID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]Project, 0)
+	for rows.Next() {
+		var p Project
+		if err := scanProject(rows, &p); err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// GetProjectByID godoc
+//
+//	@Summary		Retrieves a project by ID
+//	@Description	Retrieves a project with the name of its client
+//	@Tags			projects
+//	@Produce		json
+//	@Param			id	path		int	true	"Project ID"
+//	@Success		200	{object}	Project
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/projects/{id} [get]
+func (s *ProjectStore) GetProjectByID(ctx context.Context, id int64) (*Project, error) {
+	query := `
+		SELECT p.id, p.client_id, c.name, p.name, p.created_at
+		FROM projects p
+		JOIN clients c ON c.id = p.client_id
+		WHERE p.id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var project Project
+	if err := scanProject(s.db.QueryRowContext(ctx, query, id), &project); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &project, nil
+}
+
+// CreateTask godoc
+//
+//	@Summary		Creates a task
+//	@Description	Inserts a task into a project
+//	@Tags			projects
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	Task
+//	@Failure		500	{object}	error
+//	@Router			/projects/{id}/tasks [post]
+func (s *ProjectStore) CreateTask(ctx context.Context, task *Task) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `INSERT INTO tasks (project_id, name) VALUES (?, ?)`, task.ProjectID, task.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	task.ID = id
+	task.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// GetTasks godoc
+//
+//	@Summary		Retrieves the tasks of a project
+//	@Description	Retrieves the tasks of a project ordered by name
+//	@Tags			projects
+//	@Produce		json
+//	@Param			id	path		int	true	"Project ID"
+//	@Success		200	{object}	[]Task
+//	@Failure		500	{object}	error
+//	@Router			/projects/{id}/tasks [get]
+func (s *ProjectStore) GetTasks(ctx context.Context, projectID int64) ([]Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, project_id, name, created_at FROM tasks WHERE project_id = ? ORDER BY name ASC`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]Task, 0)
+	for rows.Next() {
+		var t Task
+		if err := scanTask(rows, &t); err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// GetTaskByID godoc
+//
+//	@Summary		Retrieves a task by ID
+//	@Description	Retrieves a task by its ID
+//	@Tags			projects
+//	@Produce		json
+//	@Param			id	path		int	true	"Task ID"
+//	@Success		200	{object}	Task
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+func (s *ProjectStore) GetTaskByID(ctx context.Context, id int64) (*Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var task Task
+	row := s.db.QueryRowContext(ctx, `SELECT id, project_id, name, created_at FROM tasks WHERE id = ?`, id)
+	if err := scanTask(row, &task); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &task, nil
+}
+
+// scanProject scans a project row joined with its client name into p.
+func scanProject(row scanner, p *Project) error {
+	var createdAt []byte
+	if err := row.Scan(&p.ID, &p.ClientID, &p.ClientName, &p.Name, &createdAt); err != nil {
+		return err
+	}
+
+	var err error
+	p.CreatedAt, err = parseDBTime(string(createdAt))
+	return err
+}
+
+// scanTask scans a row of the tasks column list into t.
+func scanTask(row scanner, t *Task) error {
+	var createdAt []byte
+	if err := row.Scan(&t.ID, &t.ProjectID, &t.Name, &createdAt); err != nil {
+		return err
+	}
+
+	var err error
+	t.CreatedAt, err = parseDBTime(string(createdAt))
+	return err
+}
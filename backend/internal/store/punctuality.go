@@ -0,0 +1,181 @@
+package store
+
+import (
+	"time"
+)
+
+// Punctuality finding types.
+const (
+	FindingLateArrival     = "late_arrival"
+	FindingEarlyDeparture  = "early_departure"
+	FindingMissingWorkday  = "missing_workday"
+	FindingUnscheduledWork = "unscheduled_work"
+)
+
+// PunctualityGrace holds the default grace periods used when a work pattern does not set its own.
+type PunctualityGrace struct {
+	Late  time.Duration // How late a user may sign in before it counts as a late arrival
+	Early time.Duration // How early a user may sign out before it counts as an early departure
+}
+
+// PunctualityFinding describes a deviation of the recorded shifts from the work pattern on a local date.
+// Minutes is how late, how early, how long the missed workday was or how much unscheduled work was done.
+type PunctualityFinding struct {
+	Type         string     `json:"type"`
+	Date         string     `json:"date"`          // Local date (YYYY-MM-DD) of the workday
+	SignInID     *int64     `json:"sign_in_id"`    // The shift the finding is about, if any
+	Scheduled    *time.Time `json:"scheduled"`     // Scheduled start or end the shift is compared with
+	Actual       *time.Time `json:"actual"`        // Recorded sign-in or sign-out
+	Minutes      int        `json:"minutes"`       // Size of the deviation in whole minutes
+	GraceMinutes int        `json:"grace_minutes"` // Grace period that was allowed
+}
+
+// PunctualityReport compares the finished shifts of a user with the user's work pattern between two local dates.
+type PunctualityReport struct {
+	UserID             int64                `json:"user_id"`
+	TimeZone           string               `json:"time_zone"`
+	From               string               `json:"from"`
+	To                 string               `json:"to"`
+	Pattern            *WorkPattern         `json:"pattern"`
+	ScheduledDays      int                  `json:"scheduled_days"` // Workdays in the range that have ended
+	LateArrivals       int                  `json:"late_arrivals"`
+	LateMinutes        int                  `json:"late_minutes"`
+	EarlyDepartures    int                  `json:"early_departures"`
+	EarlyMinutes       int                  `json:"early_minutes"`
+	MissingWorkdays    int                  `json:"missing_workdays"`
+	UnscheduledShifts  int                  `json:"unscheduled_shifts"`
+	UnscheduledMinutes int                  `json:"unscheduled_minutes"`
+	Findings           []PunctualityFinding `json:"findings"`
+}
+
+// ComparePattern checks the shifts of user on the local dates from..to against pattern. Shifts must be
+// localized and in chronological order. Holidays are not workdays. Workdays that have not ended by now
+// are not reported as missing, nor checked for early departure.
+func ComparePattern(user *User, pattern *WorkPattern, grace PunctualityGrace, from, to time.Time, shifts []Shift, holidays map[string]bool, now time.Time) (*PunctualityReport, error) {
+	loc := user.Location()
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+
+	report := &PunctualityReport{
+		UserID:   user.ID,
+		TimeZone: loc.String(),
+		From:     from.Format(time.DateOnly),
+		To:       to.Format(time.DateOnly),
+		Pattern:  pattern,
+		Findings: make([]PunctualityFinding, 0),
+	}
+
+	start, err := time.Parse("15:04", pattern.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse("15:04", pattern.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	lateGrace := grace.Late
+	if pattern.LateGraceMinutes != nil {
+		lateGrace = time.Duration(*pattern.LateGraceMinutes) * time.Minute
+	}
+	earlyGrace := grace.Early
+	if pattern.EarlyGraceMinutes != nil {
+		earlyGrace = time.Duration(*pattern.EarlyGraceMinutes) * time.Minute
+	}
+
+	byWorkday := make(map[string][]Shift)
+	for _, sh := range shifts {
+		byWorkday[sh.Workday] = append(byWorkday[sh.Workday], sh)
+	}
+
+	days := weekdayMask(pattern.Weekdays)
+
+	for d := from; !d.After(to); d = time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc) {
+		date := d.Format(time.DateOnly)
+		worked := byWorkday[date]
+
+		if days&(1<<d.Weekday()) == 0 || holidays[date] {
+			for _, sh := range worked {
+				signInID := sh.SignInID
+				signIn := sh.SignIn
+				minutes := int(sh.NetWorkTime / 60)
+
+				report.UnscheduledShifts++
+				report.UnscheduledMinutes += minutes
+				report.Findings = append(report.Findings, PunctualityFinding{
+					Type:     FindingUnscheduledWork,
+					Date:     date,
+					SignInID: &signInID,
+					Actual:   &signIn,
+					Minutes:  minutes,
+				})
+			}
+			continue
+		}
+
+		scheduledStart := time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		scheduledEnd := time.Date(d.Year(), d.Month(), d.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !scheduledEnd.After(scheduledStart) {
+			scheduledEnd = time.Date(d.Year(), d.Month(), d.Day()+1, end.Hour(), end.Minute(), 0, 0, loc)
+		}
+		ended := !scheduledEnd.After(now)
+
+		if ended {
+			report.ScheduledDays++
+		}
+
+		if len(worked) == 0 {
+			if ended {
+				report.MissingWorkdays++
+				report.Findings = append(report.Findings, PunctualityFinding{
+					Type:      FindingMissingWorkday,
+					Date:      date,
+					Scheduled: &scheduledStart,
+					Minutes:   int(scheduledEnd.Sub(scheduledStart) / time.Minute),
+				})
+			}
+			continue
+		}
+
+		// Judge the day by its first sign-in and, once the workday is over, by its last sign-out
+		first, last := worked[0], worked[len(worked)-1]
+
+		if late := first.SignIn.Sub(scheduledStart); late > lateGrace {
+			signInID := first.SignInID
+			signIn := first.SignIn
+			minutes := int(late / time.Minute)
+
+			report.LateArrivals++
+			report.LateMinutes += minutes
+			report.Findings = append(report.Findings, PunctualityFinding{
+				Type:         FindingLateArrival,
+				Date:         date,
+				SignInID:     &signInID,
+				Scheduled:    &scheduledStart,
+				Actual:       &signIn,
+				Minutes:      minutes,
+				GraceMinutes: int(lateGrace / time.Minute),
+			})
+		}
+
+		if early := scheduledEnd.Sub(last.SignOut); ended && early > earlyGrace {
+			signInID := last.SignInID
+			signOut := last.SignOut
+			minutes := int(early / time.Minute)
+
+			report.EarlyDepartures++
+			report.EarlyMinutes += minutes
+			report.Findings = append(report.Findings, PunctualityFinding{
+				Type:         FindingEarlyDeparture,
+				Date:         date,
+				SignInID:     &signInID,
+				Scheduled:    &scheduledEnd,
+				Actual:       &signOut,
+				Minutes:      minutes,
+				GraceMinutes: int(earlyGrace / time.Minute),
+			})
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,687 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrScheduleOverlap is returned when a planned shift overlaps another planned shift of the same user.
+var ErrScheduleOverlap = errors.New("planned shift overlaps another planned shift")
+
+// PlannedShift is a shift a manager has scheduled for a user. It is a draft that only the
+// managers see until the roster of its week is published. StartsAt and EndsAt are stored in UTC.
+type PlannedShift struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	Location    string     `json:"location"`
+	Role        string     `json:"role"`        // What the user works as during the shift, e.g. "cashier"
+	TemplateID  *int64     `json:"template_id"` // Template the shift was generated from, if any
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedBy   int64      `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// In converts the times of the planned shift to loc for display.
+func (p *PlannedShift) In(loc *time.Location) {
+	p.StartsAt = p.StartsAt.In(loc)
+	p.EndsAt = p.EndsAt.In(loc)
+	p.CreatedAt = p.CreatedAt.In(loc)
+	if p.PublishedAt != nil {
+		publishedAt := p.PublishedAt.In(loc)
+		p.PublishedAt = &publishedAt
+	}
+}
+
+// ShiftTemplate describes a recurring shift, e.g. Monday to Friday 09:00-17:00. The clock times
+// are read in the time zone of the user the template is applied to; an end time that is not after
+// the start time ends the shift on the next day.
+type ShiftTemplate struct {
+	ID        int64          `json:"id"`
+	Name      string         `json:"name"`
+	Weekdays  []time.Weekday `json:"weekdays"`   // 0 is Sunday
+	StartTime string         `json:"start_time"` // HH:MM
+	EndTime   string         `json:"end_time"`   // HH:MM
+	Location  string         `json:"location"`
+	Role      string         `json:"role"`
+	CreatedBy int64          `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Occurrences returns the draft shifts the template yields for a user on the local dates from..to in loc.
+func (t *ShiftTemplate) Occurrences(userID int64, from, to time.Time, loc *time.Location) ([]PlannedShift, error) {
+	start, err := time.Parse("15:04", t.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse("15:04", t.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	days := weekdayMask(t.Weekdays)
+	shifts := make([]PlannedShift, 0)
+
+	for d := from.In(loc); !d.After(to.In(loc)); d = time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc) {
+		if days&(1<<d.Weekday()) == 0 {
+			continue
+		}
+
+		startsAt := time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		endsAt := time.Date(d.Year(), d.Month(), d.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !endsAt.After(startsAt) {
+			endsAt = time.Date(d.Year(), d.Month(), d.Day()+1, end.Hour(), end.Minute(), 0, 0, loc)
+		}
+
+		id := t.ID
+		shifts = append(shifts, PlannedShift{
+			UserID:     userID,
+			StartsAt:   startsAt.UTC(),
+			EndsAt:     endsAt.UTC(),
+			Location:   t.Location,
+			Role:       t.Role,
+			TemplateID: &id,
+			CreatedBy:  t.CreatedBy,
+		})
+	}
+
+	return shifts, nil
+}
+
+// weekdayMask packs weekdays into a bit mask with bit n set for time.Weekday(n).
+func weekdayMask(days []time.Weekday) int {
+	mask := 0
+	for _, d := range days {
+		mask |= 1 << d
+	}
+	return mask
+}
+
+// weekdaysFromMask unpacks a bit mask written by weekdayMask.
+func weekdaysFromMask(mask int) []time.Weekday {
+	days := make([]time.Weekday, 0, 7)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if mask&(1<<d) != 0 {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// ScheduleStore provides methods for managing planned shifts, rosters and shift templates.
+type ScheduleStore struct {
+	db *sql.DB
+}
+
+const plannedShiftSelect = `
+	SELECT ps.id, ps.user_id, ps.starts_at, ps.ends_at, ps.location, ps.role,
+		ps.template_id, ps.published_at, ps.created_by, ps.created_at
+	FROM planned_shifts ps
+`
+
+// Create godoc
+//
+//	@Summary		Creates a planned shift
+//	@Description	Inserts an unpublished planned shift that must not overlap another planned shift of the same user
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	PlannedShift
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/planned-shifts [post]
+func (s *ScheduleStore) Create(ctx context.Context, shift *PlannedShift) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, shift.UserID); err != nil {
+			return err
+		}
+
+		return insertPlannedShift(ctx, tx, shift)
+	})
+}
+
+// CreateBatch godoc
+//
+//	@Summary		Creates planned shifts in bulk
+//	@Description	Inserts unpublished planned shifts, skipping those that overlap a planned shift of the same user
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	[]PlannedShift
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/shift-templates/{id}/apply [post]
+func (s *ScheduleStore) CreateBatch(ctx context.Context, shifts []PlannedShift) ([]PlannedShift, int, error) {
+	created := make([]PlannedShift, 0, len(shifts))
+	skipped := 0
+
+	// Lock the users in a fixed order so concurrent batches cannot deadlock
+	userIDs := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, sh := range shifts {
+		if !seen[sh.UserID] {
+			seen[sh.UserID] = true
+			userIDs = append(userIDs, sh.UserID)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		for _, id := range userIDs {
+			if err := lockUser(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+
+		for _, sh := range shifts {
+			err := insertPlannedShift(ctx, tx, &sh)
+			switch {
+			case err == nil:
+				created = append(created, sh)
+			case errors.Is(err, ErrScheduleOverlap):
+				skipped++
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return created, skipped, nil
+}
+
+// GetByID godoc
+//
+//	@Summary		Retrieves a planned shift by ID
+//	@Description	Retrieves a planned shift by its ID
+//	@Tags			schedule
+//	@Produce		json
+//	@Param			id	path		int	true	"Planned shift ID"
+//	@Success		200	{object}	PlannedShift
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+func (s *ScheduleStore) GetByID(ctx context.Context, id int64) (*PlannedShift, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var shift PlannedShift
+	if err := scanPlannedShift(s.db.QueryRowContext(ctx, plannedShiftSelect+` WHERE ps.id = ?`, id), &shift); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &shift, nil
+}
+
+// Update godoc
+//
+//	@Summary		Updates a planned shift
+//	@Description	Changes the time, location or role of a planned shift; the shift must not come to overlap another planned shift
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	PlannedShift
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/planned-shifts/{id} [patch]
+func (s *ScheduleStore) Update(ctx context.Context, shift *PlannedShift) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, shift.UserID); err != nil {
+			return err
+		}
+
+		if err := checkPlannedOverlap(ctx, tx, shift); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(
+			ctx,
+			`UPDATE planned_shifts SET starts_at = ?, ends_at = ?, location = ?, role = ? WHERE id = ?`,
+			shift.StartsAt.UTC(),
+			shift.EndsAt.UTC(),
+			shift.Location,
+			shift.Role,
+			shift.ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			// MySQL reports 0 for an update that changes nothing, so check the row is really gone
+			var exists int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM planned_shifts WHERE id = ?`, shift.ID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists == 0 {
+				return ErrNotFound
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete godoc
+//
+//	@Summary		Deletes a planned shift
+//	@Description	Deletes a planned shift by its ID
+//	@Tags			schedule
+//	@Produce		json
+//	@Param			id	path		int		true	"Planned shift ID"
+//	@Success		204	{string}	string	"Planned shift deleted"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/planned-shifts/{id} [delete]
+func (s *ScheduleStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM planned_shifts WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetForTeam godoc
+//
+//	@Summary		Retrieves the planned shifts of a team
+//	@Description	Retrieves the published and unpublished planned shifts of a manager's reports, or of one of them if userID is not 0, that start in [from, to)
+//	@Tags			schedule
+//	@Produce		json
+//	@Success		200	{object}	[]PlannedShift
+//	@Failure		500	{object}	error
+//	@Router			/planned-shifts [get]
+func (s *ScheduleStore) GetForTeam(ctx context.Context, managerID, userID int64, from, to time.Time) ([]PlannedShift, error) {
+	query := plannedShiftSelect + `
+		JOIN users u ON u.id = ps.user_id
+		WHERE u.manager_id = ? AND (? = 0 OR ps.user_id = ?) AND ps.starts_at >= ? AND ps.starts_at < ?
+		ORDER BY ps.starts_at ASC, ps.user_id ASC
+	`
+
+	return s.queryPlannedShifts(ctx, query, managerID, userID, userID, from.UTC(), to.UTC())
+}
+
+// GetUpcoming godoc
+//
+//	@Summary		Retrieves a user's upcoming shifts
+//	@Description	Retrieves the published planned shifts of a user that have not ended by now and start before until
+//	@Tags			schedule
+//	@Produce		json
+//	@Success		200	{object}	[]PlannedShift
+//	@Failure		500	{object}	error
+//	@Router			/planned-shifts/upcoming [get]
+func (s *ScheduleStore) GetUpcoming(ctx context.Context, userID int64, now, until time.Time) ([]PlannedShift, error) {
+	query := plannedShiftSelect + `
+		WHERE ps.user_id = ? AND ps.published_at IS NOT NULL AND ps.ends_at > ? AND ps.starts_at < ?
+		ORDER BY ps.starts_at ASC
+	`
+
+	return s.queryPlannedShifts(ctx, query, userID, now.UTC(), until.UTC())
+}
+
+// Publish godoc
+//
+//	@Summary		Publishes a roster
+//	@Description	Publishes the unpublished planned shifts of a manager's reports that start in [from, to) and returns how many were published
+//	@Tags			schedule
+//	@Produce		json
+//	@Success		200	{object}	int64
+//	@Failure		500	{object}	error
+//	@Router			/planned-shifts/publish [post]
+func (s *ScheduleStore) Publish(ctx context.Context, managerID int64, from, to time.Time) (int64, error) {
+	query := `
+		UPDATE planned_shifts ps
+		JOIN users u ON u.id = ps.user_id
+		SET ps.published_at = ?
+		WHERE u.manager_id = ? AND ps.published_at IS NULL AND ps.starts_at >= ? AND ps.starts_at < ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, time.Now().UTC(), managerID, from.UTC(), to.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// CreateTemplate godoc
+//
+//	@Summary		Creates a shift template
+//	@Description	Inserts a recurring shift template; template names are unique
+//	@Tags			schedule
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	ShiftTemplate
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/shift-templates [post]
+func (s *ScheduleStore) CreateTemplate(ctx context.Context, tmpl *ShiftTemplate) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM shift_templates WHERE name = ?`, tmpl.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrConflict
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO shift_templates (name, weekdays, start_time, end_time, location, role, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		tmpl.Name,
+		weekdayMask(tmpl.Weekdays),
+		tmpl.StartTime,
+		tmpl.EndTime,
+		tmpl.Location,
+		tmpl.Role,
+		tmpl.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	tmpl.ID = id
+	tmpl.Weekdays = weekdaysFromMask(weekdayMask(tmpl.Weekdays))
+	tmpl.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// GetTemplates godoc
+//
+//	@Summary		Retrieves all shift templates
+//	@Description	Retrieves all shift templates ordered by name
+//	@Tags			schedule
+//	@Produce		json
+//	@Success		200	{object}	[]ShiftTemplate
+//	@Failure		500	{object}	error
+//	@Router			/shift-templates [get]
+func (s *ScheduleStore) GetTemplates(ctx context.Context) ([]ShiftTemplate, error) {
+	query := `
+		SELECT id, name, weekdays, start_time, end_time, location, role, created_by, created_at
+		FROM shift_templates
+		ORDER BY name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]ShiftTemplate, 0)
+	for rows.Next() {
+		var t ShiftTemplate
+		if err := scanShiftTemplate(rows, &t); err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// GetTemplateByID godoc
+//
+//	@Summary		Retrieves a shift template by ID
+//	@Description	Retrieves a shift template by its ID
+//	@Tags			schedule
+//	@Produce		json
+//	@Param			id	path		int	true	"Shift template ID"
+//	@Success		200	{object}	ShiftTemplate
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+func (s *ScheduleStore) GetTemplateByID(ctx context.Context, id int64) (*ShiftTemplate, error) {
+	query := `
+		SELECT id, name, weekdays, start_time, end_time, location, role, created_by, created_at
+		FROM shift_templates
+		WHERE id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var tmpl ShiftTemplate
+	if err := scanShiftTemplate(s.db.QueryRowContext(ctx, query, id), &tmpl); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tmpl, nil
+}
+
+// DeleteTemplate godoc
+//
+//	@Summary		Deletes a shift template
+//	@Description	Deletes a shift template; shifts generated from it are kept
+//	@Tags			schedule
+//	@Produce		json
+//	@Param			id	path		int		true	"Shift template ID"
+//	@Success		204	{string}	string	"Shift template deleted"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/shift-templates/{id} [delete]
+func (s *ScheduleStore) DeleteTemplate(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM shift_templates WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// queryPlannedShifts runs a query selecting plannedShiftSelect.
+func (s *ScheduleStore) queryPlannedShifts(ctx context.Context, query string, args ...any) ([]PlannedShift, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shifts := make([]PlannedShift, 0)
+	for rows.Next() {
+		var p PlannedShift
+		if err := scanPlannedShift(rows, &p); err != nil {
+			return nil, err
+		}
+
+		shifts = append(shifts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+// insertPlannedShift inserts an unpublished planned shift within tx, which must hold the lock on the user.
+func insertPlannedShift(ctx context.Context, tx *sql.Tx, shift *PlannedShift) error {
+	if err := checkPlannedOverlap(ctx, tx, shift); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO planned_shifts (user_id, starts_at, ends_at, location, role, template_id, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		shift.UserID,
+		shift.StartsAt.UTC(),
+		shift.EndsAt.UTC(),
+		shift.Location,
+		shift.Role,
+		shift.TemplateID,
+		shift.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	shift.ID = id
+	shift.PublishedAt = nil
+	shift.CreatedAt = time.Now().UTC()
+
+	return nil
+}
+
+// checkPlannedOverlap returns ErrScheduleOverlap if another planned shift of the user overlaps shift.
+func checkPlannedOverlap(ctx context.Context, q querier, shift *PlannedShift) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var other int64
+	err := q.QueryRowContext(
+		ctx,
+		`SELECT id FROM planned_shifts WHERE user_id = ? AND id <> ? AND starts_at < ? AND ends_at > ? LIMIT 1`,
+		shift.UserID,
+		shift.ID,
+		shift.EndsAt.UTC(),
+		shift.StartsAt.UTC(),
+	).Scan(&other)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return err
+	default:
+		return fmt.Errorf("%w: planned shift %d", ErrScheduleOverlap, other)
+	}
+}
+
+// scanPlannedShift scans a row selected with plannedShiftSelect into p.
+func scanPlannedShift(row scanner, p *PlannedShift) error {
+	var startsAt, endsAt, publishedAt, createdAt []byte
+	var templateID sql.NullInt64
+
+	err := row.Scan(&p.ID, &p.UserID, &startsAt, &endsAt, &p.Location, &p.Role, &templateID, &publishedAt, &p.CreatedBy, &createdAt)
+	if err != nil {
+		return err
+	}
+
+	if p.StartsAt, err = parseDBTime(string(startsAt)); err != nil {
+		return err
+	}
+	if p.EndsAt, err = parseDBTime(string(endsAt)); err != nil {
+		return err
+	}
+	if p.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return err
+	}
+
+	if templateID.Valid {
+		p.TemplateID = &templateID.Int64
+	}
+
+	if publishedAt != nil {
+		t, err := parseDBTime(string(publishedAt))
+		if err != nil {
+			return err
+		}
+		p.PublishedAt = &t
+	}
+
+	return nil
+}
+
+// scanShiftTemplate scans a row of the shift_templates column list into t.
+func scanShiftTemplate(row scanner, t *ShiftTemplate) error {
+	var weekdays int
+	var startTime, endTime, createdAt []byte
+
+	err := row.Scan(&t.ID, &t.Name, &weekdays, &startTime, &endTime, &t.Location, &t.Role, &t.CreatedBy, &createdAt)
+	if err != nil {
+		return err
+	}
+
+	t.Weekdays = weekdaysFromMask(weekdays)
+
+	// TIME columns come back as HH:MM:SS
+	t.StartTime = clockTime(startTime)
+	t.EndTime = clockTime(endTime)
+
+	t.CreatedAt, err = parseDBTime(string(createdAt))
+	return err
+}
+
+// clockTime trims the seconds from a TIME column value.
+func clockTime(raw []byte) string {
+	if len(raw) > 5 {
+		return string(raw[:5])
+	}
+	return string(raw)
+}
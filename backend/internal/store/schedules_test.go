@@ -0,0 +1,212 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestShiftTemplateOccurrences(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+	tests := []struct {
+		name     string
+		template ShiftTemplate
+		from, to string // Local dates
+		loc      *time.Location
+		want     []string // UTC start and end, 2006-01-02 15:04
+		wantErr  bool
+	}{
+		{
+			name:     "weekdays",
+			template: ShiftTemplate{Weekdays: weekdays, StartTime: "09:00", EndTime: "17:00"},
+			from:     "2024-01-06", // Saturday
+			to:       "2024-01-14",
+			loc:      time.UTC,
+			want: []string{
+				"2024-01-08 09:00 - 2024-01-08 17:00",
+				"2024-01-09 09:00 - 2024-01-09 17:00",
+				"2024-01-10 09:00 - 2024-01-10 17:00",
+				"2024-01-11 09:00 - 2024-01-11 17:00",
+				"2024-01-12 09:00 - 2024-01-12 17:00",
+			},
+		},
+		{
+			name:     "night shift ends on the next day",
+			template: ShiftTemplate{Weekdays: []time.Weekday{time.Friday}, StartTime: "22:00", EndTime: "06:00"},
+			from:     "2024-01-08",
+			to:       "2024-01-12",
+			loc:      time.UTC,
+			want:     []string{"2024-01-12 22:00 - 2024-01-13 06:00"},
+		},
+		{
+			name:     "equal times span a whole day",
+			template: ShiftTemplate{Weekdays: []time.Weekday{time.Monday}, StartTime: "08:00", EndTime: "08:00"},
+			from:     "2024-01-08",
+			to:       "2024-01-08",
+			loc:      time.UTC,
+			want:     []string{"2024-01-08 08:00 - 2024-01-09 08:00"},
+		},
+		{
+			name:     "clock times are read in the user's time zone",
+			template: ShiftTemplate{Weekdays: []time.Weekday{time.Monday}, StartTime: "09:00", EndTime: "17:00"},
+			from:     "2024-01-08",
+			to:       "2024-01-08",
+			loc:      berlin,
+			want:     []string{"2024-01-08 08:00 - 2024-01-08 16:00"},
+		},
+		{
+			name:     "shift across the start of daylight saving time is an hour shorter",
+			template: ShiftTemplate{Weekdays: []time.Weekday{time.Saturday, time.Sunday}, StartTime: "22:00", EndTime: "06:00"},
+			from:     "2024-03-30",
+			to:       "2024-03-31",
+			loc:      berlin,
+			want: []string{
+				"2024-03-30 21:00 - 2024-03-31 04:00",
+				"2024-03-31 20:00 - 2024-04-01 04:00",
+			},
+		},
+		{
+			name:     "no weekdays",
+			template: ShiftTemplate{StartTime: "09:00", EndTime: "17:00"},
+			from:     "2024-01-08",
+			to:       "2024-01-14",
+			loc:      time.UTC,
+		},
+		{
+			name:     "invalid start time",
+			template: ShiftTemplate{Weekdays: weekdays, StartTime: "9am", EndTime: "17:00"},
+			from:     "2024-01-08",
+			to:       "2024-01-14",
+			loc:      time.UTC,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, err := time.ParseInLocation(time.DateOnly, tt.from, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := time.ParseInLocation(time.DateOnly, tt.to, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.template.ID = 7
+			tt.template.Location = "Warehouse"
+			tt.template.Role = "picker"
+
+			shifts, err := tt.template.Occurrences(42, from, to, tt.loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(shifts))
+			for i, s := range shifts {
+				got[i] = s.StartsAt.Format("2006-01-02 15:04") + " - " + s.EndsAt.Format("2006-01-02 15:04")
+
+				if s.StartsAt.Location() != time.UTC || s.EndsAt.Location() != time.UTC {
+					t.Errorf("shift %d is not in UTC", i)
+				}
+				if s.UserID != 42 || s.TemplateID == nil || *s.TemplateID != 7 || s.Location != "Warehouse" || s.Role != "picker" {
+					t.Errorf("shift %d does not describe the template: %+v", i, s)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeekdayMask(t *testing.T) {
+	days := []time.Weekday{time.Sunday, time.Wednesday, time.Saturday}
+
+	mask := weekdayMask(days)
+	if mask != 1|1<<3|1<<6 {
+		t.Errorf("got mask %b", mask)
+	}
+	if got := weekdaysFromMask(mask); !slices.Equal(got, days) {
+		t.Errorf("got %v, want %v", got, days)
+	}
+}
+
+func TestScheduleStoreCreateBatchSkipsOverlaps(t *testing.T) {
+	conn := newTestDB(t)
+	s := &ScheduleStore{conn}
+	ctx := context.Background()
+
+	userID := createTestUser(t, conn)
+
+	tmpl := &ShiftTemplate{
+		Name:      fmt.Sprintf("test-%d", time.Now().UnixNano()),
+		Weekdays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday},
+		StartTime: "09:00",
+		EndTime:   "17:00",
+		CreatedBy: userID,
+	}
+	if err := s.CreateTemplate(ctx, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.ExecContext(ctx, `DELETE FROM planned_shifts WHERE user_id = ?`, userID)
+		conn.ExecContext(ctx, `DELETE FROM shift_templates WHERE id = ?`, tmpl.ID)
+	})
+
+	// Tuesday already has a shift that overlaps the template's
+	existing := &PlannedShift{
+		UserID:    userID,
+		StartsAt:  time.Date(2024, time.January, 9, 16, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2024, time.January, 9, 20, 0, 0, 0, time.UTC),
+		CreatedBy: userID,
+	}
+	if err := s.Create(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	occurrences, err := tmpl.Occurrences(userID,
+		time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC),
+		time.UTC,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, skipped, err := s.CreateBatch(ctx, occurrences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || skipped != 1 {
+		t.Fatalf("got %d created and %d skipped, want 2 and 1", len(created), skipped)
+	}
+	for _, sh := range created {
+		if sh.ID == 0 || sh.PublishedAt != nil {
+			t.Errorf("got %+v, want a stored draft", sh)
+		}
+	}
+
+	// Applying the template again creates nothing
+	created, skipped, err = s.CreateBatch(ctx, occurrences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 0 || skipped != 3 {
+		t.Errorf("got %d created and %d skipped on the second run, want 0 and 3", len(created), skipped)
+	}
+}
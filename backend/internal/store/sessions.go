@@ -0,0 +1,340 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSessionExpired is returned when a refresh token belongs to a session that has expired or was revoked.
+	ErrSessionExpired = errors.New("session has expired or was revoked")
+	// ErrRefreshTokenReused is returned when a refresh token that was already rotated is presented again.
+	// The token may have been stolen, so its whole session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token was already used, the session has been revoked")
+)
+
+// Session is a login of a user on one device. The session stays alive for as long as its refresh
+// token is rotated before ExpiresAt; every refresh token can only be used once.
+type Session struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Device     string    `json:"device"` // Name the client gave the device, e.g. "Front desk tablet"
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // Whether the listing was requested from this session
+}
+
+// In converts the times of the session to loc for display.
+func (s *Session) In(loc *time.Location) {
+	s.CreatedAt = s.CreatedAt.In(loc)
+	s.LastUsedAt = s.LastUsedAt.In(loc)
+	s.ExpiresAt = s.ExpiresAt.In(loc)
+}
+
+// SessionStore provides methods for managing login sessions and their refresh tokens.
+type SessionStore struct {
+	db *sql.DB
+}
+
+const sessionSelect = `
+	SELECT id, user_id, device, user_agent, ip_address, created_at, last_used_at, expires_at
+	FROM sessions
+`
+
+// Create godoc
+//
+//	@Summary		Creates a session
+//	@Description	Inserts a session that lasts until session.ExpiresAt and its first refresh token, which is stored hashed
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		201	{string}	string	"Token"
+//	@Failure		500	{object}	error
+//	@Router			/authentication/token [post]
+func (s *SessionStore) Create(ctx context.Context, session *Session, token string) error {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO sessions (user_id, device, user_agent, ip_address, created_at, last_used_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			session.UserID,
+			session.Device,
+			session.UserAgent,
+			session.IPAddress,
+			now,
+			now,
+			session.ExpiresAt.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		if session.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		if err := insertRefreshToken(ctx, tx, session.ID, token, now); err != nil {
+			return err
+		}
+
+		session.CreatedAt = now
+		session.LastUsedAt = now
+
+		return nil
+	})
+}
+
+// Rotate godoc
+//
+//	@Summary		Rotates a refresh token
+//	@Description	Exchanges an unused refresh token of a live session for newToken. Presenting a token that was already exchanged revokes the session.
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		201	{string}	string	"Token"
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/authentication/refresh [post]
+func (s *SessionStore) Rotate(ctx context.Context, token, newToken string) (*Session, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var session Session
+	var result error
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var usedAt, revokedAt []byte
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT rt.session_id, rt.used_at, s.revoked_at
+			FROM refresh_tokens rt
+			JOIN sessions s ON s.id = rt.session_id
+			WHERE rt.token_hash = ?
+			FOR UPDATE`,
+			hashToken(token),
+		).Scan(&session.ID, &usedAt, &revokedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := scanSession(tx.QueryRowContext(ctx, sessionSelect+` WHERE id = ?`, session.ID), &session); err != nil {
+			return err
+		}
+
+		switch {
+		case revokedAt != nil || !session.ExpiresAt.After(now):
+			result = ErrSessionExpired
+			return nil
+		case usedAt != nil:
+			// Keep the revocation even though the refresh fails
+			result = ErrRefreshTokenReused
+			_, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = ? WHERE id = ?`, now, session.ID)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ?`, now, hashToken(token)); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE sessions SET last_used_at = ? WHERE id = ?`, now, session.ID); err != nil {
+			return err
+		}
+
+		session.LastUsedAt = now
+
+		return insertRefreshToken(ctx, tx, session.ID, newToken, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result != nil {
+		return nil, result
+	}
+
+	return &session, nil
+}
+
+// GetActive godoc
+//
+//	@Summary		Retrieves the active sessions of a user
+//	@Description	Retrieves the sessions of a user that have neither expired nor been revoked, most recently used first
+//	@Tags			sessions
+//	@Produce		json
+//	@Success		200	{object}	[]Session
+//	@Failure		500	{object}	error
+//	@Router			/sessions [get]
+func (s *SessionStore) GetActive(ctx context.Context, userID int64) ([]Session, error) {
+	query := sessionSelect + `
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC, id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		var session Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// IsActive reports whether a session of a user has neither expired nor been revoked. Access
+// tokens carry their session and stop working with it.
+func (s *SessionStore) IsActive(ctx context.Context, userID, sessionID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM sessions WHERE id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var active bool
+	if err := s.db.QueryRowContext(ctx, query, sessionID, userID, time.Now().UTC()).Scan(&active); err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+// Revoke godoc
+//
+//	@Summary		Revokes a session
+//	@Description	Revokes an active session of a user so that its refresh token and access tokens stop working
+//	@Tags			sessions
+//	@Success		204	{string}	string	"Session revoked"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/sessions/{id} [delete]
+func (s *SessionStore) Revoke(ctx context.Context, userID, sessionID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(),
+		sessionID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RevokeAll godoc
+//
+//	@Summary		Revokes all sessions of a user
+//	@Description	Revokes every active session and access token of a user and returns how many sessions were revoked
+//	@Tags			sessions
+//	@Success		204	{string}	string	"Sessions revoked"
+//	@Failure		500	{object}	error
+//	@Router			/users/{id}/sessions [delete]
+func (s *SessionStore) RevokeAll(ctx context.Context, userID int64) (int64, error) {
+	var revoked int64
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var err error
+		if revoked, err = revokeSessions(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		return revokeAccessTokens(ctx, tx, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+// revokeSessions revokes every active session of a user and returns how many were revoked.
+func revokeSessions(ctx context.Context, tx *sql.Tx, userID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(),
+		userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// insertRefreshToken stores the hash of a new refresh token of a session.
+func insertRefreshToken(ctx context.Context, tx *sql.Tx, sessionID int64, token string, now time.Time) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO refresh_tokens (token_hash, session_id, created_at) VALUES (?, ?, ?)`,
+		hashToken(token),
+		sessionID,
+		now,
+	)
+	return err
+}
+
+// scanSession scans a row selected with sessionSelect into s.
+func scanSession(row scanner, s *Session) error {
+	var createdAt, lastUsedAt, expiresAt []byte
+
+	err := row.Scan(&s.ID, &s.UserID, &s.Device, &s.UserAgent, &s.IPAddress, &createdAt, &lastUsedAt, &expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if s.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+		return err
+	}
+	if s.LastUsedAt, err = parseDBTime(string(lastUsedAt)); err != nil {
+		return err
+	}
+	if s.ExpiresAt, err = parseDBTime(string(expiresAt)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessionStoreIsActive(t *testing.T) {
+	conn := newTestDB(t)
+	s := &SessionStore{conn}
+	ctx := context.Background()
+
+	userID := createTestUser(t, conn)
+	other := createTestUser(t, conn)
+
+	session := &Session{UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := s.Create(ctx, session, "refresh-"+time.Now().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(userID int64, want bool) {
+		t.Helper()
+
+		active, err := s.IsActive(ctx, userID, session.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != want {
+			t.Fatalf("got active %v, want %v", active, want)
+		}
+	}
+
+	check(userID, true)
+	check(other, false)
+
+	if err := s.Revoke(ctx, userID, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	check(userID, false)
+}
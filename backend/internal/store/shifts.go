@@ -0,0 +1,400 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Shift represents a work shift with sign-in, sign-out, and break times.
+type Shift struct {
+	SignInID       int64              `json:"SignInID"` // ID of the sign-in timestamp that opened the shift
+	SignIn         time.Time          `json:"SignIn"`
+	SignOut        time.Time          `json:"SignOut"`
+	Breaks         [][]time.Time      `json:"Breaks"`
+	TotalBreakTime float64            `json:"TotalBreakTime"` // TotalBreakTime in seconds (float64)
+	TotalShiftTime float64            `json:"TotalShiftTime"` // TotalShiftTime in seconds (float64)
+	NetWorkTime    float64            `json:"NetWorkTime"`    // NetWorkTime in seconds (float64)
+	PaidBreakTime  float64            `json:"PaidBreakTime"`  // Paid break time in seconds, included in NetWorkTime
+	TypeTotals     map[string]float64 `json:"TypeTotals"`     // Seconds spent after each stamp type, e.g. travel or on-call
+	Workday        string             `json:"Workday"`        // Local date (YYYY-MM-DD) the shift is attributed to
+	Days           []ShiftDay         `json:"Days"`           // The shift split at local midnights
+	ClientID       *int64             `json:"ClientID"`       // Client the shift is billed to, if any
+	HolidayTime    float64            `json:"HolidayTime"`    // Net work time on holidays in seconds (float64)
+
+	Overtime   *OvertimeBreakdown    `json:"Overtime,omitempty"`   // Set when an overtime policy applies to the user
+	Violations []ComplianceViolation `json:"Violations,omitempty"` // Working-time rules the shift breaks
+}
+
+// ShiftDay is the part of a shift that falls on one local calendar day.
+type ShiftDay struct {
+	Date        string    `json:"Date"` // YYYY-MM-DD in the user's time zone
+	Start       time.Time `json:"Start"`
+	End         time.Time `json:"End"`
+	BreakTime   float64   `json:"BreakTime"`   // BreakTime in seconds (float64)
+	NetWorkTime float64   `json:"NetWorkTime"` // NetWorkTime in seconds (float64)
+	Holiday     string    `json:"Holiday"`     // Name of the holiday on the date, empty on other days
+
+	Overtime *OvertimeBreakdown `json:"Overtime,omitempty"`
+}
+
+// localize renders the shift in loc and splits it into local calendar days.
+// Day boundaries are computed with time.Date so DST changes give 23 or 25 hour days.
+func (sh *Shift) localize(loc *time.Location) {
+	sh.SignIn = sh.SignIn.In(loc)
+	sh.SignOut = sh.SignOut.In(loc)
+	for _, b := range sh.Breaks {
+		for i := range b {
+			b[i] = b[i].In(loc)
+		}
+	}
+
+	// A shift belongs to the workday on which it started, even if it runs past midnight
+	sh.Workday = sh.SignIn.Format(time.DateOnly)
+
+	sh.Days = make([]ShiftDay, 0, 1)
+	y, m, d := sh.SignIn.Date()
+	for dayStart := time.Date(y, m, d, 0, 0, 0, 0, loc); dayStart.Before(sh.SignOut); {
+		y, m, d := dayStart.Date()
+		nextDay := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+
+		start := latest(dayStart, sh.SignIn)
+		end := earliest(nextDay, sh.SignOut)
+
+		day := ShiftDay{
+			Date:  dayStart.Format(time.DateOnly),
+			Start: start,
+			End:   end,
+		}
+
+		for _, b := range sh.Breaks {
+			if len(b) == 2 {
+				day.BreakTime += overlap(start, end, b[0], b[1]).Seconds()
+			}
+		}
+		day.NetWorkTime = end.Sub(start).Seconds() - day.BreakTime
+
+		sh.Days = append(sh.Days, day)
+		dayStart = nextDay
+	}
+}
+
+// overlap returns how long [aStart, aEnd) and [bStart, bEnd) overlap.
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	start := latest(aStart, bStart)
+	end := earliest(aEnd, bEnd)
+	if !end.After(start) {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// GetFinishedShifts godoc
+//
+//	@Summary		Retrieves finished shifts
+//	@Description	Retrieves finished shifts overlapping a time window, one page at a time
+//	@Tags			shifts
+//	@Produce		json
+//	@Param			userID	query		int		true	"User ID"
+//	@Param			from	query		string	false	"Only shifts ending after this time (RFC 3339)"
+//	@Param			to		query		string	false	"Only shifts starting before this time (RFC 3339)"
+//	@Param			limit	query		int		false	"Page size"
+//	@Param			cursor	query		string	false	"Cursor returned by the previous page"
+//	@Success		200		{object}	[]Shift
+//	@Failure		500		{object}	error
+//	@Router			/shifts [get]
+func (s *TimestampStore) GetFinishedShifts(ctx context.Context, userID int64, sq ShiftQuery) ([]Shift, string, error) {
+	graph, err := getStampGraph(ctx, s.db)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 1. Pair shift starts with their ends in SQL to find the shifts of the page. Like buildShifts,
+	// a start repeated within an open shift is skipped so that every row is a shift of the page.
+	query := `
+		SELECT si.id, si.time, (
+			SELECT so.time
+			FROM timestamps so
+			WHERE so.user_id = si.user_id AND so.status = 'approved'
+				AND so.stamp_type IN (SELECT name FROM stamp_types WHERE category = 'end')
+				AND (so.time > si.time OR (so.time = si.time AND so.id > si.id))
+			ORDER BY so.time ASC, so.id ASC
+			LIMIT 1
+		) AS sign_out, sc.client_id
+		FROM timestamps si
+		LEFT JOIN shift_clients sc ON sc.sign_in_id = si.id
+		WHERE si.user_id = ? AND si.status = 'approved'
+			AND si.stamp_type IN (SELECT name FROM stamp_types WHERE category = 'start')
+			AND COALESCE((
+				SELECT st.category
+				FROM timestamps p
+				JOIN stamp_types st ON st.name = p.stamp_type
+				WHERE p.user_id = si.user_id AND p.status = 'approved' AND st.category IN ('start', 'end')
+					AND (p.time < si.time OR (p.time = si.time AND p.id < si.id))
+				ORDER BY p.time DESC, p.id DESC
+				LIMIT 1
+			), 'end') = 'end'
+	`
+	args := []any{userID}
+
+	if !sq.From.IsZero() {
+		// A shift crossing the start of the window began after the last shift end before it
+		query += `
+			AND si.time >= COALESCE((
+				SELECT MAX(p.time)
+				FROM timestamps p
+				WHERE p.user_id = ? AND p.status = 'approved' AND p.time <= ?
+					AND p.stamp_type IN (SELECT name FROM stamp_types WHERE category = 'end')
+			), si.time)
+		`
+		args = append(args, userID, sq.From)
+	}
+
+	if !sq.To.IsZero() {
+		query += ` AND si.time < ?`
+		args = append(args, sq.To)
+	}
+
+	if sq.Cursor != "" {
+		cursorTime, cursorID, err := decodeShiftCursor(sq.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+
+		query += ` AND (si.time > ? OR (si.time = ? AND si.id > ?))`
+		args = append(args, cursorTime, cursorTime, cursorID)
+	}
+
+	query += ` HAVING sign_out IS NOT NULL`
+	if !sq.From.IsZero() {
+		query += ` AND sign_out > ?`
+		args = append(args, sq.From)
+	}
+
+	query += ` ORDER BY si.time ASC, si.id ASC`
+	if sq.Limit > 0 {
+		// Fetch one extra row to know whether there is a next page
+		query += ` LIMIT ?`
+		args = append(args, sq.Limit+1)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	type bounds struct {
+		id       int64
+		signIn   time.Time
+		signOut  time.Time
+		clientID sql.NullInt64
+	}
+
+	var page []bounds
+	for rows.Next() {
+		var b bounds
+		var rawSignIn, rawSignOut []byte
+		if err := rows.Scan(&b.id, &rawSignIn, &rawSignOut, &b.clientID); err != nil {
+			return nil, "", err
+		}
+
+		if b.signIn, err = parseDBTime(string(rawSignIn)); err != nil {
+			return nil, "", err
+		}
+		if b.signOut, err = parseDBTime(string(rawSignOut)); err != nil {
+			return nil, "", err
+		}
+
+		page = append(page, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if sq.Limit > 0 && len(page) > sq.Limit {
+		page = page[:sq.Limit]
+		last := page[len(page)-1]
+		nextCursor = encodeShiftCursor(last.signIn, last.id)
+	}
+
+	if len(page) == 0 {
+		return []Shift{}, "", nil
+	}
+
+	// 2. Read only the stamps between the first sign-in and the last sign-out of the page
+	stamps, err := s.getStampsBetween(ctx, userID, page[0].signIn, page[len(page)-1].signOut)
+	if err != nil {
+		return nil, "", err
+	}
+
+	inPage := make(map[int64]bounds, len(page))
+	for _, b := range page {
+		inPage[b.id] = b
+	}
+
+	loc := sq.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	shifts := make([]Shift, 0, len(page))
+	for _, shift := range buildShifts(graph, stamps) {
+		if b, ok := inPage[shift.SignInID]; ok {
+			if b.clientID.Valid {
+				shift.ClientID = &b.clientID.Int64
+			}
+			shift.localize(loc)
+			shifts = append(shifts, shift)
+		}
+	}
+
+	return shifts, nextCursor, nil
+}
+
+// getStampsBetween loads the approved stamps of a user within [from, to] in time order.
+func (s *TimestampStore) getStampsBetween(ctx context.Context, userID int64, from, to time.Time) ([]Timestamp, error) {
+	query := `
+		SELECT id, user_id, stamp_type, time, created_at, updated_at, version,
+			status, is_manual, reason, decided_by, decided_at, is_auto_generated, kiosk_id
+		FROM timestamps
+		WHERE user_id = ? AND status = 'approved' AND time >= ? AND time <= ?
+		ORDER BY time ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stamps []Timestamp
+	for rows.Next() {
+		var t Timestamp
+		if err := scanTimestamp(rows, &t); err != nil {
+			return nil, err
+		}
+
+		stamps = append(stamps, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stamps, nil
+}
+
+// buildShifts pairs a time-ordered stamp sequence into finished shifts. The category of each stamp
+// in graph decides how the time until the next stamp is counted.
+func buildShifts(graph *StampGraph, stamps []Timestamp) []Shift {
+	var shifts []Shift
+	var currentShift *Shift
+	var current Timestamp // The stamp the running segment of the shift started with
+
+	for _, stamp := range stamps {
+		stampTime := stamp.StampTime
+		category := graph.Category(stamp.StampType)
+
+		if currentShift == nil {
+			if category == StampCategoryStart {
+				currentShift = &Shift{SignInID: stamp.ID, SignIn: stampTime, TypeTotals: make(map[string]float64)}
+				current = stamp
+			}
+			continue
+		}
+
+		if category == StampCategoryStart {
+			continue
+		}
+
+		// Close the segment that ran since the previous stamp
+		duration := stampTime.Sub(current.StampTime).Seconds()
+		currentShift.TypeTotals[current.StampType] += duration
+
+		switch graph.Category(current.StampType) {
+		case StampCategoryUnpaidBreak:
+			currentShift.Breaks = append(currentShift.Breaks, []time.Time{current.StampTime, stampTime})
+			currentShift.TotalBreakTime += duration // Keep TotalBreakTime as seconds (float64)
+
+		case StampCategoryPaidBreak:
+			currentShift.PaidBreakTime += duration
+		}
+
+		current = stamp
+
+		if category == StampCategoryEnd {
+			currentShift.SignOut = stampTime
+			// Calculate total shift time in seconds (float64)
+			currentShift.TotalShiftTime = currentShift.SignOut.Sub(currentShift.SignIn).Seconds() // TotalShiftTime in seconds
+			// NetWorkTime is shift time minus unpaid break time
+			currentShift.NetWorkTime = currentShift.TotalShiftTime - currentShift.TotalBreakTime
+
+			shifts = append(shifts, *currentShift)
+			currentShift = nil
+		}
+	}
+
+	return shifts
+}
+
+// encodeShiftCursor builds the opaque cursor pointing after the given sign-in.
+func encodeShiftCursor(signIn time.Time, id int64) string {
+	raw := fmt.Sprintf("%d:%d", signIn.Unix(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeShiftCursor reverses encodeShiftCursor.
+func decodeShiftCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	unix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	return time.Unix(unix, 0).UTC(), id, nil
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testStamp returns an approved stamp of a type at a time given as 2006-01-02 15:04 in loc.
+func testStamp(t *testing.T, id int64, stampType, at string, loc *time.Location) Timestamp {
+	t.Helper()
+
+	stampTime, err := time.ParseInLocation("2006-01-02 15:04", at, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Timestamp{ID: id, StampType: stampType, StampTime: stampTime.UTC(), Status: "approved"}
+}
+
+func TestBuildShifts(t *testing.T) {
+	graph := testStampGraph()
+	utc := time.UTC
+
+	stamps := []Timestamp{
+		testStamp(t, 1, "sign-out", "2024-01-07 17:00", utc), // Closes a shift that started before the range
+		testStamp(t, 2, "sign-in", "2024-01-08 08:00", utc),
+		testStamp(t, 3, "start-break", "2024-01-08 12:00", utc),
+		testStamp(t, 4, "end-break", "2024-01-08 12:30", utc),
+		testStamp(t, 5, "sign-in", "2024-01-08 12:31", utc), // Repeated start inside a shift is ignored
+		testStamp(t, 6, "sign-out", "2024-01-08 16:30", utc),
+		testStamp(t, 7, "sign-in", "2024-01-09 08:00", utc), // Still open
+	}
+
+	shifts := buildShifts(graph, stamps)
+	if len(shifts) != 1 {
+		t.Fatalf("got %d shifts, want 1", len(shifts))
+	}
+
+	shift := shifts[0]
+	if shift.SignInID != 2 || !shift.SignIn.Equal(stamps[1].StampTime) || !shift.SignOut.Equal(stamps[5].StampTime) {
+		t.Errorf("got shift %d from %v to %v", shift.SignInID, shift.SignIn, shift.SignOut)
+	}
+	if len(shift.Breaks) != 1 || !shift.Breaks[0][0].Equal(stamps[2].StampTime) || !shift.Breaks[0][1].Equal(stamps[3].StampTime) {
+		t.Errorf("got breaks %v", shift.Breaks)
+	}
+
+	hour := time.Hour.Seconds()
+	if shift.TotalShiftTime != 8.5*hour || shift.TotalBreakTime != 0.5*hour || shift.NetWorkTime != 8*hour {
+		t.Errorf("got shift %v, break %v, net %v seconds", shift.TotalShiftTime, shift.TotalBreakTime, shift.NetWorkTime)
+	}
+	if shift.TypeTotals["sign-in"] != 4*hour || shift.TypeTotals["start-break"] != 0.5*hour || shift.TypeTotals["end-break"] != 4*hour {
+		t.Errorf("got type totals %v", shift.TypeTotals)
+	}
+}
+
+func TestBuildShiftsPaidBreak(t *testing.T) {
+	graph := NewStampGraph(
+		[]StampType{
+			{Name: "sign-in", Category: StampCategoryStart},
+			{Name: "sign-out", Category: StampCategoryEnd},
+			{Name: "coffee", Category: StampCategoryPaidBreak},
+			{Name: "back", Category: StampCategoryWork},
+		},
+		[]StampTransition{
+			{From: "sign-out", To: "sign-in"},
+			{From: "sign-in", To: "coffee"},
+			{From: "coffee", To: "back"},
+			{From: "back", To: "sign-out"},
+		},
+	)
+
+	shifts := buildShifts(graph, []Timestamp{
+		testStamp(t, 1, "sign-in", "2024-01-08 08:00", time.UTC),
+		testStamp(t, 2, "coffee", "2024-01-08 10:00", time.UTC),
+		testStamp(t, 3, "back", "2024-01-08 10:15", time.UTC),
+		testStamp(t, 4, "sign-out", "2024-01-08 16:00", time.UTC),
+	})
+	if len(shifts) != 1 {
+		t.Fatalf("got %d shifts, want 1", len(shifts))
+	}
+
+	if shifts[0].PaidBreakTime != 15*60 || shifts[0].TotalBreakTime != 0 || shifts[0].NetWorkTime != 8*time.Hour.Seconds() {
+		t.Errorf("got paid break %v, break %v, net %v seconds", shifts[0].PaidBreakTime, shifts[0].TotalBreakTime, shifts[0].NetWorkTime)
+	}
+}
+
+func TestShiftLocalizeAcrossDST(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	type day struct {
+		date      string
+		net       time.Duration
+		breakTime time.Duration
+	}
+
+	tests := []struct {
+		name    string
+		stamps  [][2]string // Stamp type and local time
+		workday string
+		days    []day
+	}{
+		{
+			name:    "night shift into spring forward",
+			stamps:  [][2]string{{"sign-in", "2024-03-30 22:00"}, {"sign-out", "2024-03-31 06:00"}},
+			workday: "2024-03-30",
+			days:    []day{{"2024-03-30", 2 * time.Hour, 0}, {"2024-03-31", 5 * time.Hour, 0}},
+		},
+		{
+			name:    "night shift into fall back",
+			stamps:  [][2]string{{"sign-in", "2024-10-26 22:00"}, {"sign-out", "2024-10-27 06:00"}},
+			workday: "2024-10-26",
+			days:    []day{{"2024-10-26", 2 * time.Hour, 0}, {"2024-10-27", 7 * time.Hour, 0}},
+		},
+		{
+			name:    "a whole 23 hour day",
+			stamps:  [][2]string{{"sign-in", "2024-03-30 20:00"}, {"sign-out", "2024-04-01 04:00"}},
+			workday: "2024-03-30",
+			days:    []day{{"2024-03-30", 4 * time.Hour, 0}, {"2024-03-31", 23 * time.Hour, 0}, {"2024-04-01", 4 * time.Hour, 0}},
+		},
+		{
+			name: "break across the skipped hour",
+			stamps: [][2]string{
+				{"sign-in", "2024-03-30 23:00"},
+				{"start-break", "2024-03-31 01:30"},
+				{"end-break", "2024-03-31 03:30"},
+				{"sign-out", "2024-03-31 05:00"},
+			},
+			workday: "2024-03-30",
+			days:    []day{{"2024-03-30", time.Hour, 0}, {"2024-03-31", 3 * time.Hour, time.Hour}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stamps := make([]Timestamp, len(tt.stamps))
+			for i, s := range tt.stamps {
+				stamps[i] = testStamp(t, int64(i+1), s[0], s[1], stockholm)
+			}
+
+			shifts := buildShifts(testStampGraph(), stamps)
+			if len(shifts) != 1 {
+				t.Fatalf("got %d shifts, want 1", len(shifts))
+			}
+
+			shift := shifts[0]
+			shift.localize(stockholm)
+
+			if shift.Workday != tt.workday {
+				t.Errorf("got workday %s, want %s", shift.Workday, tt.workday)
+			}
+			if shift.SignIn.Location() != stockholm {
+				t.Errorf("sign-in is in %v, want Europe/Stockholm", shift.SignIn.Location())
+			}
+			if len(shift.Days) != len(tt.days) {
+				t.Fatalf("got %d days, want %d", len(shift.Days), len(tt.days))
+			}
+
+			var net float64
+			for i, want := range tt.days {
+				got := shift.Days[i]
+				if got.Date != want.date || got.NetWorkTime != want.net.Seconds() || got.BreakTime != want.breakTime.Seconds() {
+					t.Errorf("day %d: got %s with %v net and %v break seconds, want %s with %v and %v",
+						i, got.Date, got.NetWorkTime, got.BreakTime, want.date, want.net, want.breakTime)
+				}
+				net += got.NetWorkTime
+			}
+			if net != shift.NetWorkTime {
+				t.Errorf("days add up to %v seconds, want %v", net, shift.NetWorkTime)
+			}
+		})
+	}
+}
+
+func TestGetFinishedShiftsRepeatedStarts(t *testing.T) {
+	conn := newTestDB(t)
+	s := &TimestampStore{conn}
+	userID := createTestUser(t, conn)
+	ctx := context.Background()
+
+	// Every shift has a second sign-in, e.g. an approved manual entry, which must not cost a row of the page
+	first := time.Date(2024, time.January, 1, 8, 0, 0, 0, time.UTC)
+	var stamps []Timestamp
+	for day := range 3 {
+		signIn := first.AddDate(0, 0, day)
+		stamps = append(stamps,
+			Timestamp{StampType: "sign-in", StampTime: signIn},
+			Timestamp{StampType: "sign-in", StampTime: signIn.Add(time.Minute)},
+			Timestamp{StampType: "sign-out", StampTime: signIn.Add(8 * time.Hour)},
+		)
+	}
+	insertTestStamps(t, conn, userID, stamps)
+
+	shifts, cursor, err := s.GetFinishedShifts(ctx, userID, ShiftQuery{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shifts) != 2 || cursor == "" {
+		t.Fatalf("got %d shifts and cursor %q, want a full page of 2 and a cursor", len(shifts), cursor)
+	}
+	for i, shift := range shifts {
+		if want := first.AddDate(0, 0, i); !shift.SignIn.Equal(want) {
+			t.Errorf("shift %d signed in at %v, want %v", i, shift.SignIn, want)
+		}
+	}
+
+	shifts, cursor, err = s.GetFinishedShifts(ctx, userID, ShiftQuery{Limit: 2, Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shifts) != 1 || cursor != "" {
+		t.Fatalf("got %d shifts and cursor %q on the last page, want 1 and none", len(shifts), cursor)
+	}
+
+	// A window starting after the repeated sign-in still finds the start of the shift it cuts
+	from := first.AddDate(0, 0, 1).Add(2 * time.Hour)
+	shifts, _, err = s.GetFinishedShifts(ctx, userID, ShiftQuery{From: from, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shifts) != 1 || !shifts[0].SignIn.Equal(first.AddDate(0, 0, 1)) {
+		t.Fatalf("got %+v, want the shift of the second day", shifts)
+	}
+}
+
+// BenchmarkGetFinishedShifts reads a page of shifts from the middle of a history of 100k stamps,
+// 25,000 daily shifts with a break each.
+func BenchmarkGetFinishedShifts(b *testing.B) {
+	conn := newTestDB(b)
+	s := &TimestampStore{conn}
+	userID := createTestUser(b, conn)
+
+	const stampCount = 100_000
+
+	first := time.Date(2015, time.January, 1, 8, 0, 0, 0, time.UTC)
+	stamps := make([]Timestamp, 0, stampCount)
+	for day := 0; len(stamps) < stampCount; day++ {
+		signIn := first.AddDate(0, 0, day)
+		stamps = append(stamps,
+			Timestamp{StampType: "sign-in", StampTime: signIn},
+			Timestamp{StampType: "start-break", StampTime: signIn.Add(4 * time.Hour)},
+			Timestamp{StampType: "end-break", StampTime: signIn.Add(4*time.Hour + 30*time.Minute)},
+			Timestamp{StampType: "sign-out", StampTime: signIn.Add(8*time.Hour + 30*time.Minute)},
+		)
+	}
+	insertTestStamps(b, conn, userID, stamps)
+
+	// Four stamps a day, so this is the middle of the history
+	from := first.AddDate(0, 0, stampCount/8)
+	sq := ShiftQuery{From: from, To: from.AddDate(0, 1, 0), Limit: 50}
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for range b.N {
+		shifts, _, err := s.GetFinishedShifts(ctx, userID, sq)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(shifts) == 0 {
+			b.Fatal("no shifts in the window")
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SigningKey is a private key access tokens are signed with. Keys are shared by every API
+// instance through the database and are replaced on a schedule.
+type SigningKey struct {
+	ID          string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	PrivateKey  []byte     `json:"-"` // PKCS #8 DER, sealed with the key encryption key if one is configured
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatesAt time.Time  `json:"activates_at"` // Tokens are signed with the key from this time on
+	ExpiresAt   *time.Time `json:"expires_at"`   // Set once the key is replaced; tokens signed with it are rejected from then on
+}
+
+// SigningKeyStore provides methods for managing the keys access tokens are signed with.
+type SigningKeyStore struct {
+	db *sql.DB
+}
+
+// GetValid godoc
+//
+//	@Summary		Retrieves the signing keys
+//	@Description	Retrieves the signing keys that have not expired at now, including keys that are not active yet, newest first
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		200	{object}	[]SigningKey
+//	@Failure		500	{object}	error
+func (s *SigningKeyStore) GetValid(ctx context.Context, now time.Time) ([]SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, created_at, activates_at, expires_at
+		FROM signing_keys
+		WHERE expires_at IS NULL OR expires_at > ?
+		ORDER BY activates_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]SigningKey, 0)
+	for rows.Next() {
+		var key SigningKey
+		var createdAt, activatesAt, expiresAt []byte
+
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &createdAt, &activatesAt, &expiresAt); err != nil {
+			return nil, err
+		}
+
+		if key.CreatedAt, err = parseDBTime(string(createdAt)); err != nil {
+			return nil, err
+		}
+		if key.ActivatesAt, err = parseDBTime(string(activatesAt)); err != nil {
+			return nil, err
+		}
+		if expiresAt != nil {
+			t, err := parseDBTime(string(expiresAt))
+			if err != nil {
+				return nil, err
+			}
+			key.ExpiresAt = &t
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Rotate godoc
+//
+//	@Summary		Rotates the signing key
+//	@Description	Adds key as the new signing key if the current key was activated at or before due. The replaced keys expire grace after the new key activates, and long expired keys are deleted. Returns ErrConflict if another instance already rotated.
+//	@Tags			authentication
+//	@Success		201	{string}	string	"Key rotated"
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+func (s *SigningKeyStore) Rotate(ctx context.Context, key *SigningKey, due time.Time, grace time.Duration) error {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var activatesAt []byte
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT activates_at FROM signing_keys WHERE expires_at IS NULL ORDER BY activates_at DESC LIMIT 1 FOR UPDATE`,
+		).Scan(&activatesAt)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return err
+		default:
+			current, err := parseDBTime(string(activatesAt))
+			if err != nil {
+				return err
+			}
+			if current.After(due) {
+				return ErrConflict
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM signing_keys WHERE expires_at < ?`, now); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE signing_keys SET expires_at = ? WHERE expires_at IS NULL`,
+			key.ActivatesAt.UTC().Add(grace),
+		); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO signing_keys (kid, algorithm, private_key, created_at, activates_at) VALUES (?, ?, ?, ?, ?)`,
+			key.ID,
+			key.Algorithm,
+			key.PrivateKey,
+			now,
+			key.ActivatesAt.UTC(),
+		); err != nil {
+			return err
+		}
+
+		key.CreatedAt = now
+
+		return nil
+	})
+}
@@ -0,0 +1,394 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Stamp categories tell the shift builder how to count the time after a stamp.
+const (
+	StampCategoryStart       = "start"        // Opens a shift, the time after it is work
+	StampCategoryEnd         = "end"          // Closes a shift
+	StampCategoryWork        = "work"         // The time after it is work, e.g. travel or training
+	StampCategoryPaidBreak   = "paid-break"   // The time after it is a break that counts as work
+	StampCategoryUnpaidBreak = "unpaid-break" // The time after it is a break that is deducted from work
+)
+
+var stampCategories = []string{
+	StampCategoryStart,
+	StampCategoryEnd,
+	StampCategoryWork,
+	StampCategoryPaidBreak,
+	StampCategoryUnpaidBreak,
+}
+
+var (
+	ErrUnknownStampType  = errors.New("unknown stamp type")
+	ErrInvalidStampGraph = errors.New("invalid stamp graph")
+	ErrStampTypeInUse    = errors.New("stamp type is in use")
+)
+
+// StampType is a kind of stamp users can record, e.g. sign-in, travel or on-call.
+type StampType struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+}
+
+// StampTransition allows a stamp of type To to follow a stamp of type From.
+type StampTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// StampGraph holds the stamp types and the transitions allowed between them.
+type StampGraph struct {
+	Types       []StampType       `json:"types"`
+	Transitions []StampTransition `json:"transitions"`
+
+	types map[string]StampType
+	next  map[string][]string
+}
+
+// NewStampGraph indexes types and transitions for lookups.
+func NewStampGraph(types []StampType, transitions []StampTransition) *StampGraph {
+	g := &StampGraph{
+		Types:       types,
+		Transitions: transitions,
+		types:       make(map[string]StampType, len(types)),
+		next:        make(map[string][]string, len(types)),
+	}
+
+	for _, t := range types {
+		g.types[t.Name] = t
+	}
+	for _, t := range transitions {
+		g.next[t.From] = append(g.next[t.From], t.To)
+	}
+	for from := range g.next {
+		sort.Strings(g.next[from])
+	}
+
+	return g
+}
+
+// Category returns the category of the stamp type name, or "" if the type does not exist.
+func (g *StampGraph) Category(name string) string {
+	return g.types[name].Category
+}
+
+// Has reports whether the stamp type name exists.
+func (g *StampGraph) Has(name string) bool {
+	_, ok := g.types[name]
+	return ok
+}
+
+// Allows reports whether a stamp of type next may follow a stamp of type prev.
+// An empty prev means the user has no previous stamps, which only allows start types.
+func (g *StampGraph) Allows(prev, next string) bool {
+	if prev == "" {
+		return g.Category(next) == StampCategoryStart
+	}
+	return contains(g.next[prev], next)
+}
+
+// Next returns the stamp types that may follow a stamp of type prev, sorted by name.
+func (g *StampGraph) Next(prev string) []string {
+	next := make([]string, 0)
+	if prev == "" {
+		for _, t := range g.Types {
+			if t.Category == StampCategoryStart {
+				next = append(next, t.Name)
+			}
+		}
+		sort.Strings(next)
+		return next
+	}
+
+	return append(next, g.next[prev]...)
+}
+
+// PathToEnd returns the shortest sequence of stamp types that leads from a stamp of type from
+// to an end type, or nil if from is an end type or no end type can be reached.
+func (g *StampGraph) PathToEnd(from string) []string {
+	if g.Category(from) == StampCategoryEnd {
+		return nil
+	}
+
+	parent := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, next := range g.next[current] {
+			if _, seen := parent[next]; seen {
+				continue
+			}
+			parent[next] = current
+
+			if g.Category(next) == StampCategoryEnd {
+				path := []string{next}
+				for p := current; p != from; p = parent[p] {
+					path = append([]string{p}, path...)
+				}
+				return path
+			}
+
+			queue = append(queue, next)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the graph describes well-formed shifts: every type is known and reachable
+// from a start type, every shift can be closed, and shifts are only opened after they were closed.
+func (g *StampGraph) Validate() error {
+	problems := make([]string, 0)
+
+	starts := make([]string, 0)
+	hasEnd := false
+	for _, t := range g.Types {
+		if !contains(stampCategories, t.Category) {
+			problems = append(problems, fmt.Sprintf("%s has unknown category %q", t.Name, t.Category))
+		}
+		switch t.Category {
+		case StampCategoryStart:
+			starts = append(starts, t.Name)
+		case StampCategoryEnd:
+			hasEnd = true
+		}
+	}
+	if len(g.types) != len(g.Types) {
+		problems = append(problems, "stamp type names must be unique")
+	}
+	if len(starts) == 0 {
+		problems = append(problems, "at least one start type is required")
+	}
+	if !hasEnd {
+		problems = append(problems, "at least one end type is required")
+	}
+
+	for _, t := range g.Transitions {
+		if !g.Has(t.From) || !g.Has(t.To) {
+			problems = append(problems, fmt.Sprintf("transition %s -> %s uses an unknown type", t.From, t.To))
+			continue
+		}
+		if t.From == t.To {
+			problems = append(problems, fmt.Sprintf("%s may not follow itself", t.From))
+		}
+
+		// A shift is opened only after the previous one was closed, and a closed shift only reopens
+		fromEnd := g.Category(t.From) == StampCategoryEnd
+		toStart := g.Category(t.To) == StampCategoryStart
+		if fromEnd != toStart {
+			problems = append(problems, fmt.Sprintf("transition %s -> %s must lead from an end type to a start type", t.From, t.To))
+		}
+	}
+
+	reachable := make(map[string]bool, len(g.Types))
+	queue := append([]string(nil), starts...)
+	for _, s := range starts {
+		reachable[s] = true
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range g.next[current] {
+			if !reachable[next] {
+				reachable[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for _, t := range g.Types {
+		if !reachable[t.Name] {
+			problems = append(problems, fmt.Sprintf("%s cannot be reached from a start type", t.Name))
+		}
+
+		switch t.Category {
+		case StampCategoryEnd:
+			if len(g.next[t.Name]) == 0 {
+				problems = append(problems, fmt.Sprintf("no new shift can be started after %s", t.Name))
+			}
+		default:
+			if g.PathToEnd(t.Name) == nil {
+				problems = append(problems, fmt.Sprintf("no end type can be reached from %s", t.Name))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidStampGraph, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// StampTypeStore provides methods for managing stamp types and their transition graph.
+type StampTypeStore struct {
+	db *sql.DB
+}
+
+// GetGraph godoc
+//
+//	@Summary		Retrieves the stamp graph
+//	@Description	Retrieves all stamp types and the transitions allowed between them
+//	@Tags			stamp-types
+//	@Produce		json
+//	@Success		200	{object}	StampGraph
+//	@Failure		500	{object}	error
+//	@Router			/stamp-graph [get]
+func (s *StampTypeStore) GetGraph(ctx context.Context) (*StampGraph, error) {
+	return getStampGraph(ctx, s.db)
+}
+
+// ReplaceGraph godoc
+//
+//	@Summary		Replaces the stamp graph
+//	@Description	Validates the graph and replaces all stamp types and transitions with it. Types that were stamped may not be removed or change their category.
+//	@Tags			stamp-types
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	StampGraph
+//	@Failure		400	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/stamp-graph [put]
+func (s *StampTypeStore) ReplaceGraph(ctx context.Context, graph *StampGraph) error {
+	if err := graph.Validate(); err != nil {
+		return err
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		current, err := getStampGraph(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM stamp_transitions`); err != nil {
+			return err
+		}
+
+		// Removing a type would leave the stamps recorded with it without a category, and changing
+		// its category would change how past shifts are counted and paid
+		for _, t := range current.Types {
+			removed := !graph.Has(t.Name)
+			if !removed && graph.Category(t.Name) == t.Category {
+				continue
+			}
+
+			var used bool
+			err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM timestamps WHERE stamp_type = ?)`, t.Name).Scan(&used)
+			if err != nil {
+				return err
+			}
+			if used && removed {
+				return fmt.Errorf("%w: %s", ErrStampTypeInUse, t.Name)
+			}
+			if used {
+				return fmt.Errorf("%w: the category of %s cannot change from %s to %s", ErrStampTypeInUse, t.Name, t.Category, graph.Category(t.Name))
+			}
+
+			if !removed {
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, `DELETE FROM stamp_types WHERE name = ?`, t.Name); err != nil {
+				return err
+			}
+		}
+
+		for _, t := range graph.Types {
+			_, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO stamp_types (name, category, description) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE category = VALUES(category), description = VALUES(description)`,
+				t.Name,
+				t.Category,
+				t.Description,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, t := range graph.Transitions {
+			_, err := tx.ExecContext(ctx, `INSERT INTO stamp_transitions (from_type, to_type) VALUES (?, ?)`, t.From, t.To)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// getStampGraph loads the stamp types and transitions.
+func getStampGraph(ctx context.Context, q querier) (*StampGraph, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := q.QueryContext(ctx, `SELECT name, category, description FROM stamp_types ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make([]StampType, 0)
+	for rows.Next() {
+		var t StampType
+		if err := rows.Scan(&t.Name, &t.Category, &t.Description); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, `SELECT from_type, to_type FROM stamp_transitions ORDER BY from_type ASC, to_type ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := make([]StampTransition, 0)
+	for rows.Next() {
+		var t StampTransition
+		if err := rows.Scan(&t.From, &t.To); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewStampGraph(types, transitions), nil
+}
+
+// checkStampType fails with ErrUnknownStampType if name is not a defined stamp type.
+func checkStampType(ctx context.Context, q querier, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM stamp_types WHERE name = ?)`, name).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownStampType, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testStampGraph returns the stamp graph the database is seeded with.
+func testStampGraph() *StampGraph {
+	return NewStampGraph(
+		[]StampType{
+			{Name: "sign-in", Category: StampCategoryStart},
+			{Name: "sign-out", Category: StampCategoryEnd},
+			{Name: "start-break", Category: StampCategoryUnpaidBreak},
+			{Name: "end-break", Category: StampCategoryWork},
+		},
+		[]StampTransition{
+			{From: "sign-out", To: "sign-in"},
+			{From: "sign-in", To: "sign-out"},
+			{From: "sign-in", To: "start-break"},
+			{From: "start-break", To: "end-break"},
+			{From: "end-break", To: "sign-out"},
+			{From: "end-break", To: "start-break"},
+		},
+	)
+}
+
+func TestStampGraphPathToEnd(t *testing.T) {
+	g := testStampGraph()
+
+	tests := []struct {
+		from string
+		want []string
+	}{
+		{"sign-in", []string{"sign-out"}},
+		{"start-break", []string{"end-break", "sign-out"}},
+		{"end-break", []string{"sign-out"}},
+		{"sign-out", nil},
+		{"unknown", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			if got := g.PathToEnd(tt.from); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStampGraphPathToEndShortest(t *testing.T) {
+	// travel -> on-site -> sign-out is longer than travel -> sign-out
+	g := NewStampGraph(
+		[]StampType{
+			{Name: "sign-in", Category: StampCategoryStart},
+			{Name: "sign-out", Category: StampCategoryEnd},
+			{Name: "travel", Category: StampCategoryWork},
+			{Name: "on-site", Category: StampCategoryWork},
+		},
+		[]StampTransition{
+			{From: "sign-out", To: "sign-in"},
+			{From: "sign-in", To: "travel"},
+			{From: "travel", To: "on-site"},
+			{From: "on-site", To: "sign-out"},
+			{From: "travel", To: "sign-out"},
+		},
+	)
+
+	if got := g.PathToEnd("sign-in"); !slices.Equal(got, []string{"travel", "sign-out"}) {
+		t.Errorf("got %v, want [travel sign-out]", got)
+	}
+}
+
+func TestStampGraphValidate(t *testing.T) {
+	base := testStampGraph()
+
+	tests := []struct {
+		name        string
+		types       []StampType
+		transitions []StampTransition
+		problems    []string // Substrings of the error; none means the graph is valid
+	}{
+		{
+			name:        "seeded graph",
+			types:       base.Types,
+			transitions: base.Transitions,
+		},
+		{
+			name:        "unknown category",
+			types:       append(slices.Clone(base.Types), StampType{Name: "nap", Category: "sleep"}),
+			transitions: append(slices.Clone(base.Transitions), StampTransition{"sign-in", "nap"}, StampTransition{"nap", "sign-out"}),
+			problems:    []string{`nap has unknown category "sleep"`},
+		},
+		{
+			name:        "duplicate names",
+			types:       append(slices.Clone(base.Types), StampType{Name: "sign-in", Category: StampCategoryStart}),
+			transitions: base.Transitions,
+			problems:    []string{"stamp type names must be unique"},
+		},
+		{
+			name:     "no start or end type",
+			types:    []StampType{{Name: "travel", Category: StampCategoryWork}},
+			problems: []string{"at least one start type is required", "at least one end type is required"},
+		},
+		{
+			name:        "transition with an unknown type",
+			types:       base.Types,
+			transitions: append(slices.Clone(base.Transitions), StampTransition{"sign-in", "lunch"}),
+			problems:    []string{"transition sign-in -> lunch uses an unknown type"},
+		},
+		{
+			name:        "type following itself",
+			types:       base.Types,
+			transitions: append(slices.Clone(base.Transitions), StampTransition{"start-break", "start-break"}),
+			problems:    []string{"start-break may not follow itself"},
+		},
+		{
+			name:        "shift opened without being closed",
+			types:       base.Types,
+			transitions: append(slices.Clone(base.Transitions), StampTransition{"end-break", "sign-in"}),
+			problems:    []string{"transition end-break -> sign-in must lead from an end type to a start type"},
+		},
+		{
+			name:        "closed shift continued",
+			types:       base.Types,
+			transitions: append(slices.Clone(base.Transitions), StampTransition{"sign-out", "start-break"}),
+			problems:    []string{"transition sign-out -> start-break must lead from an end type to a start type"},
+		},
+		{
+			name:  "unreachable type",
+			types: append(slices.Clone(base.Types), StampType{Name: "travel", Category: StampCategoryWork}),
+			transitions: append(slices.Clone(base.Transitions),
+				StampTransition{"travel", "sign-out"},
+			),
+			problems: []string{"travel cannot be reached from a start type"},
+		},
+		{
+			name:  "shift that cannot be closed",
+			types: append(slices.Clone(base.Types), StampType{Name: "travel", Category: StampCategoryWork}),
+			transitions: append(slices.Clone(base.Transitions),
+				StampTransition{"sign-in", "travel"},
+			),
+			problems: []string{"no end type can be reached from travel"},
+		},
+		{
+			name:        "no new shift after an end",
+			types:       base.Types,
+			transitions: slices.DeleteFunc(slices.Clone(base.Transitions), func(t StampTransition) bool { return t.From == "sign-out" }),
+			problems:    []string{"no new shift can be started after sign-out"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewStampGraph(tt.types, tt.transitions).Validate()
+
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("got %v, want a valid graph", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidStampGraph) {
+				t.Fatalf("got %v, want ErrInvalidStampGraph", err)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("%q does not mention %q", err, problem)
+				}
+			}
+		})
+	}
+}
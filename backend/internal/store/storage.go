@@ -23,7 +23,14 @@ type Storage struct {
 		Update(context.Context, *Timestamp) error
 		GetUserFeed(context.Context, int64, Query) ([]Timestamp, error)
 		GetLatestTimestamp(context.Context, int64) (*Timestamp, error)
-		GetFinishedShifts(context.Context, int64) ([]Shift, error)
+		GetFinishedShifts(context.Context, int64, ShiftQuery) ([]Shift, string, error)
+		CreateManual(context.Context, *Timestamp) error
+		GetPending(context.Context, int64) ([]Timestamp, error)
+		Decide(context.Context, *Timestamp, string, int64) error
+		ValidateLedger(context.Context, int64) ([]LedgerViolation, error)
+		GetCurrentShift(context.Context, int64) (*CurrentShift, error)
+		GetOpenShifts(context.Context, time.Time) ([]Timestamp, error)
+		AutoSignOut(context.Context, int64, time.Duration) (*ClosedShift, error)
 	}
 
 	// Users interface provides methods for managing users in the database.
@@ -31,10 +38,12 @@ type Storage struct {
 		GetAll(context.Context) ([]*User, error)
 		GetByID(context.Context, int64) (*User, error)
 		GetByEmail(context.Context, string) (*User, error)
+		GetByManager(context.Context, int64) ([]*User, error)
 		Create(context.Context, *sql.Tx, *User) error
 		CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error
 		Activate(context.Context, string) error
 		Update(context.Context, *User) error
+		UpdateTimeZone(context.Context, int64, string) error
 		ChangePassword(context.Context, *User) error
 		ResetPassword(context.Context, string, *User) error
 		RequestPasswordAndEmailReset(context.Context, *User, string, time.Duration) error
@@ -45,17 +54,217 @@ type Storage struct {
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+
+	// PayPeriods interface provides methods for managing timesheet periods and their approval cycle.
+	PayPeriods interface {
+		Create(context.Context, *PayPeriod) error
+		GetByID(context.Context, int64) (*PayPeriod, error)
+		GetByUser(context.Context, int64) ([]PayPeriod, error)
+		Transition(context.Context, *PayPeriod, string, int64, string) error
+		GetTransitions(context.Context, int64) ([]PayPeriodTransition, error)
+	}
+
+	// OvertimePolicies interface provides methods for managing overtime policies and their assignments.
+	OvertimePolicies interface {
+		Create(context.Context, *OvertimePolicy) error
+		GetAll(context.Context) ([]OvertimePolicy, error)
+		GetByID(context.Context, int64) (*OvertimePolicy, error)
+		GetForUser(context.Context, *User) (*OvertimePolicy, error)
+		Update(context.Context, *OvertimePolicy) error
+		Delete(context.Context, int64) error
+		AssignToUser(context.Context, int64, int64) error
+		AssignToRole(context.Context, int64, int64) error
+	}
+
+	// Holidays interface provides methods for managing holiday calendars and their holidays in the database.
+	Holidays interface {
+		GetCalendars(context.Context) ([]HolidayCalendar, error)
+		CreateCalendar(context.Context, *HolidayCalendar) error
+		DeleteCalendar(context.Context, int64) error
+		GetBetween(context.Context, int64, string, string) ([]Holiday, error)
+		GetForUser(context.Context, int64, string, string) ([]Holiday, error)
+		Create(context.Context, *Holiday) error
+		Import(context.Context, int64, []Holiday) (int, int, error)
+		SetUserCalendar(context.Context, int64, *int64) error
+		SetSiteCalendar(context.Context, int64, *int64) error
+		Delete(context.Context, int64) error
+	}
+
+	// Leave interface provides methods for managing leave types, accrual policies, leave requests and balances.
+	Leave interface {
+		CreateType(context.Context, *LeaveType) error
+		GetTypes(context.Context) ([]LeaveType, error)
+		CreatePolicy(context.Context, *LeavePolicy) error
+		GetPolicies(context.Context) ([]LeavePolicy, error)
+		AssignPolicy(context.Context, int64, int64, string) error
+		CreateRequest(context.Context, *LeaveRequest, []LeaveDay) error
+		GetRequestByID(context.Context, int64) (*LeaveRequest, error)
+		GetRequests(context.Context, int64) ([]LeaveRequest, error)
+		GetPending(context.Context, int64) ([]LeaveRequest, error)
+		Decide(context.Context, *LeaveRequest, string, int64) error
+		Cancel(context.Context, *LeaveRequest, string) error
+		GetBalances(context.Context, int64, string) ([]LeaveBalance, error)
+		GetDays(context.Context, int64, string, string) ([]LeaveDay, error)
+	}
+
+	// StampTypes interface provides methods for managing stamp types and their transition graph.
+	StampTypes interface {
+		GetGraph(context.Context) (*StampGraph, error)
+		ReplaceGraph(context.Context, *StampGraph) error
+	}
+
+	// Projects interface provides methods for managing clients, projects and tasks.
+	Projects interface {
+		CreateClient(context.Context, *Client) error
+		GetClients(context.Context) ([]Client, error)
+		GetClientByID(context.Context, int64) (*Client, error)
+		CreateProject(context.Context, *Project) error
+		GetProjects(context.Context, int64) ([]Project, error)
+		GetProjectByID(context.Context, int64) (*Project, error)
+		CreateTask(context.Context, *Task) error
+		GetTasks(context.Context, int64) ([]Task, error)
+		GetTaskByID(context.Context, int64) (*Task, error)
+	}
+
+	// TaskEntries interface provides methods for recording time against tasks.
+	TaskEntries interface {
+		Create(context.Context, *TaskEntry) error
+		Start(context.Context, *TaskEntry) error
+		Stop(context.Context, int64) (*TaskEntry, error)
+		GetRunning(context.Context, int64) (*TaskEntry, error)
+		GetByID(context.Context, int64) (*TaskEntry, error)
+		GetByUser(context.Context, int64, time.Time, time.Time) ([]TaskEntry, error)
+		Delete(context.Context, int64) error
+		GetHours(context.Context, time.Time, time.Time, int64) (*TaskHoursReport, error)
+	}
+
+	// Billing interface provides methods for managing billing rates and the clients shifts are billed to.
+	Billing interface {
+		CreateRate(context.Context, *BillingRate) error
+		GetRates(context.Context, int64, int64) ([]BillingRate, error)
+		GetRatesForUser(context.Context, *User) ([]BillingRate, error)
+		AssignShiftClient(context.Context, int64, int64) error
+		UnassignShiftClient(context.Context, int64) error
+		GetClientUsers(context.Context, int64, time.Time, time.Time) ([]int64, error)
+	}
+
+	// Schedules interface provides methods for managing planned shifts, rosters and shift templates.
+	Schedules interface {
+		Create(context.Context, *PlannedShift) error
+		CreateBatch(context.Context, []PlannedShift) ([]PlannedShift, int, error)
+		GetByID(context.Context, int64) (*PlannedShift, error)
+		Update(context.Context, *PlannedShift) error
+		Delete(context.Context, int64) error
+		GetForTeam(context.Context, int64, int64, time.Time, time.Time) ([]PlannedShift, error)
+		GetUpcoming(context.Context, int64, time.Time, time.Time) ([]PlannedShift, error)
+		Publish(context.Context, int64, time.Time, time.Time) (int64, error)
+		CreateTemplate(context.Context, *ShiftTemplate) error
+		GetTemplates(context.Context) ([]ShiftTemplate, error)
+		GetTemplateByID(context.Context, int64) (*ShiftTemplate, error)
+		DeleteTemplate(context.Context, int64) error
+	}
+
+	// WorkPatterns interface provides methods for managing the work patterns of users.
+	WorkPatterns interface {
+		Get(context.Context, int64) (*WorkPattern, error)
+		Set(context.Context, *WorkPattern) error
+		Delete(context.Context, int64) error
+	}
+
+	// Kiosks interface provides methods for managing sites, shared kiosks and kiosk PINs.
+	Kiosks interface {
+		CreateSite(context.Context, *Site) error
+		GetSites(context.Context) ([]Site, error)
+		Create(context.Context, *Kiosk, string) error
+		GetAll(context.Context) ([]Kiosk, error)
+		Authenticate(context.Context, string) (*Kiosk, error)
+		RotateToken(context.Context, int64, string) (*Kiosk, error)
+		Deactivate(context.Context, int64) error
+		SetUserSite(context.Context, int64, *int64) error
+		GetSiteUsers(context.Context, int64) ([]KioskUser, error)
+		SetPIN(context.Context, int64, string) error
+		CheckPIN(context.Context, int64, int64, string, PINPolicy) error
+	}
+
+	// Sessions interface provides methods for managing login sessions and rotating their refresh tokens.
+	Sessions interface {
+		Create(context.Context, *Session, string) error
+		Rotate(context.Context, string, string) (*Session, error)
+		GetActive(context.Context, int64) ([]Session, error)
+		IsActive(context.Context, int64, int64) (bool, error)
+		Revoke(context.Context, int64, int64) error
+		RevokeAll(context.Context, int64) (int64, error)
+	}
+
+	// LoginThrottles interface provides methods for throttling failed logins per account and IP address.
+	LoginThrottles interface {
+		Attempt(context.Context, string, string, LoginPolicy, LoginPolicy) (*LoginAttempt, error)
+		Succeed(context.Context, string, string) error
+		Unlock(context.Context, string) error
+	}
+
+	// MFA interface provides methods for managing TOTP secrets, recovery codes and login challenges.
+	MFA interface {
+		GetTOTP(context.Context, int64) (*TOTP, error)
+		SetPendingTOTP(context.Context, int64, string) error
+		EnableTOTP(context.Context, int64, int64, []string) error
+		UseTOTP(context.Context, int64, int64) error
+		DisableTOTP(context.Context, int64) error
+		ReplaceRecoveryCodes(context.Context, int64, []string) error
+		CountRecoveryCodes(context.Context, int64) (int, error)
+		UseRecoveryCode(context.Context, int64, string) error
+		CreateChallenge(context.Context, *MFAChallenge, string) error
+		GetChallenge(context.Context, string, int) (*MFAChallenge, error)
+		FailChallenge(context.Context, string) error
+		CompleteChallenge(context.Context, string) error
+	}
+
+	// SigningKeys interface provides methods for managing and rotating the keys access tokens are signed with.
+	SigningKeys interface {
+		GetValid(context.Context, time.Time) ([]SigningKey, error)
+		Rotate(context.Context, *SigningKey, time.Time, time.Duration) error
+	}
+
+	// Idempotency interface provides methods for storing replayable responses in the database.
+	Idempotency interface {
+		Get(context.Context, int64, string) (*IdempotencyRecord, error)
+		Reserve(context.Context, *IdempotencyRecord) (bool, error)
+		Save(context.Context, *IdempotencyRecord) error
+		Delete(context.Context, int64, string) error
+	}
 }
 
 // NewStorage creates a new Storage instance with the provided database connection.
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Timestamps: &TimestampStore{db},
-		Users:      &UserStore{db},
-		Roles:      &RoleStore{db},
+		Timestamps:       &TimestampStore{db},
+		Users:            &UserStore{db},
+		Roles:            &RoleStore{db},
+		Idempotency:      &IdempotencyStore{db},
+		PayPeriods:       &PayPeriodStore{db},
+		OvertimePolicies: &OvertimePolicyStore{db},
+		Holidays:         &HolidayStore{db},
+		Leave:            &LeaveStore{db},
+		StampTypes:       &StampTypeStore{db},
+		Projects:         &ProjectStore{db},
+		TaskEntries:      &TaskEntryStore{db},
+		Billing:          &BillingStore{db},
+		Schedules:        &ScheduleStore{db},
+		WorkPatterns:     &WorkPatternStore{db},
+		Kiosks:           &KioskStore{db},
+		Sessions:         &SessionStore{db},
+		SigningKeys:      &SigningKeyStore{db},
+		MFA:              &MFAStore{db},
+		LoginThrottles:   &LoginThrottleStore{db},
 	}
 }
 
+// parseDBTime parses a DATETIME/TIMESTAMP column value. The connection runs in UTC
+// (see db.New), so the result is always in UTC.
+func parseDBTime(raw string) (time.Time, error) {
+	return time.ParseInLocation(time.DateTime, raw, time.UTC)
+}
+
 // withTx executes a function within a database transaction.
 func withTx(db *sql.DB, ctx context.Context, fn func(*sql.Tx) error) error {
 	tx, err := db.BeginTx(ctx, nil)
@@ -0,0 +1,584 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrOutsideShift is returned when task time does not lie within one of the user's shifts.
+	ErrOutsideShift = errors.New("task time must lie within a single shift")
+	// ErrTimerRunning is returned when a task timer is started while another one runs.
+	ErrTimerRunning = errors.New("a task timer is already running")
+	// ErrNoTimerRunning is returned when there is no task timer to stop.
+	ErrNoTimerRunning = errors.New("no task timer is running")
+	// ErrTaskOverlap is returned when task time overlaps another task entry of the user.
+	ErrTaskOverlap = errors.New("task time overlaps another task entry")
+)
+
+// TaskEntry is time a user spent on a task, either logged afterwards or recorded with a timer.
+// EndedAt is nil while the timer runs.
+type TaskEntry struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	TaskID      int64      `json:"task_id"`
+	TaskName    string     `json:"task_name"`
+	ProjectID   int64      `json:"project_id"`
+	ProjectName string     `json:"project_name"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at"`
+	Seconds     float64    `json:"seconds"` // Elapsed time so far for a running timer
+	Note        string     `json:"note"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// In renders every time of the entry in loc.
+func (e *TaskEntry) In(loc *time.Location) {
+	e.StartedAt = e.StartedAt.In(loc)
+	e.CreatedAt = e.CreatedAt.In(loc)
+	if e.EndedAt != nil {
+		endedAt := e.EndedAt.In(loc)
+		e.EndedAt = &endedAt
+	}
+}
+
+// TaskHours is the time recorded against one project, task or user.
+type TaskHours struct {
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// TaskHoursReport sums the task time within [From, To) per project, task and user.
+// Entries crossing the bounds are clipped and running timers count up to now.
+type TaskHoursReport struct {
+	From         time.Time   `json:"from"`
+	To           time.Time   `json:"to"`
+	TotalSeconds float64     `json:"total_seconds"`
+	ByProject    []TaskHours `json:"by_project"`
+	ByTask       []TaskHours `json:"by_task"`
+	ByUser       []TaskHours `json:"by_user"`
+}
+
+// TaskEntryStore provides methods for recording time against tasks.
+type TaskEntryStore struct {
+	db *sql.DB
+}
+
+const taskEntryColumns = `
+	e.id, e.user_id, e.task_id, t.name, p.id, p.name, e.started_at, e.ended_at, e.note, e.created_at
+	FROM task_entries e
+	JOIN tasks t ON t.id = e.task_id
+	JOIN projects p ON p.id = t.project_id
+`
+
+// Create godoc
+//
+//	@Summary		Logs task time
+//	@Description	Inserts a finished task entry; the time must lie within one of the user's shifts and must not overlap other entries
+//	@Tags			tasks
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	TaskEntry
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/task-entries [post]
+func (s *TaskEntryStore) Create(ctx context.Context, entry *TaskEntry) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Lock the user so concurrent entries cannot overlap
+		if err := lockUser(ctx, tx, entry.UserID); err != nil {
+			return err
+		}
+
+		_, signOut, err := getShiftEnvelope(ctx, tx, entry.UserID, entry.StartedAt)
+		if err != nil {
+			return err
+		}
+		if signOut != nil && entry.EndedAt.After(*signOut) {
+			return ErrOutsideShift
+		}
+
+		if err := checkTaskOverlap(ctx, tx, entry.UserID, entry.StartedAt, *entry.EndedAt); err != nil {
+			return err
+		}
+
+		return s.insert(ctx, tx, entry)
+	})
+}
+
+// Start godoc
+//
+//	@Summary		Starts a task timer
+//	@Description	Starts a timer for the user on a task; the user must be in an open shift and have no other timer running
+//	@Tags			tasks
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	TaskEntry
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/task-entries/timer [post]
+func (s *TaskEntryStore) Start(ctx context.Context, entry *TaskEntry) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, entry.UserID); err != nil {
+			return err
+		}
+
+		running, err := getRunningTaskEntry(ctx, tx, entry.UserID)
+		if err != nil {
+			return err
+		}
+		if running != nil {
+			return ErrTimerRunning
+		}
+
+		entry.StartedAt = time.Now().UTC()
+		entry.EndedAt = nil
+
+		_, signOut, err := getShiftEnvelope(ctx, tx, entry.UserID, entry.StartedAt)
+		if err != nil {
+			return err
+		}
+		if signOut != nil {
+			return ErrOutsideShift
+		}
+
+		return s.insert(ctx, tx, entry)
+	})
+}
+
+// Stop godoc
+//
+//	@Summary		Stops the task timer
+//	@Description	Stops the running task timer of the user. A timer that outlived its shift ends at the end of the shift.
+//	@Tags			tasks
+//	@Produce		json
+//	@Success		200	{object}	TaskEntry
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/task-entries/timer/stop [post]
+func (s *TaskEntryStore) Stop(ctx context.Context, userID int64) (*TaskEntry, error) {
+	var entry *TaskEntry
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		running, err := getRunningTaskEntry(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if running == nil {
+			return ErrNoTimerRunning
+		}
+
+		endedAt := time.Now().UTC()
+
+		// Task time may not run past the end of the shift the timer was started in
+		_, signOut, err := getShiftEnvelope(ctx, tx, userID, running.StartedAt)
+		if err != nil && !errors.Is(err, ErrOutsideShift) {
+			return err
+		}
+		if signOut != nil && signOut.Before(endedAt) {
+			endedAt = *signOut
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, `UPDATE task_entries SET ended_at = ? WHERE id = ?`, endedAt, running.ID); err != nil {
+			return err
+		}
+
+		running.EndedAt = &endedAt
+		running.Seconds = endedAt.Sub(running.StartedAt).Seconds()
+		entry = running
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetRunning godoc
+//
+//	@Summary		Retrieves the running task timer
+//	@Description	Retrieves the running task timer of the user, or nil if there is none
+//	@Tags			tasks
+//	@Produce		json
+//	@Success		200	{object}	TaskEntry
+//	@Failure		500	{object}	error
+//	@Router			/task-entries/timer [get]
+func (s *TaskEntryStore) GetRunning(ctx context.Context, userID int64) (*TaskEntry, error) {
+	return getRunningTaskEntry(ctx, s.db, userID)
+}
+
+// GetByID godoc
+//
+//	@Summary		Retrieves a task entry by ID
+//	@Description	Retrieves a task entry with its task and project names
+//	@Tags			tasks
+//	@Produce		json
+//	@Param			id	path		int	true	"Task entry ID"
+//	@Success		200	{object}	TaskEntry
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+func (s *TaskEntryStore) GetByID(ctx context.Context, id int64) (*TaskEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var entry TaskEntry
+	if err := scanTaskEntry(s.db.QueryRowContext(ctx, `SELECT `+taskEntryColumns+` WHERE e.id = ?`, id), &entry); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+// GetByUser godoc
+//
+//	@Summary		Retrieves the task entries of a user
+//	@Description	Retrieves the task entries of a user that overlap [from, to) in time order
+//	@Tags			tasks
+//	@Produce		json
+//	@Success		200	{object}	[]TaskEntry
+//	@Failure		500	{object}	error
+//	@Router			/task-entries [get]
+func (s *TaskEntryStore) GetByUser(ctx context.Context, userID int64, from, to time.Time) ([]TaskEntry, error) {
+	query := `SELECT ` + taskEntryColumns + `
+		WHERE e.user_id = ? AND e.started_at < ? AND (e.ended_at IS NULL OR e.ended_at > ?)
+		ORDER BY e.started_at ASC, e.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, to.UTC(), from.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]TaskEntry, 0)
+	for rows.Next() {
+		var e TaskEntry
+		if err := scanTaskEntry(rows, &e); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Delete godoc
+//
+//	@Summary		Deletes a task entry
+//	@Description	Removes a task entry by its ID
+//	@Tags			tasks
+//	@Produce		json
+//	@Param			id	path		int	true	"Task entry ID"
+//	@Success		204	{string}	string	"Task entry deleted"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/task-entries/{id} [delete]
+func (s *TaskEntryStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM task_entries WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetHours godoc
+//
+//	@Summary		Reports task hours
+//	@Description	Sums the task time within [from, to) per project, task and user. A userID of 0 includes all users.
+//	@Tags			tasks
+//	@Produce		json
+//	@Success		200	{object}	TaskHoursReport
+//	@Failure		500	{object}	error
+//	@Router			/task-entries/report [get]
+func (s *TaskEntryStore) GetHours(ctx context.Context, from, to time.Time, userID int64) (*TaskHoursReport, error) {
+	query := `
+		SELECT e.user_id, CONCAT_WS(' ', u.first_name, u.last_name), e.task_id, t.name, p.id, p.name,
+			e.started_at, e.ended_at
+		FROM task_entries e
+		JOIN tasks t ON t.id = e.task_id
+		JOIN projects p ON p.id = t.project_id
+		JOIN users u ON u.id = e.user_id
+		WHERE e.started_at < ? AND (e.ended_at IS NULL OR e.ended_at > ?) AND (? = 0 OR e.user_id = ?)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, to.UTC(), from.UTC(), userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byProject := make(map[int64]*TaskHours)
+	byTask := make(map[int64]*TaskHours)
+	byUser := make(map[int64]*TaskHours)
+	add := func(totals map[int64]*TaskHours, id int64, name string, seconds float64) {
+		if totals[id] == nil {
+			totals[id] = &TaskHours{ID: id, Name: name}
+		}
+		totals[id].Seconds += seconds
+	}
+
+	report := &TaskHoursReport{From: from, To: to}
+	now := time.Now()
+
+	for rows.Next() {
+		var userID, taskID, projectID int64
+		var userName, taskName, projectName string
+		var rawStartedAt, rawEndedAt []byte
+		if err := rows.Scan(&userID, &userName, &taskID, &taskName, &projectID, &projectName, &rawStartedAt, &rawEndedAt); err != nil {
+			return nil, err
+		}
+
+		startedAt, err := parseDBTime(string(rawStartedAt))
+		if err != nil {
+			return nil, err
+		}
+
+		endedAt := now
+		if rawEndedAt != nil {
+			if endedAt, err = parseDBTime(string(rawEndedAt)); err != nil {
+				return nil, err
+			}
+		}
+
+		seconds := overlap(from, to, startedAt, endedAt).Seconds()
+
+		report.TotalSeconds += seconds
+		add(byProject, projectID, projectName, seconds)
+		add(byTask, taskID, taskName, seconds)
+		add(byUser, userID, userName, seconds)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.ByProject = sortedTaskHours(byProject)
+	report.ByTask = sortedTaskHours(byTask)
+	report.ByUser = sortedTaskHours(byUser)
+
+	return report, nil
+}
+
+// insert stores a new task entry within tx.
+func (s *TaskEntryStore) insert(ctx context.Context, tx *sql.Tx, entry *TaskEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO task_entries (user_id, task_id, started_at, ended_at, note) VALUES (?, ?, ?, ?, ?)`,
+		entry.UserID,
+		entry.TaskID,
+		entry.StartedAt,
+		entry.EndedAt,
+		entry.Note,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+	entry.CreatedAt = time.Now().UTC()
+	if entry.EndedAt != nil {
+		entry.Seconds = entry.EndedAt.Sub(entry.StartedAt).Seconds()
+	}
+
+	return nil
+}
+
+// getRunningTaskEntry returns the task entry of the user whose timer is still running, or nil.
+func getRunningTaskEntry(ctx context.Context, q querier, userID int64) (*TaskEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var entry TaskEntry
+	err := scanTaskEntry(q.QueryRowContext(ctx, `SELECT `+taskEntryColumns+` WHERE e.user_id = ? AND e.ended_at IS NULL`, userID), &entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+// getShiftEnvelope returns the start and end of the user's shift that contains t. The end is nil
+// while the shift is open. ErrOutsideShift is returned if t does not fall into any shift.
+func getShiftEnvelope(ctx context.Context, q querier, userID int64, t time.Time) (time.Time, *time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rawSignIn []byte
+	err := q.QueryRowContext(
+		ctx,
+		`SELECT MAX(time) FROM timestamps WHERE user_id = ? AND status = 'approved' AND time <= ?
+			AND stamp_type IN (SELECT name FROM stamp_types WHERE category = 'start')`,
+		userID,
+		t.UTC(),
+	).Scan(&rawSignIn)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if rawSignIn == nil {
+		return time.Time{}, nil, ErrOutsideShift
+	}
+
+	signIn, err := parseDBTime(string(rawSignIn))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	var rawSignOut []byte
+	err = q.QueryRowContext(
+		ctx,
+		`SELECT MIN(time) FROM timestamps WHERE user_id = ? AND status = 'approved' AND time > ?
+			AND stamp_type IN (SELECT name FROM stamp_types WHERE category = 'end')`,
+		userID,
+		signIn,
+	).Scan(&rawSignOut)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if rawSignOut == nil {
+		return signIn, nil, nil
+	}
+
+	signOut, err := parseDBTime(string(rawSignOut))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if !t.Before(signOut) {
+		return time.Time{}, nil, ErrOutsideShift
+	}
+
+	return signIn, &signOut, nil
+}
+
+// checkTaskOverlap fails with ErrTaskOverlap if [start, end) overlaps a task entry of the user.
+func checkTaskOverlap(ctx context.Context, q querier, userID int64, start, end time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var overlaps bool
+	err := q.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM task_entries
+			WHERE user_id = ? AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)
+		)`,
+		userID,
+		end.UTC(),
+		start.UTC(),
+	).Scan(&overlaps)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return ErrTaskOverlap
+	}
+
+	return nil
+}
+
+// scanTaskEntry scans a row selected with taskEntryColumns into e.
+func scanTaskEntry(row scanner, e *TaskEntry) error {
+	var rawStartedAt, rawEndedAt, rawCreatedAt []byte
+
+	err := row.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.TaskID,
+		&e.TaskName,
+		&e.ProjectID,
+		&e.ProjectName,
+		&rawStartedAt,
+		&rawEndedAt,
+		&e.Note,
+		&rawCreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if e.StartedAt, err = parseDBTime(string(rawStartedAt)); err != nil {
+		return err
+	}
+
+	if e.CreatedAt, err = parseDBTime(string(rawCreatedAt)); err != nil {
+		return err
+	}
+
+	end := time.Now().UTC()
+	if rawEndedAt != nil {
+		if end, err = parseDBTime(string(rawEndedAt)); err != nil {
+			return err
+		}
+		e.EndedAt = &end
+	}
+	e.Seconds = end.Sub(e.StartedAt).Seconds()
+
+	return nil
+}
+
+// sortedTaskHours returns the totals ordered by name.
+func sortedTaskHours(totals map[int64]*TaskHours) []TaskHours {
+	hours := make([]TaskHours, 0, len(totals))
+	for _, h := range totals {
+		hours = append(hours, *h)
+	}
+
+	sort.Slice(hours, func(i, j int) bool {
+		if hours[i].Name != hours[j].Name {
+			return hours[i].Name < hours[j].Name
+		}
+		return hours[i].ID < hours[j].ID
+	})
+
+	return hours
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimesheetPeriod holds the totals of one day, ISO week or month of a timesheet.
+// All durations are in seconds.
+type TimesheetPeriod struct {
+	Period       string  `json:"period"` // "2024-05-13", "2024-W20" or "2024-05"
+	Start        string  `json:"start"`  // First local date of the period within the timesheet range
+	End          string  `json:"end"`    // Last local date of the period within the timesheet range
+	Shifts       int     `json:"shifts"`
+	ShiftTime    float64 `json:"shift_time"`
+	BreakTime    float64 `json:"break_time"`
+	NetWorkTime  float64 `json:"net_work_time"`
+	ExpectedTime float64 `json:"expected_time"` // Contracted time for the period
+	Variance     float64 `json:"variance"`      // NetWorkTime plus LeaveTime minus ExpectedTime
+
+	Holidays    int     `json:"holidays"`          // Holidays in the period, on which no time is expected
+	HolidayTime float64 `json:"holiday_time"`      // Net work time on holidays
+	Holiday     string  `json:"holiday,omitempty"` // Name of the holiday, on days only
+
+	LeaveDays float64 `json:"leave_days"`      // Days of approved leave in the period
+	LeaveTime float64 `json:"leave_time"`      // Expected time covered by paid leave, counted as paid non-worked time
+	Leave     string  `json:"leave,omitempty"` // Leave type, on days only
+
+	Overtime *OvertimeBreakdown `json:"overtime,omitempty"` // Set when an overtime policy applies to the user
+}
+
+// Timesheet aggregates a user's finished shifts per day, ISO week and month
+// between two local dates (inclusive) and compares them with the contracted hours.
+type Timesheet struct {
+	UserID          int64             `json:"user_id"`
+	TimeZone        string            `json:"time_zone"`
+	From            string            `json:"from"`
+	To              string            `json:"to"`
+	ContractedHours float64           `json:"contracted_hours"` // Contracted work hours per week
+	Days            []TimesheetPeriod `json:"days"`
+	Weeks           []TimesheetPeriod `json:"weeks"`
+	Months          []TimesheetPeriod `json:"months"`
+	Total           TimesheetPeriod   `json:"total"`
+
+	Violations []ComplianceViolation `json:"violations"` // Compliance violations on days in the range
+	Leave      []LeaveDay            `json:"leave"`      // Approved leave days in the range
+}
+
+// workdaysPerWeek spreads the weekly contracted hours over Monday to Friday.
+const workdaysPerWeek = 5
+
+// NewTimesheet builds the timesheet of user for the local dates from..to from shifts
+// that were split into days in the user's time zone (see ShiftQuery.Location).
+// No time is expected on the holidays of the user's calendar. Approved paid leave covers the expected
+// time of its days as paid non-worked time, while unpaid leave removes it.
+func NewTimesheet(user *User, from, to time.Time, shifts []Shift, holidays []Holiday, leave []LeaveDay) *Timesheet {
+	loc := user.Location()
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+
+	ts := &Timesheet{
+		UserID:          user.ID,
+		TimeZone:        loc.String(),
+		From:            from.Format(time.DateOnly),
+		To:              to.Format(time.DateOnly),
+		ContractedHours: user.ContractedHours,
+		Days:            make([]TimesheetPeriod, 0),
+		Weeks:           make([]TimesheetPeriod, 0),
+		Months:          make([]TimesheetPeriod, 0),
+		Total:           TimesheetPeriod{Period: "total"},
+		Violations:      make([]ComplianceViolation, 0),
+		Leave:           make([]LeaveDay, 0),
+	}
+
+	names := make(map[string]string, len(holidays))
+	for _, h := range holidays {
+		names[h.Date] = h.Name
+	}
+
+	// One entry per local date in the range, even if nothing was worked
+	days := make(map[string]*TimesheetPeriod)
+	for d := from; !d.After(to); d = time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc) {
+		date := d.Format(time.DateOnly)
+
+		day := TimesheetPeriod{Period: date, Start: date, End: date, Holiday: names[date]}
+		switch {
+		case day.Holiday != "":
+			day.Holidays = 1
+		case d.Weekday() != time.Saturday && d.Weekday() != time.Sunday:
+			day.ExpectedTime = user.ContractedHours * 3600 / workdaysPerWeek
+		}
+
+		ts.Days = append(ts.Days, day)
+	}
+	for i := range ts.Days {
+		days[ts.Days[i].Period] = &ts.Days[i]
+	}
+
+	for _, l := range leave {
+		day, ok := days[l.Date]
+		if !ok {
+			continue
+		}
+
+		covered := day.ExpectedTime * l.Amount
+		if l.Paid {
+			day.LeaveTime += covered
+		} else {
+			day.ExpectedTime -= covered
+		}
+		day.LeaveDays += l.Amount
+		day.Leave = l.LeaveType
+
+		ts.Leave = append(ts.Leave, l)
+	}
+
+	for _, shift := range shifts {
+		// Count the shift on the workday it started on
+		if day, ok := days[shift.Workday]; ok {
+			day.Shifts++
+		}
+
+		for _, v := range shift.Violations {
+			if _, ok := days[v.Date]; ok {
+				ts.Violations = append(ts.Violations, v)
+			}
+		}
+
+		for _, part := range shift.Days {
+			day, ok := days[part.Date]
+			if !ok {
+				continue
+			}
+
+			day.ShiftTime += part.End.Sub(part.Start).Seconds()
+			day.BreakTime += part.BreakTime
+			day.NetWorkTime += part.NetWorkTime
+			if day.Holiday != "" {
+				day.HolidayTime += part.NetWorkTime
+			}
+
+			if part.Overtime != nil {
+				if day.Overtime == nil {
+					day.Overtime = &OvertimeBreakdown{}
+				}
+				day.Overtime.Add(*part.Overtime)
+			}
+		}
+	}
+
+	for i := range ts.Days {
+		day := &ts.Days[i]
+		day.Variance = day.NetWorkTime + day.LeaveTime - day.ExpectedTime
+
+		d, _ := time.ParseInLocation(time.DateOnly, day.Period, loc)
+		year, week := d.ISOWeek()
+
+		ts.Weeks = addToPeriod(ts.Weeks, fmt.Sprintf("%04d-W%02d", year, week), *day)
+		ts.Months = addToPeriod(ts.Months, d.Format("2006-01"), *day)
+		ts.Total = sumPeriods(ts.Total, *day)
+	}
+
+	return ts
+}
+
+// addToPeriod adds day to the last period if it has the same key, or starts a new period.
+// Days are visited in order, so periods stay contiguous.
+func addToPeriod(periods []TimesheetPeriod, key string, day TimesheetPeriod) []TimesheetPeriod {
+	if n := len(periods); n > 0 && periods[n-1].Period == key {
+		periods[n-1] = sumPeriods(periods[n-1], day)
+		return periods
+	}
+
+	return append(periods, sumPeriods(TimesheetPeriod{Period: key}, day))
+}
+
+// sumPeriods adds the totals of day to period and widens its date range.
+func sumPeriods(period, day TimesheetPeriod) TimesheetPeriod {
+	if period.Start == "" {
+		period.Start = day.Start
+	}
+	period.End = day.End
+
+	period.Shifts += day.Shifts
+	period.ShiftTime += day.ShiftTime
+	period.BreakTime += day.BreakTime
+	period.NetWorkTime += day.NetWorkTime
+	period.ExpectedTime += day.ExpectedTime
+	period.Holidays += day.Holidays
+	period.HolidayTime += day.HolidayTime
+	period.LeaveDays += day.LeaveDays
+	period.LeaveTime += day.LeaveTime
+	period.Variance = period.NetWorkTime + period.LeaveTime - period.ExpectedTime
+
+	if day.Overtime != nil {
+		// Copy so that periods never share a breakdown with the day they were built from
+		sum := OvertimeBreakdown{}
+		if period.Overtime != nil {
+			sum = *period.Overtime
+		}
+		sum.Add(*day.Overtime)
+		period.Overtime = &sum
+	}
+
+	return period
+}
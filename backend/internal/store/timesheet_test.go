@@ -0,0 +1,190 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewTimesheet(t *testing.T) {
+	// 8 hours are expected on weekdays
+	user := &User{ID: 1, ContractedHours: 40}
+
+	type shift struct {
+		start  string
+		length time.Duration
+	}
+
+	// Durations are compared in hours
+	type period struct {
+		period    string
+		start     string
+		end       string
+		shifts    int
+		expected  float64
+		net       float64
+		leave     float64
+		leaveDays float64
+		holidays  int
+		onHoliday float64
+		variance  float64
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		shifts   []shift
+		holidays []Holiday
+		leave    []LeaveDay
+		days     int
+		weeks    []period
+		months   []period
+	}{
+		{
+			name: "ISO weeks across a year boundary",
+			from: "2024-12-26",
+			to:   "2025-01-07",
+			// Two hours in each year, the second two on a holiday
+			shifts:   []shift{{"2024-12-31 22:00", 4 * time.Hour}},
+			holidays: []Holiday{{Date: "2025-01-01", Name: "New Year's Day"}},
+			days:     13,
+			weeks: []period{
+				{period: "2024-W52", start: "2024-12-26", end: "2024-12-29", expected: 16, variance: -16},
+				{period: "2025-W01", start: "2024-12-30", end: "2025-01-05", shifts: 1, expected: 32, net: 4, holidays: 1, onHoliday: 2, variance: -28},
+				{period: "2025-W02", start: "2025-01-06", end: "2025-01-07", expected: 16, variance: -16},
+			},
+			months: []period{
+				{period: "2024-12", start: "2024-12-26", end: "2024-12-31", shifts: 1, expected: 32, net: 2, variance: -30},
+				{period: "2025-01", start: "2025-01-01", end: "2025-01-07", expected: 32, net: 2, holidays: 1, onHoliday: 2, variance: -30},
+			},
+		},
+		{
+			name:   "month split inside a week",
+			from:   "2024-04-29",
+			to:     "2024-05-05",
+			shifts: []shift{{"2024-04-30 20:00", 6 * time.Hour}},
+			days:   7,
+			weeks: []period{
+				{period: "2024-W18", start: "2024-04-29", end: "2024-05-05", shifts: 1, expected: 40, net: 6, variance: -34},
+			},
+			months: []period{
+				{period: "2024-04", start: "2024-04-29", end: "2024-04-30", shifts: 1, expected: 16, net: 4, variance: -12},
+				{period: "2024-05", start: "2024-05-01", end: "2024-05-05", expected: 24, net: 2, variance: -22},
+			},
+		},
+		{
+			name: "paid and unpaid leave",
+			from: "2024-01-08",
+			to:   "2024-01-12",
+			shifts: []shift{
+				{"2024-01-10 08:00", 4 * time.Hour},
+				{"2024-01-11 08:00", 4 * time.Hour},
+				{"2024-01-12 08:00", 9 * time.Hour},
+			},
+			leave: []LeaveDay{
+				{Date: "2024-01-05", Paid: true, Amount: 1}, // Outside the range
+				{Date: "2024-01-08", Paid: true, Amount: 1},
+				{Date: "2024-01-09", Paid: false, Amount: 1},
+				{Date: "2024-01-10", Paid: true, Amount: 0.5},
+				{Date: "2024-01-11", Paid: false, Amount: 0.5},
+			},
+			days: 5,
+			// Paid leave covers 12 hours and unpaid leave removes 12 of the 40 expected
+			weeks: []period{
+				{period: "2024-W02", start: "2024-01-08", end: "2024-01-12", shifts: 3, expected: 28, net: 17, leave: 12, leaveDays: 3, variance: 1},
+			},
+			months: []period{
+				{period: "2024-01", start: "2024-01-08", end: "2024-01-12", shifts: 3, expected: 28, net: 17, leave: 12, leaveDays: 3, variance: 1},
+			},
+		},
+		{
+			name:     "leave on a holiday or a weekend covers nothing",
+			from:     "2024-12-23",
+			to:       "2024-12-29",
+			holidays: []Holiday{{Date: "2024-12-25", Name: "Christmas Day"}},
+			leave: []LeaveDay{
+				{Date: "2024-12-24", Paid: true, Amount: 1},
+				{Date: "2024-12-25", Paid: true, Amount: 1},
+				{Date: "2024-12-28", Paid: false, Amount: 1},
+			},
+			days: 7,
+			weeks: []period{
+				{period: "2024-W52", start: "2024-12-23", end: "2024-12-29", expected: 32, leave: 8, leaveDays: 3, holidays: 1, variance: -24},
+			},
+			months: []period{
+				{period: "2024-12", start: "2024-12-23", end: "2024-12-29", expected: 32, leave: 8, leaveDays: 3, holidays: 1, variance: -24},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, err := time.Parse(time.DateOnly, tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := time.Parse(time.DateOnly, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			shifts := make([]Shift, 0, len(tt.shifts))
+			for _, s := range tt.shifts {
+				shifts = append(shifts, testShift(t, s.start, s.length))
+			}
+
+			ts := NewTimesheet(user, from, to, shifts, tt.holidays, tt.leave)
+
+			if len(ts.Days) != tt.days {
+				t.Errorf("got %d days, want %d", len(ts.Days), tt.days)
+			}
+
+			summarize := func(periods []TimesheetPeriod) []period {
+				got := make([]period, len(periods))
+				for i, p := range periods {
+					got[i] = period{
+						period:    p.Period,
+						start:     p.Start,
+						end:       p.End,
+						shifts:    p.Shifts,
+						expected:  p.ExpectedTime / 3600,
+						net:       p.NetWorkTime / 3600,
+						leave:     p.LeaveTime / 3600,
+						leaveDays: p.LeaveDays,
+						holidays:  p.Holidays,
+						onHoliday: p.HolidayTime / 3600,
+						variance:  p.Variance / 3600,
+					}
+				}
+				return got
+			}
+
+			for _, c := range []struct {
+				name      string
+				got, want []period
+			}{
+				{"weeks", summarize(ts.Weeks), tt.weeks},
+				{"months", summarize(ts.Months), tt.months},
+			} {
+				if len(c.got) != len(c.want) {
+					t.Errorf("got %s %+v, want %+v", c.name, c.got, c.want)
+					continue
+				}
+				for i := range c.got {
+					if c.got[i] != c.want[i] {
+						t.Errorf("%s %d: got %+v, want %+v", c.name, i, c.got[i], c.want[i])
+					}
+				}
+			}
+
+			// The total covers the whole range and agrees with the weeks
+			var weeks TimesheetPeriod
+			for _, w := range ts.Weeks {
+				weeks = sumPeriods(weeks, w)
+			}
+			if ts.Total.Start != tt.from || ts.Total.End != tt.to || ts.Total.NetWorkTime != weeks.NetWorkTime ||
+				ts.Total.ExpectedTime != weeks.ExpectedTime || ts.Total.Variance != weeks.Variance {
+				t.Errorf("got total %+v, weeks add up to %+v", ts.Total, weeks)
+			}
+		})
+	}
+}
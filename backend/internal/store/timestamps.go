@@ -5,29 +5,51 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 )
 
+// Approval states of a timestamp. Live stamps are approved on creation, manual
+// (backdated) entries start as pending until a manager decides on them.
+const (
+	TimestampStatusPending  = "pending"
+	TimestampStatusApproved = "approved"
+	TimestampStatusRejected = "rejected"
+)
+
+var (
+	ErrNotPending      = errors.New("timestamp is not pending approval")
+	ErrFutureTimestamp = errors.New("manual timestamps must be in the past")
+	ErrSelfDecision    = errors.New("users cannot decide on their own timestamps")
+)
+
 // Timestamp represents a timestamp entry in the system.
 type Timestamp struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	StampType string    `json:"stamp_type"`
-	StampTime time.Time `json:"stamp_time"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Version   int       `json:"version"`
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	StampType string     `json:"stamp_type"`
+	StampTime time.Time  `json:"stamp_time"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Version   int        `json:"version"`
+	Status    string     `json:"status"`
+	IsManual  bool       `json:"is_manual"`
+	Reason    string     `json:"reason,omitempty"`
+	DecidedBy *int64     `json:"decided_by"`
+	DecidedAt *time.Time `json:"decided_at"`
+
+	IsAutoGenerated bool   `json:"is_auto_generated"` // Inserted by the system, e.g. to close a forgotten shift
+	KioskID         *int64 `json:"kiosk_id"`          // Shared kiosk the stamp was posted from, if any
 }
 
-// Shift represents a work shift with sign-in, sign-out, and break times.
-type Shift struct {
-	SignIn         time.Time     `json:"SignIn"`
-	SignOut        time.Time     `json:"SignOut"`
-	Breaks         [][]time.Time `json:"Breaks"`
-	TotalBreakTime float64       `json:"TotalBreakTime"` // TotalBreakTime in seconds (float64)
-	TotalShiftTime float64       `json:"TotalShiftTime"` // TotalShiftTime in seconds (float64)
-	NetWorkTime    float64       `json:"NetWorkTime"`    // NetWorkTime in seconds (float64)
+// In renders every time of the timestamp in loc.
+func (t *Timestamp) In(loc *time.Location) {
+	t.StampTime = t.StampTime.In(loc)
+	t.CreatedAt = t.CreatedAt.In(loc)
+	t.UpdatedAt = t.UpdatedAt.In(loc)
+	if t.DecidedAt != nil {
+		decidedAt := t.DecidedAt.In(loc)
+		t.DecidedAt = &decidedAt
+	}
 }
 
 // TimestampStore provides methods for managing timestamps in the database.
@@ -51,7 +73,8 @@ type TimestampStore struct {
 func (s *TimestampStore) GetUserFeed(ctx context.Context, userID int64, fq Query) ([]Timestamp, error) {
 	query := `
 		SELECT 
-			p.id, p.user_id, p.stamp_type, p.time, p.created_at, p.version
+			p.id, p.user_id, p.stamp_type, p.time, p.created_at, p.updated_at, p.version,
+			p.status, p.is_manual, p.reason, p.decided_by, p.decided_at, p.is_auto_generated, p.kiosk_id
 		FROM timestamps p
 		LEFT JOIN users u ON p.user_id = u.id
 		WHERE 
@@ -73,29 +96,7 @@ func (s *TimestampStore) GetUserFeed(ctx context.Context, userID int64, fq Query
 	var feed []Timestamp
 	for rows.Next() {
 		var p Timestamp
-		var rawStampTime, rawCreatedAt []byte // Temporarily hold time fields as byte slices
-
-		err := rows.Scan(
-			&p.ID,
-			&p.UserID,
-			&p.StampType,  // Scan the stamp_type directly into the Timestamp struct
-			&rawStampTime, // Scan into rawStampTime as []byte
-			&rawCreatedAt, // Scan into rawCreatedAt as []byte
-			&p.Version,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		// Parse rawStampTime into a time.Time value
-		p.StampTime, err = time.Parse("2006-01-02 15:04:05", string(rawStampTime))
-		if err != nil {
-			return nil, err
-		}
-
-		// Parse rawCreatedAt into a time.Time value
-		p.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawCreatedAt))
-		if err != nil {
+		if err := scanTimestamp(rows, &p); err != nil {
 			return nil, err
 		}
 
@@ -116,114 +117,89 @@ func (s *TimestampStore) GetUserFeed(ctx context.Context, userID int64, fq Query
 //	@Failure		500		{object}	error
 //	@Router			/timestamps/latest [get]
 func (s *TimestampStore) GetLatestTimestamp(ctx context.Context, userID int64) (*Timestamp, error) {
-	// Define a query to fetch only the latest timestamp for the user
-	fq := Query{
-		Limit:  1,
-		Offset: 0,
-		Sort:   "desc", // Sort by created_at in descending order to get the latest timestamp
-	}
-
-	// Call GetUserFeed with the modified PaginatedFeedQuery
-	timestamps, err := s.GetUserFeed(ctx, userID, fq)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check if any timestamps were retrieved
-	if len(timestamps) == 0 {
-		return nil, nil
-	}
+	return getLatestTimestamp(ctx, s.db, userID)
+}
 
-	// Return the first (and only) timestamp in the list
-	return &timestamps[0], nil
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-// GetFinishedShifts godoc
-//
-//	@Summary		Retrieves finished shifts
-//	@Description	Retrieves finished shifts for a specific user
-//	@Tags			shifts
-//	@Produce		json
-//	@Param			userID	query		int		true	"User ID"
-//	@Success		200		{object}	[]Shift
-//	@Failure		500		{object}	error
-//	@Router			/shifts [get]
-func (s *TimestampStore) GetFinishedShifts(ctx context.Context, userID int64) ([]Shift, error) {
+// getLatestTimestamp fetches the newest approved stamp of a user, or nil if there is none.
+func getLatestTimestamp(ctx context.Context, q querier, userID int64) (*Timestamp, error) {
+	// Only approved stamps take part in the sign-in/sign-out sequence, so pending
+	// or rejected manual entries must not be treated as the latest state.
 	query := `
-		SELECT stamp_type, time
+		SELECT id, user_id, stamp_type, time, created_at, updated_at, version,
+			status, is_manual, reason, decided_by, decided_at, is_auto_generated, kiosk_id
 		FROM timestamps
-		WHERE user_id = ?
-		ORDER BY time ASC
+		WHERE user_id = ? AND status = 'approved'
+		ORDER BY time DESC, id DESC
+		LIMIT 1
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	var timestamp Timestamp
+	err := scanTimestamp(q.QueryRowContext(ctx, query, userID), &timestamp)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var shifts []Shift
-	var currentShift *Shift
-	var currentBreakStart time.Time
-
-	for rows.Next() {
-		var stampType string
-		var stampTimeRaw string
-		if err := rows.Scan(&stampType, &stampTimeRaw); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// No timestamps recorded yet
+			return nil, nil
+		default:
 			return nil, err
 		}
+	}
 
-		// Parse the raw timestamp string with the correct format
-		stampTime, err := time.Parse("2006-01-02 15:04:05", stampTimeRaw)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse timestamp: %v", err)
-		}
-
-		switch stampType {
-		case "sign-in":
-			if currentShift == nil {
-				currentShift = &Shift{SignIn: stampTime}
-			}
-
-		case "start-break":
-			if currentShift != nil && currentShift.SignOut.IsZero() {
-				currentBreakStart = stampTime
-			}
+	return &timestamp, nil
+}
 
-		case "end-break":
-			if currentShift != nil && !currentBreakStart.IsZero() {
-				breakEnd := stampTime
-				currentShift.Breaks = append(currentShift.Breaks, []time.Time{currentBreakStart, breakEnd})
+// lockUser takes a row lock on the user for the rest of tx, serialising every
+// change to that user's ledger so that concurrent stamps cannot both pass validation.
+func lockUser(ctx context.Context, tx *sql.Tx, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
-				// Calculate break duration in seconds (float64)
-				breakDuration := breakEnd.Sub(currentBreakStart).Seconds()
-				currentShift.TotalBreakTime += breakDuration // Keep TotalBreakTime as seconds (float64)
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE id = ? FOR UPDATE`, userID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
 
-				currentBreakStart = time.Time{}
-			}
+	return nil
+}
 
-		case "sign-out":
-			if currentShift != nil && currentShift.SignOut.IsZero() {
-				currentShift.SignOut = stampTime
-				// Calculate total shift time in seconds (float64)
-				currentShift.TotalShiftTime = currentShift.SignOut.Sub(currentShift.SignIn).Seconds() // TotalShiftTime in seconds
-				// NetWorkTime is shift time minus break time
-				currentShift.NetWorkTime = currentShift.TotalShiftTime - currentShift.TotalBreakTime
+// getStampOwner returns the user and time of a stamp within tx.
+func getStampOwner(ctx context.Context, tx *sql.Tx, timestampID int64) (int64, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
-				shifts = append(shifts, *currentShift)
-				currentShift = nil
-			}
+	var userID int64
+	var stampTime []byte
+	err := tx.QueryRowContext(ctx, `SELECT user_id, time FROM timestamps WHERE id = ?`, timestampID).Scan(&userID, &stampTime)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, time.Time{}, ErrNotFound
+		default:
+			return 0, time.Time{}, err
 		}
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	t, err := parseDBTime(string(stampTime))
+	if err != nil {
+		return 0, time.Time{}, err
 	}
 
-	return shifts, nil
+	return userID, t, nil
 }
 
 // Create godoc
@@ -236,71 +212,68 @@ func (s *TimestampStore) GetFinishedShifts(ctx context.Context, userID int64) ([
 //	@Param			payload	body		Timestamp	true	"Timestamp information"
 //	@Success		201		{object}	Timestamp	"Timestamp created"
 //	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
 //	@Failure		500		{object}	error
 //	@Router			/timestamps [post]
 func (s *TimestampStore) Create(ctx context.Context, timestamp *Timestamp) error {
-	// Define allowed previous states for each stamp type
-	validTransitions := map[string]string{
-		"sign-in":     "sign-out",          // Only allowed if the last stamp is "sign-out"
-		"sign-out":    "sign-in,end-break", // Only allowed if the last stamp is "sign-in" or "end-break"
-		"start-break": "sign-in,end-break", // Only allowed if the last stamp is "sign-in" or "end-break"
-		"end-break":   "start-break",       // Only allowed if the last stamp is "start-break"
-	}
-
-	latestTimestamp, err := s.GetLatestTimestamp(ctx, timestamp.UserID)
-
-	// Handle case where no previous timestamps exist (first action should be "sign-in")
-	if latestTimestamp == nil && timestamp.StampType != "sign-in" {
-		return errors.New("first action must be sign-in")
-	}
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Lock the user before reading the latest stamp so validation and insert are atomic
+		if err := lockUser(ctx, tx, timestamp.UserID); err != nil {
+			return err
+		}
 
-	// Validate the transition if there is a previous timestamp
-	if latestTimestamp != nil {
-		previousType := latestTimestamp.StampType
+		latestTimestamp, err := getLatestTimestamp(ctx, tx, timestamp.UserID)
+		if err != nil {
+			return err
+		}
 
-		// Ensure no duplicate consecutive timestamps
-		if previousType == timestamp.StampType {
-			return errors.New("duplicate timestamp")
+		previousType := ""
+		if latestTimestamp != nil {
+			previousType = latestTimestamp.StampType
 		}
 
-		// Get valid previous types for the current stamp type
-		validPrevTypes, exists := validTransitions[timestamp.StampType]
-		if !exists {
-			return errors.New("invalid stamp type")
+		graph, err := getStampGraph(ctx, tx)
+		if err != nil {
+			return err
 		}
 
-		// Check if the last stamp type is within the allowed types
-		allowedTypes := strings.Split(validPrevTypes, ",")
-		if !contains(allowedTypes, previousType) {
-			return fmt.Errorf("invalid transition from %s to %s", previousType, timestamp.StampType)
+		if err := checkTransition(graph, previousType, timestamp.StampType); err != nil {
+			return err
 		}
-	}
 
-	timestamp.StampTime = time.Now()
+		timestamp.StampTime = time.Now().UTC()
+		timestamp.Status = TimestampStatusApproved
 
-	query := `INSERT INTO timestamps (user_id, stamp_type, time) VALUES (?, ?, ?)`
+		if err := checkPeriodsOpen(ctx, tx, timestamp.UserID, timestamp.StampTime); err != nil {
+			return err
+		}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+		query := `INSERT INTO timestamps (user_id, stamp_type, time, status, kiosk_id) VALUES (?, ?, ?, ?, ?)`
 
-	result, err := s.db.ExecContext(
-		ctx,
-		query,
-		timestamp.UserID,
-		timestamp.StampType,
-		timestamp.StampTime,
-	)
-	if err != nil {
-		return err
-	}
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return err
-	}
-	timestamp.ID = id
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			timestamp.UserID,
+			timestamp.StampType,
+			timestamp.StampTime,
+			timestamp.Status,
+			timestamp.KioskID,
+		)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		timestamp.ID = id
+
+		return nil
+	})
 }
 
 // GetByID godoc
@@ -316,7 +289,8 @@ func (s *TimestampStore) Create(ctx context.Context, timestamp *Timestamp) error
 //	@Router			/timestamps/{id} [get]
 func (s *TimestampStore) GetByID(ctx context.Context, id int64) (*Timestamp, error) {
 	query := `
-		SELECT id, user_id, stamp_type, time, created_at, updated_at, version
+		SELECT id, user_id, stamp_type, time, created_at, updated_at, version,
+			status, is_manual, reason, decided_by, decided_at, is_auto_generated, kiosk_id
 		FROM timestamps
 		WHERE id = ?
 		`
@@ -325,21 +299,7 @@ func (s *TimestampStore) GetByID(ctx context.Context, id int64) (*Timestamp, err
 	defer cancel()
 
 	var timestamp Timestamp
-	var rawStampTime, rawCreatedAt, rawUpdatedAt []byte // Temporarily hold time fields as byte slices
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		id,
-	).Scan(
-		&timestamp.ID,
-		&timestamp.UserID,
-		&timestamp.StampType,
-		&rawStampTime, // Scan into rawStampTime as []byte
-		&rawCreatedAt, // Scan into rawCreatedAt as []byte
-		&rawUpdatedAt, // Scan into rawUpdatedAt as []byte
-		&timestamp.Version,
-	)
+	err := scanTimestamp(s.db.QueryRowContext(ctx, query, id), &timestamp)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -349,24 +309,6 @@ func (s *TimestampStore) GetByID(ctx context.Context, id int64) (*Timestamp, err
 		}
 	}
 
-	// Parse rawStampTime into a time.Time value
-	timestamp.StampTime, err = time.Parse("2006-01-02 15:04:05", string(rawStampTime))
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse rawCreatedAt into a time.Time value
-	timestamp.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawCreatedAt))
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse rawUpdatedAt into a time.Time value
-	timestamp.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawUpdatedAt))
-	if err != nil {
-		return nil, err
-	}
-
 	return &timestamp, nil
 }
 
@@ -382,24 +324,37 @@ func (s *TimestampStore) GetByID(ctx context.Context, id int64) (*Timestamp, err
 //	@Failure		500	{object}	error
 //	@Router			/timestamps/{id} [delete]
 func (s *TimestampStore) Delete(ctx context.Context, timestampID int64) error {
-	query := `DELETE FROM timestamps WHERE id = ?`
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		userID, stampTime, err := getStampOwner(ctx, tx, timestampID)
+		if err != nil {
+			return err
+		}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+		return s.revalidate(ctx, tx, userID, func() error {
+			if err := checkPeriodsOpen(ctx, tx, userID, stampTime); err != nil {
+				return err
+			}
 
-	res, err := s.db.ExecContext(ctx, query, timestampID)
-	if err != nil {
-		return err
-	}
+			query := `DELETE FROM timestamps WHERE id = ?`
 
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return ErrNotFound
-	}
-	return nil
+			ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+			defer cancel()
+
+			res, err := tx.ExecContext(ctx, query, timestampID)
+			if err != nil {
+				return err
+			}
+
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows == 0 {
+				return ErrNotFound
+			}
+			return nil
+		})
+	})
 }
 
 // Update godoc
@@ -414,51 +369,329 @@ func (s *TimestampStore) Delete(ctx context.Context, timestampID int64) error {
 //	@Success		200		{object}	Timestamp
 //	@Failure		400		{object}	error
 //	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
 //	@Failure		500		{object}	error
 //	@Router			/timestamps/{id} [patch]
 func (s *TimestampStore) Update(ctx context.Context, timestamp *Timestamp) error {
-	// SQL query to update a timestamp based on its ID and version, and to increment the version
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := checkStampType(ctx, tx, timestamp.StampType); err != nil {
+			return err
+		}
+
+		oldUserID, oldTime, err := getStampOwner(ctx, tx, timestamp.ID)
+		if err != nil {
+			return err
+		}
+
+		change := func() error {
+			// Neither the old nor the new position of the stamp may be in a locked period
+			if err := checkPeriodsOpen(ctx, tx, oldUserID, oldTime); err != nil {
+				return err
+			}
+			if err := checkPeriodsOpen(ctx, tx, timestamp.UserID, timestamp.StampTime); err != nil {
+				return err
+			}
+
+			// SQL query to update a timestamp based on its ID and version, and to increment the version
+			query := `
+				UPDATE timestamps
+				SET
+					user_id = ?,
+					stamp_type = ?,
+					time = ?,
+					version = version + 1
+				WHERE id = ? AND version = ?
+			`
+
+			// Set up the context with a timeout for the query execution
+			ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+			defer cancel()
+
+			// Execute the query with the provided parameters
+			res, err := tx.ExecContext(
+				ctx,
+				query,
+				timestamp.UserID,
+				timestamp.StampType,
+				timestamp.StampTime,
+				timestamp.ID,
+				timestamp.Version,
+			)
+			if err != nil {
+				return err
+			}
+
+			// If no rows were affected the timestamp is gone or was changed concurrently
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows == 0 {
+				return ErrNotFound
+			}
+
+			timestamp.Version++
+			return nil
+		}
+
+		if oldUserID == timestamp.UserID {
+			return s.revalidate(ctx, tx, timestamp.UserID, change)
+		}
+
+		// Moving a stamp to another user changes the old user's ledger as well. Both users are
+		// locked in ID order first so that opposite moves cannot deadlock.
+		if err := lockUser(ctx, tx, min(oldUserID, timestamp.UserID)); err != nil {
+			return err
+		}
+		if err := lockUser(ctx, tx, max(oldUserID, timestamp.UserID)); err != nil {
+			return err
+		}
+
+		return s.revalidate(ctx, tx, oldUserID, func() error {
+			return s.revalidate(ctx, tx, timestamp.UserID, change)
+		})
+	})
+}
+
+// CreateManual godoc
+//
+//	@Summary		Creates a manual timestamp
+//	@Description	Inserts a backdated timestamp that stays pending until a manager approves it
+//	@Tags			timestamps
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		Timestamp	true	"Timestamp information"
+//	@Success		201		{object}	Timestamp	"Timestamp created"
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/timestamps/manual [post]
+func (s *TimestampStore) CreateManual(ctx context.Context, timestamp *Timestamp) error {
+	if err := checkStampType(ctx, s.db, timestamp.StampType); err != nil {
+		return err
+	}
+
+	if !timestamp.StampTime.Before(time.Now()) {
+		return ErrFutureTimestamp
+	}
+
+	if err := checkPeriodsOpen(ctx, s.db, timestamp.UserID, timestamp.StampTime); err != nil {
+		return err
+	}
+
+	timestamp.Status = TimestampStatusPending
+	timestamp.IsManual = true
+
 	query := `
-		UPDATE timestamps
-		SET
-			user_id = ?,
-			stamp_type = ?,
-			time = ?,
-			version = version + 1
-		WHERE id = ? AND version = ?
-		RETURNING version
+		INSERT INTO timestamps (user_id, stamp_type, time, status, is_manual, reason)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 
-	// Set up the context with a timeout for the query execution
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	// Execute the query with the provided parameters
-	err := s.db.QueryRowContext(
+	result, err := s.db.ExecContext(
 		ctx,
 		query,
 		timestamp.UserID,
 		timestamp.StampType,
 		timestamp.StampTime,
-		timestamp.ID,
-		timestamp.Version,
-	).Scan(
-		&timestamp.Version, // Scan the returned version into the timestamp struct
+		timestamp.Status,
+		timestamp.IsManual,
+		timestamp.Reason,
 	)
+	if err != nil {
+		return err
+	}
 
-	// Handle errors that occur during query execution
+	id, err := result.LastInsertId()
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			// If no rows were affected, return a "not found" error
-			return ErrNotFound
-		default:
-			// Return any other errors encountered during the update
+		return err
+	}
+	timestamp.ID = id
+
+	return nil
+}
+
+// GetPending godoc
+//
+//	@Summary		Retrieves pending timestamps
+//	@Description	Retrieves the manual timestamps of a manager's reports that are waiting for a decision
+//	@Tags			timestamps
+//	@Produce		json
+//	@Success		200	{object}	[]Timestamp
+//	@Failure		500	{object}	error
+//	@Router			/timestamps/pending [get]
+func (s *TimestampStore) GetPending(ctx context.Context, managerID int64) ([]Timestamp, error) {
+	query := `
+		SELECT t.id, t.user_id, t.stamp_type, t.time, t.created_at, t.updated_at, t.version,
+			t.status, t.is_manual, t.reason, t.decided_by, t.decided_at, t.is_auto_generated, t.kiosk_id
+		FROM timestamps t
+		JOIN users u ON u.id = t.user_id
+		WHERE u.manager_id = ? AND t.status = 'pending'
+		ORDER BY t.time ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, managerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := make([]Timestamp, 0)
+	for rows.Next() {
+		var p Timestamp
+		if err := scanTimestamp(rows, &p); err != nil {
+			return nil, err
+		}
+
+		pending = append(pending, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// Decide godoc
+//
+//	@Summary		Approves or rejects a pending timestamp
+//	@Description	Records the decision, the approver and the decision time of a pending timestamp. Users cannot decide on their own timestamps.
+//	@Tags			timestamps
+//	@Produce		json
+//	@Param			id	path		int	true	"Timestamp ID"
+//	@Success		200	{object}	Timestamp
+//	@Failure		403	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/timestamps/{id}/approve [patch]
+func (s *TimestampStore) Decide(ctx context.Context, timestamp *Timestamp, status string, deciderID int64) error {
+	if status != TimestampStatusApproved && status != TimestampStatusRejected {
+		return fmt.Errorf("invalid decision %q", status)
+	}
+
+	if deciderID == timestamp.UserID {
+		return ErrSelfDecision
+	}
+
+	decidedAt := time.Now().UTC()
+
+	decide := func(tx *sql.Tx) error {
+		query := `
+			UPDATE timestamps
+			SET status = ?, decided_by = ?, decided_at = ?, version = version + 1
+			WHERE id = ? AND status = 'pending'
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, query, status, deciderID, decidedAt, timestamp.ID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotPending
+		}
+		return nil
+	}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Approving inserts the stamp into the ledger, so it must still fit the sequence
+		if status == TimestampStatusApproved {
+			return s.revalidate(ctx, tx, timestamp.UserID, func() error {
+				if err := checkPeriodsOpen(ctx, tx, timestamp.UserID, timestamp.StampTime); err != nil {
+					return err
+				}
+				return decide(tx)
+			})
+		}
+		return decide(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	timestamp.Status = status
+	timestamp.DecidedBy = &deciderID
+	timestamp.DecidedAt = &decidedAt
+	timestamp.Version++
+
+	return nil
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTimestamp scans a row selected with the full timestamp column list into t.
+func scanTimestamp(row scanner, t *Timestamp) error {
+	var rawStampTime, rawCreatedAt, rawUpdatedAt []byte // Temporarily hold time fields as byte slices
+	var rawReason sql.NullString
+	var rawDecidedBy sql.NullInt64
+	var rawDecidedAt []byte
+	var rawKioskID sql.NullInt64
+
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.StampType,
+		&rawStampTime,
+		&rawCreatedAt,
+		&rawUpdatedAt,
+		&t.Version,
+		&t.Status,
+		&t.IsManual,
+		&rawReason,
+		&rawDecidedBy,
+		&rawDecidedAt,
+		&t.IsAutoGenerated,
+		&rawKioskID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if t.StampTime, err = parseDBTime(string(rawStampTime)); err != nil {
+		return err
+	}
+
+	if t.CreatedAt, err = parseDBTime(string(rawCreatedAt)); err != nil {
+		return err
+	}
+
+	if t.UpdatedAt, err = parseDBTime(string(rawUpdatedAt)); err != nil {
+		return err
+	}
+
+	t.Reason = rawReason.String
+
+	if rawDecidedBy.Valid {
+		t.DecidedBy = &rawDecidedBy.Int64
+	}
+
+	if rawDecidedAt != nil {
+		decidedAt, err := parseDBTime(string(rawDecidedAt))
+		if err != nil {
 			return err
 		}
+		t.DecidedAt = &decidedAt
+	}
+
+	if rawKioskID.Valid {
+		t.KioskID = &rawKioskID.Int64
 	}
 
-	// Return nil if the update was successful
 	return nil
 }
 
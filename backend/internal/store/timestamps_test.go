@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimestampStoreCreateConcurrent(t *testing.T) {
+	conn := newTestDB(t)
+	s := &TimestampStore{conn}
+	userID := createTestUser(t, conn)
+
+	// A double-tapped phone and a kiosk signing in at once: only the first may pass validation
+	const n = 20
+
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = s.Create(context.Background(), &Timestamp{UserID: userID, StampType: "sign-in"})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case errors.Is(err, ErrInvalidTransition):
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if wins != 1 {
+		t.Fatalf("got %d successful sign-ins, want 1", wins)
+	}
+
+	var stored int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM timestamps WHERE user_id = ?`, userID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1 {
+		t.Fatalf("got %d stored stamps, want 1", stored)
+	}
+}
+
+func TestTimestampStoreUpdateRevalidatesOldOwner(t *testing.T) {
+	conn := newTestDB(t)
+	s := &TimestampStore{conn}
+	ctx := context.Background()
+
+	from := createTestUser(t, conn)
+	to := createTestUser(t, conn)
+
+	signIn := time.Date(2024, time.January, 8, 8, 0, 0, 0, time.UTC)
+	insertTestStamps(t, conn, from, []Timestamp{
+		{StampType: "sign-in", StampTime: signIn},
+		{StampType: "sign-out", StampTime: signIn.Add(8 * time.Hour)},
+	})
+
+	stamps, err := s.getStampsBetween(ctx, from, signIn, signIn)
+	if err != nil || len(stamps) != 1 {
+		t.Fatalf("loading the sign-in: %v", err)
+	}
+
+	// The new owner's ledger stays valid, but the old owner would be left with a lone sign-out
+	moved := stamps[0]
+	moved.UserID = to
+
+	var ledgerErr *LedgerError
+	if err := s.Update(ctx, &moved); !errors.As(err, &ledgerErr) {
+		t.Fatalf("got %v, want a ledger error for the old owner", err)
+	}
+
+	var owner int64
+	if err := conn.QueryRow(`SELECT user_id FROM timestamps WHERE id = ?`, moved.ID).Scan(&owner); err != nil {
+		t.Fatal(err)
+	}
+	if owner != from {
+		t.Fatalf("stamp moved to user %d, want it to stay with user %d", owner, from)
+	}
+}
@@ -26,6 +26,25 @@ type User struct {
 	RoleID    int64     `json:"role_id"`
 	Role      Role      `json:"role"`
 	ManagerID int64     `json:"manager_id"`
+	TimeZone  string    `json:"time_zone"` // IANA time zone name, e.g. "Europe/Stockholm"
+	SiteID    *int64    `json:"site_id"`   // Site whose kiosks the user may stamp at
+
+	HolidayCalendarID *int64 `json:"holiday_calendar_id"` // Overrides the holiday calendar of the user's site
+
+	ContractedHours float64 `json:"contracted_hours"` // Contracted work hours per week
+
+	// TokenVersion is embedded in every access token of the user. Bumping it revokes the tokens issued before.
+	TokenVersion int `json:"-"`
+}
+
+// Location returns the user's time zone, falling back to UTC if it is unset or unknown.
+func (u *User) Location() *time.Location {
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil || u.TimeZone == "" {
+		return time.UTC
+	}
+
+	return loc
 }
 
 type password struct {
@@ -98,13 +117,68 @@ func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 	return nil
 }
 
+// userSelect selects every column scanned by scanUser.
+const userSelect = `
+	SELECT users.id, email, first_name, last_name, passhash, created_at,
+		roles.id, roles.name, roles.level, roles.description, manager_id, time_zone,
+		contracted_hours, site_id, holiday_calendar_id, token_version
+	FROM users
+	JOIN roles ON (users.role_id = roles.id)
+`
+
+// scanUser scans a row selected with userSelect into user.
+func scanUser(row scanner, user *User) error {
+	var rawFirstName, rawLastName sql.NullString
+	var rawCreatedAt []byte // For scanning the DATETIME field
+	var rawManagerID sql.NullInt64
+	var rawSiteID, rawHolidayCalendarID sql.NullInt64
+
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&rawFirstName, // Use sql.NullString for nullable first_name
+		&rawLastName,  // Use sql.NullString for nullable last_name
+		&user.Password.hash,
+		&rawCreatedAt, // Scan created_at as raw bytes
+		&user.Role.ID,
+		&user.Role.Name,
+		&user.Role.Level,
+		&user.Role.Description,
+		&rawManagerID,
+		&user.TimeZone,
+		&user.ContractedHours,
+		&rawSiteID,
+		&rawHolidayCalendarID,
+		&user.TokenVersion,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Assign nullable columns only if they are not NULL
+	user.FirstName = rawFirstName.String
+	user.LastName = rawLastName.String
+	user.ManagerID = rawManagerID.Int64
+	user.RoleID = user.Role.ID
+
+	if rawSiteID.Valid {
+		user.SiteID = &rawSiteID.Int64
+	}
+	if rawHolidayCalendarID.Valid {
+		user.HolidayCalendarID = &rawHolidayCalendarID.Int64
+	}
+
+	// Parse rawCreatedAt into a time.Time value
+	user.CreatedAt, err = parseDBTime(string(rawCreatedAt))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *UserStore) GetAll(ctx context.Context) ([]*User, error) {
-	query := `
-		SELECT users.id, email, first_name, last_name, created_at, roles.*, manager_id
-		FROM users
-		JOIN roles ON (users.role_id = roles.id)
-		WHERE is_active = 1
-	`
+	query := userSelect + ` WHERE is_active = 1`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -118,48 +192,7 @@ func (s *UserStore) GetAll(ctx context.Context) ([]*User, error) {
 	users := make([]*User, 0)
 	for rows.Next() {
 		user := &User{}
-		var rawFirstName, rawLastName sql.NullString
-		var rawCreatedAt []byte // For scanning the DATETIME field
-		var rawManagerID sql.NullInt64
-
-		err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&rawFirstName, // Use sql.NullString for nullable first_name
-			&rawLastName,  // Use sql.NullString for nullable last_name
-			&rawCreatedAt, // Scan created_at as raw bytes
-			&user.Role.ID,
-			&user.Role.Name,
-			&user.Role.Level,
-			&user.Role.Description,
-			&rawManagerID,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		// Assign first_name and last_name only if they are not NULL
-		if rawFirstName.Valid {
-			user.FirstName = rawFirstName.String
-		} else {
-			user.FirstName = "" // Set a default or handle as needed
-		}
-
-		if rawLastName.Valid {
-			user.LastName = rawLastName.String
-		} else {
-			user.LastName = "" // Set a default or handle as needed
-		}
-
-		if rawManagerID.Valid {
-			user.ManagerID = rawManagerID.Int64
-		} else {
-			user.ManagerID = 0 // Set a default or handle as needed
-		}
-
-		// Parse rawCreatedAt into a time.Time value
-		user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawCreatedAt))
-		if err != nil {
+		if err := scanUser(rows, user); err != nil {
 			return nil, err
 		}
 
@@ -174,38 +207,13 @@ func (s *UserStore) GetAll(ctx context.Context) ([]*User, error) {
 }
 
 func (s *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
-	query := `
-		SELECT users.id, email, first_name, last_name, passhash, created_at, roles.*, manager_id
-		FROM users
-		JOIN roles ON (users.role_id = roles.id)
-		WHERE users.id = ? AND is_active = 1
-	`
+	query := userSelect + ` WHERE users.id = ? AND is_active = 1`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	user := &User{}
-	var rawFirstName, rawLastName sql.NullString
-	var rawCreatedAt []byte // For scanning the DATETIME field
-	var rawManagerID sql.NullInt64
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		userID,
-	).Scan(
-		&user.ID,
-		&user.Email,
-		&rawFirstName, // Use sql.NullString for nullable first_name
-		&rawLastName,  // Use sql.NullString for nullable last_name
-		&user.Password.hash,
-		&rawCreatedAt, // Scan created_at as raw bytes
-		&user.Role.ID,
-		&user.Role.Name,
-		&user.Role.Level,
-		&user.Role.Description,
-		&rawManagerID,
-	)
+	err := scanUser(s.db.QueryRowContext(ctx, query, userID), user)
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -215,67 +223,17 @@ func (s *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 		}
 	}
 
-	// Assign first_name and last_name only if they are not NULL
-	if rawFirstName.Valid {
-		user.FirstName = rawFirstName.String
-	} else {
-		user.FirstName = "" // Set a default or handle as needed
-	}
-
-	if rawLastName.Valid {
-		user.LastName = rawLastName.String
-	} else {
-		user.LastName = "" // Set a default or handle as needed
-	}
-
-	if rawManagerID.Valid {
-		user.ManagerID = rawManagerID.Int64
-	} else {
-		user.ManagerID = 0 // Set a default or handle as needed
-	}
-
-	// Parse rawCreatedAt into a time.Time value
-	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawCreatedAt))
-	if err != nil {
-		return nil, err
-	}
-
 	return user, nil
 }
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
-	query := `
-	SELECT users.id, email, first_name, last_name, passhash, created_at, roles.*, manager_id
-	FROM users
-	JOIN roles ON (users.role_id = roles.id)
-	WHERE users.email = ? AND is_active = 1
-`
+	query := userSelect + ` WHERE users.email = ? AND is_active = 1`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	user := &User{}
-	var rawFirstName, rawLastName sql.NullString
-	var rawCreatedAt []byte // For scanning the DATETIME field
-	var rawManagerID sql.NullInt64
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		email,
-	).Scan(
-		&user.ID,
-		&user.Email,
-		&rawFirstName, // Use sql.NullString for nullable first_name
-		&rawLastName,  // Use sql.NullString for nullable last_name
-		&user.Password.hash,
-		&rawCreatedAt, // Scan created_at as raw bytes
-		&user.Role.ID,
-		&user.Role.Name,
-		&user.Role.Level,
-		&user.Role.Description,
-		&rawManagerID,
-	)
+	err := scanUser(s.db.QueryRowContext(ctx, query, email), user)
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -285,32 +243,36 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		}
 	}
 
-	// Assign first_name and last_name only if they are not NULL
-	if rawFirstName.Valid {
-		user.FirstName = rawFirstName.String
-	} else {
-		user.FirstName = "" // Set a default or handle as needed
-	}
+	return user, nil
+}
+
+func (s *UserStore) GetByManager(ctx context.Context, managerID int64) ([]*User, error) {
+	query := userSelect + ` WHERE manager_id = ? AND is_active = 1 ORDER BY users.id`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
-	if rawLastName.Valid {
-		user.LastName = rawLastName.String
-	} else {
-		user.LastName = "" // Set a default or handle as needed
+	rows, err := s.db.QueryContext(ctx, query, managerID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	users := make([]*User, 0)
+	for rows.Next() {
+		user := &User{}
+		if err := scanUser(rows, user); err != nil {
+			return nil, err
+		}
 
-	if rawManagerID.Valid {
-		user.ManagerID = rawManagerID.Int64
-	} else {
-		user.ManagerID = 0 // Set a default or handle as needed
+		users = append(users, user)
 	}
 
-	// Parse rawCreatedAt into a time.Time value
-	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawCreatedAt))
-	if err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	return users, nil
 }
 
 func (s *UserStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration) error {
@@ -340,9 +302,8 @@ func (s *UserStore) Activate(ctx context.Context, token string) error {
 			user.Role.ID = 3
 		}
 
-		// 3. update the user
-		user.IsActive = 1
-		if err := s.update(ctx, tx, user); err != nil {
+		// 3. activate the user
+		if err := s.activate(ctx, tx, user.ID); err != nil {
 			return err
 		}
 
@@ -370,16 +331,45 @@ func (s *UserStore) Update(ctx context.Context, user *User) error {
 			return err
 		}
 
+		if user.RoleID != 0 {
+			if err := s.updateRole(ctx, tx, user.ID, user.RoleID); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
 
+// UpdateTimeZone sets the IANA time zone of a user.
+func (s *UserStore) UpdateTimeZone(ctx context.Context, userID int64, timeZone string) error {
+	query := `UPDATE users SET time_zone = ? WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, timeZone, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *UserStore) ChangePassword(ctx context.Context, user *User) error {
 	return withTx(s.db, ctx, func(tx *sql.Tx) error {
 		if err := s.updatePassword(ctx, tx, user); err != nil {
 			return err
 		}
 
+		if _, err := revokeSessions(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		if err := revokeAccessTokens(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
@@ -396,6 +386,15 @@ func (s *UserStore) ResetPassword(ctx context.Context, token string, user *User)
 			return err
 		}
 
+		// 3. log out the sessions started with the old password
+		if _, err := revokeSessions(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		if err := revokeAccessTokens(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
@@ -433,7 +432,7 @@ func (s *UserStore) getUserFromPasswordReset(ctx context.Context, tx *sql.Tx, to
 	}
 
 	// Parse rawCreatedAt into a time.Time value
-	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawCreatedAt))
+	user.CreatedAt, err = parseDBTime(string(rawCreatedAt))
 	if err != nil {
 		return nil, err
 	}
@@ -488,7 +487,7 @@ func (s *UserStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, token
 	}
 
 	// Parse rawCreatedAt into a time.Time value
-	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(rawCreatedAt))
+	user.CreatedAt, err = parseDBTime(string(rawCreatedAt))
 	if err != nil {
 		return nil, err
 	}
@@ -511,12 +510,47 @@ func (s *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token
 }
 
 func (s *UserStore) update(ctx context.Context, tx *sql.Tx, user *User) error {
-	query := `UPDATE users SET email = ?, is_active = ?, first_name = ?, last_name = ?, manager_id = ? WHERE id = ?`
+	query := `
+		UPDATE users
+		SET email = ?, is_active = ?, first_name = ?, last_name = ?, manager_id = ?, time_zone = ?, contracted_hours = ?
+		WHERE id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	timeZone := user.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	_, err := tx.ExecContext(ctx, query, user.Email, user.IsActive, user.FirstName, user.LastName, user.ManagerID, timeZone, user.ContractedHours, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateRole sets the role of a user. A role change revokes the user's access tokens;
+// token_version is assigned before role_id so that it compares against the old role.
+func (s *UserStore) updateRole(ctx context.Context, tx *sql.Tx, userID, roleID int64) error {
+	query := `UPDATE users SET token_version = token_version + (role_id <> ?), role_id = ? WHERE id = ?`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := tx.ExecContext(ctx, query, user.Email, user.IsActive, user.FirstName, user.LastName, user.ManagerID, user.ID)
+	_, err := tx.ExecContext(ctx, query, roleID, roleID, userID)
+	return err
+}
+
+func (s *UserStore) activate(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `UPDATE users SET is_active = 1 WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
 	if err != nil {
 		return err
 	}
@@ -538,6 +572,16 @@ func (s *UserStore) updatePassword(ctx context.Context, tx *sql.Tx, user *User)
 	return nil
 }
 
+// revokeAccessTokens bumps the token version of a user so that every access token issued
+// before is rejected.
+func revokeAccessTokens(ctx context.Context, tx *sql.Tx, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `UPDATE users SET token_version = token_version + 1 WHERE id = ?`, userID)
+	return err
+}
+
 func (s *UserStore) deleteUserInvitations(ctx context.Context, tx *sql.Tx, userID int64) error {
 	query := `DELETE FROM user_invitations WHERE user_id = ?`
 
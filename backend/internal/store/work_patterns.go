@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// WorkPattern is the weekly schedule a user is expected to keep, e.g. Monday to Friday 08:30-17:00.
+// The clock times are in the user's time zone; an end time that is not after the start time ends
+// the workday on the next day. Nil grace periods fall back to the configured defaults.
+type WorkPattern struct {
+	UserID            int64          `json:"user_id"`
+	Weekdays          []time.Weekday `json:"weekdays"`   // 0 is Sunday
+	StartTime         string         `json:"start_time"` // HH:MM
+	EndTime           string         `json:"end_time"`   // HH:MM
+	LateGraceMinutes  *int           `json:"late_grace_minutes"`
+	EarlyGraceMinutes *int           `json:"early_grace_minutes"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// WorkPatternStore provides methods for managing the work patterns of users.
+type WorkPatternStore struct {
+	db *sql.DB
+}
+
+// Get godoc
+//
+//	@Summary		Retrieves the work pattern of a user
+//	@Description	Retrieves the work pattern of a user
+//	@Tags			punctuality
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	WorkPattern
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/work-patterns/{id} [get]
+func (s *WorkPatternStore) Get(ctx context.Context, userID int64) (*WorkPattern, error) {
+	query := `
+		SELECT user_id, weekdays, start_time, end_time, late_grace_minutes, early_grace_minutes, updated_at
+		FROM work_patterns
+		WHERE user_id = ?
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var pattern WorkPattern
+	var weekdays int
+	var startTime, endTime, updatedAt []byte
+	var lateGrace, earlyGrace sql.NullInt64
+
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&pattern.UserID,
+		&weekdays,
+		&startTime,
+		&endTime,
+		&lateGrace,
+		&earlyGrace,
+		&updatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	pattern.Weekdays = weekdaysFromMask(weekdays)
+	pattern.StartTime = clockTime(startTime)
+	pattern.EndTime = clockTime(endTime)
+
+	if lateGrace.Valid {
+		minutes := int(lateGrace.Int64)
+		pattern.LateGraceMinutes = &minutes
+	}
+	if earlyGrace.Valid {
+		minutes := int(earlyGrace.Int64)
+		pattern.EarlyGraceMinutes = &minutes
+	}
+
+	if pattern.UpdatedAt, err = parseDBTime(string(updatedAt)); err != nil {
+		return nil, err
+	}
+
+	return &pattern, nil
+}
+
+// Set godoc
+//
+//	@Summary		Sets the work pattern of a user
+//	@Description	Creates or replaces the work pattern of a user
+//	@Tags			punctuality
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	WorkPattern
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/work-patterns/{id} [put]
+func (s *WorkPatternStore) Set(ctx context.Context, pattern *WorkPattern) error {
+	query := `
+		INSERT INTO work_patterns (user_id, weekdays, start_time, end_time, late_grace_minutes, early_grace_minutes, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			weekdays = VALUES(weekdays),
+			start_time = VALUES(start_time),
+			end_time = VALUES(end_time),
+			late_grace_minutes = VALUES(late_grace_minutes),
+			early_grace_minutes = VALUES(early_grace_minutes),
+			updated_at = VALUES(updated_at)
+	`
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := lockUser(ctx, tx, pattern.UserID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		updatedAt := time.Now().UTC().Truncate(time.Second)
+
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			pattern.UserID,
+			weekdayMask(pattern.Weekdays),
+			pattern.StartTime,
+			pattern.EndTime,
+			pattern.LateGraceMinutes,
+			pattern.EarlyGraceMinutes,
+			updatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		pattern.Weekdays = weekdaysFromMask(weekdayMask(pattern.Weekdays))
+		pattern.UpdatedAt = updatedAt
+
+		return nil
+	})
+}
+
+// Delete godoc
+//
+//	@Summary		Deletes the work pattern of a user
+//	@Description	Deletes the work pattern of a user, who is then no longer checked for punctuality
+//	@Tags			punctuality
+//	@Produce		json
+//	@Param			id	path		int		true	"User ID"
+//	@Success		204	{string}	string	"Work pattern deleted"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/work-patterns/{id} [delete]
+func (s *WorkPatternStore) Delete(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM work_patterns WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
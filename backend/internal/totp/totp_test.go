@@ -0,0 +1,108 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890", base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The test vectors of RFC 6238 appendix B for SHA-1. The RFC lists eight digit codes; six digit
+// codes are their last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Counter(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v.code {
+			t.Errorf("at %d: got %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	got, err := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("got %s, want 287082", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// The code of 1111111109 is valid from 1111111080 to 1111111109
+	now := time.Unix(1111111109, 0)
+	step := Counter(now)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		code string
+		ok   bool
+	}{
+		{"current step", now, "081804", true},
+		{"one step late", now.Add(Period), "081804", true},
+		{"one step early", now.Add(-Period), "081804", true},
+		{"two steps late", now.Add(2 * Period), "081804", false},
+		{"two steps early", now.Add(-2 * Period), "081804", false},
+		{"wrong code", now, "081805", false},
+		{"eight digits", now, "07081804", false},
+		{"empty", now, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Validate(rfcSecret, tt.code, tt.at)
+			if ok != tt.ok {
+				t.Fatalf("got %v, want %v", ok, tt.ok)
+			}
+			if ok && counter != step {
+				t.Errorf("matched step %d, want %d", counter, step)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidSecret(t *testing.T) {
+	if _, ok := Validate("not base32!", "000000", time.Now()); ok {
+		t.Error("accepted a code of an invalid secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("got a %d byte secret, want %d", len(key), secretSize)
+	}
+}
+
+func TestURI(t *testing.T) {
+	got := URI("Time Tracker", "jane@example.com", rfcSecret)
+	want := "otpauth://totp/Time%20Tracker:jane@example.com?algorithm=SHA1&digits=6&issuer=Time%20Tracker&period=30&secret=" + rfcSecret
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}